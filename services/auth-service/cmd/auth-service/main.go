@@ -1,83 +1,98 @@
 package main
 
 import (
+	"auth-service/internal/config"
 	"auth-service/internal/handler"
-	"auth-service/internal/models"
+	"auth-service/internal/migrations"
 	"auth-service/internal/repository"
 	"auth-service/internal/service"
-	"fmt"
 	"log/slog"
-	"net/http"
+	"os"
 	"time"
-    "github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/config"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/app"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/logger"
-    "github.com/evgeniyfimushkin/event-planner/services/common/pkg/middlewarelogger"
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/httprate"
 )
 
 func main(){
-    cfg := config.MustLoadConfig()
-    log := logger.SetupLogger(cfg.Env)
+    a := app.New("auth-service")
+    cfg, log := a.Config, a.Log
+    var authCfg config.Config
+    a.MustLoadSection("auth", &authCfg)
 
-    log.Info("Connecting to db with params: ")
-    log.Info("Database: ", slog.String("host", cfg.Database.Host), slog.String("port", cfg.Database.Port))
-
-    dsn := fmt.Sprintf("user=%s password=%s dbname=%s host=%s port=%s sslmode=%s",cfg.Database.User, cfg.Database.Password, cfg.Database.Name, cfg.Database.Host, cfg.Database.Port, "disable")
-    //TODO: configure sllmode with postgres
-    dbConnection := db.SetupDB(dsn, &models.User{})
+    dbConnection := a.MustConnectDB(migrations.FS)
 
+    uow := db.NewUnitOfWork(dbConnection)
     userRepo := repository.NewUserRepository(dbConnection)
-    _ = userRepo
+    refreshTokenRepo := repository.NewRefreshTokenRepository(dbConnection)
+    orgRepo := repository.NewOrganizationRepository(dbConnection)
+    roleRepo := repository.NewRoleRepository(dbConnection)
 
-    loginService, err := service.NewLoginService(userRepo, cfg.PrivateKey, cfg.TokenTTL)
-    registerService, err := service.NewRegisterService(userRepo)
+    loginService, err := service.NewLoginService(userRepo, refreshTokenRepo, cfg.PrivateKey, cfg.TokenTTL)
+    registerService, err := service.NewRegisterService(userRepo, uow, loginService)
     if err != nil {
         log.Error("failed to init login service", logger.Err(err))
         panic("failed to init login service")
     }
 
-    refreshService, err := service.NewRefreshService(userRepo, cfg.PrivateKey, cfg.PublicKey)
+    refreshService, err := service.NewRefreshService(userRepo, refreshTokenRepo, orgRepo, roleRepo, cfg.PrivateKey, cfg.PublicKey)
     if err != nil {
         log.Error("failed to init refresh service", logger.Err(err))
         panic("failed to init refresh service")
     }
 
-    router := chi.NewRouter()
-    router.Use(middleware.RequestID)
-    router.Use(middleware.RealIP)
-    router.Use(middlewarelogger.New(log))
-    router.Use(middleware.Recoverer)
-    router.Use(middleware.URLFormat)
+    serviceTokenService, err := service.NewServiceTokenService(cfg.PrivateKey, authCfg.ServiceClients, authCfg.ServiceTokenTTL)
+    if err != nil {
+        log.Error("failed to init service token service", logger.Err(err))
+        os.Exit(1)
+    }
+
+    verifier, err := auth.NewVerifier(cfg.PublicKey)
+    if err != nil {
+        log.Error("failed to init JWT verifier", logger.Err(err))
+        os.Exit(1)
+    }
+    orgService := service.NewOrganizationService(orgRepo, userRepo)
+    adminService := service.NewAdminService(userRepo, roleRepo, refreshTokenRepo, uow)
+    missing, err := adminService.PromoteAdmins(authCfg.Admins)
+    if err != nil {
+        log.Error("failed to promote admins", logger.Err(err))
+        os.Exit(1)
+    }
+    for _, username := range missing {
+        log.Warn("admin is not registered yet, restart once they are", slog.String("username", username))
+    }
+
+    router := a.Router
     router.Post("/api/v1/auth/login", handler.Login(loginService))
     router.Get("/api/v1/auth/refresh", handler.Refresh(refreshService))
     registerLimiter := httprate.LimitByIP(5, 1*time.Minute)
     router.With(registerLimiter).Post("/api/v1/auth/register", handler.Register(registerService))
+    serviceTokenLimiter := httprate.LimitByIP(30, 1*time.Minute)
+    router.With(serviceTokenLimiter).Post("/api/v1/auth/service-token", handler.ServiceToken(serviceTokenService))
 
+    router.Post("/api/v1/organizations", handler.CreateOrganization(orgService, verifier))
+    router.Get("/api/v1/organizations", handler.MyOrganizations(orgService, verifier))
+    router.Get("/api/v1/organizations/{orgID}/members", handler.Members(orgService, verifier))
+    router.Post("/api/v1/organizations/{orgID}/members", handler.AddMember(orgService, verifier))
+    router.Put("/api/v1/organizations/{orgID}/members/{userID}", handler.SetMemberRole(orgService, verifier))
+    router.Delete("/api/v1/organizations/{orgID}/members/{userID}", handler.RemoveMember(orgService, verifier))
 
-    // TODO: Oauth
+    readUsers := verifier.RequirePermission(auth.PermUsersRead)
+    router.With(readUsers).Get("/api/v1/admin/users", handler.ListUsers(adminService))
+    router.With(readUsers).Get("/api/v1/admin/users/{userID}", handler.GetUser(adminService))
+    router.With(readUsers).Get("/api/v1/admin/roles", handler.ListRoles(adminService))
+    router.With(verifier.RequirePermission(auth.PermUsersRoles)).Put("/api/v1/admin/users/{userID}/role", handler.SetUserRole(adminService))
+    banUsers := verifier.RequirePermission(auth.PermUsersBan)
+    router.With(banUsers).Put("/api/v1/admin/users/{userID}/ban", handler.BanUser(adminService))
+    router.With(banUsers).Delete("/api/v1/admin/users/{userID}/ban", handler.UnbanUser(adminService))
 
-    srv := &http.Server{
-        Addr: fmt.Sprintf("%s:%d",cfg.Server.Addr, cfg.Server.Port),
-        Handler: router,
-        ReadTimeout: cfg.Server.ReadTimeout,
-        WriteTimeout: cfg.Server.WriteTimeout,
-        IdleTimeout: cfg.Server.IdleTimeout,
-    }
 
-    // go prometheus metrics
-    go func (){
-        http.Handle("/metrics", promhttp.Handler())
-        http.ListenAndServe(":9100", nil)
-    }()
+    // TODO: Oauth
 
-    log.Info(fmt.Sprintf("Server listening on port %d", cfg.Server.Port))
-    if err := srv.ListenAndServe(); err != nil {
-        log.Error("failed to start server", logger.Err(err))
+    if err := a.Run(); err != nil {
+        os.Exit(1)
     }
-    log.Info("server stopped")
-
 }
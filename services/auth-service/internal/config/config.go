@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+// Config is the auth-service section of the config file, under the `auth` key.
+// Shared settings like the server, database and keys are in the common config.
+type Config struct {
+    // ServiceClients are the services allowed to get a service token, as name:secret
+    // entries, the secret being the SERVICE_TOKEN_SECRET of the service
+    ServiceClients  []string      `yaml:"service_clients" envconfig:"SERVICE_CLIENTS" secret:"true"`
+    ServiceTokenTTL time.Duration `yaml:"service_token_ttl" envconfig:"SERVICE_TOKEN_TTL" default:"1h" validate:"min=1m"`
+    // Admins are the usernames given the admin role on start, the way the first
+    // administrators are made. The others get their roles through the admin API
+    Admins          []string      `yaml:"admins" envconfig:"ADMINS"`
+}
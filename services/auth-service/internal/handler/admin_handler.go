@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"auth-service/internal/repository"
+	"auth-service/internal/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+// Defaults and bounds of the pages of ListUsers
+const (
+    defaultUsersPageSize = 20
+    maxUsersPageSize = 100
+)
+
+type RoleRequest struct {
+    Role string `json:"role"`
+}
+
+type BanRequest struct {
+    Reason string `json:"reason"`
+}
+
+// ListUsers returns a page of the users, filtered by the search, role and banned query
+// parameters and paged by page and pageSize. Its route requires auth.PermUsersRead.
+func ListUsers(adminService *service.AdminService) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        q := r.URL.Query()
+        filter := repository.UserFilter{
+            Search: q.Get("search"),
+            Role: q.Get("role"),
+        }
+        if value := q.Get("banned"); value != "" {
+            banned, err := strconv.ParseBool(value)
+            if err != nil {
+                http.Error(w, "Invalid banned parameter", http.StatusBadRequest)
+                return
+            }
+            filter.Banned = &banned
+        }
+        page, ok := intParam(w, q.Get("page"), "page", 1, 1, 0)
+        if !ok {
+            return
+        }
+        pageSize, ok := intParam(w, q.Get("pageSize"), "pageSize", defaultUsersPageSize, 1, maxUsersPageSize)
+        if !ok {
+            return
+        }
+
+        users, err := adminService.Users(filter, page, pageSize)
+        if err != nil {
+            adminError(w, err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(users)
+    }
+}
+
+// GetUser returns a user by id. Its route requires auth.PermUsersRead.
+func GetUser(adminService *service.AdminService) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        id, ok := userIDParam(w, r)
+        if !ok {
+            return
+        }
+
+        user, err := adminService.User(id)
+        if err != nil {
+            adminError(w, err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(user)
+    }
+}
+
+// ListRoles returns the roles with their permissions. Its route requires auth.PermUsersRead.
+func ListRoles(adminService *service.AdminService) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        roles, err := adminService.Roles()
+        if err != nil {
+            adminError(w, err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(roles)
+    }
+}
+
+// SetUserRole gives a user another role. Its route requires auth.PermUsersRoles.
+func SetUserRole(adminService *service.AdminService) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        id, ok := userIDParam(w, r)
+        if !ok {
+            return
+        }
+
+        var req RoleRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Role == "" {
+            http.Error(w, "Invalid input", http.StatusBadRequest)
+            return
+        }
+
+        user, err := adminService.SetRole(auth.ClaimsFrom(r.Context()), id, req.Role)
+        if err != nil {
+            adminError(w, err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(user)
+    }
+}
+
+// BanUser bans a user, the body with the reason is optional. Its route requires auth.PermUsersBan.
+func BanUser(adminService *service.AdminService) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        id, ok := userIDParam(w, r)
+        if !ok {
+            return
+        }
+
+        var req BanRequest
+        if r.ContentLength != 0 {
+            if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+                http.Error(w, "Invalid input", http.StatusBadRequest)
+                return
+            }
+        }
+
+        user, err := adminService.Ban(r.Context(), auth.ClaimsFrom(r.Context()), id, req.Reason)
+        if err != nil {
+            adminError(w, err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(user)
+    }
+}
+
+// UnbanUser lifts the ban of a user. Its route requires auth.PermUsersBan.
+func UnbanUser(adminService *service.AdminService) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        id, ok := userIDParam(w, r)
+        if !ok {
+            return
+        }
+
+        user, err := adminService.Unban(id)
+        if err != nil {
+            adminError(w, err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(user)
+    }
+}
+
+func userIDParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+    id, err := strconv.Atoi(chi.URLParam(r, "userID"))
+    if err != nil || id <= 0 {
+        http.Error(w, "Invalid userID parameter", http.StatusBadRequest)
+        return 0, false
+    }
+    return id, true
+}
+
+// intParam parses an optional integer query parameter between min and max, max being
+// unbounded when zero
+func intParam(w http.ResponseWriter, value, name string, fallback, min, max int) (int, bool) {
+    if value == "" {
+        return fallback, true
+    }
+    n, err := strconv.Atoi(value)
+    if err != nil || n < min || (max > 0 && n > max) {
+        http.Error(w, "Invalid "+name+" parameter", http.StatusBadRequest)
+        return 0, false
+    }
+    return n, true
+}
+
+func adminError(w http.ResponseWriter, err error) {
+    switch {
+    case errors.Is(err, auth.ErrForbidden), errors.Is(err, service.ErrOwnAccount):
+        http.Error(w, err.Error(), http.StatusForbidden)
+    case errors.Is(err, service.ErrUserNotFound):
+        http.Error(w, err.Error(), http.StatusNotFound)
+    case errors.Is(err, service.ErrUnknownRole):
+        http.Error(w, err.Error(), http.StatusBadRequest)
+    default:
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
@@ -3,7 +3,11 @@ package handler
 import (
 	"auth-service/internal/service"
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/metrics"
 )
 
 type LoginRequest struct {
@@ -20,23 +24,34 @@ func Login(loginService *service.LoginService) http.HandlerFunc {
             return
         }
 
-        user, refreshToken, err := loginService.Login(req.Username, req.PassHash)
+        user, refreshToken, err := loginService.Login(r.Context(), req.Username, req.PassHash)
         if err != nil {
+            metrics.Logins.WithLabelValues(metrics.ResultFailed).Inc()
+            if errors.Is(err, auth.ErrBanned) {
+                http.Error(w, err.Error(), http.StatusForbidden)
+                return
+            }
             http.Error(w, err.Error(), http.StatusUnauthorized)
             return
         }
 
-        http.SetCookie(w, &http.Cookie{
-            Name: "refresh_token",
-            Value: refreshToken,
-            Path: "api/v1/auth/refresh",
-            HttpOnly: true,
-            Secure: false,
-            SameSite: http.SameSiteLaxMode,
-            MaxAge: 604800, //7days
-        })
+        setRefreshCookie(w, refreshToken)
+        metrics.Logins.WithLabelValues(metrics.ResultSucceeded).Inc()
         user.PassHash = ""
         w.WriteHeader(http.StatusOK)
         json.NewEncoder(w).Encode(user) 
     }
 }
+
+// setRefreshCookie hands the refresh token of a new session to the browser
+func setRefreshCookie(w http.ResponseWriter, refreshToken string) {
+    http.SetCookie(w, &http.Cookie{
+        Name: "refresh_token",
+        Value: refreshToken,
+        Path: "api/v1/auth/refresh",
+        HttpOnly: true,
+        Secure: false,
+        SameSite: http.SameSiteLaxMode,
+        MaxAge: 604800, //7days
+    })
+}
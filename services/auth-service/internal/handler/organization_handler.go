@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"auth-service/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type OrganizationRequest struct {
+    Name string `json:"name"`
+}
+
+type MemberRequest struct {
+    Username string `json:"username,omitempty"`
+    Role string `json:"role"`
+}
+
+// CreateOrganization creates an organization owned by the caller
+func CreateOrganization(orgService *service.OrganizationService, verifier *auth.Verifier) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        claims, ok := checkToken(w, r, verifier)
+        if !ok {
+            return
+        }
+
+        var req OrganizationRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            http.Error(w, "Invalid input", http.StatusBadRequest)
+            return
+        }
+
+        org, err := orgService.Create(claims, req.Name)
+        if err != nil {
+            organizationError(w, err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusCreated)
+        json.NewEncoder(w).Encode(org)
+    }
+}
+
+// MyOrganizations lists the organizations of the caller with their role in them
+func MyOrganizations(orgService *service.OrganizationService, verifier *auth.Verifier) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        claims, ok := checkToken(w, r, verifier)
+        if !ok {
+            return
+        }
+
+        orgs, err := orgService.Mine(claims)
+        if err != nil {
+            organizationError(w, err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(orgs)
+    }
+}
+
+// Members lists the members of the organization to one of them
+func Members(orgService *service.OrganizationService, verifier *auth.Verifier) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        claims, ok := checkToken(w, r, verifier)
+        if !ok {
+            return
+        }
+        orgID, ok := orgIDParam(w, r)
+        if !ok {
+            return
+        }
+
+        members, err := orgService.Members(claims, orgID)
+        if err != nil {
+            organizationError(w, err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(members)
+    }
+}
+
+// AddMember adds a user to the organization by username, only owners can call it
+func AddMember(orgService *service.OrganizationService, verifier *auth.Verifier) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        claims, ok := checkToken(w, r, verifier)
+        if !ok {
+            return
+        }
+        orgID, ok := orgIDParam(w, r)
+        if !ok {
+            return
+        }
+
+        var req MemberRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+            http.Error(w, "Invalid input", http.StatusBadRequest)
+            return
+        }
+
+        member, err := orgService.AddMember(claims, orgID, req.Username, req.Role)
+        if err != nil {
+            organizationError(w, err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusCreated)
+        json.NewEncoder(w).Encode(member)
+    }
+}
+
+// SetMemberRole changes the role of a member, only owners can call it
+func SetMemberRole(orgService *service.OrganizationService, verifier *auth.Verifier) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        claims, ok := checkToken(w, r, verifier)
+        if !ok {
+            return
+        }
+        orgID, ok := orgIDParam(w, r)
+        if !ok {
+            return
+        }
+        userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+        if err != nil || userID <= 0 {
+            http.Error(w, "Invalid userID parameter", http.StatusBadRequest)
+            return
+        }
+
+        var req MemberRequest
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            http.Error(w, "Invalid input", http.StatusBadRequest)
+            return
+        }
+
+        if err := orgService.SetRole(claims, orgID, userID, req.Role); err != nil {
+            organizationError(w, err)
+            return
+        }
+        w.WriteHeader(http.StatusNoContent)
+    }
+}
+
+// RemoveMember removes a member from the organization, members can remove themselves
+func RemoveMember(orgService *service.OrganizationService, verifier *auth.Verifier) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        claims, ok := checkToken(w, r, verifier)
+        if !ok {
+            return
+        }
+        orgID, ok := orgIDParam(w, r)
+        if !ok {
+            return
+        }
+        userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+        if err != nil || userID <= 0 {
+            http.Error(w, "Invalid userID parameter", http.StatusBadRequest)
+            return
+        }
+
+        if err := orgService.RemoveMember(claims, orgID, userID); err != nil {
+            organizationError(w, err)
+            return
+        }
+        w.WriteHeader(http.StatusNoContent)
+    }
+}
+
+// checkToken verifies the access_token cookie, answering Unauthorized when it isn't valid
+func checkToken(w http.ResponseWriter, r *http.Request, verifier *auth.Verifier) (jwt.MapClaims, bool) {
+    cookie, err := r.Cookie("access_token")
+    if err != nil {
+        http.Error(w, fmt.Sprintf("Unauthorized: %v", auth.ErrMissingToken), http.StatusUnauthorized)
+        return nil, false
+    }
+    claims, err := verifier.VerifyJWTToken(cookie.Value)
+    if err != nil {
+        http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+        return nil, false
+    }
+    return claims, true
+}
+
+func orgIDParam(w http.ResponseWriter, r *http.Request) (uint, bool) {
+    orgID, err := strconv.ParseUint(chi.URLParam(r, "orgID"), 10, 0)
+    if err != nil || orgID == 0 {
+        http.Error(w, "Invalid orgID parameter", http.StatusBadRequest)
+        return 0, false
+    }
+    return uint(orgID), true
+}
+
+func organizationError(w http.ResponseWriter, err error) {
+    switch {
+    case errors.Is(err, auth.ErrForbidden):
+        http.Error(w, err.Error(), http.StatusForbidden)
+    case errors.Is(err, service.ErrOrganizationNotFound), errors.Is(err, service.ErrUserNotFound), errors.Is(err, service.ErrNotMember):
+        http.Error(w, err.Error(), http.StatusNotFound)
+    case errors.Is(err, service.ErrAlreadyMember), errors.Is(err, service.ErrLastOwner):
+        http.Error(w, err.Error(), http.StatusConflict)
+    case errors.Is(err, service.ErrInvalidOrgRole), errors.Is(err, service.ErrEmptyOrgName):
+        http.Error(w, err.Error(), http.StatusBadRequest)
+    default:
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
@@ -2,7 +2,10 @@ package handler
 
 import (
 	"auth-service/internal/service"
+	"errors"
 	"net/http"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
 )
 
 
@@ -16,6 +19,10 @@ func Refresh(refreshService *service.RefreshService) http.HandlerFunc {
 
         accessToken, err := refreshService.Refresh(cookie.Value)
         if err != nil {
+            if errors.Is(err, auth.ErrBanned) {
+                http.Error(w, err.Error(), http.StatusForbidden)
+                return
+            }
             http.Error(w, err.Error(), http.StatusUnauthorized)
             return
         }
@@ -24,7 +24,7 @@ func Register(registerService *service.RegisterService) http.HandlerFunc {
             return
         }
 
-        user, err := registerService.Register(req.Username, req.Email, req.PassHash)
+        user, refreshToken, err := registerService.Register(r.Context(), req.Username, req.Email, req.PassHash)
         if err != nil {
             var pgErr *pgconn.PgError
             if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -35,6 +35,7 @@ func Register(registerService *service.RegisterService) http.HandlerFunc {
             return
         }
 
+        setRefreshCookie(w, refreshToken)
         user.PassHash = ""
         w.WriteHeader(http.StatusCreated)
         json.NewEncoder(w).Encode(user)
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"auth-service/internal/service"
+	"encoding/json"
+	"net/http"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/grpcauth"
+)
+
+// ServiceToken issues a service token to a service authenticated with basic auth
+// by its name and SERVICE_TOKEN_SECRET
+func ServiceToken(serviceTokenService *service.ServiceTokenService) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        name, secret, ok := r.BasicAuth()
+        if !ok {
+            w.Header().Set("WWW-Authenticate", `Basic realm="services"`)
+            http.Error(w, "service credentials are missing", http.StatusUnauthorized)
+            return
+        }
+
+        token, ttl, err := serviceTokenService.Issue(name, secret)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusUnauthorized)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        w.Header().Set("Cache-Control", "no-store")
+        json.NewEncoder(w).Encode(grpcauth.TokenResponse{
+            AccessToken: token,
+            TokenType: "Bearer",
+            ExpiresIn: int(ttl.Seconds()),
+        })
+    }
+}
@@ -0,0 +1,10 @@
+-- the schema AutoMigrate created, existing databases are left as they are
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    username text NOT NULL CONSTRAINT uni_users_username UNIQUE,
+    email text CONSTRAINT uni_users_email UNIQUE,
+    pass_hash text NOT NULL,
+    role text NOT NULL,
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
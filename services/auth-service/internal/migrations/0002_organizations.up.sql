@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS organizations (
+    id bigserial PRIMARY KEY,
+    name varchar(255) NOT NULL,
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS memberships (
+    organization_id bigint NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role varchar(50) NOT NULL CONSTRAINT chk_memberships_role CHECK (role IN ('owner', 'organizer', 'viewer')),
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_memberships_user_id ON memberships (user_id);
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS roles (
+    name varchar(50) PRIMARY KEY,
+    description text NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role varchar(50) NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    permission varchar(100) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+INSERT INTO roles (name, description) VALUES
+    ('user', 'creates events, registers for them and chats'),
+    ('organizer', 'also imports events in bulk'),
+    ('moderator', 'also manages the events of everyone and bans users'),
+    ('admin', 'has every permission, including changing the roles of users')
+ON CONFLICT (name) DO NOTHING;
+INSERT INTO role_permissions (role, permission) VALUES
+    ('organizer', 'events:bulk'),
+    ('moderator', 'events:bulk'),
+    ('moderator', 'events:update:any'),
+    ('moderator', 'events:delete:any'),
+    ('moderator', 'users:read'),
+    ('moderator', 'users:ban'),
+    ('admin', '*')
+ON CONFLICT DO NOTHING;
+
+UPDATE users SET role = 'user' WHERE role NOT IN (SELECT name FROM roles);
+ALTER TABLE users ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES roles (name);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS banned_at timestamptz;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS ban_reason text;
+CREATE INDEX IF NOT EXISTS idx_users_banned_at ON users (banned_at);
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Organization is a club or a team whose members organize events together
+type Organization struct {
+    ID              uint      `gorm:"primaryKey" json:"id"`
+    Name            string    `gorm:"type:varchar(255);not null" json:"name"`
+    CreatedAt       time.Time `gorm:"autoCreateTime;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// Membership is the role of a user in an organization, one of the auth.Org* roles
+type Membership struct {
+    OrganizationID  uint      `gorm:"primaryKey" json:"organization_id"`
+    UserID          int       `gorm:"primaryKey;index" json:"user_id"`
+    Role            string    `gorm:"type:varchar(50);not null" json:"role"`
+    CreatedAt       time.Time `gorm:"autoCreateTime;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// Member is a member of an organization as listed to the others
+type Member struct {
+    UserID          int       `json:"user_id"`
+    Username        string    `json:"username"`
+    Role            string    `json:"role"`
+}
+
+// UserOrganization is an organization of a user with their role in it
+type UserOrganization struct {
+    ID              uint      `json:"id"`
+    Name            string    `json:"name"`
+    Role            string    `json:"role"`
+}
+
+// JSON EXAMPLE
+
+// {
+//     "name": "Novosibirsk basketball club"
+// }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// RefreshToken is a refresh token issued to a user, told apart by the jti claim of the
+// token. Refreshing takes a token neither expired nor revoked, so revoking the tokens
+// of a user ends their sessions.
+type RefreshToken struct {
+    ID        int        `gorm:"primaryKey" json:"id"`
+    JTI       string     `gorm:"column:jti;type:varchar(64);uniqueIndex;not null" json:"-"`
+    UserID    int        `gorm:"index;not null" json:"user_id"`
+    ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+    RevokedAt *time.Time `json:"revoked_at,omitempty"`
+    CreatedAt time.Time  `gorm:"autoCreateTime;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
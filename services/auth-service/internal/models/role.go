@@ -0,0 +1,14 @@
+package models
+
+// Role is a role of the users, one of the auth.Role* roles, with the permissions it grants
+type Role struct {
+    Name            string    `gorm:"primaryKey;type:varchar(50)" json:"name"`
+    Description     string    `gorm:"type:text;not null;default:''" json:"description"`
+    Permissions     []string  `gorm:"-" json:"permissions"`
+}
+
+// RolePermission is a permission granted by a role, see the auth.Perm* permissions
+type RolePermission struct {
+    Role            string    `gorm:"primaryKey;type:varchar(50)" json:"role"`
+    Permission      string    `gorm:"primaryKey;type:varchar(100)" json:"permission"`
+}
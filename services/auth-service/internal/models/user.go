@@ -8,6 +8,10 @@ type User struct {
 	Email           string `gorm:"unique" json:"email"`
 	PassHash        string `gorm:"not null" json:"passhash"`
     Role            string `gorm:"not null" json:"role"`
+    // BannedAt is when an administrator banned the user, who can't log in since. Nil
+    // for the users in good standing
+    BannedAt        *time.Time `gorm:"index" json:"banned_at,omitempty"`
+    BanReason       string    `gorm:"type:text" json:"ban_reason,omitempty"`
     CreatedAt       time.Time `gorm:"autoCreateTime;default:CURRENT_TIMESTAMP" json:"created_at"`
     UpdatedAt       time.Time `gorm:"autoUpdateTime;default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
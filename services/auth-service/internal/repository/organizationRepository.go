@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"fmt"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+	"gorm.io/gorm"
+)
+
+// Fields of Membership for the typed queries
+var (
+    MembershipOrganizationID = repository.FieldOf[models.Membership, uint]("OrganizationID")
+    MembershipUserID         = repository.FieldOf[models.Membership, int]("UserID")
+    MembershipRole           = repository.FieldOf[models.Membership, string]("Role")
+)
+
+// MembershipOf is the query of the membership of the user in the organization
+func MembershipOf(orgID uint, userID int) repository.Query[models.Membership] {
+    return repository.Where(MembershipOrganizationID.Eq(orgID), MembershipUserID.Eq(userID))
+}
+
+// OrganizationRepository stores the organizations and the memberships of their users
+type OrganizationRepository struct {
+    *repository.GenericRepository[models.Organization]
+    Memberships *repository.GenericRepository[models.Membership]
+}
+
+func NewOrganizationRepository(db *gorm.DB) *OrganizationRepository {
+    return &OrganizationRepository{
+        GenericRepository: repository.NewGenericRepository[models.Organization](db),
+        Memberships:       repository.NewGenericRepository[models.Membership](db),
+    }
+}
+
+// CreateWithOwner creates the organization with the user as its owner
+func (repo *OrganizationRepository) CreateWithOwner(org *models.Organization, ownerID int) error {
+    return repo.Db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(org).Error; err != nil {
+            return fmt.Errorf("%w: %v", repository.ErrCreateEntity, err)
+        }
+        owner := &models.Membership{OrganizationID: org.ID, UserID: ownerID, Role: auth.OrgOwner}
+        if err := tx.Create(owner).Error; err != nil {
+            return fmt.Errorf("%w: %v", repository.ErrCreateEntity, err)
+        }
+        return nil
+    })
+}
+
+// OrganizationsOf returns the organizations of the user with their role in them
+func (repo *OrganizationRepository) OrganizationsOf(userID int) ([]models.UserOrganization, error) {
+    var orgs []models.UserOrganization
+    err := repo.Db.Table("memberships").
+        Select("organizations.id, organizations.name, memberships.role").
+        Joins("JOIN organizations ON organizations.id = memberships.organization_id").
+        Where("memberships.user_id = ?", userID).
+        Order("organizations.id").
+        Scan(&orgs).Error
+    if err != nil {
+        return nil, fmt.Errorf("%w: %v", repository.ErrFindEntities, err)
+    }
+    return orgs, nil
+}
+
+// Members returns the members of the organization in the order they joined it
+func (repo *OrganizationRepository) Members(orgID uint) ([]models.Member, error) {
+    var members []models.Member
+    err := repo.Db.Table("memberships").
+        Select("memberships.user_id, users.username, memberships.role").
+        Joins("JOIN users ON users.id = memberships.user_id").
+        Where("memberships.organization_id = ?", orgID).
+        Order("memberships.created_at, memberships.user_id").
+        Scan(&members).Error
+    if err != nil {
+        return nil, fmt.Errorf("%w: %v", repository.ErrFindEntities, err)
+    }
+    return members, nil
+}
+
+// Roles returns the roles of the user in their organizations by organization ID, as
+// put in auth.OrgsClaim
+func (repo *OrganizationRepository) Roles(userID int) (map[string]string, error) {
+    memberships, err := repo.Memberships.FindBy(repository.Where(MembershipUserID.Eq(userID)))
+    if err != nil {
+        return nil, err
+    }
+    roles := make(map[string]string, len(memberships))
+    for _, membership := range memberships {
+        roles[fmt.Sprint(membership.OrganizationID)] = membership.Role
+    }
+    return roles, nil
+}
+
+// CountOwners returns the number of owners of the organization
+func (repo *OrganizationRepository) CountOwners(orgID uint) (int64, error) {
+    return repo.Memberships.CountBy(repository.Where(MembershipOrganizationID.Eq(orgID), MembershipRole.Eq(auth.OrgOwner)))
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"context"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+	"gorm.io/gorm"
+)
+
+// Fields of RefreshToken for the typed queries
+var (
+    RefreshTokenJTI       = repository.FieldOf[models.RefreshToken, string]("JTI")
+    RefreshTokenUserID    = repository.FieldOf[models.RefreshToken, int]("UserID")
+    RefreshTokenExpiresAt = repository.FieldOf[models.RefreshToken, time.Time]("ExpiresAt")
+    RefreshTokenRevokedAt = repository.FieldOf[models.RefreshToken, time.Time]("RevokedAt")
+)
+
+// RefreshTokenRepository stores the refresh tokens issued to the users
+type RefreshTokenRepository struct {
+    *repository.GenericRepository[models.RefreshToken]
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+    return &RefreshTokenRepository{GenericRepository: repository.NewGenericRepository[models.RefreshToken](db)}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx, in the
+// unit of work in ctx if there is one
+func (r *RefreshTokenRepository) WithContext(ctx context.Context) *RefreshTokenRepository {
+    return &RefreshTokenRepository{GenericRepository: r.GenericRepository.WithContext(ctx)}
+}
+
+// Active returns the token with the jti unless it expired or was revoked,
+// gorm.ErrRecordNotFound otherwise
+func (r *RefreshTokenRepository) Active(jti string) (*models.RefreshToken, error) {
+    return r.FirstBy(repository.Where(
+        RefreshTokenJTI.Eq(jti),
+        RefreshTokenRevokedAt.IsNull(),
+        RefreshTokenExpiresAt.Gt(time.Now()),
+    ))
+}
+
+// RevokeOfUser revokes the tokens of the user not revoked yet
+func (r *RefreshTokenRepository) RevokeOfUser(userID int) error {
+    return r.UpdateBy(
+        repository.Where(RefreshTokenUserID.Eq(userID), RefreshTokenRevokedAt.IsNull()),
+        map[string]interface{}{"revoked_at": time.Now()},
+    )
+}
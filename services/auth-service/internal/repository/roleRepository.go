@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+	"gorm.io/gorm"
+)
+
+// Fields of Role and RolePermission for the typed queries
+var (
+    RoleName                 = repository.FieldOf[models.Role, string]("Name")
+    RolePermissionRole       = repository.FieldOf[models.RolePermission, string]("Role")
+    RolePermissionPermission = repository.FieldOf[models.RolePermission, string]("Permission")
+)
+
+// RoleRepository stores the roles of the users and the permissions they grant
+type RoleRepository struct {
+    *repository.GenericRepository[models.Role]
+    Permissions *repository.GenericRepository[models.RolePermission]
+}
+
+func NewRoleRepository(db *gorm.DB) *RoleRepository {
+    return &RoleRepository{
+        GenericRepository: repository.NewGenericRepository[models.Role](db),
+        Permissions:       repository.NewGenericRepository[models.RolePermission](db),
+    }
+}
+
+// PermissionsOf returns the permissions granted by the role, as put in auth.PermissionsClaim
+func (repo *RoleRepository) PermissionsOf(role string) ([]string, error) {
+    query := repository.Where(RolePermissionRole.Eq(role)).OrderBy(RolePermissionPermission.Asc())
+    return repository.Pluck(repo.Permissions, query, RolePermissionPermission)
+}
+
+// Exists tells whether the role is stored
+func (repo *RoleRepository) Exists(role string) (bool, error) {
+    return repo.ExistsBy(repository.Where(RoleName.Eq(role)))
+}
+
+// All returns the roles with their permissions
+func (repo *RoleRepository) All() ([]models.Role, error) {
+    roles, err := repo.FindBy(repository.Query[models.Role]{})
+    if err != nil {
+        return nil, err
+    }
+    for i := range roles {
+        if roles[i].Permissions, err = repo.PermissionsOf(roles[i].Name); err != nil {
+            return nil, err
+        }
+    }
+    return roles, nil
+}
@@ -2,10 +2,30 @@ package repository
 
 import (
 	"auth-service/internal/models"
+	"context"
 	"fmt"
+	"strings"
+	"time"
+
     "gorm.io/gorm"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
 )
+
+// Fields of User for the typed queries
+var (
+    UserID       = repository.FieldOf[models.User, int]("ID")
+    UserRole     = repository.FieldOf[models.User, string]("Role")
+    UserBannedAt = repository.FieldOf[models.User, time.Time]("BannedAt")
+)
+
+// UserFilter selects the users returned by Search, zero fields match every user
+type UserFilter struct {
+    // Search matches the username or the email, ignoring the case
+    Search string
+    Role   string
+    Banned *bool
+}
+
 type UserRepository struct {
 	*repository.GenericRepository[models.User]
 }
@@ -14,6 +34,12 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
     return &UserRepository{GenericRepository: repository.NewGenericRepository[models.User](db)}
 }
 
+// WithContext returns a copy of the repository whose queries run with ctx, in the
+// unit of work in ctx if there is one
+func (r *UserRepository) WithContext(ctx context.Context) *UserRepository {
+    return &UserRepository{GenericRepository: r.GenericRepository.WithContext(ctx)}
+}
+
 func (repo *UserRepository) GetByEmail(email string) (*models.User, error) {
 	var user models.User
 	result := repo.Db.Where("email = ?", email).First(&user)
@@ -30,3 +56,39 @@ func (r *UserRepository) GetUserByUsername(username string) (*models.User, error
 	}
 	return &user, nil
 }
+
+// Search returns a page of the users matching the filter in id order with the count of
+// all the matching ones
+func (r *UserRepository) Search(filter UserFilter, page, pageSize int) ([]models.User, int64, error) {
+    var specs []repository.Spec[models.User]
+    if search := strings.ToLower(strings.TrimSpace(filter.Search)); search != "" {
+        pattern := "%" + search + "%"
+        specs = append(specs, repository.Raw[models.User]("LOWER(username) LIKE ? OR LOWER(email) LIKE ?", pattern, pattern))
+    }
+    if filter.Role != "" {
+        specs = append(specs, UserRole.Eq(filter.Role))
+    }
+    if filter.Banned != nil {
+        if *filter.Banned {
+            specs = append(specs, UserBannedAt.NotNull())
+        } else {
+            specs = append(specs, UserBannedAt.IsNull())
+        }
+    }
+    query := repository.Where(specs...)
+
+    total, err := r.CountBy(query)
+    if err != nil {
+        return nil, 0, err
+    }
+    users, err := r.FindBy(query.OrderBy(UserID.Asc()).Page(page, pageSize))
+    if err != nil {
+        return nil, 0, err
+    }
+    return users, total, nil
+}
+
+// UpdateFields updates the columns of the user with the id, zero values included
+func (r *UserRepository) UpdateFields(id int, fields map[string]interface{}) error {
+    return r.UpdateBy(repository.Where(UserID.Eq(id)), fields)
+}
@@ -27,7 +27,7 @@ func TestUserRepository_CRUD(t *testing.T) {
 		Username: "testuser",
 	}
 
-	err := repo.Create(user)
+	_, err := repo.Create(user)
 	assert.NoError(t, err)
 
 	retrievedUser, err := repo.GetByID(user.ID)
@@ -37,7 +37,7 @@ func TestUserRepository_CRUD(t *testing.T) {
 	assert.Equal(t, user.Username, retrievedUser.Username)
 
 	user.Username = "updateduser"
-	err = repo.Update(user)
+	_, err = repo.Update(user)
 	assert.NoError(t, err)
 
 	updatedUser, err := repo.GetByID(user.ID)
@@ -60,7 +60,7 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 		Email:    "user@example.com",
 		Username: "user1",
 	}
-	err := repo.Create(user)
+	_, err := repo.Create(user)
 	assert.NoError(t, err)
 
 	retrievedUser, err := repo.GetByEmail("user@example.com")
@@ -77,7 +77,7 @@ func TestUserRepository_GetUserByUsername(t *testing.T) {
 		Email:    "test2@example.com",
 		Username: "user2",
 	}
-	err := repo.Create(user)
+	_, err := repo.Create(user)
 	assert.NoError(t, err)
 
 	retrievedUser, err := repo.GetUserByUsername("user2")
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var (
+    ErrUnknownRole = errors.New("unknown role")
+    ErrOwnAccount  = errors.New("users can't change their own role or ban themselves")
+)
+
+// UserPage is a page of the users found by AdminService.Users
+type UserPage struct {
+    Users []models.User `json:"users"`
+    Total int64         `json:"total"`
+}
+
+// AdminService lets moderators and administrators manage the users. The permissions are
+// checked by the routes, see auth.Verifier.RequirePermission. Changes reach the access
+// tokens of the users on their next refresh, a banned user can't refresh at all.
+type AdminService struct {
+    users  *repository.UserRepository
+    roles  *repository.RoleRepository
+    tokens *repository.RefreshTokenRepository
+    uow    *db.UnitOfWork
+}
+
+func NewAdminService(users *repository.UserRepository, roles *repository.RoleRepository, tokens *repository.RefreshTokenRepository, uow *db.UnitOfWork) *AdminService {
+    return &AdminService{
+        users:  users,
+        roles:  roles,
+        tokens: tokens,
+        uow:    uow,
+    }
+}
+
+// Users returns a page of the users matching the filter
+func (s *AdminService) Users(filter repository.UserFilter, page, pageSize int) (*UserPage, error) {
+    users, total, err := s.users.Search(filter, page, pageSize)
+    if err != nil {
+        return nil, err
+    }
+    for i := range users {
+        users[i].PassHash = ""
+    }
+    return &UserPage{Users: users, Total: total}, nil
+}
+
+// User returns the user with the id
+func (s *AdminService) User(id int) (*models.User, error) {
+    user, err := s.user(id)
+    if err != nil {
+        return nil, err
+    }
+    user.PassHash = ""
+    return user, nil
+}
+
+// Roles returns the roles with the permissions they grant
+func (s *AdminService) Roles() ([]models.Role, error) {
+    return s.roles.All()
+}
+
+// SetRole gives the user another role
+func (s *AdminService) SetRole(claims jwt.MapClaims, id int, role string) (*models.User, error) {
+    exists, err := s.roles.Exists(role)
+    if err != nil {
+        return nil, err
+    }
+    if !exists {
+        return nil, fmt.Errorf("%w: %q", ErrUnknownRole, role)
+    }
+    // an administrator demoting themselves could leave no one to undo it
+    if err := notSelf(claims, id); err != nil {
+        return nil, err
+    }
+    user, err := s.user(id)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.users.UpdateFields(id, map[string]interface{}{"role": role}); err != nil {
+        return nil, err
+    }
+    user.Role = role
+    user.PassHash = ""
+    return user, nil
+}
+
+// Ban keeps the user from logging in and refreshing their access token, their refresh
+// tokens are revoked along with the ban. Administrators can't be banned, they must be
+// given another role first.
+func (s *AdminService) Ban(ctx context.Context, claims jwt.MapClaims, id int, reason string) (*models.User, error) {
+    if err := notSelf(claims, id); err != nil {
+        return nil, err
+    }
+    user, err := s.user(id)
+    if err != nil {
+        return nil, err
+    }
+    if user.Role == auth.RoleAdmin {
+        return nil, fmt.Errorf("%w: administrators can't be banned", auth.ErrForbidden)
+    }
+    now := time.Now()
+    reason = strings.TrimSpace(reason)
+    err = s.uow.Do(ctx, func(ctx context.Context) error {
+        if err := s.users.WithContext(ctx).UpdateFields(id, map[string]interface{}{"banned_at": now, "ban_reason": reason}); err != nil {
+            return err
+        }
+        return s.tokens.WithContext(ctx).RevokeOfUser(id)
+    })
+    if err != nil {
+        return nil, err
+    }
+    user.BannedAt = &now
+    user.BanReason = reason
+    user.PassHash = ""
+    return user, nil
+}
+
+// Unban lets a banned user log in again
+func (s *AdminService) Unban(id int) (*models.User, error) {
+    user, err := s.user(id)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.users.UpdateFields(id, map[string]interface{}{"banned_at": nil, "ban_reason": ""}); err != nil {
+        return nil, err
+    }
+    user.BannedAt = nil
+    user.BanReason = ""
+    user.PassHash = ""
+    return user, nil
+}
+
+// PromoteAdmins gives the admin role to the users, the way the first administrators
+// are made. Usernames of no user are returned, they may register later.
+func (s *AdminService) PromoteAdmins(usernames []string) ([]string, error) {
+    var missing []string
+    for _, username := range usernames {
+        user, err := s.users.GetUserByUsername(username)
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            missing = append(missing, username)
+            continue
+        }
+        if err != nil {
+            return nil, err
+        }
+        if user.Role == auth.RoleAdmin {
+            continue
+        }
+        if err := s.users.UpdateFields(user.ID, map[string]interface{}{"role": auth.RoleAdmin}); err != nil {
+            return nil, err
+        }
+    }
+    return missing, nil
+}
+
+func (s *AdminService) user(id int) (*models.User, error) {
+    user, err := s.users.GetByID(id)
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrUserNotFound
+    }
+    return user, err
+}
+
+func notSelf(claims jwt.MapClaims, id int) error {
+    callerID, err := userIDOf(claims)
+    if err != nil {
+        return err
+    }
+    if callerID == id {
+        return ErrOwnAccount
+    }
+    return nil
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAdmin(t *testing.T) (*AdminService, *repository.RoleRepository, []models.User) {
+	conn, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, conn.AutoMigrate(&models.User{}, &models.Role{}, &models.RolePermission{}, &models.RefreshToken{}))
+	// the roles seeded by the migrations
+	require.NoError(t, conn.Create(&[]models.Role{{Name: auth.RoleUser}, {Name: auth.RoleModerator}, {Name: auth.RoleAdmin}}).Error)
+	require.NoError(t, conn.Create(&[]models.RolePermission{
+		{Role: auth.RoleModerator, Permission: auth.PermUsersRead},
+		{Role: auth.RoleModerator, Permission: auth.PermUsersBan},
+		{Role: auth.RoleAdmin, Permission: "*"},
+	}).Error)
+	users := []models.User{
+		{Username: "anna", Email: "anna@example.com", PassHash: "x", Role: auth.RoleUser},
+		{Username: "Boris", Email: "boris@mail.ru", PassHash: "x", Role: auth.RoleUser},
+		{Username: "vera", Email: "vera@example.com", PassHash: "x", Role: auth.RoleUser},
+	}
+	require.NoError(t, conn.Create(&users).Error)
+	roles := repository.NewRoleRepository(conn)
+	return NewAdminService(repository.NewUserRepository(conn), roles, repository.NewRefreshTokenRepository(conn), db.NewUnitOfWork(conn)), roles, users
+}
+
+func TestAdminService_RolesAndSearch(t *testing.T) {
+	s, roles, users := setupAdmin(t)
+
+	missing, err := s.PromoteAdmins([]string{"anna", "nobody"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nobody"}, missing)
+	anna := claimsOf(users[0])
+
+	_, err = s.SetRole(anna, users[1].ID, "superuser")
+	assert.ErrorIs(t, err, ErrUnknownRole)
+	_, err = s.SetRole(anna, users[0].ID, auth.RoleUser)
+	assert.ErrorIs(t, err, ErrOwnAccount)
+	boris, err := s.SetRole(anna, users[1].ID, auth.RoleModerator)
+	require.NoError(t, err)
+	assert.Equal(t, auth.RoleModerator, boris.Role)
+	assert.Empty(t, boris.PassHash)
+
+	permissions, err := roles.PermissionsOf(auth.RoleModerator)
+	require.NoError(t, err)
+	assert.Equal(t, []string{auth.PermUsersBan, auth.PermUsersRead}, permissions)
+
+	page, err := s.Users(repository.UserFilter{Search: "BOR"}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, "Boris", page.Users[0].Username)
+
+	page, err = s.Users(repository.UserFilter{Role: auth.RoleUser}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, "vera", page.Users[0].Username)
+
+	page, err = s.Users(repository.UserFilter{}, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), page.Total)
+	assert.Len(t, page.Users, 1)
+}
+
+func TestAdminService_Ban(t *testing.T) {
+	s, _, users := setupAdmin(t)
+	_, err := s.PromoteAdmins([]string{"anna"})
+	require.NoError(t, err)
+	boris := claimsOf(users[1])
+
+	_, err = s.tokens.Create(&models.RefreshToken{JTI: "vera", UserID: users[2].ID, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+
+	_, err = s.Ban(context.Background(), boris, users[0].ID, "spam")
+	assert.ErrorIs(t, err, auth.ErrForbidden, "administrators can't be banned")
+	_, err = s.Ban(context.Background(), boris, users[1].ID, "spam")
+	assert.ErrorIs(t, err, ErrOwnAccount)
+
+	vera, err := s.Ban(context.Background(), boris, users[2].ID, " spam ")
+	require.NoError(t, err)
+	assert.NotNil(t, vera.BannedAt)
+	assert.Equal(t, "spam", vera.BanReason)
+	_, err = s.tokens.Active("vera")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "the refresh tokens are revoked with the ban")
+
+	banned := true
+	page, err := s.Users(repository.UserFilter{Banned: &banned}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, "vera", page.Users[0].Username)
+
+	_, err = s.Unban(users[2].ID)
+	require.NoError(t, err)
+	stored, err := s.User(users[2].ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.BannedAt)
+
+	_, err = s.User(42)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
@@ -3,7 +3,9 @@ package service
 import (
 	"auth-service/internal/models"
 	"auth-service/internal/repository"
+	"context"
 	"crypto/ecdsa"
+	"crypto/rand"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
@@ -11,16 +13,21 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// refreshTokenTTL is how long a refresh token and the session it keeps last
+const refreshTokenTTL = 7 * 24 * time.Hour
+
 type LoginService struct {
     userRepo *repository.UserRepository
+    tokens *repository.RefreshTokenRepository
     privateKey *ecdsa.PrivateKey
     tokenTTL time.Duration
 }
 
-func NewLoginService(userRepo *repository.UserRepository, secret string, tokenTTL time.Duration) (*LoginService, error) {
+func NewLoginService(userRepo *repository.UserRepository, tokens *repository.RefreshTokenRepository, secret string, tokenTTL time.Duration) (*LoginService, error) {
 	keyBytes, err := base64.StdEncoding.DecodeString(secret)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode secret: %w", err)
@@ -37,13 +44,14 @@ func NewLoginService(userRepo *repository.UserRepository, secret string, tokenTT
 
 	return &LoginService{
 		userRepo:  userRepo,
+		tokens:    tokens,
 		privateKey: privateKey,
         tokenTTL: tokenTTL,
 	}, nil
 }
 
-func (s *LoginService) Login(username, passhash string) (*models.User, string, error) {
-	user, err := s.userRepo.GetUserByUsername(username)
+func (s *LoginService) Login(ctx context.Context, username, passhash string) (*models.User, string, error) {
+	user, err := s.userRepo.WithContext(ctx).GetUserByUsername(username)
 	if err != nil {
 		return  nil, "", fmt.Errorf("User not found")
 	}
@@ -52,7 +60,11 @@ func (s *LoginService) Login(username, passhash string) (*models.User, string, e
 		return  nil, "", fmt.Errorf("Incorrect password")
 	}
 
-	refreshToken, err := s.generateRefreshJWT(user, 7 * 24 * time.Hour)
+	if user.BannedAt != nil {
+		return  nil, "", auth.ErrBanned
+	}
+
+	refreshToken, err := s.IssueRefreshToken(ctx, user)
 	if err != nil {
 		return  nil, "", fmt.Errorf("Failed to generate token")
 	}
@@ -60,11 +72,30 @@ func (s *LoginService) Login(username, passhash string) (*models.User, string, e
 	return user, refreshToken, nil
 }
 
-func (s *LoginService) generateRefreshJWT(user *models.User, tokenTTL time.Duration)(string, error) {
+// IssueRefreshToken stores a new refresh token of the user, in the unit of work in ctx
+// if there is one, and returns it signed.
+func (s *LoginService) IssueRefreshToken(ctx context.Context, user *models.User) (string, error) {
+    secret := make([]byte, 16)
+    if _, err := rand.Read(secret); err != nil {
+        return "", fmt.Errorf("failed to generate token id: %w", err)
+    }
+    stored, err := s.tokens.WithContext(ctx).Create(&models.RefreshToken{
+        JTI:       base64.RawURLEncoding.EncodeToString(secret),
+        UserID:    user.ID,
+        ExpiresAt: time.Now().Add(refreshTokenTTL),
+    })
+    if err != nil {
+        return "", err
+    }
+    return s.generateRefreshJWT(user, stored)
+}
+
+func (s *LoginService) generateRefreshJWT(user *models.User, stored *models.RefreshToken)(string, error) {
     token := jwt.New(jwt.SigningMethodES256)
     claims := token.Claims.(jwt.MapClaims)
     claims["userID"] = user.ID
-    claims["exp"] = time.Now().Add(tokenTTL).Unix()
+    claims["jti"] = stored.JTI
+    claims["exp"] = stored.ExpiresAt.Unix()
     tokenString, err := token.SignedString(s.privateKey)
     if err != nil {
         return "", err
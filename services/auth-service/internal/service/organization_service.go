@@ -0,0 +1,207 @@
+package service
+
+import (
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var (
+    ErrOrganizationNotFound = errors.New("organization not found")
+    ErrEmptyOrgName         = errors.New("name must not be empty")
+    ErrUserNotFound         = errors.New("user not found")
+    ErrNotMember            = errors.New("user is not a member of the organization")
+    ErrAlreadyMember        = errors.New("user is already a member of the organization")
+    ErrInvalidOrgRole       = errors.New("role must be owner, organizer or viewer")
+    ErrLastOwner            = errors.New("the organization must keep an owner")
+)
+
+// OrganizationService manages the organizations and their members. Any member sees the
+// members of an organization, only owners manage them. Changes reach the access tokens
+// of the members on their next refresh.
+type OrganizationService struct {
+    orgs  *repository.OrganizationRepository
+    users *repository.UserRepository
+}
+
+func NewOrganizationService(orgs *repository.OrganizationRepository, users *repository.UserRepository) *OrganizationService {
+    return &OrganizationService{
+        orgs:  orgs,
+        users: users,
+    }
+}
+
+// Create creates an organization owned by the user of the token
+func (s *OrganizationService) Create(claims jwt.MapClaims, name string) (*models.Organization, error) {
+    userID, err := userIDOf(claims)
+    if err != nil {
+        return nil, err
+    }
+    name = strings.TrimSpace(name)
+    if name == "" {
+        return nil, ErrEmptyOrgName
+    }
+    org := &models.Organization{Name: name}
+    if err := s.orgs.CreateWithOwner(org, userID); err != nil {
+        return nil, err
+    }
+    return org, nil
+}
+
+// Mine returns the organizations of the user of the token
+func (s *OrganizationService) Mine(claims jwt.MapClaims) ([]models.UserOrganization, error) {
+    userID, err := userIDOf(claims)
+    if err != nil {
+        return nil, err
+    }
+    return s.orgs.OrganizationsOf(userID)
+}
+
+// Members returns the members of the organization to one of them
+func (s *OrganizationService) Members(claims jwt.MapClaims, orgID uint) ([]models.Member, error) {
+    if _, err := s.roleOf(claims, orgID); err != nil {
+        return nil, err
+    }
+    return s.orgs.Members(orgID)
+}
+
+// AddMember adds the user with the role to the organization, the user of the token must own it
+func (s *OrganizationService) AddMember(claims jwt.MapClaims, orgID uint, username, role string) (*models.Member, error) {
+    if !auth.IsOrgRole(role) {
+        return nil, ErrInvalidOrgRole
+    }
+    if err := s.requireOwner(claims, orgID); err != nil {
+        return nil, err
+    }
+    user, err := s.users.GetUserByUsername(username)
+    if err != nil {
+        return nil, ErrUserNotFound
+    }
+
+    exists, err := s.orgs.Memberships.ExistsBy(repository.MembershipOf(orgID, user.ID))
+    if err != nil {
+        return nil, err
+    }
+    if exists {
+        return nil, ErrAlreadyMember
+    }
+    membership := &models.Membership{OrganizationID: orgID, UserID: user.ID, Role: role}
+    if _, err := s.orgs.Memberships.Create(membership); err != nil {
+        return nil, err
+    }
+    return &models.Member{UserID: user.ID, Username: user.Username, Role: role}, nil
+}
+
+// SetRole changes the role of a member, the user of the token must own the organization
+func (s *OrganizationService) SetRole(claims jwt.MapClaims, orgID uint, userID int, role string) error {
+    if !auth.IsOrgRole(role) {
+        return ErrInvalidOrgRole
+    }
+    if err := s.requireOwner(claims, orgID); err != nil {
+        return err
+    }
+    membership, err := s.membership(orgID, userID)
+    if err != nil {
+        return err
+    }
+    if membership.Role == auth.OrgOwner && role != auth.OrgOwner {
+        if err := s.keepOwner(orgID); err != nil {
+            return err
+        }
+    }
+    return s.orgs.Memberships.UpdateBy(repository.MembershipOf(orgID, userID), map[string]interface{}{"role": role})
+}
+
+// RemoveMember removes a member from the organization. Owners remove anyone, the
+// other members only leave it themselves.
+func (s *OrganizationService) RemoveMember(claims jwt.MapClaims, orgID uint, userID int) error {
+    callerID, err := userIDOf(claims)
+    if err != nil {
+        return err
+    }
+    role, err := s.roleOf(claims, orgID)
+    if err != nil {
+        return err
+    }
+    if role != auth.OrgOwner && callerID != userID {
+        return fmt.Errorf("%w: only owners remove the other members", auth.ErrForbidden)
+    }
+    membership, err := s.membership(orgID, userID)
+    if err != nil {
+        return err
+    }
+    if membership.Role == auth.OrgOwner {
+        if err := s.keepOwner(orgID); err != nil {
+            return err
+        }
+    }
+    return s.orgs.Memberships.DeleteBy(repository.MembershipOf(orgID, userID))
+}
+
+// roleOf returns the stored role of the user of the token in the organization. The
+// role is read from the database rather than the token, which may predate a change.
+func (s *OrganizationService) roleOf(claims jwt.MapClaims, orgID uint) (string, error) {
+    userID, err := userIDOf(claims)
+    if err != nil {
+        return "", err
+    }
+    if _, err := s.orgs.GetByID(int(orgID)); err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return "", ErrOrganizationNotFound
+        }
+        return "", err
+    }
+    membership, err := s.membership(orgID, userID)
+    if errors.Is(err, ErrNotMember) {
+        return "", fmt.Errorf("%w: not a member of organization %d", auth.ErrForbidden, orgID)
+    }
+    if err != nil {
+        return "", err
+    }
+    return membership.Role, nil
+}
+
+func (s *OrganizationService) requireOwner(claims jwt.MapClaims, orgID uint) error {
+    role, err := s.roleOf(claims, orgID)
+    if err != nil {
+        return err
+    }
+    if role != auth.OrgOwner {
+        return fmt.Errorf("%w: only owners manage the members", auth.ErrForbidden)
+    }
+    return nil
+}
+
+func (s *OrganizationService) membership(orgID uint, userID int) (*models.Membership, error) {
+    membership, err := s.orgs.Memberships.FirstBy(repository.MembershipOf(orgID, userID))
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrNotMember
+    }
+    return membership, err
+}
+
+// keepOwner fails when the organization has a single owner left
+func (s *OrganizationService) keepOwner(orgID uint) error {
+    owners, err := s.orgs.CountOwners(orgID)
+    if err != nil {
+        return err
+    }
+    if owners <= 1 {
+        return ErrLastOwner
+    }
+    return nil
+}
+
+func userIDOf(claims jwt.MapClaims) (int, error) {
+    userID, ok := claims["userID"].(float64)
+    if !ok {
+        return 0, fmt.Errorf("invalid token: userID not found or not a number")
+    }
+    return int(userID), nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"testing"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupOrganizations(t *testing.T) (*OrganizationService, *repository.OrganizationRepository, []models.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Organization{}, &models.Membership{}))
+	users := []models.User{
+		{Username: "anna", Email: "anna@example.com", PassHash: "x", Role: "user"},
+		{Username: "boris", Email: "boris@example.com", PassHash: "x", Role: "user"},
+		{Username: "vera", Email: "vera@example.com", PassHash: "x", Role: "user"},
+	}
+	require.NoError(t, db.Create(&users).Error)
+	orgs := repository.NewOrganizationRepository(db)
+	return NewOrganizationService(orgs, repository.NewUserRepository(db)), orgs, users
+}
+
+func claimsOf(user models.User) jwt.MapClaims {
+	return jwt.MapClaims{"userID": float64(user.ID), "username": user.Username}
+}
+
+func TestOrganizationService_Members(t *testing.T) {
+	s, orgs, users := setupOrganizations(t)
+	anna, boris, vera := claimsOf(users[0]), claimsOf(users[1]), claimsOf(users[2])
+
+	org, err := s.Create(anna, " Basketball club ")
+	require.NoError(t, err)
+	assert.Equal(t, "Basketball club", org.Name)
+
+	_, err = s.AddMember(anna, org.ID, "boris", auth.OrgOrganizer)
+	require.NoError(t, err)
+	_, err = s.AddMember(anna, org.ID, "boris", auth.OrgViewer)
+	assert.ErrorIs(t, err, ErrAlreadyMember)
+	_, err = s.AddMember(anna, org.ID, "vera", "admin")
+	assert.ErrorIs(t, err, ErrInvalidOrgRole)
+	_, err = s.AddMember(boris, org.ID, "vera", auth.OrgViewer)
+	assert.ErrorIs(t, err, auth.ErrForbidden, "only owners add members")
+	_, err = s.Members(vera, org.ID)
+	assert.ErrorIs(t, err, auth.ErrForbidden, "only members see the members")
+
+	members, err := s.Members(boris, org.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.Member{
+		{UserID: users[0].ID, Username: "anna", Role: auth.OrgOwner},
+		{UserID: users[1].ID, Username: "boris", Role: auth.OrgOrganizer},
+	}, members)
+
+	roles, err := orgs.Roles(users[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"1": auth.OrgOrganizer}, roles)
+
+	mine, err := s.Mine(boris)
+	require.NoError(t, err)
+	assert.Equal(t, []models.UserOrganization{{ID: org.ID, Name: "Basketball club", Role: auth.OrgOrganizer}}, mine)
+}
+
+func TestOrganizationService_KeepsAnOwner(t *testing.T) {
+	s, _, users := setupOrganizations(t)
+	anna, boris := claimsOf(users[0]), claimsOf(users[1])
+
+	org, err := s.Create(anna, "Chess club")
+	require.NoError(t, err)
+	_, err = s.AddMember(anna, org.ID, "boris", auth.OrgViewer)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, s.SetRole(anna, org.ID, users[0].ID, auth.OrgViewer), ErrLastOwner)
+	assert.ErrorIs(t, s.RemoveMember(anna, org.ID, users[0].ID), ErrLastOwner)
+	assert.ErrorIs(t, s.RemoveMember(boris, org.ID, users[0].ID), auth.ErrForbidden)
+
+	require.NoError(t, s.SetRole(anna, org.ID, users[1].ID, auth.OrgOwner))
+	require.NoError(t, s.RemoveMember(anna, org.ID, users[0].ID), "anna leaves boris the owner")
+	assert.ErrorIs(t, s.RemoveMember(boris, org.ID, users[2].ID), ErrNotMember)
+
+	_, err = s.Members(anna, org.ID+1)
+	assert.ErrorIs(t, err, ErrOrganizationNotFound)
+}
@@ -11,16 +11,20 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type RefreshService struct {
     userRepo *repository.UserRepository
+    tokens *repository.RefreshTokenRepository
+    orgRepo *repository.OrganizationRepository
+    roleRepo *repository.RoleRepository
     privateKey *ecdsa.PrivateKey
     publicKey *ecdsa.PublicKey
 }
 
-func NewRefreshService (userRepo *repository.UserRepository, privateKeyString string, publicKeyString string) (*RefreshService, error) {
+func NewRefreshService (userRepo *repository.UserRepository, tokens *repository.RefreshTokenRepository, orgRepo *repository.OrganizationRepository, roleRepo *repository.RoleRepository, privateKeyString string, publicKeyString string) (*RefreshService, error) {
     privateKeyBytes, err := base64.StdEncoding.DecodeString(privateKeyString)
     if err != nil {
         return nil, fmt.Errorf("failed to decode private key: %w", err)
@@ -57,6 +61,9 @@ func NewRefreshService (userRepo *repository.UserRepository, privateKeyString st
 
     return &RefreshService{
         userRepo: userRepo,
+        tokens: tokens,
+        orgRepo: orgRepo,
+        roleRepo: roleRepo,
         privateKey: privateKey,
         publicKey:  publicKey,
     }, nil
@@ -97,22 +104,49 @@ func (r *RefreshService) Refresh(refreshToken string) (string, error) {
     
     userID := int(userIDFloat)
 
+    // the token must still be stored, revoking it ends the session before it expires
+    jti, ok := claims["jti"].(string)
+    if !ok {
+        return "", errors.New("invalid jti claim in refresh token")
+    }
+    stored, err := r.tokens.Active(jti)
+    if err != nil || stored.UserID != userID {
+        return "", errors.New("refresh token was revoked")
+    }
 
     user, err := r.userRepo.GetByID(userID)
     if err != nil {
         return "", fmt.Errorf("authentication failed: %w", err)
     }
 
-    return r.generateAccessJWT(user, 15 * time.Minute)
+    if user.BannedAt != nil {
+        return "", auth.ErrBanned
+    }
+
+    // the permissions and organizations are read on every refresh, so role and membership
+    // changes reach the token within its TTL
+    permissions, err := r.roleRepo.PermissionsOf(user.Role)
+    if err != nil {
+        return "", fmt.Errorf("failed to get permissions: %w", err)
+    }
+
+    orgs, err := r.orgRepo.Roles(user.ID)
+    if err != nil {
+        return "", fmt.Errorf("failed to get organizations: %w", err)
+    }
+
+    return r.generateAccessJWT(user, permissions, orgs, 15 * time.Minute)
 }
 
-func (r *RefreshService) generateAccessJWT(user *models.User, tokenTTL time.Duration) (string, error) {
+func (r *RefreshService) generateAccessJWT(user *models.User, permissions []string, orgs map[string]string, tokenTTL time.Duration) (string, error) {
     token := jwt.New(jwt.SigningMethodES256)
     claims := token.Claims.(jwt.MapClaims)
     claims["userID"] = user.ID
     claims["username"] = user.Username
     claims["email"] = user.Email
     claims["role"] = user.Role
+    claims[auth.PermissionsClaim] = permissions
+    claims[auth.OrgsClaim] = orgs
     claims["exp"] = time.Now().Add(tokenTTL).Unix()
     tokenString, err := token.SignedString(r.privateKey)
     if err != nil {
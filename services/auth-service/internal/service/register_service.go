@@ -3,39 +3,60 @@ package service
 import (
 	"auth-service/internal/models"
 	"auth-service/internal/repository"
+	"context"
 	"fmt"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
 )
 
 type RegisterService struct {
     userRepo *repository.UserRepository
+    uow *db.UnitOfWork
+    sessions *LoginService
 }
 
-func NewRegisterService(userRepo *repository.UserRepository) (*RegisterService, error) {
+func NewRegisterService(userRepo *repository.UserRepository, uow *db.UnitOfWork, sessions *LoginService) (*RegisterService, error) {
     return &RegisterService{
         userRepo: userRepo,
+        uow: uow,
+        sessions: sessions,
     }, nil
 }
 
-func (s *RegisterService) Register(username, email, passhash string) (*models.User, error) {
+// Register creates the user and logs them in, returning their refresh token. The user
+// and the token are stored in one unit of work, so there's no user left behind without
+// the session they signed up for.
+func (s *RegisterService) Register(ctx context.Context, username, email, passhash string) (*models.User, string, error) {
     if username == ""{
-        return  nil, fmt.Errorf("username is required")
+        return  nil, "", fmt.Errorf("username is required")
     }
     if email == "" {
-        return  nil, fmt.Errorf("email is required")
+        return  nil, "", fmt.Errorf("email is required")
     }
     if passhash == "" {
-        return  nil, fmt.Errorf("passhash is required")
+        return  nil, "", fmt.Errorf("passhash is required")
     }
-    // TODO: add admin role
-    user, err := s.userRepo.Create(&models.User{
-        Username: username,
-        Email: email,
-        PassHash: passhash,
-        Role: "user",
+    var user *models.User
+    var refreshToken string
+    err := s.uow.Do(ctx, func(ctx context.Context) error {
+        var err error
+        // administrators give the other roles, see AdminService
+        user, err = s.userRepo.WithContext(ctx).Create(&models.User{
+            Username: username,
+            Email: email,
+            PassHash: passhash,
+            Role: auth.RoleUser,
+        })
+        if err != nil {
+            return err
+        }
+        refreshToken, err = s.sessions.IssueRefreshToken(ctx, user)
+        return err
     })
     if err != nil {
-        return nil, err
+        return nil, "", err
     }
-    return user, nil
+    return user, refreshToken, nil
 }
     
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// testKeys returns a key pair encoded the way the config holds it
+func testKeys(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	private, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: private})),
+		base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}))
+}
+
+func TestRegister_StoresTheUserWithItsRefreshToken(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, conn.AutoMigrate(&models.User{}, &models.Role{}, &models.RolePermission{}, &models.Organization{}, &models.Membership{}))
+	private, public := testKeys(t)
+	users, tokens := repository.NewUserRepository(conn), repository.NewRefreshTokenRepository(conn)
+	login, err := NewLoginService(users, tokens, private, time.Minute)
+	require.NoError(t, err)
+	register, err := NewRegisterService(users, db.NewUnitOfWork(conn), login)
+	require.NoError(t, err)
+	refresh, err := NewRefreshService(users, tokens, repository.NewOrganizationRepository(conn), repository.NewRoleRepository(conn), private, public)
+	require.NoError(t, err)
+
+	// the refresh tokens can't be stored, so the user isn't either
+	_, _, err = register.Register(context.Background(), "ivan", "ivan@example.com", "x")
+	require.Error(t, err)
+	_, err = users.GetUserByUsername("ivan")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	require.NoError(t, conn.AutoMigrate(&models.RefreshToken{}))
+	user, refreshToken, err := register.Register(context.Background(), "ivan", "ivan@example.com", "x")
+	require.NoError(t, err)
+	_, err = refresh.Refresh(refreshToken)
+	require.NoError(t, err)
+
+	require.NoError(t, tokens.RevokeOfUser(user.ID))
+	_, err = refresh.Refresh(refreshToken)
+	assert.ErrorContains(t, err, "revoked")
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"chat-service/internal/access"
+	grpcclient "chat-service/internal/client/grpc-client"
+	"chat-service/internal/config"
+	"chat-service/internal/handler"
+	"chat-service/internal/hub"
+	"chat-service/internal/migrations"
+	"chat-service/internal/repository"
+	"chat-service/internal/service"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/app"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventsclient"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/logger"
+)
+
+func main() {
+	a := app.New("chat-service")
+	cfg, log := a.Config, a.Log
+	var chatCfg config.Config
+	a.MustLoadSection("chat", &chatCfg)
+
+	dbConnection := a.MustConnectDB(migrations.FS)
+	messageRepo := repository.NewMessageRepository(dbConnection)
+	muteRepo := repository.NewMuteRepository(dbConnection)
+
+	verifier, err := auth.NewVerifier(cfg.PublicKey)
+	if err != nil {
+		log.Error("failed to init JWT verifier", logger.Err(err))
+		panic("failed to init JWT verifier")
+	}
+
+	// ----------------INIT GRPC CLIENTS---------------------
+
+	eventClient, err := eventsclient.New(
+		context.Background(),
+		log,
+		fmt.Sprintf("%s:%d", cfg.GRPC.Client.Host, cfg.GRPC.Client.Port),
+		a.GRPCDialOptions(),
+		eventsclient.Options{
+			Timeout:         cfg.GRPC.Client.RetryTimeout,
+			Retries:         cfg.GRPC.Client.RetryCount,
+			Backoff:         cfg.GRPC.Client.RetryBackoff,
+			BreakerFailures: cfg.GRPC.Client.BreakerFailures,
+			BreakerCooldown: cfg.GRPC.Client.BreakerCooldown,
+		},
+	)
+	if err != nil {
+		log.Error("failed to init event client", logger.Err(err))
+		os.Exit(1)
+	}
+	registrationClient, err := grpcclient.NewRegistrationClient(
+		context.Background(),
+		log,
+		fmt.Sprintf("%s:%d", chatCfg.RegistrationService.Host, chatCfg.RegistrationService.GRPCPort),
+		a.GRPCDialOptions(),
+		cfg.GRPC.Client.RetryTimeout,
+		cfg.GRPC.Client.RetryCount,
+	)
+	if err != nil {
+		log.Error("failed to init registration client", logger.Err(err))
+		os.Exit(1)
+	}
+	// open rooms keep working from the access cache while the upstreams are down
+	a.Health.AddOptional("event-service", eventClient.Health)
+	a.Health.AddOptional("registration-service", registrationClient.Health)
+
+	checker := access.NewCachedChecker(access.NewRemoteChecker(eventClient, registrationClient), chatCfg.AccessCacheTTL)
+	rooms := hub.New(64)
+	// hijacked websocket connections aren't drained by the HTTP server
+	a.OnShutdown("chat rooms", func(context.Context) error {
+		rooms.Close()
+		return nil
+	})
+	chatService := service.NewChatService(messageRepo, muteRepo, checker, rooms, service.Options{
+		MaxMessageLength: chatCfg.MaxMessageLength,
+		HistoryLimit:     chatCfg.HistoryLimit,
+		EditWindow:       chatCfg.EditWindow,
+	})
+
+	// -----------------HTTP SERVER------------------------
+
+	handler := handler.NewChatHandler(chatService, rooms, verifier, log)
+
+	router := a.Router
+	router.Get("/api/v1/chats/{eventID}/ws", handler.WebSocketHandler())
+	router.Get("/api/v1/chats/{eventID}/messages", handler.GetMessagesHandler())
+	router.Post("/api/v1/chats/{eventID}/messages", handler.PostMessageHandler())
+	router.Put("/api/v1/chats/{eventID}/messages/{messageID}", handler.UpdateMessageHandler())
+	router.Delete("/api/v1/chats/{eventID}/messages/{messageID}", handler.DeleteMessageHandler())
+	router.Get("/api/v1/chats/{eventID}/mutes", handler.GetMutesHandler())
+	router.Put("/api/v1/chats/{eventID}/mutes/{userID}", handler.MuteHandler())
+	router.Delete("/api/v1/chats/{eventID}/mutes/{userID}", handler.UnmuteHandler())
+
+	if err := a.Run(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,150 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventsclient"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/protos/events"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/protos/registrations"
+)
+
+// Role of a user in the chat room of an event
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleParticipant
+	RoleOrganizer
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleParticipant:
+		return "participant"
+	case RoleOrganizer:
+		return "organizer"
+	default:
+		return "none"
+	}
+}
+
+var ErrEventNotFound = errors.New("event not found")
+
+// Checker resolves the role of a user in the room of an event.
+type Checker interface {
+	Role(ctx context.Context, eventID, userID uint, username string) (Role, error)
+}
+
+// EventGetter is implemented by the event-service gRPC client.
+type EventGetter interface {
+	GetEvent(ctx context.Context, eventID uint32) (*events.GetEventResponse, error)
+}
+
+// RegistrationChecker is implemented by the registration-service gRPC client.
+type RegistrationChecker interface {
+	IsRegistered(ctx context.Context, eventID, userID uint32) (*registrations.IsRegisteredResponse, error)
+}
+
+// RemoteChecker asks event-service for the organizer of the event
+// and registration-service for the registration of the user.
+type RemoteChecker struct {
+	events        EventGetter
+	registrations RegistrationChecker
+}
+
+func NewRemoteChecker(events EventGetter, registrations RegistrationChecker) *RemoteChecker {
+	return &RemoteChecker{
+		events:        events,
+		registrations: registrations,
+	}
+}
+
+func (c *RemoteChecker) Role(ctx context.Context, eventID, userID uint, username string) (Role, error) {
+	event, err := c.events.GetEvent(ctx, uint32(eventID))
+	if eventsclient.Outcome(err) == events.ReserveStatus_EVENT_NOT_FOUND {
+		return RoleNone, ErrEventNotFound
+	}
+	if err != nil {
+		return RoleNone, err
+	}
+	if strings.TrimSpace(event.GetEvent().GetCreatedBy()) == strings.TrimSpace(username) {
+		return RoleOrganizer, nil
+	}
+
+	registration, err := c.registrations.IsRegistered(ctx, uint32(eventID), uint32(userID))
+	if err != nil {
+		return RoleNone, err
+	}
+	if registration.Registered {
+		return RoleParticipant, nil
+	}
+	return RoleNone, nil
+}
+
+// maxCacheEntries triggers a sweep of expired entries
+const maxCacheEntries = 10000
+
+type cacheKey struct {
+	eventID uint
+	userID  uint
+}
+
+type cacheEntry struct {
+	role    Role
+	expires time.Time
+}
+
+// CachedChecker remembers granted roles for ttl, so that every chat message
+// does not cost two gRPC calls. Denials are not cached: a user who has just
+// registered gets access immediately.
+type CachedChecker struct {
+	next    Checker
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[cacheKey]cacheEntry
+}
+
+func NewCachedChecker(next Checker, ttl time.Duration) *CachedChecker {
+	return &CachedChecker{
+		next:    next,
+		ttl:     ttl,
+		entries: make(map[cacheKey]cacheEntry),
+	}
+}
+
+func (c *CachedChecker) Role(ctx context.Context, eventID, userID uint, username string) (Role, error) {
+	key := cacheKey{eventID: eventID, userID: userID}
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && now.After(entry.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+	if ok {
+		return entry.role, nil
+	}
+
+	role, err := c.next.Role(ctx, eventID, userID, username)
+	if err != nil || role == RoleNone {
+		return role, err
+	}
+
+	c.mu.Lock()
+	if len(c.entries) >= maxCacheEntries {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = cacheEntry{role: role, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return role, nil
+}
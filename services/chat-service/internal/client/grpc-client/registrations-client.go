@@ -0,0 +1,81 @@
+package grpcclient
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventsclient"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/health"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/metrics"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/protos/registrations"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/tracing"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+type RegistrationClient struct {
+	api    registrations.RegistrationServiceClient
+	health health.Check
+	log    *slog.Logger
+}
+
+func NewRegistrationClient(
+	ctx context.Context,
+	log *slog.Logger,
+	addr string,
+	dialOpts []grpc.DialOption,
+	timeout time.Duration,
+	retriesCount int,
+) (*RegistrationClient, error) {
+	cc, err := dial(ctx, log, addr, dialOpts, timeout, retriesCount)
+	if err != nil {
+		return nil, err
+	}
+	return &RegistrationClient{
+		api:    registrations.NewRegistrationServiceClient(cc),
+		health: health.GRPC(cc, registrations.RegistrationService_ServiceDesc.ServiceName),
+		log:    log,
+	}, nil
+}
+
+// dial opens a client connection with logging and retries.
+func dial(ctx context.Context, log *slog.Logger, addr string, dialOpts []grpc.DialOption, timeout time.Duration, retriesCount int) (*grpc.ClientConn, error) {
+	retryOpts := []retry.CallOption{
+		retry.WithCodes(codes.Unavailable, codes.Aborted, codes.DeadlineExceeded),
+		retry.WithMax(uint(retriesCount)),
+		retry.WithPerRetryTimeout(timeout),
+	}
+
+	logOpts := []logging.Option{
+		logging.WithLogOnEvents(logging.PayloadReceived, logging.PayloadSent),
+	}
+
+	return grpc.DialContext(ctx, addr, append(dialOpts,
+		tracing.DialOption(),
+		grpc.WithChainUnaryInterceptor(
+			metrics.UnaryClientInterceptor(),
+			logging.UnaryClientInterceptor(eventsclient.InterceptorLogger(log), logOpts...),
+			retry.UnaryClientInterceptor(retryOpts...),
+		),
+	)...)
+}
+
+func (c *RegistrationClient) IsRegistered(ctx context.Context, eventID, userID uint32) (*registrations.IsRegisteredResponse, error) {
+	resp, err := c.api.IsRegistered(ctx, &registrations.IsRegisteredRequest{
+		EventId: eventID,
+		UserId:  userID,
+	})
+	if err != nil {
+		c.log.Error("failed to call IsRegistered", "error", err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Health checks that the upstream reports the service as serving
+func (c *RegistrationClient) Health(ctx context.Context) error {
+	return c.health(ctx)
+}
@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+// Config is the chat-service section of the config file, under the `chat` key.
+// Shared settings like the server and database are in the common config.
+type Config struct {
+	MaxMessageLength int           `yaml:"max_message_length" envconfig:"CHAT_MAX_MESSAGE_LENGTH" default:"4000" validate:"min=1"`
+	HistoryLimit     int           `yaml:"history_limit" envconfig:"CHAT_HISTORY_LIMIT" default:"50" validate:"min=1"`
+	EditWindow       time.Duration `yaml:"edit_window" envconfig:"CHAT_EDIT_WINDOW" default:"15m" validate:"min=0s"`
+	AccessCacheTTL   time.Duration `yaml:"access_cache_ttl" envconfig:"CHAT_ACCESS_CACHE_TTL" default:"1m" validate:"min=0s"`
+
+	// RegistrationService is the gRPC address of registration-service,
+	// event-service is the common gRPC client
+	RegistrationService struct {
+		Host     string `yaml:"host" envconfig:"REGISTRATION_SERVICE_HOST" default:"localhost" validate:"required"`
+		GRPCPort int    `yaml:"grpc_port" envconfig:"REGISTRATION_SERVICE_GRPC_PORT" default:"9092" validate:"port"`
+	} `yaml:"registration_service"`
+}
@@ -0,0 +1,437 @@
+package handler
+
+import (
+	"chat-service/internal/access"
+	"chat-service/internal/hub"
+	"chat-service/internal/models"
+	"chat-service/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/handler"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Frame types sent by clients over the WebSocket.
+const (
+	frameSend   = "message.send"
+	frameEdit   = "message.edit"
+	frameDelete = "message.delete"
+	frameTyping = "typing"
+)
+
+const (
+	pingInterval = 30 * time.Second
+	writeTimeout = 10 * time.Second
+)
+
+// inbound is a frame sent by a client over the WebSocket
+type inbound struct {
+	Type      string `json:"type"`
+	MessageID uint   `json:"message_id"`
+	Body      string `json:"body"`
+	Typing    bool   `json:"typing"`
+}
+
+type messageRequest struct {
+	Body string `json:"body"`
+}
+
+type muteRequest struct {
+	Until  *time.Time `json:"until"`
+	Reason string     `json:"reason"`
+}
+
+type historyResponse struct {
+	Messages   []models.Message `json:"messages"`
+	NextCursor uint             `json:"next_cursor,omitempty"`
+}
+
+type ChatHandler struct {
+	*handler.GenericHandler[models.Message]
+	chat *service.ChatService
+	hub  *hub.Hub
+	log  *slog.Logger
+}
+
+func NewChatHandler(chat *service.ChatService, h *hub.Hub, verifier *auth.Verifier, log *slog.Logger) *ChatHandler {
+	return &ChatHandler{
+		GenericHandler: handler.NewGenericHandler[models.Message](chat.GenericService, verifier),
+		chat:           chat,
+		hub:            h,
+		log:            log.With(slog.String("component", "handler/chat")),
+	}
+}
+
+// GetMessagesHandler returns a page of the room history, newest first.
+// Pass next_cursor of the response as the before parameter to get older messages.
+func (h *ChatHandler) GetMessagesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		eventID, err := uintParam(r, "eventID")
+		if err != nil {
+			http.Error(w, "Invalid eventID parameter", http.StatusBadRequest)
+			return
+		}
+
+		var before uint64
+		if v := r.URL.Query().Get("before"); v != "" {
+			if before, err = strconv.ParseUint(v, 10, 64); err != nil {
+				http.Error(w, "Invalid before parameter", http.StatusBadRequest)
+				return
+			}
+		}
+		limit := 0
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+				http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+				return
+			}
+		}
+
+		messages, cursor, err := h.chat.History(r.Context(), claims, eventID, uint(before), limit)
+		if err != nil {
+			h.fail(w, "Error retrieving messages: ", err)
+			return
+		}
+		if messages == nil {
+			messages = []models.Message{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(historyResponse{Messages: messages, NextCursor: cursor})
+	}
+}
+
+// PostMessageHandler sends a message to the room.
+func (h *ChatHandler) PostMessageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		eventID, err := uintParam(r, "eventID")
+		if err != nil {
+			http.Error(w, "Invalid eventID parameter", http.StatusBadRequest)
+			return
+		}
+		var req messageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		message, err := h.chat.Post(r.Context(), claims, eventID, req.Body)
+		if err != nil {
+			h.fail(w, "Error sending message: ", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(message)
+	}
+}
+
+// UpdateMessageHandler edits a message of the current user.
+func (h *ChatHandler) UpdateMessageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		eventID, err := uintParam(r, "eventID")
+		if err != nil {
+			http.Error(w, "Invalid eventID parameter", http.StatusBadRequest)
+			return
+		}
+		messageID, err := uintParam(r, "messageID")
+		if err != nil {
+			http.Error(w, "Invalid messageID parameter", http.StatusBadRequest)
+			return
+		}
+		var req messageRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		message, err := h.chat.Edit(r.Context(), claims, eventID, messageID, req.Body)
+		if err != nil {
+			h.fail(w, "Error editing message: ", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(message)
+	}
+}
+
+// DeleteMessageHandler deletes a message of the current user, or any message if the user is the organizer.
+func (h *ChatHandler) DeleteMessageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		eventID, err := uintParam(r, "eventID")
+		if err != nil {
+			http.Error(w, "Invalid eventID parameter", http.StatusBadRequest)
+			return
+		}
+		messageID, err := uintParam(r, "messageID")
+		if err != nil {
+			http.Error(w, "Invalid messageID parameter", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.chat.Delete(r.Context(), claims, eventID, messageID); err != nil {
+			h.fail(w, "Error deleting message: ", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetMutesHandler lists muted users of the room. Organizer only.
+func (h *ChatHandler) GetMutesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		eventID, err := uintParam(r, "eventID")
+		if err != nil {
+			http.Error(w, "Invalid eventID parameter", http.StatusBadRequest)
+			return
+		}
+
+		mutes, err := h.chat.Mutes(r.Context(), claims, eventID)
+		if err != nil {
+			h.fail(w, "Error retrieving mutes: ", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(mutes)
+	}
+}
+
+// MuteHandler mutes a user in the room. Organizer only.
+func (h *ChatHandler) MuteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		eventID, err := uintParam(r, "eventID")
+		if err != nil {
+			http.Error(w, "Invalid eventID parameter", http.StatusBadRequest)
+			return
+		}
+		userID, err := uintParam(r, "userID")
+		if err != nil {
+			http.Error(w, "Invalid userID parameter", http.StatusBadRequest)
+			return
+		}
+		var req muteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		mute, err := h.chat.Mute(r.Context(), claims, eventID, userID, req.Until, req.Reason)
+		if err != nil {
+			h.fail(w, "Error muting user: ", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(mute)
+	}
+}
+
+// UnmuteHandler lifts the mute of a user in the room. Organizer only.
+func (h *ChatHandler) UnmuteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		eventID, err := uintParam(r, "eventID")
+		if err != nil {
+			http.Error(w, "Invalid eventID parameter", http.StatusBadRequest)
+			return
+		}
+		userID, err := uintParam(r, "userID")
+		if err != nil {
+			http.Error(w, "Invalid userID parameter", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.chat.Unmute(r.Context(), claims, eventID, userID); err != nil {
+			h.fail(w, "Error unmuting user: ", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// WebSocketHandler upgrades the request to a WebSocket joined to the room of the event.
+// The connection is closed when the access token expires; the client reconnects with a fresh one.
+func (h *ChatHandler) WebSocketHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		eventID, err := uintParam(r, "eventID")
+		if err != nil {
+			http.Error(w, "Invalid eventID parameter", http.StatusBadRequest)
+			return
+		}
+		member, err := h.chat.Member(r.Context(), claims, eventID)
+		if err != nil {
+			h.fail(w, "Error joining chat: ", err)
+			return
+		}
+
+		// Server timeouts are meant for plain requests, not for long-lived connections.
+		rc := http.NewResponseController(w)
+		_ = rc.SetReadDeadline(time.Time{})
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			h.log.Warn("failed to accept websocket", logger.Err(err))
+			return
+		}
+		defer conn.CloseNow()
+
+		ctx, cancel := sessionContext(claims)
+		defer cancel()
+
+		client := h.hub.Join(eventID, member.UserID, member.Username)
+		defer h.hub.Leave(client)
+
+		go h.writeLoop(ctx, cancel, conn, client)
+		h.readLoop(ctx, conn, claims, client)
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			conn.Close(websocket.StatusPolicyViolation, "access token expired")
+			return
+		}
+		conn.Close(websocket.StatusNormalClosure, "")
+	}
+}
+
+func (h *ChatHandler) readLoop(ctx context.Context, conn *websocket.Conn, claims jwt.MapClaims, client *hub.Client) {
+	for {
+		var in inbound
+		if err := wsjson.Read(ctx, conn, &in); err != nil {
+			return
+		}
+		if err := h.dispatch(ctx, claims, client, in); err != nil {
+			h.hub.Send(client, hub.Frame{Type: hub.FrameError, EventID: client.EventID, Error: err.Error()})
+		}
+	}
+}
+
+func (h *ChatHandler) writeLoop(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, client *hub.Client) {
+	defer cancel()
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case frame, ok := <-client.Frames():
+			if !ok {
+				// the hub dropped the client because it fell behind or the service is stopping
+				return
+			}
+			writeCtx, writeCancel := context.WithTimeout(ctx, writeTimeout)
+			err := wsjson.Write(writeCtx, conn, frame)
+			writeCancel()
+			if err != nil {
+				return
+			}
+		case <-ticker.C:
+			pingCtx, pingCancel := context.WithTimeout(ctx, writeTimeout)
+			err := conn.Ping(pingCtx)
+			pingCancel()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (h *ChatHandler) dispatch(ctx context.Context, claims jwt.MapClaims, client *hub.Client, in inbound) error {
+	var err error
+	switch in.Type {
+	case frameSend:
+		_, err = h.chat.Post(ctx, claims, client.EventID, in.Body)
+	case frameEdit:
+		_, err = h.chat.Edit(ctx, claims, client.EventID, in.MessageID, in.Body)
+	case frameDelete:
+		err = h.chat.Delete(ctx, claims, client.EventID, in.MessageID)
+	case frameTyping:
+		err = h.chat.Typing(ctx, claims, client, in.Typing)
+	default:
+		err = fmt.Errorf("unknown frame type %q", in.Type)
+	}
+	return err
+}
+
+// fail writes err with the status code matching its kind.
+func (h *ChatHandler) fail(w http.ResponseWriter, prefix string, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrInvalidClaims):
+		code = http.StatusUnauthorized
+	case errors.Is(err, access.ErrEventNotFound), errors.Is(err, service.ErrMessageNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, service.ErrForbidden), errors.Is(err, service.ErrNotOrganizer),
+		errors.Is(err, service.ErrNotAuthor), errors.Is(err, service.ErrMuted):
+		code = http.StatusForbidden
+	case errors.Is(err, service.ErrEmptyMessage), errors.Is(err, service.ErrMessageTooLong),
+		errors.Is(err, service.ErrEditExpired), errors.Is(err, service.ErrMessageDeleted),
+		errors.Is(err, service.ErrInvalidMute):
+		code = http.StatusBadRequest
+	}
+	http.Error(w, prefix+err.Error(), code)
+}
+
+// sessionContext lives until the access token expires, or forever for tokens without expiry.
+func sessionContext(claims jwt.MapClaims) (context.Context, context.CancelFunc) {
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		return context.WithDeadline(context.Background(), exp.Time)
+	}
+	return context.WithCancel(context.Background())
+}
+
+func uintParam(r *http.Request, name string) (uint, error) {
+	v, err := strconv.ParseUint(chi.URLParam(r, name), 10, 64)
+	if err != nil || v == 0 {
+		return 0, fmt.Errorf("invalid %s", name)
+	}
+	return uint(v), nil
+}
@@ -0,0 +1,176 @@
+package hub
+
+import (
+	"chat-service/internal/models"
+	"sync"
+	"time"
+)
+
+// Frame types pushed to clients of a room.
+const (
+	FrameMessageCreated = "message.created"
+	FrameMessageUpdated = "message.updated"
+	FrameMessageDeleted = "message.deleted"
+	FrameTyping         = "typing"
+	FrameUserMuted      = "user.muted"
+	FrameUserUnmuted    = "user.unmuted"
+	FrameError          = "error"
+)
+
+// Frame is a single update pushed to the clients of a room.
+type Frame struct {
+	Type     string          `json:"type"`
+	EventID  uint            `json:"event_id"`
+	Message  *models.Message `json:"message,omitempty"`
+	UserID   uint            `json:"user_id,omitempty"`
+	Username string          `json:"username,omitempty"`
+	Typing   bool            `json:"typing,omitempty"`
+	Until    *time.Time      `json:"until,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// Client is a single connection joined to the room of an event.
+type Client struct {
+	EventID  uint
+	UserID   uint
+	Username string
+	send     chan Frame
+}
+
+// Frames returns the queue of frames to write to the connection.
+// It is closed when the client leaves the room or falls behind.
+func (c *Client) Frames() <-chan Frame {
+	return c.send
+}
+
+// Hub keeps track of connected clients per event room.
+type Hub struct {
+	mu     sync.RWMutex
+	rooms  map[uint]map[*Client]struct{}
+	buffer int
+}
+
+// New creates a hub whose clients buffer up to buffer frames.
+func New(buffer int) *Hub {
+	return &Hub{
+		rooms:  make(map[uint]map[*Client]struct{}),
+		buffer: buffer,
+	}
+}
+
+// Join registers a new client in the room of the event.
+func (h *Hub) Join(eventID, userID uint, username string) *Client {
+	c := &Client{
+		EventID:  eventID,
+		UserID:   userID,
+		Username: username,
+		send:     make(chan Frame, h.buffer),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	room, ok := h.rooms[eventID]
+	if !ok {
+		room = make(map[*Client]struct{})
+		h.rooms[eventID] = room
+	}
+	room[c] = struct{}{}
+	return c
+}
+
+// Leave removes the client from its room and closes its queue.
+// It is safe to call Leave more than once.
+func (h *Hub) Leave(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leave(c)
+}
+
+func (h *Hub) leave(c *Client) {
+	room, ok := h.rooms[c.EventID]
+	if !ok {
+		return
+	}
+	if _, ok := room[c]; !ok {
+		return
+	}
+	delete(room, c)
+	close(c.send)
+	if len(room) == 0 {
+		delete(h.rooms, c.EventID)
+	}
+}
+
+// Broadcast queues the frame for every client in the room except the given one.
+// Clients with a full queue are dropped instead of blocking the room.
+func (h *Hub) Broadcast(eventID uint, frame Frame, except *Client) {
+	var slow []*Client
+	h.mu.RLock()
+	for c := range h.rooms[eventID] {
+		if c == except {
+			continue
+		}
+		select {
+		case c.send <- frame:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+	h.drop(slow)
+}
+
+// Send queues the frame for a single client.
+func (h *Hub) Send(c *Client, frame Frame) {
+	h.mu.RLock()
+	_, joined := h.rooms[c.EventID][c]
+	delivered := false
+	if joined {
+		select {
+		case c.send <- frame:
+			delivered = true
+		default:
+		}
+	}
+	h.mu.RUnlock()
+	if joined && !delivered {
+		h.drop([]*Client{c})
+	}
+}
+
+// Online returns ids of users connected to the room, without duplicates.
+func (h *Hub) Online(eventID uint) []uint {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	seen := make(map[uint]struct{})
+	var ids []uint
+	for c := range h.rooms[eventID] {
+		if _, ok := seen[c.UserID]; ok {
+			continue
+		}
+		seen[c.UserID] = struct{}{}
+		ids = append(ids, c.UserID)
+	}
+	return ids
+}
+
+// Close drops every client, which ends their connections, e.g. on shutdown.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, room := range h.rooms {
+		for c := range room {
+			h.leave(c)
+		}
+	}
+}
+
+func (h *Hub) drop(clients []*Client) {
+	if len(clients) == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, c := range clients {
+		h.leave(c)
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS mutes;
+DROP TABLE IF EXISTS messages;
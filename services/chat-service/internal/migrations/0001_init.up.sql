@@ -0,0 +1,25 @@
+-- the schema AutoMigrate created, existing databases are left as they are
+CREATE TABLE IF NOT EXISTS messages (
+    id bigserial PRIMARY KEY,
+    event_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    username varchar(255) NOT NULL,
+    body text NOT NULL,
+    edited_at timestamptz,
+    deleted_at timestamptz,
+    deleted_by varchar(255),
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_messages_event_id ON messages (event_id);
+CREATE INDEX IF NOT EXISTS idx_messages_user_id ON messages (user_id);
+
+CREATE TABLE IF NOT EXISTS mutes (
+    id bigserial PRIMARY KEY,
+    event_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    until timestamptz,
+    reason text,
+    created_by varchar(255) NOT NULL,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mute_event_user ON mutes (event_id, user_id);
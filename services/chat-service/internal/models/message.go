@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// Message is a single chat message in the room of an event.
+// Deleted messages are kept as tombstones with an empty body, so cursors stay valid.
+type Message struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	EventID   uint       `gorm:"not null;index" json:"event_id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Username  string     `gorm:"type:varchar(255);not null" json:"username"`
+	Body      string     `gorm:"type:text;not null" json:"body"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy string     `gorm:"type:varchar(255)" json:"deleted_by,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Deleted reports whether the message was removed by its author or a moderator.
+func (m *Message) Deleted() bool {
+	return m.DeletedAt != nil
+}
+
+// JSON EXAMPLE
+
+// {
+//   "id": 42,
+//   "event_id": 7,
+//   "user_id": 3,
+//   "username": "evgeniyfimushkin",
+//   "body": "Всем привет! Встречаемся у входа",
+//   "created_at": "2025-05-15T16:40:00+07:00"
+// }
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Mute forbids a user to write into the room of an event.
+// A nil Until means the mute never expires.
+type Mute struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	EventID   uint       `gorm:"not null;uniqueIndex:idx_mute_event_user" json:"event_id"`
+	UserID    uint       `gorm:"not null;uniqueIndex:idx_mute_event_user" json:"user_id"`
+	Until     *time.Time `json:"until,omitempty"`
+	Reason    string     `gorm:"type:text" json:"reason,omitempty"`
+	CreatedBy string     `gorm:"type:varchar(255);not null" json:"created_by"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// ActiveAt reports whether the mute is in effect at t.
+func (m *Mute) ActiveAt(t time.Time) bool {
+	return m.Until == nil || m.Until.After(t)
+}
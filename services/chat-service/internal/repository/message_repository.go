@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"chat-service/internal/models"
+	"fmt"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+	"gorm.io/gorm"
+)
+
+type MessageRepository struct {
+	*repository.GenericRepository[models.Message]
+}
+
+func NewMessageRepository(db *gorm.DB) *MessageRepository {
+	return &MessageRepository{
+		GenericRepository: repository.NewGenericRepository[models.Message](db),
+	}
+}
+
+// History returns up to limit messages of the event older than the message with id before,
+// newest first. A zero before starts from the latest message.
+func (repo *MessageRepository) History(eventID, before uint, limit int) ([]models.Message, error) {
+	var messages []models.Message
+	query := repo.Db.Where("event_id = ?", eventID)
+	if before > 0 {
+		query = query.Where("id < ?", before)
+	}
+	result := query.Order("id desc").Limit(limit).Find(&messages)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%w: %v", repository.ErrFindEntities, result.Error)
+	}
+	return messages, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"chat-service/internal/models"
+	"errors"
+	"fmt"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Fields of Mute for the typed queries
+var (
+	MuteEventID = repository.FieldOf[models.Mute, uint]("EventID")
+	MuteUserID  = repository.FieldOf[models.Mute, uint]("UserID")
+)
+
+func muteOf(eventID, userID uint) repository.Query[models.Mute] {
+	return repository.Where(MuteEventID.Eq(eventID), MuteUserID.Eq(userID))
+}
+
+type MuteRepository struct {
+	*repository.GenericRepository[models.Mute]
+}
+
+func NewMuteRepository(db *gorm.DB) *MuteRepository {
+	return &MuteRepository{
+		GenericRepository: repository.NewGenericRepository[models.Mute](db),
+	}
+}
+
+// Get returns the mute of the user in the event room or nil if there is none.
+func (repo *MuteRepository) Get(eventID, userID uint) (*models.Mute, error) {
+	mute, err := repo.FirstBy(muteOf(eventID, userID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return mute, err
+}
+
+// Save creates the mute or replaces the existing one of the same user in the same room.
+func (repo *MuteRepository) Save(mute *models.Mute) (*models.Mute, error) {
+	result := repo.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"until", "reason", "created_by"}),
+	}).Create(mute)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%w: %v", repository.ErrCreateEntity, result.Error)
+	}
+	return repo.FirstBy(muteOf(mute.EventID, mute.UserID))
+}
+
+// Remove lifts the mute of the user in the event room.
+func (repo *MuteRepository) Remove(eventID, userID uint) error {
+	return repo.DeleteBy(muteOf(eventID, userID))
+}
+
+// OfRoom returns all mutes of the event room.
+func (repo *MuteRepository) OfRoom(eventID uint) ([]models.Mute, error) {
+	return repo.FindBy(repository.Where(MuteEventID.Eq(eventID)))
+}
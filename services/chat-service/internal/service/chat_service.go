@@ -0,0 +1,327 @@
+package service
+
+import (
+	"chat-service/internal/access"
+	"chat-service/internal/hub"
+	"chat-service/internal/models"
+	"chat-service/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/service"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidClaims   = errors.New("invalid token")
+	ErrForbidden       = errors.New("you are not a participant of this event")
+	ErrNotOrganizer    = errors.New("only the organizer can moderate this chat")
+	ErrMuted           = errors.New("you are muted in this chat")
+	ErrEmptyMessage    = errors.New("message must not be empty")
+	ErrMessageTooLong  = errors.New("message is too long")
+	ErrMessageNotFound = errors.New("message not found")
+	ErrNotAuthor       = errors.New("it's not your message")
+	ErrEditExpired     = errors.New("message can no longer be edited")
+	ErrMessageDeleted  = errors.New("message is deleted")
+	ErrInvalidMute     = errors.New("invalid mute")
+)
+
+// Member is a user acting in the room of an event
+type Member struct {
+	UserID   uint        `json:"user_id"`
+	Username string      `json:"username"`
+	Role     access.Role `json:"-"`
+}
+
+// Options configures limits of the chat
+type Options struct {
+	MaxMessageLength int
+	HistoryLimit     int
+	EditWindow       time.Duration
+}
+
+// ChatService stores messages of event rooms and pushes every change to connected clients.
+// Access to a room is limited to the organizer and registered participants of the event.
+type ChatService struct {
+	*service.GenericService[models.Message]
+	messages *repository.MessageRepository
+	mutes    *repository.MuteRepository
+	access   access.Checker
+	hub      *hub.Hub
+	opts     Options
+}
+
+// NewChatService creates a new instance of ChatService.
+func NewChatService(
+	messages *repository.MessageRepository,
+	mutes *repository.MuteRepository,
+	checker access.Checker,
+	h *hub.Hub,
+	opts Options,
+) *ChatService {
+	if opts.HistoryLimit < 1 {
+		opts.HistoryLimit = 50
+	}
+	return &ChatService{
+		GenericService: service.NewGenericService[models.Message](messages),
+		messages:       messages,
+		mutes:          mutes,
+		access:         checker,
+		hub:            h,
+		opts:           opts,
+	}
+}
+
+// Member resolves the user from claims and checks that they may enter the room of the event.
+func (s *ChatService) Member(ctx context.Context, claims jwt.MapClaims, eventID uint) (*Member, error) {
+	userIDFloat, ok := claims["userID"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: userID not found or not a number", ErrInvalidClaims)
+	}
+	username, ok := claims["username"].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: username not found or not a string", ErrInvalidClaims)
+	}
+
+	member := &Member{UserID: uint(userIDFloat), Username: username}
+	role, err := s.access.Role(ctx, eventID, member.UserID, member.Username)
+	if err != nil {
+		return nil, err
+	}
+	if role == access.RoleNone {
+		return nil, ErrForbidden
+	}
+	member.Role = role
+	return member, nil
+}
+
+// History returns a page of messages, newest first, older than the message with id before.
+// The returned cursor is the id to pass as before to get the next page, or 0 at the beginning of the room.
+func (s *ChatService) History(ctx context.Context, claims jwt.MapClaims, eventID, before uint, limit int) ([]models.Message, uint, error) {
+	if _, err := s.Member(ctx, claims, eventID); err != nil {
+		return nil, 0, err
+	}
+	if limit < 1 || limit > s.opts.HistoryLimit {
+		limit = s.opts.HistoryLimit
+	}
+	messages, err := s.messages.History(eventID, before, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	var cursor uint
+	if len(messages) == limit {
+		cursor = messages[len(messages)-1].ID
+	}
+	return messages, cursor, nil
+}
+
+// Post stores a new message and sends it to everyone in the room, including the author.
+func (s *ChatService) Post(ctx context.Context, claims jwt.MapClaims, eventID uint, body string) (*models.Message, error) {
+	member, err := s.writer(ctx, claims, eventID)
+	if err != nil {
+		return nil, err
+	}
+	body, err = s.validate(body)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := s.messages.Create(&models.Message{
+		EventID:  eventID,
+		UserID:   member.UserID,
+		Username: member.Username,
+		Body:     body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.hub.Broadcast(eventID, hub.Frame{Type: hub.FrameMessageCreated, EventID: eventID, Message: message}, nil)
+	return message, nil
+}
+
+// Edit changes the body of a message. Only the author may edit, and only within the edit window.
+func (s *ChatService) Edit(ctx context.Context, claims jwt.MapClaims, eventID, messageID uint, body string) (*models.Message, error) {
+	member, err := s.writer(ctx, claims, eventID)
+	if err != nil {
+		return nil, err
+	}
+	body, err = s.validate(body)
+	if err != nil {
+		return nil, err
+	}
+	message, err := s.message(eventID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.UserID != member.UserID {
+		return nil, ErrNotAuthor
+	}
+	if message.Deleted() {
+		return nil, ErrMessageDeleted
+	}
+	now := time.Now()
+	if s.opts.EditWindow > 0 && now.Sub(message.CreatedAt) > s.opts.EditWindow {
+		return nil, ErrEditExpired
+	}
+
+	message.Body = body
+	message.EditedAt = &now
+	updated, err := s.messages.Update(message)
+	if err != nil {
+		return nil, err
+	}
+	s.hub.Broadcast(eventID, hub.Frame{Type: hub.FrameMessageUpdated, EventID: eventID, Message: updated}, nil)
+	return updated, nil
+}
+
+// Delete removes a message. The author may delete their own messages, the organizer any message.
+func (s *ChatService) Delete(ctx context.Context, claims jwt.MapClaims, eventID, messageID uint) error {
+	member, err := s.Member(ctx, claims, eventID)
+	if err != nil {
+		return err
+	}
+	message, err := s.message(eventID, messageID)
+	if err != nil {
+		return err
+	}
+	if message.UserID != member.UserID && member.Role != access.RoleOrganizer {
+		return ErrNotAuthor
+	}
+	if message.Deleted() {
+		return nil
+	}
+
+	now := time.Now()
+	message.Body = ""
+	message.DeletedAt = &now
+	message.DeletedBy = member.Username
+	updated, err := s.messages.Update(message)
+	if err != nil {
+		return err
+	}
+	s.hub.Broadcast(eventID, hub.Frame{Type: hub.FrameMessageDeleted, EventID: eventID, Message: updated}, nil)
+	return nil
+}
+
+// Typing tells the others in the room that the user of the client started or stopped typing.
+func (s *ChatService) Typing(ctx context.Context, claims jwt.MapClaims, client *hub.Client, typing bool) error {
+	member, err := s.writer(ctx, claims, client.EventID)
+	if err != nil {
+		return err
+	}
+	s.hub.Broadcast(client.EventID, hub.Frame{
+		Type:     hub.FrameTyping,
+		EventID:  client.EventID,
+		UserID:   member.UserID,
+		Username: member.Username,
+		Typing:   typing,
+	}, client)
+	return nil
+}
+
+// Mutes returns all mutes of the room. Only the organizer can see them.
+func (s *ChatService) Mutes(ctx context.Context, claims jwt.MapClaims, eventID uint) ([]models.Mute, error) {
+	if _, err := s.organizer(ctx, claims, eventID); err != nil {
+		return nil, err
+	}
+	return s.mutes.OfRoom(eventID)
+}
+
+// Mute forbids the user to write into the room until the given time, or forever if until is nil.
+func (s *ChatService) Mute(ctx context.Context, claims jwt.MapClaims, eventID, userID uint, until *time.Time, reason string) (*models.Mute, error) {
+	member, err := s.organizer(ctx, claims, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if userID == 0 || userID == member.UserID {
+		return nil, fmt.Errorf("%w: organizer cannot mute themselves", ErrInvalidMute)
+	}
+	if until != nil && !until.After(time.Now()) {
+		return nil, fmt.Errorf("%w: until must be in the future", ErrInvalidMute)
+	}
+
+	mute, err := s.mutes.Save(&models.Mute{
+		EventID:   eventID,
+		UserID:    userID,
+		Until:     until,
+		Reason:    strings.TrimSpace(reason),
+		CreatedBy: member.Username,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.hub.Broadcast(eventID, hub.Frame{Type: hub.FrameUserMuted, EventID: eventID, UserID: userID, Until: mute.Until}, nil)
+	return mute, nil
+}
+
+// Unmute lifts the mute of the user in the room.
+func (s *ChatService) Unmute(ctx context.Context, claims jwt.MapClaims, eventID, userID uint) error {
+	if _, err := s.organizer(ctx, claims, eventID); err != nil {
+		return err
+	}
+	if err := s.mutes.Remove(eventID, userID); err != nil {
+		return err
+	}
+	s.hub.Broadcast(eventID, hub.Frame{Type: hub.FrameUserUnmuted, EventID: eventID, UserID: userID}, nil)
+	return nil
+}
+
+// writer returns the member if they are allowed to write into the room.
+func (s *ChatService) writer(ctx context.Context, claims jwt.MapClaims, eventID uint) (*Member, error) {
+	member, err := s.Member(ctx, claims, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == access.RoleOrganizer {
+		return member, nil
+	}
+	mute, err := s.mutes.Get(eventID, member.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if mute != nil && mute.ActiveAt(time.Now()) {
+		return nil, ErrMuted
+	}
+	return member, nil
+}
+
+func (s *ChatService) organizer(ctx context.Context, claims jwt.MapClaims, eventID uint) (*Member, error) {
+	member, err := s.Member(ctx, claims, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role != access.RoleOrganizer {
+		return nil, ErrNotOrganizer
+	}
+	return member, nil
+}
+
+func (s *ChatService) message(eventID, messageID uint) (*models.Message, error) {
+	message, err := s.messages.GetByID(int(messageID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if message.EventID != eventID {
+		return nil, ErrMessageNotFound
+	}
+	return message, nil
+}
+
+func (s *ChatService) validate(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", ErrEmptyMessage
+	}
+	if s.opts.MaxMessageLength > 0 && utf8.RuneCountInString(body) > s.opts.MaxMessageLength {
+		return "", fmt.Errorf("%w: at most %d characters", ErrMessageTooLong, s.opts.MaxMessageLength)
+	}
+	return body, nil
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"chat-service/internal/access"
+	"chat-service/internal/hub"
+	"chat-service/internal/models"
+	"chat-service/internal/repository"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	eventID     = 1
+	organizerID = 10
+	aliceID     = 11
+	bobID       = 12
+	strangerID  = 13
+)
+
+// fakeChecker grants roles from a fixed table
+type fakeChecker map[uint]access.Role
+
+func (f fakeChecker) Role(ctx context.Context, eventID, userID uint, username string) (access.Role, error) {
+	return f[userID], nil
+}
+
+func claims(userID uint, username string) jwt.MapClaims {
+	return jwt.MapClaims{"userID": float64(userID), "username": username}
+}
+
+func setupService(t *testing.T, opts Options) (*ChatService, *hub.Hub) {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Message{}, &models.Mute{}))
+
+	checker := fakeChecker{
+		organizerID: access.RoleOrganizer,
+		aliceID:     access.RoleParticipant,
+		bobID:       access.RoleParticipant,
+	}
+	h := hub.New(16)
+	svc := NewChatService(repository.NewMessageRepository(db), repository.NewMuteRepository(db), checker, h, opts)
+	return svc, h
+}
+
+func TestPost_BroadcastsToRoomAndRejectsStrangers(t *testing.T) {
+	svc, h := setupService(t, Options{MaxMessageLength: 10})
+	ctx := context.Background()
+	bob := h.Join(eventID, bobID, "bob")
+
+	message, err := svc.Post(ctx, claims(aliceID, "alice"), eventID, "  hello  ")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", message.Body)
+	assert.Equal(t, "alice", message.Username)
+
+	frame := <-bob.Frames()
+	assert.Equal(t, hub.FrameMessageCreated, frame.Type)
+	assert.Equal(t, message.ID, frame.Message.ID)
+
+	_, err = svc.Post(ctx, claims(strangerID, "mallory"), eventID, "hi")
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = svc.Post(ctx, claims(aliceID, "alice"), eventID, "   ")
+	assert.ErrorIs(t, err, ErrEmptyMessage)
+	_, err = svc.Post(ctx, claims(aliceID, "alice"), eventID, "this is way too long")
+	assert.ErrorIs(t, err, ErrMessageTooLong)
+}
+
+func TestHistory_CursorPagination(t *testing.T) {
+	svc, _ := setupService(t, Options{HistoryLimit: 2})
+	ctx := context.Background()
+	for i := 1; i <= 5; i++ {
+		_, err := svc.Post(ctx, claims(aliceID, "alice"), eventID, fmt.Sprintf("message %d", i))
+		require.NoError(t, err)
+	}
+
+	var bodies []string
+	var before uint
+	for page := 0; page < 5; page++ {
+		messages, cursor, err := svc.History(ctx, claims(bobID, "bob"), eventID, before, 10)
+		require.NoError(t, err)
+		for _, m := range messages {
+			bodies = append(bodies, m.Body)
+		}
+		if cursor == 0 {
+			break
+		}
+		before = cursor
+	}
+	assert.Equal(t, []string{"message 5", "message 4", "message 3", "message 2", "message 1"}, bodies)
+
+	_, _, err := svc.History(ctx, claims(strangerID, "mallory"), eventID, 0, 10)
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestEditAndDelete_Permissions(t *testing.T) {
+	svc, _ := setupService(t, Options{EditWindow: time.Hour})
+	ctx := context.Background()
+
+	message, err := svc.Post(ctx, claims(aliceID, "alice"), eventID, "original")
+	require.NoError(t, err)
+
+	_, err = svc.Edit(ctx, claims(bobID, "bob"), eventID, message.ID, "hacked")
+	assert.ErrorIs(t, err, ErrNotAuthor)
+	_, err = svc.Edit(ctx, claims(organizerID, "organizer"), eventID, message.ID, "moderated")
+	assert.ErrorIs(t, err, ErrNotAuthor)
+
+	edited, err := svc.Edit(ctx, claims(aliceID, "alice"), eventID, message.ID, "edited")
+	require.NoError(t, err)
+	assert.Equal(t, "edited", edited.Body)
+	assert.NotNil(t, edited.EditedAt)
+
+	assert.ErrorIs(t, svc.Delete(ctx, claims(bobID, "bob"), eventID, message.ID), ErrNotAuthor)
+	assert.ErrorIs(t, svc.Delete(ctx, claims(aliceID, "alice"), eventID+1, message.ID), ErrMessageNotFound)
+	require.NoError(t, svc.Delete(ctx, claims(organizerID, "organizer"), eventID, message.ID))
+
+	messages, _, err := svc.History(ctx, claims(aliceID, "alice"), eventID, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.True(t, messages[0].Deleted())
+	assert.Empty(t, messages[0].Body)
+	assert.Equal(t, "organizer", messages[0].DeletedBy)
+
+	_, err = svc.Edit(ctx, claims(aliceID, "alice"), eventID, message.ID, "again")
+	assert.ErrorIs(t, err, ErrMessageDeleted)
+}
+
+func TestEdit_WindowExpired(t *testing.T) {
+	svc, _ := setupService(t, Options{EditWindow: time.Minute})
+	ctx := context.Background()
+
+	message, err := svc.Post(ctx, claims(aliceID, "alice"), eventID, "original")
+	require.NoError(t, err)
+	message.CreatedAt = time.Now().Add(-time.Hour)
+	_, err = svc.messages.Update(message)
+	require.NoError(t, err)
+
+	_, err = svc.Edit(ctx, claims(aliceID, "alice"), eventID, message.ID, "late")
+	assert.ErrorIs(t, err, ErrEditExpired)
+}
+
+func TestMute_OrganizerModeration(t *testing.T) {
+	svc, h := setupService(t, Options{})
+	ctx := context.Background()
+	alice := h.Join(eventID, aliceID, "alice")
+
+	_, err := svc.Mute(ctx, claims(bobID, "bob"), eventID, aliceID, nil, "")
+	assert.ErrorIs(t, err, ErrNotOrganizer)
+	_, err = svc.Mute(ctx, claims(organizerID, "organizer"), eventID, organizerID, nil, "")
+	assert.ErrorIs(t, err, ErrInvalidMute)
+
+	until := time.Now().Add(time.Hour)
+	mute, err := svc.Mute(ctx, claims(organizerID, "organizer"), eventID, aliceID, &until, "spam")
+	require.NoError(t, err)
+	assert.Equal(t, "spam", mute.Reason)
+	assert.Equal(t, hub.FrameUserMuted, (<-alice.Frames()).Type)
+
+	_, err = svc.Post(ctx, claims(aliceID, "alice"), eventID, "let me speak")
+	assert.ErrorIs(t, err, ErrMuted)
+	assert.ErrorIs(t, svc.Typing(ctx, claims(aliceID, "alice"), alice, true), ErrMuted)
+
+	// muting again replaces the existing mute
+	_, err = svc.Mute(ctx, claims(organizerID, "organizer"), eventID, aliceID, nil, "forever")
+	require.NoError(t, err)
+	mutes, err := svc.Mutes(ctx, claims(organizerID, "organizer"), eventID)
+	require.NoError(t, err)
+	require.Len(t, mutes, 1)
+	assert.Nil(t, mutes[0].Until)
+
+	require.NoError(t, svc.Unmute(ctx, claims(organizerID, "organizer"), eventID, aliceID))
+	_, err = svc.Post(ctx, claims(aliceID, "alice"), eventID, "thanks")
+	assert.NoError(t, err)
+}
+
+func TestTyping_NotEchoedToSender(t *testing.T) {
+	svc, h := setupService(t, Options{})
+	ctx := context.Background()
+	alice := h.Join(eventID, aliceID, "alice")
+	bob := h.Join(eventID, bobID, "bob")
+
+	require.NoError(t, svc.Typing(ctx, claims(aliceID, "alice"), alice, true))
+
+	frame := <-bob.Frames()
+	assert.Equal(t, hub.FrameTyping, frame.Type)
+	assert.Equal(t, "alice", frame.Username)
+	assert.True(t, frame.Typing)
+	assert.Len(t, alice.Frames(), 0)
+}
@@ -0,0 +1,11 @@
+# generate the keys with ../scripts/gen-secrets.sh ../secrets first
+export PRIVATE_KEY_FILE="$(pwd)/../secrets/private_key"
+export PUBLIC_KEY_FILE="$(pwd)/../secrets/public_key"
+export SERVER_PORT=8088
+export DB_NAME=chat_db
+export ENV=local
+export SERVICE_TOKEN_SECRET_FILE="$(pwd)/../secrets/chat_service_secret"
+export GRPC_CLIENT_USE_TLS=true
+export GRPC_CLIENT_CERT_FILE="$(pwd)/../secrets/grpc/chat-service.crt"
+export GRPC_CLIENT_KEY_FILE="$(pwd)/../secrets/grpc/chat-service.key"
+export GRPC_CLIENT_CA_FILE="$(pwd)/../secrets/grpc/ca.crt"
@@ -0,0 +1,38 @@
+// Command devca writes a dev CA and a certificate per service for running the gRPC
+// channels over mutual TLS locally:
+//
+//	go run ./cmd/devca -out ../certs event-service registration-service chat-service
+//
+// Every service gets <name>.crt and <name>.key, valid for its name and localhost,
+// to use as both its server and client certificate, and all of them share ca.crt.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/mtls/devca"
+)
+
+func main() {
+	out := flag.String("out", "certs", "directory to write the certificates to")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: devca [-out dir] service...")
+		os.Exit(2)
+	}
+
+	ca, err := devca.New()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, name := range flag.Args() {
+		if err := ca.WriteFiles(*out, name, "localhost", "127.0.0.1"); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s/%s.crt\n", *out, name)
+	}
+}
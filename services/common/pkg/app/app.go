@@ -0,0 +1,343 @@
+// Package app is the lifecycle of a service: it wires config, logger, tracing,
+// database and the HTTP, gRPC and metrics servers, and on SIGTERM/SIGINT shuts
+// everything down in order.
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/config"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/grpcauth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/health"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/logger"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/metrics"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/middlewarelogger"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/mtls"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/tracing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"gorm.io/gorm"
+)
+
+// Hook is a shutdown step, ctx is cancelled when the step runs out of time
+type Hook func(ctx context.Context) error
+
+type namedHook struct {
+	name string
+	fn   Hook
+}
+
+type worker struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// App is a running service. Build it with New, register routes, gRPC services,
+// workers and shutdown hooks, then call Run.
+type App struct {
+	Name   string
+	Config *config.Config
+	Log    *slog.Logger
+	// Health holds the readiness checks served on /readyz
+	Health *health.Checker
+	// Router serves the HTTP API behind the common middleware chain
+	Router *chi.Mux
+
+	db         *gorm.DB
+	grpc       *grpc.Server
+	grpcHealth *health.GRPCServer
+	grpcPolicy mtls.Policy
+	grpcAuth   grpcauth.Policy
+	grpcCreds  credentials.TransportCredentials
+	grpcTokens *grpcauth.ServiceTokens
+	workers    []worker
+	hooks      []namedHook
+
+	configPath      string
+	shutdownTracing func(context.Context) error
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New loads the config and sets up logging, tracing and the HTTP router of the service.
+// Started as `<binary> healthcheck` it probes /readyz of the running instance and exits,
+// which is how the scratch images are health checked. `<binary> migrate create <name>`
+// writes the files of a new migration and exits.
+func New(name string) *App {
+	// new migrations are written to the source tree, neither config nor database is needed
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	if flag.Arg(0) == "migrate" && flag.Arg(1) == "create" {
+		if err := db.CreateCommand(flag.Args()[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	cfg := config.MustLoadConfig()
+	if flag.Arg(0) == "healthcheck" {
+		os.Exit(health.Probe(fmt.Sprintf("http://127.0.0.1:%d/readyz", cfg.Server.Port)))
+	}
+	log := logger.SetupLogger(cfg.Env)
+	if err := logger.SetLevel(cfg.LogLevel); err != nil {
+		log.Error("failed to load config", slog.String("log_level", cfg.LogLevel), logger.Err(err))
+		os.Exit(1)
+	}
+	log.Debug("config loaded", slog.Any("config", config.Redact(cfg)))
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName: name,
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Error("failed to init tracing", logger.Err(err))
+		panic("failed to init tracing")
+	}
+
+	a := &App{
+		Name:            name,
+		Config:          cfg,
+		Log:             log,
+		Health:          health.New(cfg.Health.CheckTimeout),
+		grpcPolicy:      mtls.Policy{},
+		// probes of orchestrators and load balancers carry no tokens
+		grpcAuth:        grpcauth.Policy{"/grpc.health.v1.Health/": {Public: true}},
+		configPath:      config.Path(),
+		shutdownTracing: shutdownTracing,
+		stop:            make(chan struct{}),
+	}
+
+	a.Router = chi.NewRouter()
+	a.Router.Use(middleware.RequestID)
+	a.Router.Use(middleware.RealIP)
+	a.Router.Use(grpcauth.ForwardUserToken)
+	a.Router.Use(tracing.Middleware)
+	a.Router.Use(metrics.Middleware)
+	a.Router.Use(middlewarelogger.New(log))
+	a.Router.Use(middleware.Recoverer)
+	a.Router.Use(middleware.URLFormat)
+	a.Router.Get("/healthz", a.Health.LivenessHandler())
+	a.Router.Get("/readyz", a.Health.ReadinessHandler())
+
+	return a
+}
+
+// MustLoadSection loads the settings of the service from the section of the config file
+// and env variables into target, see config.LoadSection. Invalid settings stop the service.
+func (a *App) MustLoadSection(section string, target interface{}) {
+	if err := config.LoadSection(a.configPath, section, target); err != nil {
+		a.Log.Error("failed to load config", slog.String("section", section), logger.Err(err))
+		os.Exit(1)
+	}
+	a.Log.Debug("config loaded", slog.String("section", section), slog.Any("config", config.Redact(target)))
+}
+
+// Go runs fn in the background until shutdown, when its ctx is cancelled.
+// An error is logged but doesn't stop the service.
+func (a *App) Go(name string, fn func(ctx context.Context) error) {
+	a.workers = append(a.workers, worker{name: name, run: fn})
+}
+
+// OnShutdown adds a step run after the servers and workers stopped and before the database is closed.
+// Steps run in the order they were added, each limited by SERVER_SHUTDOWN_TIMEOUT.
+func (a *App) OnShutdown(name string, fn Hook) {
+	a.hooks = append(a.hooks, namedHook{name: name, fn: fn})
+}
+
+// OnClose is OnShutdown for anything that only needs to be closed.
+func (a *App) OnClose(name string, c io.Closer) {
+	a.OnShutdown(name, func(context.Context) error { return c.Close() })
+}
+
+// Stop makes Run shut down as if the service got SIGTERM.
+func (a *App) Stop() {
+	a.stopOnce.Do(func() { close(a.stop) })
+}
+
+// Run serves until SIGTERM/SIGINT, Stop or a server failure and then shuts down:
+// readiness goes off and requests are drained for HEALTH_SHUTDOWN_DELAY, then
+// the HTTP and gRPC servers stop, workers are cancelled, the OnShutdown hooks run,
+// the database is closed and the remaining spans are flushed.
+// It returns the error of the failed server, if any.
+func (a *App) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(signals)
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	failed := make(chan error, 2)
+	var servers []namedHook
+	if a.Config.Metrics.Addr != "" {
+		servers = append(servers, a.serveMetrics())
+	}
+	if a.grpc != nil {
+		grpcStop, err := a.serveGRPC(ctx, failed)
+		if err != nil {
+			a.Log.Error("failed to start gRPC server", logger.Err(err))
+			a.shutdown(servers, cancel, nil)
+			return err
+		}
+		servers = append(servers, grpcStop)
+	}
+	httpStop, err := a.serveHTTP(failed)
+	if err != nil {
+		a.Log.Error("failed to start HTTP server", logger.Err(err))
+		a.shutdown(servers, cancel, nil)
+		return err
+	}
+	servers = append(servers, httpStop)
+	workers := a.startWorkers(ctx)
+
+	var runErr error
+wait:
+	for {
+		select {
+		case <-reload:
+			a.reload()
+		case sig := <-signals:
+			a.Log.Info("received signal, shutting down", slog.String("signal", sig.String()))
+			a.drain()
+			break wait
+		case <-a.stop:
+			a.Log.Info("shutting down")
+			a.drain()
+			break wait
+		case runErr = <-failed:
+			a.Log.Error("server failed, shutting down", logger.Err(runErr))
+			break wait
+		}
+	}
+
+	a.shutdown(servers, cancel, workers)
+	return runErr
+}
+
+// reload applies the settings that are safe to change while serving,
+// for now the log level, from the config file and env variables.
+func (a *App) reload() {
+	var cfg config.Config
+	if err := config.Load(a.configPath, &cfg); err != nil {
+		a.Log.Error("failed to reload config", logger.Err(err))
+		return
+	}
+	if err := logger.SetLevel(cfg.LogLevel); err != nil {
+		a.Log.Error("failed to set log level", logger.Err(err))
+		return
+	}
+	a.Config.LogLevel = cfg.LogLevel
+	a.Log.Info("config reloaded", slog.String("log_level", cfg.LogLevel))
+}
+
+// drain turns readiness off and gives load balancers time to notice it
+// before the servers stop accepting connections.
+func (a *App) drain() {
+	a.Health.Shutdown()
+	if a.grpcHealth != nil {
+		a.grpcHealth.Shutdown()
+	}
+	if a.Config.Health.ShutdownDelay <= 0 {
+		return
+	}
+	a.Log.Info("readiness is off, draining requests", slog.Duration("delay", a.Config.Health.ShutdownDelay))
+	time.Sleep(a.Config.Health.ShutdownDelay)
+}
+
+// shutdown stops the servers in reverse order of start, so the metrics server goes last
+// and is scraped while the others drain, then the workers, hooks, database and tracing.
+func (a *App) shutdown(servers []namedHook, cancel context.CancelFunc, workers *sync.WaitGroup) {
+	var steps []namedHook
+	for i := len(servers) - 1; i >= 0; i-- {
+		steps = append(steps, servers[i])
+	}
+	steps = append(steps, namedHook{name: "workers", fn: func(ctx context.Context) error {
+		cancel()
+		if workers == nil {
+			return nil
+		}
+		done := make(chan struct{})
+		go func() {
+			workers.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}})
+	steps = append(steps, a.hooks...)
+	if a.db != nil {
+		steps = append(steps, namedHook{name: "database", fn: func(context.Context) error {
+			sqlDB, err := a.db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.Close()
+		}})
+	}
+	steps = append(steps, namedHook{name: "tracing", fn: a.shutdownTracing})
+
+	for _, step := range steps {
+		a.runStep(step)
+	}
+	a.Log.Info("service stopped")
+}
+
+func (a *App) runStep(step namedHook) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.Config.Server.ShutdownTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- step.fn(ctx) }()
+
+	select {
+	case err := <-done:
+		if err != nil && !errors.Is(err, context.Canceled) {
+			a.Log.Error("shutdown step failed", slog.String("step", step.name), logger.Err(err))
+			return
+		}
+		a.Log.Info("stopped", slog.String("step", step.name))
+	case <-ctx.Done():
+		a.Log.Error("shutdown step timed out", slog.String("step", step.name), slog.Duration("timeout", a.Config.Server.ShutdownTimeout))
+	}
+}
+
+func (a *App) startWorkers(ctx context.Context) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for _, w := range a.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.run(ctx); err != nil {
+				a.Log.Error("worker stopped", slog.String("worker", w.name), logger.Err(err))
+			}
+		}()
+	}
+	return &wg
+}
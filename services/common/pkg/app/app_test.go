@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestApp(t *testing.T) *App {
+	t.Setenv("PUBLIC_KEY", "test_public_key")
+	t.Setenv("GRPC_SERVER_USE_TLS", "false")
+	t.Setenv("GRPC_CLIENT_USE_TLS", "false")
+	a := New("test-service")
+	a.Config.Server.Addr = "127.0.0.1"
+	a.Config.Server.Port = 0
+	a.Config.Metrics.Addr = ""
+	a.Config.Health.ShutdownDelay = 0
+	a.Config.Server.ShutdownTimeout = 200 * time.Millisecond
+	return a
+}
+
+type recorder struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (r *recorder) add(step string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, step)
+}
+
+func TestRun_StopsWorkersBeforeHooksInOrder(t *testing.T) {
+	a := newTestApp(t)
+	rec := &recorder{}
+
+	started := make(chan struct{})
+	a.Go("consumer", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		rec.add("consumer")
+		return nil
+	})
+	a.OnShutdown("outbox", func(context.Context) error {
+		rec.add("outbox")
+		return nil
+	})
+	a.OnShutdown("bus", func(context.Context) error {
+		rec.add("bus")
+		return nil
+	})
+
+	go func() {
+		<-started
+		a.Stop()
+	}()
+	require.NoError(t, a.Run())
+
+	assert.Equal(t, []string{"consumer", "outbox", "bus"}, rec.steps)
+	_, ready := a.Health.Ready(context.Background())
+	assert.False(t, ready)
+}
+
+func TestRun_StepTimeoutDoesNotBlockShutdown(t *testing.T) {
+	a := newTestApp(t)
+	rec := &recorder{}
+
+	a.OnShutdown("stuck", func(context.Context) error {
+		select {}
+	})
+	a.OnShutdown("next", func(context.Context) error {
+		rec.add("next")
+		return nil
+	})
+
+	a.Stop()
+	start := time.Now()
+	require.NoError(t, a.Run())
+
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.Equal(t, []string{"next"}, rec.steps)
+}
+
+func TestRun_ReturnsListenError(t *testing.T) {
+	a := newTestApp(t)
+	a.Config.Server.Addr = "256.0.0.1"
+
+	assert.Error(t, a.Run())
+}
@@ -0,0 +1,355 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/config"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventbus"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/grpcauth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/health"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/logger"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/metrics"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/mtls"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/tracing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"gorm.io/gorm"
+)
+
+// MustConnectDB connects to the database from the config and applies the pending
+// migrations of the service, unless DB_AUTO_MIGRATE is off. Started as
+// `<binary> migrate ...` it runs the migrate command instead and exits, see db.MigrateUsage.
+// A database that isn't up yet is waited for, see DB_CONNECT_RETRIES. With DB_REPLICA_HOSTS
+// reads go to the replicas, see db.Replicas. The connection is
+// instrumented, logs to the service log, is checked by /readyz and closed at the end of the shutdown.
+// The credentials are loaded again for every new connection, so the ones rotated in a
+// secret file or in Vault are used without a restart.
+func (a *App) MustConnectDB(migrations fs.FS) *gorm.DB {
+	cfg := a.Config.Database
+	a.Log.Info("Connecting to db with params")
+	a.Log.Info("Database: ", slog.String("host", cfg.Host), slog.String("port", cfg.Port))
+
+	opts := db.Options{
+		MaxOpenConns:     cfg.MaxOpenConns,
+		MaxIdleConns:     cfg.MaxIdleConns,
+		ConnMaxLifetime:  cfg.ConnMaxLifetime,
+		ConnMaxIdleTime:  cfg.ConnMaxIdleTime,
+		StatementTimeout: cfg.StatementTimeout,
+		PrepareStmt:      cfg.PrepareStmt,
+		ConnectRetries:   cfg.ConnectRetries,
+		ConnectBackoff:   cfg.ConnectBackoff,
+		BatchSize:        cfg.BatchSize,
+		Logger:           db.NewLogger(a.Log.With(slog.String("component", "db/gorm")), cfg.SlowQuery),
+	}
+	conn, err := db.OpenWithCredentials(context.Background(), a.dsn(cfg.Host, cfg.Port), a.dbCredentials, opts)
+	if err != nil {
+		a.Log.Error("failed to connect to db", logger.Err(err))
+		os.Exit(1)
+	}
+	migrator := a.mustMigrator(conn, migrations)
+	if flag.Arg(0) == "migrate" {
+		if err := migrator.Command(context.Background(), flag.Args()[1:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if cfg.AutoMigrate {
+		applied, err := migrator.Up(context.Background())
+		for _, m := range applied {
+			a.Log.Info("applied migration", slog.Int64("version", m.Version), slog.String("name", m.Name))
+		}
+		if err != nil {
+			a.Log.Error("failed to migrate db", logger.Err(err))
+			os.Exit(1)
+		}
+	}
+
+	if err := metrics.InstrumentDB(conn, cfg.Name); err != nil {
+		a.Log.Error("failed to instrument db", logger.Err(err))
+	}
+	if err := tracing.InstrumentDB(conn); err != nil {
+		a.Log.Error("failed to instrument db", logger.Err(err))
+	}
+
+	if len(cfg.ReplicaHosts) > 0 {
+		a.mustUseReplicas(conn, opts)
+	}
+
+	a.Health.Add("postgres", health.DB(conn))
+	a.db = conn
+	return conn
+}
+
+// mustUseReplicas sends the reads of conn to the replicas of DB_REPLICA_HOSTS, the ones
+// down are left out and reads go to the primary when all are
+func (a *App) mustUseReplicas(conn *gorm.DB, opts db.Options) {
+	cfg := a.Config.Database
+	dsns := make([]string, 0, len(cfg.ReplicaHosts))
+	for _, hostPort := range cfg.ReplicaHosts {
+		host, port, err := net.SplitHostPort(hostPort)
+		if err != nil {
+			a.Log.Error("invalid replica address", slog.String("replica", hostPort), logger.Err(err))
+			os.Exit(1)
+		}
+		dsns = append(dsns, a.dsn(host, port))
+	}
+	log := a.Log.With(slog.String("component", "db/replicas"))
+	replicas, err := db.OpenReplicas(conn, dsns, a.dbCredentials, opts, cfg.ReplicaCheckInterval, log)
+	if err != nil {
+		a.Log.Error("failed to open read replicas", logger.Err(err))
+		os.Exit(1)
+	}
+	a.Log.Info("reading from replicas", slog.Int("replicas", len(dsns)), slog.Int("healthy", replicas.Healthy()))
+	a.OnClose("db replicas", replicas)
+}
+
+// dsn returns the DSN of the database from the config at host:port
+func (a *App) dsn(host, port string) string {
+	cfg := a.Config.Database
+	dsn := fmt.Sprintf("dbname=%s host=%s port=%s sslmode=%s", cfg.Name, host, port, cfg.SSLMode)
+	if cfg.SSLRootCert != "" {
+		dsn += " sslrootcert=" + cfg.SSLRootCert
+	}
+	return dsn
+}
+
+func (a *App) mustMigrator(conn *gorm.DB, migrations fs.FS) *db.Migrator {
+	loaded, err := db.LoadMigrations(migrations)
+	if err != nil {
+		a.Log.Error("failed to load migrations", logger.Err(err))
+		os.Exit(1)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		a.Log.Error("failed to connect to db", logger.Err(err))
+		os.Exit(1)
+	}
+	return db.NewMigrator(sqlDB, loaded)
+}
+
+func (a *App) dbCredentials(context.Context) (string, string, error) {
+	var cfg config.Config
+	if err := config.Load(a.configPath, &cfg); err != nil {
+		return "", "", err
+	}
+	return cfg.Database.User, cfg.Database.Password, nil
+}
+
+// EventBus returns the kafka publisher when KAFKA_ENABLED is set and a no-op one otherwise.
+// Pending events are flushed after the servers stopped, so requests being drained can still publish.
+func (a *App) EventBus() eventbus.Publisher {
+	if !a.Config.Kafka.Enabled {
+		return eventbus.Discard
+	}
+	publisher := eventbus.NewKafkaPublisher(a.Config.Kafka.Brokers, a.Config.Kafka.Topic)
+	a.Log.Info("publishing domain events", slog.String("topic", a.Config.Kafka.Topic))
+	// events are only published after the change is saved, so kafka being down doesn't stop the service
+	a.Health.AddOptional("kafka", health.Kafka(a.Config.Kafka.Brokers))
+	a.OnClose("event bus", publisher)
+	return publisher
+}
+
+// Outbox returns a publisher writing the events to the outbox table of conn, so published in
+// a db.UnitOfWork they're committed with the change, and starts relaying it to EventBus.
+// The outbox is flushed once more on shutdown before the event bus is closed.
+// Without KAFKA_ENABLED it's the no-op EventBus and the table is left alone.
+func (a *App) Outbox(conn *gorm.DB) eventbus.Publisher {
+	if !a.Config.Kafka.Enabled {
+		return a.EventBus()
+	}
+	relay := eventbus.NewRelay(a.Log, conn, a.EventBus(), eventbus.RelayOptions{
+		Interval:  a.Config.Kafka.OutboxInterval,
+		BatchSize: a.Config.Kafka.OutboxBatchSize,
+		Backoff:   a.Config.Kafka.RetryBackoff,
+	})
+	a.Go("outbox relay", relay.Run)
+	return eventbus.NewOutbox(conn)
+}
+
+// GRPC returns the gRPC server of the service with tracing and metrics, creating it on first use.
+// Services registered on it before Run are served on GRPC_SERVER_PORT along with grpc.health.v1.
+// With GRPC_SERVER_USE_TLS it requires client certificates signed by GRPC_SERVER_CA_FILE.
+// Calls other than health checks need a user or service token, see AuthorizeGRPC.
+func (a *App) GRPC() *grpc.Server {
+	if a.grpc == nil {
+		verifier, err := auth.NewVerifier(a.Config.PublicKey)
+		if err != nil {
+			a.Log.Error("failed to init gRPC token verifier", logger.Err(err))
+			os.Exit(1)
+		}
+		opts := []grpc.ServerOption{
+			tracing.ServerOption(),
+			grpc.ChainUnaryInterceptor(
+				metrics.UnaryServerInterceptor(),
+				mtls.UnaryServerInterceptor(a.grpcPolicy),
+				grpcauth.UnaryServerInterceptor(verifier, a.grpcAuth),
+			),
+			grpc.ChainStreamInterceptor(
+				metrics.StreamServerInterceptor(),
+				mtls.StreamServerInterceptor(a.grpcPolicy),
+				grpcauth.StreamServerInterceptor(verifier, a.grpcAuth),
+			),
+		}
+		if cfg := a.Config.GRPC.Server; cfg.UseTLS {
+			certs := a.mustLoadCerts("grpc server", cfg.CertFile, cfg.KeyFile, cfg.CAFile)
+			opts = append(opts, grpc.Creds(credentials.NewTLS(certs.ServerConfig())))
+		}
+		a.grpc = grpc.NewServer(opts...)
+	}
+	return a.grpc
+}
+
+// AllowGRPC lets only the peers, names in their client certificates, call the gRPC method.
+// Without GRPC_SERVER_USE_TLS peers can't be identified, so the service doesn't start
+// rather than serving the method to everyone.
+func (a *App) AllowGRPC(method string, peers ...string) {
+	if !a.Config.GRPC.Server.UseTLS {
+		a.Log.Error("gRPC peer allow-list needs GRPC_SERVER_USE_TLS", slog.String("method", method))
+		os.Exit(1)
+	}
+	a.grpcPolicy.Allow(method, peers...)
+}
+
+// AuthorizeGRPC sets who may call the gRPC method by the tokens of the call, methods
+// without a rule may be called by any user or service with a valid token.
+func (a *App) AuthorizeGRPC(method string, rule grpcauth.Rule) {
+	a.grpcAuth[method] = rule
+}
+
+// GRPCDialOptions returns the options for clients of other services: the transport
+// credentials, mutual TLS with the GRPC_CLIENT_* files when GRPC_CLIENT_USE_TLS is set
+// and plaintext otherwise, and the tokens sent with every call, the user token forwarded
+// from the HTTP request and the service token when SERVICE_TOKEN_SECRET is set.
+// The tokens go over plaintext only with ENV=local, elsewhere the service doesn't start.
+func (a *App) GRPCDialOptions() []grpc.DialOption {
+	plaintext := !a.Config.GRPC.Client.UseTLS
+	if plaintext && a.Config.Env != "local" {
+		a.Log.Error("gRPC clients send tokens and need GRPC_CLIENT_USE_TLS outside ENV=local", slog.String("env", a.Config.Env))
+		os.Exit(1)
+	}
+	if cfg := a.Config.ServiceAuth; a.grpcTokens == nil && cfg.Secret != "" {
+		a.grpcTokens = grpcauth.NewServiceTokens(cfg.TokenURL, a.Name, cfg.Secret)
+	}
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(a.transportCredentials()),
+		grpc.WithPerRPCCredentials(grpcauth.Credentials{Services: a.grpcTokens, Plaintext: plaintext}),
+	}
+}
+
+func (a *App) transportCredentials() credentials.TransportCredentials {
+	cfg := a.Config.GRPC.Client
+	if !cfg.UseTLS {
+		return insecure.NewCredentials()
+	}
+	if a.grpcCreds == nil {
+		certs := a.mustLoadCerts("grpc client", cfg.CertFile, cfg.KeyFile, cfg.CAFile)
+		a.grpcCreds = credentials.NewTLS(certs.ClientConfig(""))
+	}
+	return a.grpcCreds
+}
+
+// mustLoadCerts loads the certificates and reloads them in the background when the files change
+func (a *App) mustLoadCerts(name, certFile, keyFile, caFile string) *mtls.Reloader {
+	certs, err := mtls.NewReloader(a.Log, certFile, keyFile, caFile)
+	if err != nil {
+		a.Log.Error("failed to load certificates", slog.String("for", name), logger.Err(err))
+		os.Exit(1)
+	}
+	a.Go(name+" certificates", func(ctx context.Context) error {
+		return certs.Run(ctx, a.Config.GRPC.CertReloadInterval)
+	})
+	return certs
+}
+
+func (a *App) serveHTTP(failed chan<- error) (namedHook, error) {
+	srv := &http.Server{
+		Addr:         fmt.Sprintf("%s:%d", a.Config.Server.Addr, a.Config.Server.Port),
+		Handler:      a.Router,
+		ReadTimeout:  a.Config.Server.ReadTimeout,
+		WriteTimeout: a.Config.Server.WriteTimeout,
+		IdleTimeout:  a.Config.Server.IdleTimeout,
+	}
+	l, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return namedHook{}, err
+	}
+
+	go func() {
+		a.Log.Info(fmt.Sprintf("Server listening on port %d", a.Config.Server.Port))
+		if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			failed <- fmt.Errorf("http server: %w", err)
+		}
+	}()
+	return namedHook{name: "http server", fn: srv.Shutdown}, nil
+}
+
+func (a *App) serveGRPC(ctx context.Context, failed chan<- error) (namedHook, error) {
+	var services []string
+	for name := range a.grpc.GetServiceInfo() {
+		services = append(services, name)
+	}
+	a.grpcHealth = health.NewGRPCServer(a.Health, services...)
+	a.grpcHealth.Register(a.grpc)
+	go a.grpcHealth.Run(ctx, a.Config.Health.GRPCInterval)
+
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", a.Config.GRPC.Server.Port))
+	if err != nil {
+		return namedHook{}, err
+	}
+
+	go func() {
+		a.Log.Info(fmt.Sprintf("grpc server is running on port %d", a.Config.GRPC.Server.Port))
+		if err := a.grpc.Serve(l); err != nil {
+			failed <- fmt.Errorf("grpc server: %w", err)
+		}
+	}()
+
+	return namedHook{name: "grpc server", fn: func(ctx context.Context) error {
+		a.grpcHealth.Shutdown()
+		done := make(chan struct{})
+		go func() {
+			a.grpc.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			// streams that don't end on their own would block GracefulStop forever
+			a.grpc.Stop()
+			return ctx.Err()
+		}
+	}}, nil
+}
+
+func (a *App) serveMetrics() namedHook {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	srv := &http.Server{
+		Addr:              a.Config.Metrics.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			// the service works without metrics, so it isn't a reason to stop
+			a.Log.Error("metrics server stopped", logger.Err(err))
+		}
+	}()
+	return namedHook{name: "metrics server", fn: srv.Shutdown}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -20,8 +21,17 @@ var (
 	ErrInvalidClaims = errors.New("cannot parse token claims")
 	ErrInvalidExpClaim = errors.New("invalid exp claim in access token")
 	ErrTokenExpired = errors.New("token is expired")
+	ErrWrongAudience = errors.New("token is issued for another audience")
 )
 
+// TicketAudience is the audience of event tickets. They are signed with the same
+// kind of keys as access tokens, so VerifyJWTToken rejects them.
+const TicketAudience = "ticket"
+
+// ServiceAudience is the audience of the tokens auth-service issues to services
+// calling each other, their subject is the name of the service. VerifyJWTToken rejects them.
+const ServiceAudience = "service"
+
 // Verifier - sevice, that contains publicKey and verifys jwt tokens
 type Verifier struct {
 	publicKey  *ecdsa.PublicKey
@@ -58,6 +68,35 @@ func NewVerifier (publicKeyString string) (*Verifier, error) {
 
 // VerifyJWTToken takes accessToken as string and verified the signature
 func (v *Verifier) VerifyJWTToken(accessToken string) (jwt.MapClaims, error) {
+	claims, err := v.verify(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	audience, err := claims.GetAudience()
+	if err != nil || slices.Contains(audience, TicketAudience) || slices.Contains(audience, ServiceAudience) {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// VerifyAudience verifies the signature of the token and checks that it was issued for the audience
+func (v *Verifier) VerifyAudience(tokenString, audience string) (jwt.MapClaims, error) {
+	claims, err := v.verify(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenAudience, err := claims.GetAudience()
+	if err != nil || !slices.Contains(tokenAudience, audience) {
+		return nil, ErrWrongAudience
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) verify(accessToken string) (jwt.MapClaims, error) {
 	if accessToken == "" {
 		return nil, ErrMissingToken
 	}
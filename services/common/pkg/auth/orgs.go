@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OrgsClaim is the claim of access tokens holding the role of the user in each of their
+// organizations by organization ID, e.g. {"3": "owner", "7": "viewer"}
+const OrgsClaim = "orgs"
+
+// Roles of the members of an organization
+const (
+	// OrgOwner manages the members and the events of the organization
+	OrgOwner = "owner"
+	// OrgOrganizer creates and manages the events of the organization
+	OrgOrganizer = "organizer"
+	// OrgViewer only sees the organization
+	OrgViewer = "viewer"
+)
+
+// ErrForbidden is returned when the user of the token isn't allowed to do something
+var ErrForbidden = errors.New("not allowed")
+
+// IsOrgRole tells whether role is one of the roles of organization members
+func IsOrgRole(role string) bool {
+	return role == OrgOwner || role == OrgOrganizer || role == OrgViewer
+}
+
+// OrgRoles returns the roles of the user in their organizations by organization ID,
+// read from OrgsClaim. Entries that aren't an ID with a role are skipped.
+func OrgRoles(claims jwt.MapClaims) map[uint]string {
+	roles := make(map[uint]string)
+	add := func(key, role string) {
+		id, err := strconv.ParseUint(key, 10, 0)
+		if err == nil && IsOrgRole(role) {
+			roles[uint(id)] = role
+		}
+	}
+	switch orgs := claims[OrgsClaim].(type) {
+	case map[string]interface{}:
+		// decoded from a token
+		for key, role := range orgs {
+			role, _ := role.(string)
+			add(key, role)
+		}
+	case map[string]string:
+		for key, role := range orgs {
+			add(key, role)
+		}
+	}
+	return roles
+}
+
+// OrgRole returns the role of the user in the organization, empty when they aren't a member
+func OrgRole(claims jwt.MapClaims, orgID uint) string {
+	return OrgRoles(claims)[orgID]
+}
+
+// ManagesOrgEvents tells whether the user can create and manage the events of the
+// organization, that is whether they are its owner or one of its organizers
+func ManagesOrgEvents(claims jwt.MapClaims, orgID uint) bool {
+	role := OrgRole(claims, orgID)
+	return role == OrgOwner || role == OrgOrganizer
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestOrgRoles(t *testing.T) {
+	var claims jwt.MapClaims
+	raw := `{"username": "ivan", "orgs": {"3": "owner", "7": "viewer", "9": "admin", "x": "organizer", "11": 1}}`
+	if err := json.Unmarshal([]byte(raw), &claims); err != nil {
+		t.Fatalf("failed to decode claims: %v", err)
+	}
+
+	roles := OrgRoles(claims)
+	if len(roles) != 2 || roles[3] != OrgOwner || roles[7] != OrgViewer {
+		t.Fatalf("unexpected roles %v", roles)
+	}
+	if !ManagesOrgEvents(claims, 3) {
+		t.Error("the owner manages the events of the organization")
+	}
+	if ManagesOrgEvents(claims, 7) {
+		t.Error("a viewer doesn't manage the events of the organization")
+	}
+	if OrgRole(claims, 42) != "" {
+		t.Error("the user isn't a member of organization 42")
+	}
+
+	signed := jwt.MapClaims{OrgsClaim: map[string]string{"5": OrgOrganizer}}
+	if !ManagesOrgEvents(signed, 5) {
+		t.Error("an organizer manages the events of the organization")
+	}
+	if len(OrgRoles(jwt.MapClaims{})) != 0 {
+		t.Error("a token without organizations has no roles")
+	}
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// PermissionsClaim is the claim of access tokens holding the permissions granted to the
+// user by their role, e.g. ["events:update:any", "users:read"]
+const PermissionsClaim = "permissions"
+
+// Roles of the users, the permissions of each are stored in auth-service
+const (
+	RoleUser      = "user"
+	RoleOrganizer = "organizer"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// IsRole tells whether role is one of the roles of users
+func IsRole(role string) bool {
+	return role == RoleUser || role == RoleOrganizer || role == RoleModerator || role == RoleAdmin
+}
+
+// Permissions are resource:action or resource:action:scope strings, the any scope
+// applying to the resources of everyone. A granted permission ending with * covers the
+// ones it prefixes, * alone covers all of them.
+const (
+	PermEventsBulk      = "events:bulk"
+	PermEventsUpdateAny = "events:update:any"
+	PermEventsDeleteAny = "events:delete:any"
+	PermUsersRead       = "users:read"
+	PermUsersBan        = "users:ban"
+	PermUsersRoles      = "users:roles"
+)
+
+// ErrBanned is returned for the users banned by an administrator
+var ErrBanned = errors.New("user is banned")
+
+// Permissions returns the permissions of the user read from PermissionsClaim
+func Permissions(claims jwt.MapClaims) []string {
+	var permissions []string
+	switch granted := claims[PermissionsClaim].(type) {
+	case []interface{}:
+		// decoded from a token
+		for _, permission := range granted {
+			if permission, ok := permission.(string); ok {
+				permissions = append(permissions, permission)
+			}
+		}
+	case []string:
+		permissions = granted
+	}
+	return permissions
+}
+
+// HasPermission tells whether the permissions of the user cover permission
+func HasPermission(claims jwt.MapClaims, permission string) bool {
+	for _, granted := range Permissions(claims) {
+		if granted == permission {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(granted, "*"); ok && strings.HasPrefix(permission, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type claimsKey struct{}
+
+// WithClaims returns a copy of ctx carrying the claims of the access token
+func WithClaims(ctx context.Context, claims jwt.MapClaims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFrom returns the claims put in ctx by RequirePermission, nil without them
+func ClaimsFrom(ctx context.Context) jwt.MapClaims {
+	claims, _ := ctx.Value(claimsKey{}).(jwt.MapClaims)
+	return claims
+}
+
+// RequirePermission is a middleware letting through the requests whose access_token
+// cookie grants permission, e.g.
+//
+//	router.With(verifier.RequirePermission(auth.PermEventsDeleteAny)).Delete("/api/v1/events", ...)
+//
+// It answers Unauthorized without a valid token and Forbidden without the permission.
+// The claims of the token are in the context of the request, see ClaimsFrom.
+func (v *Verifier) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := r.Cookie("access_token")
+			if err != nil {
+				http.Error(w, "Unauthorized: "+ErrMissingToken.Error(), http.StatusUnauthorized)
+				return
+			}
+			claims, err := v.VerifyJWTToken(cookie.Value)
+			if err != nil {
+				http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if !HasPermission(claims, permission) {
+				http.Error(w, "Forbidden: missing permission "+permission, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+		})
+	}
+}
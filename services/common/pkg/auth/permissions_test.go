@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestHasPermission(t *testing.T) {
+	claims := jwt.MapClaims{PermissionsClaim: []interface{}{"users:read", "events:*", 3}}
+	for permission, want := range map[string]bool{
+		PermUsersRead:       true,
+		PermUsersBan:        false,
+		PermEventsDeleteAny: true,
+		PermEventsBulk:      true,
+	} {
+		if got := HasPermission(claims, permission); got != want {
+			t.Errorf("HasPermission(%q) = %v, want %v", permission, got, want)
+		}
+	}
+	if !HasPermission(jwt.MapClaims{PermissionsClaim: []string{"*"}}, PermUsersRoles) {
+		t.Error("* grants every permission")
+	}
+	if HasPermission(jwt.MapClaims{}, PermUsersRead) {
+		t.Error("a token without permissions grants none")
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	priv, _, pubKeyStr := generateECDSAKeys(t)
+	verif, err := NewVerifier(pubKeyStr)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	var seen jwt.MapClaims
+	handler := verif.RequirePermission(PermUsersBan)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = ClaimsFrom(r.Context())
+	}))
+
+	serve := func(permissions ...string) int {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if permissions != nil {
+			token := createToken(t, priv, jwt.MapClaims{
+				"username":       "admin",
+				PermissionsClaim: permissions,
+				"exp":            time.Now().Add(time.Hour).Unix(),
+			}, jwt.SigningMethodES256)
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := serve(); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", code)
+	}
+	if code := serve(PermUsersRead); code != http.StatusForbidden {
+		t.Errorf("expected 403 without the permission, got %d", code)
+	}
+	if seen != nil {
+		t.Fatal("the handler ran without the permission")
+	}
+	if code := serve(PermUsersRead, PermUsersBan); code != http.StatusOK {
+		t.Errorf("expected 200 with the permission, got %d", code)
+	}
+	if seen["username"] != "admin" {
+		t.Errorf("the claims aren't in the context: %v", seen)
+	}
+}
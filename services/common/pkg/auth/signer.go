@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signer - service, that contains privateKey and signs ES256 jwt tokens
+type Signer struct {
+	privateKey *ecdsa.PrivateKey
+}
+
+// NewSigner takes a Base64-encoded EC256 private key,
+// decodes it, and returns an instance of Signer
+func NewSigner(privateKeyString string) (*Signer, error) {
+	privateKeyBytes, err := base64.StdEncoding.DecodeString(privateKeyString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode private key: %w", err)
+	}
+
+	block, _ := pem.Decode(privateKeyBytes)
+	if block == nil {
+		return nil, errors.New("failed to parse PEM block containing the private key")
+	}
+
+	privateKey, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse EC private key: %w", err)
+	}
+
+	return &Signer{
+		privateKey: privateKey,
+	}, nil
+}
+
+// Sign returns the claims as a signed ES256 token
+func (s *Signer) Sign(claims jwt.MapClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(s.privateKey)
+}
+
+// Verifier returns a Verifier for the tokens issued by the signer
+func (s *Signer) Verifier() *Verifier {
+	return &Verifier{
+		publicKey: &s.privateKey.PublicKey,
+	}
+}
+
+// JWK is the public key in JSON Web Key format (RFC 7517),
+// it lets clients verify tokens without calling the service
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// JWK returns the public key of the verifier
+func (v *Verifier) JWK() JWK {
+	size := (v.publicKey.Curve.Params().BitSize + 7) / 8
+	return JWK{
+		KeyType:   "EC",
+		Curve:     v.publicKey.Curve.Params().Name,
+		X:         encodeCoordinate(v.publicKey.X, size),
+		Y:         encodeCoordinate(v.publicKey.Y, size),
+		Algorithm: jwt.SigningMethodES256.Alg(),
+		Use:       "sig",
+	}
+}
+
+func encodeCoordinate(n *big.Int, size int) string {
+	return base64.RawURLEncoding.EncodeToString(n.FillBytes(make([]byte, size)))
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newTestSigner создаёт Signer и Verifier для одной и той же пары ключей.
+func newTestSigner(t *testing.T) (*Signer, *Verifier) {
+	priv, _, pubKeyStr := generateECDSAKeys(t)
+	privBytes, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	privKeyStr := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{
+		Type:  "EC PRIVATE KEY",
+		Bytes: privBytes,
+	}))
+
+	signer, err := NewSigner(privKeyStr)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	verif, err := NewVerifier(pubKeyStr)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	return signer, verif
+}
+
+// TestNewSigner_Invalid проверяет создание Signer с некорректной строкой приватного ключа.
+func TestNewSigner_Invalid(t *testing.T) {
+	if _, err := NewSigner("invalid-base64"); err == nil {
+		t.Fatal("expected error for invalid base64 input")
+	}
+}
+
+// TestSigner_VerifyAudience проверяет, что подписанный токен проходит проверку только для своей аудитории.
+func TestSigner_VerifyAudience(t *testing.T) {
+	signer, verif := newTestSigner(t)
+
+	tokenString, err := signer.Sign(jwt.MapClaims{
+		"aud": TicketAudience,
+		"sub": "42",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	for _, v := range []*Verifier{verif, signer.Verifier()} {
+		claims, err := v.VerifyAudience(tokenString, TicketAudience)
+		if err != nil {
+			t.Fatalf("expected valid ticket, got error: %v", err)
+		}
+		if claims["sub"] != "42" {
+			t.Fatalf("expected sub claim 42, got %v", claims["sub"])
+		}
+		if _, err := v.VerifyAudience(tokenString, "other"); !errors.Is(err, ErrWrongAudience) {
+			t.Fatalf("expected ErrWrongAudience, got %v", err)
+		}
+	}
+}
+
+// TestVerifyJWTToken_RejectsTicket проверяет, что билет нельзя использовать как access token.
+func TestVerifyJWTToken_RejectsTicket(t *testing.T) {
+	signer, verif := newTestSigner(t)
+
+	tokenString, err := signer.Sign(jwt.MapClaims{
+		"aud":      []string{TicketAudience},
+		"username": "alice",
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if _, err := verif.VerifyJWTToken(tokenString); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+// TestVerifyJWTToken_RejectsServiceToken проверяет, что токен сервиса нельзя использовать как access token.
+func TestVerifyJWTToken_RejectsServiceToken(t *testing.T) {
+	signer, verif := newTestSigner(t)
+
+	tokenString, err := signer.Sign(jwt.MapClaims{
+		"aud": []string{ServiceAudience},
+		"sub": "registration-service",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if _, err := verif.VerifyJWTToken(tokenString); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+// TestVerifier_JWK проверяет, что JWK содержит координаты публичного ключа.
+func TestVerifier_JWK(t *testing.T) {
+	signer, verif := newTestSigner(t)
+	jwk := verif.JWK()
+
+	if jwk.KeyType != "EC" || jwk.Curve != "P-256" || jwk.Algorithm != "ES256" {
+		t.Fatalf("unexpected key parameters: %+v", jwk)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	if err != nil || len(x) != 32 {
+		t.Fatalf("invalid x coordinate %q: %v", jwk.X, err)
+	}
+	if new(big.Int).SetBytes(x).Cmp(signer.privateKey.X) != 0 {
+		t.Fatal("x coordinate does not match the public key")
+	}
+}
@@ -0,0 +1,120 @@
+// Package breaker stops calling an upstream that keeps failing, so callers fail fast
+// instead of piling up on timeouts. After a cooldown a single call is let through to
+// find out whether the upstream is back.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Allow while calls are not let through
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State of a breaker
+type State int
+
+const (
+	// Closed lets every call through
+	Closed State = iota
+	// Open fails calls until the cooldown is over
+	Open
+	// HalfOpen lets a single probe call through
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+// Breaker opens after a number of consecutive failures
+type Breaker struct {
+	failures int
+	cooldown time.Duration
+	now      func() time.Time
+
+	mu          sync.Mutex
+	consecutive int
+	openedAt    time.Time
+	probing     bool
+}
+
+// New creates a breaker opening after failures consecutive failures for cooldown.
+// A breaker with failures below 1 never opens.
+func New(failures int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		failures: failures,
+		cooldown: cooldown,
+		now:      time.Now,
+	}
+}
+
+// State returns the current state
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state()
+}
+
+func (b *Breaker) state() State {
+	switch {
+	case b.failures < 1 || b.consecutive < b.failures:
+		return Closed
+	case b.now().Sub(b.openedAt) < b.cooldown:
+		return Open
+	default:
+		return HalfOpen
+	}
+}
+
+// Allow returns nil when a call may be made now, each allowed call must be followed by
+// Success, Failure or Ignore.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state() {
+	case Closed:
+		return nil
+	case HalfOpen:
+		if !b.probing {
+			b.probing = true
+			return nil
+		}
+	}
+	return ErrOpen
+}
+
+// Success records an allowed call that got an answer, closing the breaker
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutive = 0
+	b.probing = false
+}
+
+// Failure records an allowed call that failed, a failed probe opens the breaker again
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutive++
+	if b.probing || b.consecutive == b.failures {
+		b.openedAt = b.now()
+	}
+	b.probing = false
+}
+
+// Ignore records an allowed call that says nothing about the upstream, e.g. one the
+// caller cancelled
+func (b *Breaker) Ignore() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
@@ -0,0 +1,54 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker_OpensAndProbesAfterCooldown(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := New(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	for range 2 {
+		assert.NoError(t, b.Allow())
+		b.Failure()
+	}
+	assert.Equal(t, Open, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrOpen)
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, HalfOpen, b.State())
+	assert.NoError(t, b.Allow(), "the probe goes through")
+	assert.ErrorIs(t, b.Allow(), ErrOpen, "only one probe at a time")
+	b.Failure()
+	assert.Equal(t, Open, b.State(), "a failed probe opens it for another cooldown")
+
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	b.Success()
+	assert.Equal(t, Closed, b.State())
+	assert.NoError(t, b.Allow())
+}
+
+func TestBreaker_IgnoredProbeLetsAnotherOneThrough(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := New(1, time.Second)
+	b.now = func() time.Time { return now }
+
+	b.Failure()
+	now = now.Add(time.Second)
+	assert.NoError(t, b.Allow())
+	b.Ignore()
+	assert.NoError(t, b.Allow())
+}
+
+func TestBreaker_WithoutThresholdNeverOpens(t *testing.T) {
+	b := New(0, time.Minute)
+	for range 10 {
+		b.Failure()
+	}
+	assert.Equal(t, Closed, b.State())
+}
@@ -0,0 +1,41 @@
+package breaker
+
+import (
+	"context"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FailureCodes are the codes telling that the upstream is in trouble rather than that
+// the call itself was refused
+var FailureCodes = []codes.Code{
+	codes.Unavailable,
+	codes.DeadlineExceeded,
+	codes.ResourceExhausted,
+	codes.Internal,
+	codes.Unknown,
+}
+
+// UnaryClientInterceptor fails calls with Unavailable while b is open and counts the
+// calls ending with one of FailureCodes. Put it before the retry interceptor, so a call
+// is counted once however many times it is tried.
+func UnaryClientInterceptor(b *Breaker) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := b.Allow(); err != nil {
+			return status.Errorf(codes.Unavailable, "%s: %v", cc.Target(), err)
+		}
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		switch {
+		case ctx.Err() != nil:
+			b.Ignore()
+		case slices.Contains(FailureCodes, status.Code(err)):
+			b.Failure()
+		default:
+			b.Success()
+		}
+		return err
+	}
+}
@@ -0,0 +1,21 @@
+// Package cache keeps the results of repository reads in a backend shared by the
+// queries of a type, see Repository. The backend is an in-process LRU by default or
+// Redis when the cache is shared between instances.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Backend stores cached values. Counters never expire, every other key expires after
+// its ttl or earlier when the backend needs the room.
+type Backend interface {
+	// Get returns the value of key, ok is false when there is none
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Counter returns the value of the counter, 0 if it was never incremented
+	Counter(ctx context.Context, key string) (int64, error)
+	// Incr increments the counter and returns its new value
+	Incr(ctx context.Context, key string) (int64, error)
+}
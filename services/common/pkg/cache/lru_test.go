@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU_EvictsLeastRecentlyUsedAndExpired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	lru := NewLRU(2)
+	lru.now = func() time.Time { return now }
+
+	require.NoError(t, lru.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, lru.Set(ctx, "b", []byte("2"), time.Minute))
+	_, ok, _ := lru.Get(ctx, "a")
+	assert.True(t, ok)
+	require.NoError(t, lru.Set(ctx, "c", []byte("3"), time.Minute))
+
+	_, ok, _ = lru.Get(ctx, "b")
+	assert.False(t, ok, "b was used least recently")
+	value, ok, _ := lru.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+
+	now = now.Add(time.Minute)
+	_, ok, _ = lru.Get(ctx, "a")
+	assert.False(t, ok, "expired")
+	assert.Equal(t, 1, lru.Len())
+
+	for range 3 {
+		lru.Incr(ctx, "generation")
+	}
+	generation, _ := lru.Counter(ctx, "generation")
+	assert.Equal(t, int64(3), generation, "counters are never evicted")
+}
@@ -0,0 +1,224 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/logger"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+// Options configure a cached repository
+type Options struct {
+	// Prefix keeps the keys of the type apart from the others in a shared backend
+	Prefix string
+	TTL    time.Duration
+	// Log gets the backend failures, the reads fall back to the repository then
+	Log *slog.Logger
+}
+
+// Repository is a cache-aside repository.Interface[T]: reads are answered from the
+// backend and loaded from the wrapped repository on a miss, concurrent misses of a
+// query loading it once. Every write invalidates all the cached reads of the type by
+// moving them to a new generation, the old one is left to expire. Reads with
+// db.ReadYourWrites or in a db.UnitOfWork skip the cache.
+type Repository[T any] struct {
+	repo    repository.Interface[T]
+	backend Backend
+	opts    Options
+	ctx     context.Context
+	loads   *singleflight.Group
+}
+
+// NewRepository wraps repo with the cache in backend
+func NewRepository[T any](repo repository.Interface[T], backend Backend, opts Options) *Repository[T] {
+	if opts.Log == nil {
+		opts.Log = slog.Default()
+	}
+	return &Repository[T]{
+		repo:    repo,
+		backend: backend,
+		opts:    opts,
+		ctx:     context.Background(),
+		loads:   &singleflight.Group{},
+	}
+}
+
+// WithContext returns a copy of the repository whose queries and backend calls run with ctx
+func (r *Repository[T]) WithContext(ctx context.Context) repository.Interface[T] {
+	scoped := *r
+	scoped.repo = repository.WithContext(r.repo, ctx)
+	scoped.ctx = ctx
+	return &scoped
+}
+
+func (r *Repository[T]) GetByID(id int) (*T, error) {
+	return cached(r, "id", []any{id}, func() (*T, error) { return r.repo.GetByID(id) })
+}
+
+func (r *Repository[T]) GetAll() ([]T, error) {
+	return cached(r, "all", nil, r.repo.GetAll)
+}
+
+func (r *Repository[T]) Find(condition interface{}, args ...interface{}) ([]T, error) {
+	return cached(r, "find", append([]any{condition}, args...), func() ([]T, error) {
+		return r.repo.Find(condition, args...)
+	})
+}
+
+func (r *Repository[T]) FindFirst(condition interface{}, args ...interface{}) (*T, error) {
+	return cached(r, "first", append([]any{condition}, args...), func() (*T, error) {
+		return r.repo.FindFirst(condition, args...)
+	})
+}
+
+func (r *Repository[T]) Count(condition interface{}, args ...interface{}) (int64, error) {
+	return cached(r, "count", append([]any{condition}, args...), func() (int64, error) {
+		return r.repo.Count(condition, args...)
+	})
+}
+
+func (r *Repository[T]) GetPage(page int, pageSize int, condition interface{}, args ...interface{}) ([]T, error) {
+	return cached(r, "page", append([]any{page, pageSize, condition}, args...), func() ([]T, error) {
+		return r.repo.GetPage(page, pageSize, condition, args...)
+	})
+}
+
+// Aggregate isn't cached, the stats can't be told apart by a key
+func (r *Repository[T]) Aggregate(stats repository.Stats[T]) ([]repository.StatsRow, error) {
+	return r.repo.Aggregate(stats)
+}
+
+func (r *Repository[T]) Create(entity *T) (*T, error) {
+	defer r.invalidate()
+	return r.repo.Create(entity)
+}
+
+func (r *Repository[T]) Update(entity *T) (*T, error) {
+	defer r.invalidate()
+	return r.repo.Update(entity)
+}
+
+func (r *Repository[T]) Delete(id int) error {
+	defer r.invalidate()
+	return r.repo.Delete(id)
+}
+
+func (r *Repository[T]) DeleteWhere(condition interface{}, args ...interface{}) error {
+	defer r.invalidate()
+	return r.repo.DeleteWhere(condition, args...)
+}
+
+func (r *Repository[T]) BulkInsert(entities []*T) error {
+	defer r.invalidate()
+	return r.repo.BulkInsert(entities)
+}
+
+func (r *Repository[T]) InsertEach(entities []*T) (*repository.BatchResult, error) {
+	defer r.invalidate()
+	return r.repo.InsertEach(entities)
+}
+
+func (r *Repository[T]) BulkUpdate(condition interface{}, args []interface{}, updateData interface{}) error {
+	defer r.invalidate()
+	return r.repo.BulkUpdate(condition, args, updateData)
+}
+
+// ExecuteInTransaction runs fn in a transaction of the wrapped repository, anything
+// may be written in it so the cache is invalidated whatever the outcome
+func (r *Repository[T]) ExecuteInTransaction(fn func(tx *gorm.DB) error) error {
+	defer r.invalidate()
+	return r.repo.ExecuteInTransaction(fn)
+}
+
+// Invalidate drops the cached reads of the type, for writes made around the repository
+func (r *Repository[T]) Invalidate() {
+	r.invalidate()
+}
+
+// invalidate moves the reads to a new generation. In a unit of work it does it again
+// after the commit, a read between the write and the commit caches what it replaces.
+func (r *Repository[T]) invalidate() {
+	r.incrGeneration(r.ctx)
+	if db.InUnitOfWork(r.ctx) {
+		db.AfterCommit(r.ctx, r.incrGeneration)
+	}
+}
+
+func (r *Repository[T]) incrGeneration(ctx context.Context) {
+	if _, err := r.backend.Incr(ctx, r.generationKey()); err != nil {
+		r.opts.Log.Error("failed to invalidate the cache", slog.String("prefix", r.opts.Prefix), logger.Err(err))
+	}
+}
+
+func (r *Repository[T]) generationKey() string {
+	return r.opts.Prefix + ":generation"
+}
+
+// cached returns the result of the query op with params from the backend or from load.
+// Values are stored gob encoded and decoded for every caller, so none of them shares
+// an entity with another one.
+func cached[T, V any](r *Repository[T], op string, params []any, load func() (V, error)) (V, error) {
+	// a transaction reads its own writes, which aren't for the others before the commit
+	if db.UsesPrimary(r.ctx) || db.InUnitOfWork(r.ctx) {
+		return load()
+	}
+	generation, err := r.backend.Counter(r.ctx, r.generationKey())
+	if err != nil {
+		r.opts.Log.Warn("cache is not available", slog.String("prefix", r.opts.Prefix), logger.Err(err))
+		return load()
+	}
+	key := fmt.Sprintf("%s:%d:%s:%s", r.opts.Prefix, generation, op, hash(params))
+
+	data, ok, err := r.backend.Get(r.ctx, key)
+	if err != nil {
+		r.opts.Log.Warn("failed to read the cache", slog.String("key", key), logger.Err(err))
+	}
+	if !ok {
+		shared, err, _ := r.loads.Do(key, func() (any, error) {
+			value, err := load()
+			if err != nil {
+				return nil, err
+			}
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+				return nil, fmt.Errorf("encoding %s: %w", key, err)
+			}
+			if err := r.backend.Set(r.ctx, key, buf.Bytes(), r.opts.TTL); err != nil {
+				r.opts.Log.Warn("failed to write the cache", slog.String("key", key), logger.Err(err))
+			}
+			return buf.Bytes(), nil
+		})
+		if err != nil {
+			var zero V
+			return zero, err
+		}
+		data = shared.([]byte)
+	}
+
+	var value V
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value); err != nil {
+		return value, fmt.Errorf("decoding %s: %w", key, err)
+	}
+	// gob doesn't tell an empty slice from nil, the repository returns empty ones
+	if v := reflect.ValueOf(&value).Elem(); v.Kind() == reflect.Slice && v.IsNil() {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	return value, nil
+}
+
+// hash returns a short digest of the query parameters
+func hash(params []any) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%#v", params)))
+	return hex.EncodeToString(sum[:12])
+}
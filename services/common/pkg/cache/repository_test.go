@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type item struct {
+	ID   int `gorm:"primaryKey"`
+	Name string
+	// Secret is kept in the cache although it isn't in the JSON
+	Secret string `json:"-"`
+}
+
+// countingRepository counts the reads reaching the database
+type countingRepository struct {
+	*repository.GenericRepository[item]
+	reads atomic.Int32
+	gate  chan struct{}
+}
+
+func (c *countingRepository) GetByID(id int) (*item, error) {
+	c.reads.Add(1)
+	if c.gate != nil {
+		<-c.gate
+	}
+	return c.GenericRepository.GetByID(id)
+}
+
+func (c *countingRepository) GetAll() ([]item, error) {
+	c.reads.Add(1)
+	return c.GenericRepository.GetAll()
+}
+
+func newItems(t *testing.T) *countingRepository {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, database.AutoMigrate(&item{}))
+	return &countingRepository{GenericRepository: repository.NewGenericRepository[item](database)}
+}
+
+func backends(t *testing.T) map[string]Backend {
+	server := miniredis.RunT(t)
+	redis := DialRedis(server.Addr(), "", 0)
+	t.Cleanup(func() { redis.Close() })
+	return map[string]Backend{"lru": NewLRU(100), "redis": redis}
+}
+
+func TestRepository_CachesReadsUntilAWrite(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			items := newItems(t)
+			repo := NewRepository[item](items, backend, Options{Prefix: "items", TTL: time.Minute})
+
+			all, err := repo.GetAll()
+			require.NoError(t, err)
+			assert.NotNil(t, all, "an empty list stays a list")
+			assert.Empty(t, all)
+
+			created, err := repo.Create(&item{Name: "first", Secret: "s"})
+			require.NoError(t, err)
+			for range 3 {
+				got, err := repo.GetByID(created.ID)
+				require.NoError(t, err)
+				assert.Equal(t, "s", got.Secret)
+				got.Name = "changed by the caller"
+			}
+			assert.Equal(t, int32(2), items.reads.Load(), "one read per query until a write")
+
+			all, err = repo.GetAll()
+			require.NoError(t, err)
+			assert.Len(t, all, 1, "the create invalidated the empty list")
+
+			_, err = repo.Update(&item{ID: created.ID, Name: "renamed"})
+			require.NoError(t, err)
+			got, err := repo.GetByID(created.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "renamed", got.Name)
+
+			_, err = repo.GetByID(404)
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+			_, err = repo.GetByID(404)
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "misses aren't cached")
+
+			// written around the cache
+			_, err = items.Update(&item{ID: created.ID, Name: "behind the cache"})
+			require.NoError(t, err)
+			got, err = repo.GetByID(created.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "renamed", got.Name)
+			got, err = repo.WithContext(db.ReadYourWrites(context.Background())).GetByID(created.ID)
+			require.NoError(t, err)
+			assert.Equal(t, "behind the cache", got.Name, "read your writes skips the cache")
+		})
+	}
+}
+
+func TestRepository_CollapsesConcurrentMisses(t *testing.T) {
+	items := newItems(t)
+	created, err := items.Create(&item{Name: "hot"})
+	require.NoError(t, err)
+	items.gate = make(chan struct{})
+	repo := NewRepository[item](items, NewLRU(100), Options{Prefix: "items", TTL: time.Minute})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := repo.GetByID(created.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, "hot", got.Name)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(items.gate)
+	wg.Wait()
+	assert.Equal(t, int32(1), items.reads.Load())
+}
+
+type failingBackend struct{ Backend }
+
+func (failingBackend) Counter(context.Context, string) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestRepository_ReadsThroughAFailingBackend(t *testing.T) {
+	items := newItems(t)
+	repo := NewRepository[item](items, failingBackend{NewLRU(1)}, Options{Prefix: "items", TTL: time.Minute})
+
+	for range 2 {
+		_, err := repo.GetAll()
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), items.reads.Load())
+}
@@ -1,27 +1,35 @@
 package config
 
 import (
+	"errors"
 	"log"
 	"time"
-
-	"github.com/kelseyhightower/envconfig"
 )
 
 // Config contains app configuration variables
 type Config struct {
     Env string `yaml:"env" envconfig:"ENV" default:"local"`
+    // LogLevel overrides the level chosen by Env, it is reloaded on SIGHUP
+    LogLevel string `yaml:"log_level" envconfig:"LOG_LEVEL" validate:"oneof=debug info warn error"`
 	Server struct {
-		Port int    `yaml:"port" envconfig:"SERVER_PORT" default:"8080"`
+		Port int    `yaml:"port" envconfig:"SERVER_PORT" default:"8080" validate:"port"`
 		Addr string `yaml:"host" envconfig:"SERVER_ADDR" default:"0.0.0.0"`
 	    ReadTimeout   time.Duration    `yaml:"read_timeout" envconfig:"SERVER_READ_TIMEOUT" default:"10s"` 
     	WriteTimeout  time.Duration    `yaml:"write_timeout" envconfig:"SERVER_WRITE_TIMEOUT" default:"10s"`
         IdleTimeout  time.Duration    `yaml:"idle_timeout" envconfig:"SERVER_IDLE_TIMEOUT" default:"60s"`
+        // ShutdownTimeout bounds each step of the graceful shutdown
+        ShutdownTimeout time.Duration `yaml:"shutdown_timeout" envconfig:"SERVER_SHUTDOWN_TIMEOUT" default:"10s" validate:"min=1s"`
 	}
 
+    // GRPC channels between services use mutual TLS unless UseTLS is turned off, every
+    // service presents its certificate and verifies the peer's against the CA. Services
+    // without gRPC channels turn it off so they don't need certificates
     GRPC struct {
+        // CertReloadInterval is how often the certificate files are checked for changes
+        CertReloadInterval time.Duration `yaml:"cert_reload_interval" envconfig:"GRPC_CERT_RELOAD_INTERVAL" default:"1m" validate:"min=1s"`
         Server struct {
             Host     string        `yaml:"host" envconfig:"GRPC_SERVER_HOST" default:"0.0.0.0"`
-            Port     int           `yaml:"port" envconfig:"GRPC_SERVER_PORT" default:"9091"`
+            Port     int           `yaml:"port" envconfig:"GRPC_SERVER_PORT" default:"9091" validate:"port"`
             UseTLS   bool          `yaml:"use_tls" envconfig:"GRPC_SERVER_USE_TLS" default:"true"`
             CertFile string        `yaml:"cert_file" envconfig:"GRPC_SERVER_CERT_FILE" default:"certs/server.crt"`
             KeyFile  string        `yaml:"key_file" envconfig:"GRPC_SERVER_KEY_FILE" default:"certs/server.key"`
@@ -29,63 +37,132 @@ type Config struct {
             Timeout  time.Duration `yaml:"timeout" envconfig:"GRPC_SERVER_TIMEOUT" default:"5s"`
         }
         Client struct {
-            Host         string        `yaml:"host" envconfig:"GRPC_CLIENT_HOST" default:"localhost"`
-            Port         int           `yaml:"port" envconfig:"GRPC_CLIENT_PORT" default:"9091"`
-            UseTLS       bool          `yaml:"use_tls" envconfig:"GRPC_CLIENT_USE_TLS" default:"true"`
-            CertFile     string        `yaml:"cert_file" envconfig:"GRPC_CLIENT_CERT_FILE" default:"certs/client.crt"`
-            KeyFile      string        `yaml:"key_file" envconfig:"GRPC_CLIENT_KEY_FILE" default:"certs/client.key"`
-            CAFile       string        `yaml:"ca_file" envconfig:"GRPC_CLIENT_CA_FILE" default:"certs/ca.crt"`
-            Timeout      time.Duration `yaml:"timeout" envconfig:"GRPC_CLIENT_TIMEOUT" default:"5s"`
-            RetryCount   int           `yaml:"retry_count" envconfig:"GRPC_CLIENT_RETRY_COUNT" default:"3"`
-            RetryTimeout time.Duration `yaml:"retry_timeout" envconfig:"GRPC_CLIENT_RETRY_TIMEOUT" default:"2s"`
+            Host            string        `yaml:"host" envconfig:"GRPC_CLIENT_HOST" default:"localhost"`
+            Port            int           `yaml:"port" envconfig:"GRPC_CLIENT_PORT" default:"9091" validate:"port"`
+            UseTLS          bool          `yaml:"use_tls" envconfig:"GRPC_CLIENT_USE_TLS" default:"true"`
+            CertFile        string        `yaml:"cert_file" envconfig:"GRPC_CLIENT_CERT_FILE" default:"certs/client.crt"`
+            KeyFile         string        `yaml:"key_file" envconfig:"GRPC_CLIENT_KEY_FILE" default:"certs/client.key"`
+            CAFile          string        `yaml:"ca_file" envconfig:"GRPC_CLIENT_CA_FILE" default:"certs/ca.crt"`
+            Timeout         time.Duration `yaml:"timeout" envconfig:"GRPC_CLIENT_TIMEOUT" default:"5s"`
+            RetryCount      int           `yaml:"retry_count" envconfig:"GRPC_CLIENT_RETRY_COUNT" default:"3" validate:"min=0"`
+            RetryTimeout    time.Duration `yaml:"retry_timeout" envconfig:"GRPC_CLIENT_RETRY_TIMEOUT" default:"2s"`
+            RetryBackoff    time.Duration `yaml:"retry_backoff" envconfig:"GRPC_CLIENT_RETRY_BACKOFF" default:"100ms"`
+            // BreakerFailures consecutive failed calls stop calls for BreakerCooldown, 0 disables it
+            BreakerFailures int           `yaml:"breaker_failures" envconfig:"GRPC_CLIENT_BREAKER_FAILURES" default:"5" validate:"min=0"`
+            BreakerCooldown time.Duration `yaml:"breaker_cooldown" envconfig:"GRPC_CLIENT_BREAKER_COOLDOWN" default:"10s"`
         }
     }
 
+    // ServiceAuth is how the service gets its token for calling other services over gRPC,
+    // the secret is the one auth-service knows the service by in SERVICE_CLIENTS
+    ServiceAuth struct {
+        TokenURL string `yaml:"token_url" envconfig:"SERVICE_TOKEN_URL" default:"http://localhost:8081/api/v1/auth/service-token"`
+        Secret   string `yaml:"secret" envconfig:"SERVICE_TOKEN_SECRET" secret:"true"`
+    } `yaml:"service_auth"`
+
+    // Metrics is the address of the Prometheus metrics server
+    Metrics struct {
+        Addr string `yaml:"addr" envconfig:"METRICS_ADDR" default:":9100"`
+    } `yaml:"metrics"`
+
+    // Tracing configures OpenTelemetry, the exporter is none, stdout or otlp
+    Tracing struct {
+        Exporter    string  `yaml:"exporter" envconfig:"TRACING_EXPORTER" default:"none" validate:"oneof=none stdout otlp"`
+        Endpoint    string  `yaml:"endpoint" envconfig:"TRACING_OTLP_ENDPOINT" default:"localhost:4317"`
+        Insecure    bool    `yaml:"insecure" envconfig:"TRACING_OTLP_INSECURE" default:"true"`
+        SampleRatio float64 `yaml:"sample_ratio" envconfig:"TRACING_SAMPLE_RATIO" default:"1" validate:"min=0,max=1"`
+    } `yaml:"tracing"`
+
+    // Health configures dependency checks and the readiness drain on shutdown
+    Health struct {
+        CheckTimeout  time.Duration `yaml:"check_timeout" envconfig:"HEALTH_CHECK_TIMEOUT" default:"2s" validate:"min=1ms"`
+        GRPCInterval  time.Duration `yaml:"grpc_interval" envconfig:"HEALTH_GRPC_INTERVAL" default:"5s" validate:"min=1ms"`
+        ShutdownDelay time.Duration `yaml:"shutdown_delay" envconfig:"HEALTH_SHUTDOWN_DELAY" default:"5s"`
+    } `yaml:"health"`
+
+	// Database credentials may be references like vault:database/creds/events#username,
+	// they are resolved again for every new connection, so rotated ones need no restart
 	Database struct {
-        User     string `yaml:"user" envconfig:"DB_USER" default:"postgres"`
-        Password string `yaml:"password" envconfig:"DB_PASSWORD" default:"postgres"`
-		Host     string `yaml:"host" envconfig:"DB_HOST" default:"localhost"`
-		Port     string `yaml:"port" envconfig:"DB_PORT" default:"5432"`
-		Name     string `yaml:"name" envconfig:"DB_NAME" default:"authdb"`
+        User     string `yaml:"user" envconfig:"DB_USER" default:"postgres" validate:"required" secret:"true"`
+        Password string `yaml:"password" envconfig:"DB_PASSWORD" default:"postgres" secret:"true"`
+		Host     string `yaml:"host" envconfig:"DB_HOST" default:"localhost" validate:"required"`
+		Port     string `yaml:"port" envconfig:"DB_PORT" default:"5432" validate:"port"`
+		Name     string `yaml:"name" envconfig:"DB_NAME" default:"authdb" validate:"required"`
+		// SSLMode is the libpq sslmode, verify-ca and verify-full check the server against SSLRootCert
+		SSLMode     string `yaml:"sslmode" envconfig:"DB_SSLMODE" default:"disable" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+		SSLRootCert string `yaml:"sslrootcert" envconfig:"DB_SSLROOTCERT"`
+		MaxOpenConns    int           `yaml:"max_open_conns" envconfig:"DB_MAX_OPEN_CONNS" default:"25" validate:"min=1"`
+		MaxIdleConns    int           `yaml:"max_idle_conns" envconfig:"DB_MAX_IDLE_CONNS" default:"5" validate:"min=0"`
+		// ConnMaxLifetime recycles pooled connections, keep it below the lifetime of the credentials
+		ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" envconfig:"DB_CONN_MAX_LIFETIME" default:"30m" validate:"min=1s"`
+		ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" envconfig:"DB_CONN_MAX_IDLE_TIME" default:"5m"`
+		// StatementTimeout cancels statements running longer on the server, 0 disables it
+		StatementTimeout time.Duration `yaml:"statement_timeout" envconfig:"DB_STATEMENT_TIMEOUT" default:"30s"`
+		// PrepareStmt caches prepared statements per connection, turn it off behind pgbouncer
+		// in transaction mode
+		PrepareStmt bool `yaml:"prepare_stmt" envconfig:"DB_PREPARE_STMT" default:"true"`
+		// ConnectRetries is how many more times the first connection is tried at startup,
+		// waiting ConnectBackoff doubled after every attempt
+		ConnectRetries int           `yaml:"connect_retries" envconfig:"DB_CONNECT_RETRIES" default:"5" validate:"min=0"`
+		ConnectBackoff time.Duration `yaml:"connect_backoff" envconfig:"DB_CONNECT_BACKOFF" default:"1s"`
+		// BatchSize is the number of rows written or read per statement by the batched operations
+		BatchSize int `yaml:"batch_size" envconfig:"DB_BATCH_SIZE" default:"100" validate:"min=1"`
+		// SlowQuery logs queries taking longer as warnings, 0 disables it
+		SlowQuery time.Duration `yaml:"slow_query" envconfig:"DB_SLOW_QUERY" default:"200ms"`
+		// ReplicaHosts are the host:port of the read replicas, sharing name, credentials and
+		// sslmode with the primary. Reads go to the ones answering the check every ReplicaCheckInterval.
+		ReplicaHosts         []string      `yaml:"replica_hosts" envconfig:"DB_REPLICA_HOSTS"`
+		ReplicaCheckInterval time.Duration `yaml:"replica_check_interval" envconfig:"DB_REPLICA_CHECK_INTERVAL" default:"5s" validate:"min=100ms"`
+		// AutoMigrate applies the pending migrations at startup, turn it off when they are
+		// applied by running `<service> migrate up` before deploying
+		AutoMigrate bool `yaml:"auto_migrate" envconfig:"DB_AUTO_MIGRATE" default:"true"`
 	}
     // PrivateKey and PublicKey is base64 encoded ecdsa256 keys
-    PrivateKey string `yaml:"private_key" envconfig:"PRIVATE_KEY"`
-    PublicKey string `yaml:"public_key" envconfig:"PUBLIC_KEY"`
+    PrivateKey string `yaml:"private_key" envconfig:"PRIVATE_KEY" secret:"true"`
+    PublicKey string `yaml:"public_key" envconfig:"PUBLIC_KEY" validate:"required" secret:"true"`
     GoogleClientID string `yaml:"google_client_id" envconfig:"GOOGLE_CLIENT_ID"`
-    GoogleClientSecret string `yaml:"google_client_secret" envconfig:"GOOGLE_CLIENT_SECRET"`
-    TokenTTL time.Duration `yaml:"token_ttl" envconfig:"TOKEN_TTL" default:"15m"`
-
-    // Microservices
-    AuthServiceHost         string `yaml:"auth_service_host" envconfig:"AUTH_SERVICE_HOST" default:"localhost"`
-	AuthServicePort         int    `yaml:"auth_service_port" envconfig:"AUTH_SERVICE_PORT" default:"8081"`
-	EventServiceHost        string `yaml:"event_service_host" envconfig:"EVENT_SERVICE_HOST" default:"localhost"`
-	EventServicePort        int    `yaml:"event_service_port" envconfig:"EVENT_SERVICE_PORT" default:"8082"`
-	RegistrationServiceHost string `yaml:"registration_service_host" envconfig:"REGISTRATION_SERVICE_HOST" default:"localhost"`
-	RegistrationServicePort int    `yaml:"registration_service_port" envconfig:"REGISTRATION_SERVICE_PORT" default:"8083"`
-	UserServiceHost         string `yaml:"user_service_host" envconfig:"USER_SERVICE_HOST" default:"localhost"`
-	UserServicePort         int    `yaml:"user_service_port" envconfig:"USER_SERVICE_PORT" default:"8084"`
-	ReviewsServiceHost      string `yaml:"reviews_service_host" envconfig:"REVIEWS_SERVICE_HOST" default:"localhost"`
-	ReviewsServicePort      int    `yaml:"reviews_service_port" envconfig:"REVIEWS_SERVICE_PORT" default:"8085"`
-	MediaServiceHost        string `yaml:"media_service_host" envconfig:"MEDIA_SERVICE_HOST" default:"localhost"`
-	MediaServicePort        int    `yaml:"media_service_port" envconfig:"MEDIA_SERVICE_PORT" default:"8086"`
-	NotificationServiceHost string `yaml:"notification_service_host" envconfig:"NOTIFICATION_SERVICE_HOST" default:"localhost"`
-	NotificationServicePort int    `yaml:"notification_service_port" envconfig:"NOTIFICATION_SERVICE_PORT" default:"8087"`
+    GoogleClientSecret string `yaml:"google_client_secret" envconfig:"GOOGLE_CLIENT_SECRET" secret:"true"`
+    TokenTTL time.Duration `yaml:"token_ttl" envconfig:"TOKEN_TTL" default:"15m" validate:"min=1s"`
 
 	// Конфигурация для Kafka
 	Kafka struct {
-		Brokers []string `yaml:"brokers" envconfig:"KAFKA_BROKERS" default:"localhost:9092"`
+		Enabled bool     `yaml:"enabled" envconfig:"KAFKA_ENABLED" default:"false"`
+		Brokers []string `yaml:"brokers" envconfig:"KAFKA_BROKERS" default:"localhost:9092" validate:"required"`
 		GroupID string   `yaml:"group_id" envconfig:"KAFKA_GROUP_ID" default:"default_group"`
 		Topic   string   `yaml:"topic" envconfig:"KAFKA_TOPIC" default:"default_topic"`
+		// MaxAttempts and RetryBackoff control redelivery before a message goes to the dead-letter topic
+		MaxAttempts  int           `yaml:"max_attempts" envconfig:"KAFKA_MAX_ATTEMPTS" default:"5" validate:"min=1"`
+		RetryBackoff time.Duration `yaml:"retry_backoff" envconfig:"KAFKA_RETRY_BACKOFF" default:"1s"`
+		// OutboxInterval and OutboxBatchSize control how the outbox is relayed, see app.Outbox
+		OutboxInterval  time.Duration `yaml:"outbox_interval" envconfig:"KAFKA_OUTBOX_INTERVAL" default:"1s" validate:"min=1ms"`
+		OutboxBatchSize int           `yaml:"outbox_batch_size" envconfig:"KAFKA_OUTBOX_BATCH_SIZE" default:"100" validate:"min=1"`
 	} `yaml:"kafka"`
 }
 
-// MustLoadConfig load configs from env variables
+// Validate checks the settings that depend on each other
+func (c *Config) Validate() error {
+    var errs []error
+    if c.GRPC.Server.UseTLS {
+        errs = append(errs, fileExists("GRPC_SERVER_CERT_FILE", c.GRPC.Server.CertFile),
+            fileExists("GRPC_SERVER_KEY_FILE", c.GRPC.Server.KeyFile),
+            fileExists("GRPC_SERVER_CA_FILE", c.GRPC.Server.CAFile))
+    }
+    if c.GRPC.Client.UseTLS {
+        errs = append(errs, fileExists("GRPC_CLIENT_CERT_FILE", c.GRPC.Client.CertFile),
+            fileExists("GRPC_CLIENT_KEY_FILE", c.GRPC.Client.KeyFile),
+            fileExists("GRPC_CLIENT_CA_FILE", c.GRPC.Client.CAFile))
+    }
+    if c.Tracing.Exporter == "otlp" && c.Tracing.Endpoint == "" {
+        errs = append(errs, errors.New("TRACING_OTLP_ENDPOINT: required by the otlp exporter"))
+    }
+    return errors.Join(errs...)
+}
+
+// MustLoadConfig load configs from the YAML file given by -config or CONFIG_PATH and env variables
 func MustLoadConfig() *Config {
 	var config Config
-	err := envconfig.Process("", &config)
-	if err != nil {
-        log.Fatal("Env variables error: ", err)
+	if err := Load(Path(), &config); err != nil {
+        log.Fatal("Config error: ", err)
 	}
 	return &config
 }
-
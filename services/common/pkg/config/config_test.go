@@ -2,13 +2,23 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/config/vaulttest"
 	"github.com/stretchr/testify/assert"
 )
 
+// plaintextGRPC turns off the mutual TLS of the gRPC channels, on by default, so
+// the tests don't need certificate files
+func plaintextGRPC(t *testing.T) {
+	t.Setenv("GRPC_SERVER_USE_TLS", "false")
+	t.Setenv("GRPC_CLIENT_USE_TLS", "false")
+}
+
 func TestMustLoadConfig(t *testing.T) {
+	plaintextGRPC(t)
 	_ = os.Setenv("ENV", "test")
 	_ = os.Setenv("SERVER_PORT", "9090")
 	_ = os.Setenv("SERVER_ADDR", "127.0.0.1")
@@ -65,3 +75,143 @@ func TestMustLoadConfig(t *testing.T) {
 	_ = os.Unsetenv("TOKEN_TTL")
 }
 
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_LayersDefaultsFileAndEnv(t *testing.T) {
+	path := writeFile(t, `
+server:
+  port: 8085
+  read_timeout: 3s
+database:
+  host: filehost
+  port: 6432
+public_key: file_public_key
+kafka:
+  brokers: [a:9092, b:9092]
+`)
+	plaintextGRPC(t)
+	t.Setenv("DB_HOST", "envhost")
+
+	var config Config
+	assert.NoError(t, Load(path, &config))
+
+	assert.Equal(t, 8085, config.Server.Port, "file should override the default")
+	assert.Equal(t, 3*time.Second, config.Server.ReadTimeout)
+	assert.Equal(t, 10*time.Second, config.Server.WriteTimeout, "default should stay when the file has no value")
+	assert.Equal(t, "envhost", config.Database.Host, "env should override the file")
+	assert.Equal(t, "6432", config.Database.Port)
+	assert.Equal(t, "file_public_key", config.PublicKey)
+	assert.Equal(t, []string{"a:9092", "b:9092"}, config.Kafka.Brokers)
+}
+
+type chatSection struct {
+	HistoryLimit int      `yaml:"history_limit" envconfig:"TEST_CHAT_HISTORY_LIMIT" default:"50" validate:"min=1"`
+	Token        string   `yaml:"token" envconfig:"TEST_CHAT_TOKEN" secret:"true"`
+	Clients      []string `yaml:"clients" secret:"true"`
+	Upstream     struct {
+		Port int `yaml:"port" envconfig:"TEST_CHAT_UPSTREAM_PORT" default:"9092" validate:"port"`
+	} `yaml:"upstream"`
+}
+
+func TestLoadSection(t *testing.T) {
+	path := writeFile(t, `
+public_key: key
+chat:
+  history_limit: 20
+  token: secret
+  clients: [a:1, b:2]
+`)
+
+	var section chatSection
+	assert.NoError(t, LoadSection(path, "chat", &section))
+	assert.Equal(t, 20, section.HistoryLimit)
+	assert.Equal(t, 9092, section.Upstream.Port)
+
+	redacted := Redact(&section).(chatSection)
+	assert.Equal(t, Redacted, redacted.Token)
+	assert.Equal(t, []string{Redacted, Redacted}, redacted.Clients)
+	assert.Equal(t, []string{"a:1", "b:2"}, section.Clients)
+	assert.Equal(t, "secret", section.Token, "original should stay untouched")
+}
+
+func TestLoad_Validation(t *testing.T) {
+	t.Setenv("SERVER_PORT", "70000")
+	t.Setenv("TRACING_SAMPLE_RATIO", "2")
+	t.Setenv("TRACING_EXPORTER", "zipkin")
+	t.Setenv("GRPC_SERVER_CERT_FILE", "/nonexistent/server.crt")
+
+	var config Config
+	err := Load("", &config)
+	assert.Error(t, err)
+	for _, name := range []string{"PUBLIC_KEY", "SERVER_PORT", "TRACING_SAMPLE_RATIO", "TRACING_EXPORTER", "GRPC_SERVER_CERT_FILE", "GRPC_CLIENT_CERT_FILE"} {
+		assert.ErrorContains(t, err, name)
+	}
+
+	t.Setenv("DB_PORT", "postgres")
+	assert.ErrorContains(t, Load("", &config), "DB_PORT")
+}
+
+func TestLoad_FileIndirection(t *testing.T) {
+	plaintextGRPC(t)
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "public_key"), []byte("file_public_key\n"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "db_password"), []byte("s3cret"), 0o600))
+	t.Setenv("PUBLIC_KEY_FILE", filepath.Join(dir, "public_key"))
+	t.Setenv("DB_PASSWORD", "file:"+filepath.Join(dir, "db_password"))
+
+	var config Config
+	assert.NoError(t, Load("", &config))
+	assert.Equal(t, "file_public_key", config.PublicKey, "trailing newline should be trimmed")
+	assert.Equal(t, "s3cret", config.Database.Password)
+
+	t.Setenv("PUBLIC_KEY", "env_public_key")
+	assert.ErrorContains(t, Load("", &config), "both PUBLIC_KEY and PUBLIC_KEY_FILE are set")
+
+	t.Setenv("PUBLIC_KEY_FILE", filepath.Join(dir, "missing"))
+	os.Unsetenv("PUBLIC_KEY")
+	assert.ErrorContains(t, Load("", &config), "PUBLIC_KEY_FILE")
+}
+
+func TestLoad_VaultSecrets(t *testing.T) {
+	vault := vaulttest.NewServer("root")
+	defer vault.Close()
+	vault.Put("secret/data/auth", map[string]string{"public_key": "vault_public_key"})
+	vault.PutLeased("database/creds/events", map[string]string{"username": "v-events-1", "password": "p1"}, time.Hour)
+
+	provider := NewVaultProvider(vault.URL, "root")
+	provider.CacheTTL = 0
+	RegisterSecretProvider("vault", provider)
+	t.Cleanup(func() {
+		providersMu.Lock()
+		delete(providers, "vault")
+		providersMu.Unlock()
+	})
+
+	plaintextGRPC(t)
+	t.Setenv("PUBLIC_KEY", "vault:secret/data/auth#public_key")
+	t.Setenv("DB_USER", "vault:database/creds/events#username")
+	t.Setenv("DB_PASSWORD", "vault:database/creds/events#password")
+
+	var config Config
+	assert.NoError(t, Load("", &config))
+	assert.Equal(t, "vault_public_key", config.PublicKey)
+	assert.Equal(t, "v-events-1", config.Database.User)
+	assert.Equal(t, "p1", config.Database.Password)
+	assert.Equal(t, 1, vault.Reads("database/creds/events"), "user and password should come from one lease")
+
+	vault.Put("secret/data/auth", map[string]string{"public_key": "rotated_public_key"})
+	assert.NoError(t, Load("", &config))
+	assert.Equal(t, "rotated_public_key", config.PublicKey)
+	assert.Equal(t, "v-events-1", config.Database.User, "the lease should be cached until half of it passed")
+
+	t.Setenv("GOOGLE_CLIENT_SECRET", "vault:secret/data/auth#google")
+	assert.ErrorContains(t, Load("", &config), `GOOGLE_CLIENT_SECRET: vault secret: secret/data/auth has no key "google"`)
+
+	bad := NewVaultProvider(vault.URL, "wrong")
+	RegisterSecretProvider("vault", bad)
+	assert.ErrorContains(t, Load("", &config), "permission denied")
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var configPath = flag.String("config", "", "path to the YAML config file, CONFIG_PATH is used when empty")
+
+// Path returns the config file given by the -config flag or CONFIG_PATH,
+// empty when there is none and everything comes from env variables.
+func Path() string {
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	if *configPath != "" {
+		return *configPath
+	}
+	return os.Getenv("CONFIG_PATH")
+}
+
+// Validator is implemented by configs that check settings depending on each other
+type Validator interface {
+	Validate() error
+}
+
+// Load fills target, a pointer to a struct, in layers: `default` tags first, then the
+// YAML file at path (skipped when path is empty), then the env variables named by
+// `envconfig` tags, read from the file in NAME_FILE when it is set instead of NAME.
+// References in secret fields are resolved by their SecretProvider, then the result
+// is checked against `validate` tags and Validate.
+func Load(path string, target interface{}) error {
+	return LoadSection(path, "", target)
+}
+
+// LoadSection is Load for the part of the YAML file under the top-level key section,
+// so every service keeps its own settings next to the shared ones in a single file.
+func LoadSection(path, section string, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return errors.New("config: target must be a pointer to a struct")
+	}
+
+	if err := walk(v.Elem(), func(f field) error {
+		if def, ok := f.tag.Lookup("default"); ok {
+			return f.set(def)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("config: default: %w", err)
+	}
+
+	if path != "" {
+		if err := decodeFile(path, section, target); err != nil {
+			return fmt.Errorf("config: %s: %w", path, err)
+		}
+	}
+
+	if err := walk(v.Elem(), func(f field) error {
+		if f.env == "" {
+			return nil
+		}
+		value, ok, err := envOrFile(f.env)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return f.set(value)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("config: env: %w", err)
+	}
+
+	if err := resolveSecrets(v.Elem()); err != nil {
+		return fmt.Errorf("config: secrets: %w", err)
+	}
+
+	var errs []error
+	walk(v.Elem(), func(f field) error {
+		errs = append(errs, f.validate())
+		return nil
+	})
+	if validator, ok := target.(Validator); ok {
+		errs = append(errs, validator.Validate())
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return nil
+}
+
+func decodeFile(path, section string, target interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	node := doc.Content[0]
+	if section != "" {
+		node = lookup(node, section)
+		if node == nil {
+			return nil
+		}
+	}
+	return node.Decode(target)
+}
+
+func lookup(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// field is a leaf setting of a config struct
+type field struct {
+	value reflect.Value
+	tag   reflect.StructTag
+	// env is the variable overriding the field, it names the field in errors
+	env  string
+	name string
+}
+
+// walk calls fn for every settable leaf field, descending into nested structs.
+func walk(v reflect.Value, fn func(field) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			if err := walk(fv, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		f := field{value: fv, tag: sf.Tag, env: sf.Tag.Get("envconfig"), name: sf.Name}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f field) label() string {
+	if f.env != "" {
+		return f.env
+	}
+	return f.name
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set parses s into the field the way envconfig does for the types used in configs.
+func (f field) set(s string) error {
+	v := f.value
+	var err error
+	switch {
+	case v.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(s)
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		v.SetBool(b)
+	case v.CanInt():
+		var n int64
+		n, err = strconv.ParseInt(s, 0, v.Type().Bits())
+		v.SetInt(n)
+	case v.CanUint():
+		var n uint64
+		n, err = strconv.ParseUint(s, 0, v.Type().Bits())
+		v.SetUint(n)
+	case v.CanFloat():
+		var n float64
+		n, err = strconv.ParseFloat(s, v.Type().Bits())
+		v.SetFloat(n)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		err = fmt.Errorf("unsupported type %s", v.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.label(), err)
+	}
+	return nil
+}
+
+func fileExists(name, path string) error {
+	if path == "" {
+		return fmt.Errorf("%s: required", name)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package config
+
+import "reflect"
+
+// Redacted replaces the value of non-empty secrets
+const Redacted = "[REDACTED]"
+
+// Redact returns a copy of cfg, a struct or a pointer to one, with the fields
+// tagged `secret:"true"`, strings or lists of them, replaced by Redacted, so it
+// can be logged or printed.
+func Redact(cfg interface{}) interface{} {
+	v := reflect.Indirect(reflect.ValueOf(cfg))
+	if v.Kind() != reflect.Struct {
+		return cfg
+	}
+	copied := reflect.New(v.Type()).Elem()
+	copied.Set(v)
+	walk(copied, func(f field) error {
+		if f.tag.Get("secret") != "true" {
+			return nil
+		}
+		switch {
+		case f.value.Kind() == reflect.String && f.value.String() != "":
+			f.value.SetString(Redacted)
+		case f.value.Kind() == reflect.Slice && f.value.Len() > 0:
+			redacted := reflect.MakeSlice(f.value.Type(), f.value.Len(), f.value.Len())
+			for i := 0; i < redacted.Len(); i++ {
+				redacted.Index(i).SetString(Redacted)
+			}
+			f.value.Set(redacted)
+		}
+		return nil
+	})
+	return copied.Interface()
+}
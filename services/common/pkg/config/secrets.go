@@ -0,0 +1,135 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretProvider resolves references to secrets kept outside of the config. A field
+// tagged `secret:"true"` holding "scheme:ref", e.g. "vault:secret/data/auth#private_key",
+// gets the value the provider registered for the scheme returns for ref.
+type SecretProvider interface {
+	Secret(ctx context.Context, ref string) (string, error)
+}
+
+// ResolveTimeout bounds resolving all the secrets of a config
+var ResolveTimeout = 10 * time.Second
+
+var (
+	providersMu sync.Mutex
+	providers   = map[string]SecretProvider{
+		"env":  EnvProvider{},
+		"file": FileProvider{},
+	}
+)
+
+// RegisterSecretProvider makes the provider resolve "scheme:" references, replacing
+// the one registered before. env and file are always there, vault is registered on
+// first use from VAULT_ADDR and VAULT_TOKEN (or VAULT_TOKEN_FILE) when not done explicitly.
+func RegisterSecretProvider(scheme string, p SecretProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[scheme] = p
+}
+
+func secretProvider(scheme string) (SecretProvider, error) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if p, ok := providers[scheme]; ok {
+		return p, nil
+	}
+	if scheme != "vault" {
+		return nil, nil
+	}
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		return nil, fmt.Errorf("vault reference without VAULT_ADDR")
+	}
+	token, _, err := envOrFile("VAULT_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	p := NewVaultProvider(addr, token)
+	providers[scheme] = p
+	return p, nil
+}
+
+// resolveSecrets replaces references in the secret fields of v by what they point to.
+// Values without a registered scheme are kept as they are.
+func resolveSecrets(v reflect.Value) error {
+	ctx, cancel := context.WithTimeout(context.Background(), ResolveTimeout)
+	defer cancel()
+	return walk(v, func(f field) error {
+		if f.tag.Get("secret") != "true" || f.value.Kind() != reflect.String {
+			return nil
+		}
+		scheme, ref, ok := strings.Cut(f.value.String(), ":")
+		if !ok {
+			return nil
+		}
+		p, err := secretProvider(scheme)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.label(), err)
+		}
+		if p == nil {
+			return nil
+		}
+		secret, err := p.Secret(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("%s: %s secret: %w", f.label(), scheme, err)
+		}
+		f.value.SetString(secret)
+		return nil
+	})
+}
+
+// envOrFile returns the variable name or, when name_FILE is set instead, the content
+// of the file it points to, the way Docker and Kubernetes secrets are mounted.
+func envOrFile(name string) (string, bool, error) {
+	value, ok := os.LookupEnv(name)
+	path, fromFile := os.LookupEnv(name + "_FILE")
+	if !fromFile {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("both %s and %s_FILE are set", name, name)
+	}
+	value, err := readSecretFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+	}
+	return value, true, nil
+}
+
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// editors and `echo` leave a trailing newline, which is never part of a secret
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// EnvProvider resolves "env:NAME" to the value of the env variable NAME
+type EnvProvider struct{}
+
+func (EnvProvider) Secret(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("%s is not set", name)
+	}
+	return value, nil
+}
+
+// FileProvider resolves "file:/path" to the content of the file, read on every call,
+// so a secret rotated by rewriting the file is picked up the next time it's resolved.
+type FileProvider struct{}
+
+func (FileProvider) Secret(_ context.Context, path string) (string, error) {
+	return readSecretFile(path)
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validate checks the field against its `validate` tag, a comma separated list of
+//
+//	required     the value is not empty
+//	port         a TCP port, 1-65535
+//	min=N, max=N bounds of a number or a duration
+//	oneof=a b c  one of the values, an empty one is allowed unless required
+func (f field) validate() error {
+	rules := f.tag.Get("validate")
+	if rules == "" {
+		return nil
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		if err := f.check(name, arg); err != nil {
+			return fmt.Errorf("%s: %w", f.label(), err)
+		}
+	}
+	return nil
+}
+
+func (f field) check(rule, arg string) error {
+	v := f.value
+	switch rule {
+	case "required":
+		if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+			return fmt.Errorf("required")
+		}
+	case "port":
+		port, err := f.number()
+		if err != nil {
+			return err
+		}
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("%v is not a valid port", v.Interface())
+		}
+	case "min", "max":
+		n, err := f.number()
+		if err != nil {
+			return err
+		}
+		bound, err := f.parseBound(arg)
+		if err != nil {
+			return err
+		}
+		if rule == "min" && n < bound {
+			return fmt.Errorf("must be at least %s", arg)
+		}
+		if rule == "max" && n > bound {
+			return fmt.Errorf("must be at most %s", arg)
+		}
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		if s == "" {
+			return nil
+		}
+		for _, allowed := range strings.Fields(arg) {
+			if s == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %s", s, arg)
+	default:
+		return fmt.Errorf("unknown validation rule %q", rule)
+	}
+	return nil
+}
+
+func (f field) number() (float64, error) {
+	v := f.value
+	switch {
+	case v.CanInt():
+		return float64(v.Int()), nil
+	case v.CanUint():
+		return float64(v.Uint()), nil
+	case v.CanFloat():
+		return v.Float(), nil
+	case v.Kind() == reflect.String:
+		n, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", v.String())
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("unsupported type %s", v.Type())
+}
+
+func (f field) parseBound(arg string) (float64, error) {
+	if f.value.Type() == durationType {
+		d, err := time.ParseDuration(arg)
+		return float64(d), err
+	}
+	return strconv.ParseFloat(arg, 64)
+}
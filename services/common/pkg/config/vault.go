@@ -0,0 +1,112 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultProvider resolves "vault:path#key" through the HashiCorp Vault HTTP API. It reads
+// both KV v2 secrets (path "secret/data/name") and leased ones like dynamic database
+// credentials (path "database/creds/role"). Every key of a read is served from the same
+// response until it expires, so a username and a password come from the same lease.
+type VaultProvider struct {
+	Addr   string
+	Token  string
+	Client *http.Client
+	// CacheTTL is how long secrets without a lease are cached. Leased ones are read
+	// again after half of the lease, well before the credentials are revoked.
+	CacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]vaultEntry
+}
+
+type vaultEntry struct {
+	data    map[string]interface{}
+	expires time.Time
+}
+
+// NewVaultProvider creates the provider for the Vault at addr, e.g. http://vault:8200
+func NewVaultProvider(addr, token string) *VaultProvider {
+	return &VaultProvider{
+		Addr:     strings.TrimRight(addr, "/"),
+		Token:    token,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+		CacheTTL: time.Minute,
+		cache:    make(map[string]vaultEntry),
+	}
+}
+
+func (p *VaultProvider) Secret(ctx context.Context, ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || key == "" {
+		return "", fmt.Errorf("reference %q has no #key", ref)
+	}
+	data, err := p.read(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	value, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("%s has no key %q", path, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+type vaultResponse struct {
+	LeaseDuration int                    `json:"lease_duration"`
+	Data          map[string]interface{} `json:"data"`
+	Errors        []string               `json:"errors"`
+}
+
+func (p *VaultProvider) read(ctx context.Context, path string) (map[string]interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if entry, ok := p.cache[path]; ok && time.Now().Before(entry.expires) {
+		return entry.data, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Addr+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", p.Token)
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s %s", path, resp.Status, strings.Join(body.Errors, "; "))
+	}
+
+	data := body.Data
+	// KV v2 wraps the secret with its metadata
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+	ttl := p.CacheTTL
+	if body.LeaseDuration > 0 {
+		ttl = time.Duration(body.LeaseDuration) * time.Second / 2
+	}
+	if p.cache == nil {
+		p.cache = make(map[string]vaultEntry)
+	}
+	p.cache[path] = vaultEntry{data: data, expires: time.Now().Add(ttl)}
+	return data, nil
+}
@@ -0,0 +1,104 @@
+// Package vaulttest is an in-memory stand-in for the parts of the Vault HTTP API
+// config.VaultProvider uses, for tests and local runs without a Vault.
+package vaulttest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server answers GET /v1/<path> with the secrets put at path
+type Server struct {
+	*httptest.Server
+	Token string
+
+	mu      sync.Mutex
+	secrets map[string]secret
+	reads   map[string]int
+}
+
+type secret struct {
+	data    map[string]string
+	lease   time.Duration
+	version int
+}
+
+// NewServer starts a server accepting requests with token, Close it at the end
+func NewServer(token string) *Server {
+	s := &Server{
+		Token:   token,
+		secrets: make(map[string]secret),
+		reads:   make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Put stores a KV v2 secret, path is the API one, e.g. "secret/data/auth".
+// Putting the same path again creates a new version.
+func (s *Server) Put(path string, data map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets[path] = secret{data: data, version: s.secrets[path].version + 1}
+}
+
+// PutLeased stores a secret returned with a lease, like dynamic database credentials
+func (s *Server) PutLeased(path string, data map[string]string, lease time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets[path] = secret{data: data, lease: lease}
+}
+
+// Reads returns how many times path was read
+func (s *Server) Reads(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reads[path]
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Header.Get("X-Vault-Token") != s.Token {
+		writeJSON(w, http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+		return
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, "/v1/")
+	if !ok || r.Method != http.MethodGet {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+
+	s.mu.Lock()
+	sec, found := s.secrets[path]
+	s.reads[path]++
+	s.mu.Unlock()
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+
+	if sec.lease > 0 {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"lease_duration": int(sec.lease / time.Second),
+			"renewable":      true,
+			"data":           sec.data,
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"lease_duration": 0,
+		"data": map[string]interface{}{
+			"data":     sec.data,
+			"metadata": map[string]interface{}{"version": sec.version},
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -1,10 +1,19 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"strconv"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 // InitDB open connection with postgres db
@@ -17,6 +26,9 @@ func InitDB(dsn string) *gorm.DB {
 }
 
 // SetupDB setup postgres and migrates all models
+//
+// Deprecated: AutoMigrate can't drop or rename columns and races between replicas,
+// apply versioned migrations with a Migrator instead.
 func SetupDB(dsn string, models ...interface{}) *gorm.DB {
 	db := InitDB(dsn)
 	err := db.AutoMigrate(models...)
@@ -26,3 +38,104 @@ func SetupDB(dsn string, models ...interface{}) *gorm.DB {
 	return db
 }
 
+// Credentials returns the user and password to open a new connection with
+type Credentials func(ctx context.Context) (user, password string, err error)
+
+// Options configure the pool and the sessions of a database opened with OpenWithCredentials
+type Options struct {
+	MaxOpenConns int
+	MaxIdleConns int
+	// ConnMaxLifetime closes pooled connections after it, none outlives the credentials
+	// it was opened with
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// StatementTimeout cancels statements running longer on the server, 0 doesn't
+	StatementTimeout time.Duration
+	// PrepareStmt prepares and caches the statements on every connection, turn it off
+	// behind a pooler in transaction mode, which can't keep them
+	PrepareStmt bool
+	// ConnectRetries is how many times the first connection is tried again, waiting
+	// ConnectBackoff doubled after every attempt
+	ConnectRetries int
+	ConnectBackoff time.Duration
+	// BatchSize is the number of rows inserted per statement when creating a slice,
+	// and the default batch of the batched repository methods
+	BatchSize int
+	// Logger is the gorm logger, gorm's default one if nil
+	Logger gormlogger.Interface
+}
+
+// maxConnectBackoff caps the wait between connection attempts
+const maxConnectBackoff = 30 * time.Second
+
+// OpenWithCredentials opens postgres asking creds for every new connection instead of
+// taking them from the dsn, so rotated credentials are used without a restart. The first
+// connection is retried with backoff, so the service waits for a database starting with it.
+func OpenWithCredentials(ctx context.Context, dsn string, creds Credentials, opts Options) (*gorm.DB, error) {
+	sqlDB, err := open(dsn, creds, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := ping(ctx, sqlDB, opts); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		PrepareStmt:          opts.PrepareStmt,
+		CreateBatchSize:      opts.BatchSize,
+		Logger:               opts.Logger,
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// open creates the pool of dsn, it connects on first use
+func open(dsn string, creds Credentials, opts Options) (*sql.DB, error) {
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if opts.StatementTimeout > 0 {
+		connConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(opts.StatementTimeout.Milliseconds(), 10)
+	}
+	if !opts.PrepareStmt {
+		// gorm doesn't prepare then, neither should pgx
+		connConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+	}
+	sqlDB := stdlib.OpenDB(*connConfig, stdlib.OptionBeforeConnect(func(ctx context.Context, cfg *pgx.ConnConfig) error {
+		user, password, err := creds(ctx)
+		if err != nil {
+			return fmt.Errorf("database credentials: %w", err)
+		}
+		cfg.User, cfg.Password = user, password
+		return nil
+	}))
+	sqlDB.SetMaxOpenConns(opts.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(opts.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	return sqlDB, nil
+}
+
+// ping connects to the database, trying again ConnectRetries times
+func ping(ctx context.Context, sqlDB *sql.DB, opts Options) error {
+	for attempt := 0; ; attempt++ {
+		err := sqlDB.PingContext(ctx)
+		if err == nil || attempt >= opts.ConnectRetries {
+			return err
+		}
+		delay := min(opts.ConnectBackoff<<attempt, maxConnectBackoff)
+		if opts.Logger != nil {
+			opts.Logger.Warn(ctx, "database is not reachable, trying again in %s: %v", delay, err)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		}
+	}
+}
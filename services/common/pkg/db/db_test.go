@@ -1,7 +1,10 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -32,3 +35,38 @@ func TestSetupDB(t *testing.T) {
 	assert.Contains(t, tableNames, "test_models", "test_models table should exist in the database")
 }
 
+
+func TestOpenWithCredentials_AsksForEveryConnection(t *testing.T) {
+	calls := 0
+	creds := func(context.Context) (string, string, error) {
+		calls++
+		return "", "", errors.New("vault is sealed")
+	}
+
+	_, err := OpenWithCredentials(context.Background(), "host=127.0.0.1 port=1 dbname=test sslmode=disable", creds, Options{})
+	assert.ErrorContains(t, err, "vault is sealed")
+	assert.Equal(t, 1, calls)
+}
+
+func TestOpenWithCredentials_RetriesTheFirstConnection(t *testing.T) {
+	calls := 0
+	creds := func(context.Context) (string, string, error) {
+		calls++
+		return "", "", errors.New("vault is sealed")
+	}
+
+	_, err := OpenWithCredentials(context.Background(), "host=127.0.0.1 port=1 dbname=test sslmode=disable", creds, Options{
+		ConnectRetries: 2,
+		ConnectBackoff: time.Millisecond,
+	})
+	assert.ErrorContains(t, err, "vault is sealed")
+	assert.Equal(t, 3, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = OpenWithCredentials(ctx, "host=127.0.0.1 port=1 dbname=test sslmode=disable", creds, Options{
+		ConnectRetries: 5,
+		ConnectBackoff: time.Hour,
+	})
+	assert.ErrorIs(t, err, context.Canceled, "a stopping service doesn't wait for the database")
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// Logger writes the gorm log to slog. Failed queries are errors, the ones slower than
+// the threshold are warnings and the rest are debug messages, so LOG_LEVEL=debug shows
+// every query. A missing record is no failure, the repositories expect it.
+type Logger struct {
+	log           *slog.Logger
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+// NewLogger creates the gorm logger writing to log, slowThreshold 0 reports no slow queries
+func NewLogger(log *slog.Logger, slowThreshold time.Duration) *Logger {
+	return &Logger{log: log, level: gormlogger.Info, slowThreshold: slowThreshold}
+}
+
+// LogMode returns a copy of the logger logging up to level
+func (l *Logger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, data ...any) {
+	if l.level >= gormlogger.Info {
+		l.log.InfoContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *Logger) Warn(ctx context.Context, msg string, data ...any) {
+	if l.level >= gormlogger.Warn {
+		l.log.WarnContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, data ...any) {
+	if l.level >= gormlogger.Error {
+		l.log.ErrorContext(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace logs a query once it ran, fc is only called when the query is logged
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= gormlogger.Error:
+		l.log.ErrorContext(ctx, "query failed", append(l.query(elapsed, fc), slog.String("error", err.Error()))...)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		l.log.WarnContext(ctx, "slow query", append(l.query(elapsed, fc), slog.Duration("threshold", l.slowThreshold))...)
+	case l.level >= gormlogger.Info && l.log.Enabled(ctx, slog.LevelDebug):
+		l.log.DebugContext(ctx, "query", l.query(elapsed, fc)...)
+	}
+}
+
+func (l *Logger) query(elapsed time.Duration, fc func() (string, int64)) []any {
+	sql, rows := fc()
+	return []any{slog.String("sql", sql), slog.Int64("rows", rows), slog.Duration("elapsed", elapsed)}
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestLogger_Trace(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	logger := NewLogger(log, 100*time.Millisecond)
+	called := 0
+	query := func() (string, int64) {
+		called++
+		return "SELECT * FROM events", 3
+	}
+	ctx := context.Background()
+
+	logger.Trace(ctx, time.Now(), query, nil)
+	assert.Empty(t, buf.String())
+	assert.Equal(t, 0, called, "the SQL isn't built for a message nobody reads")
+
+	logger.Trace(ctx, time.Now().Add(-time.Second), query, nil)
+	assert.Contains(t, buf.String(), "level=WARN msg=\"slow query\" sql=\"SELECT * FROM events\" rows=3")
+
+	buf.Reset()
+	logger.Trace(ctx, time.Now(), query, gorm.ErrRecordNotFound)
+	assert.Empty(t, buf.String(), "a missing record is no failure")
+
+	logger.Trace(ctx, time.Now(), query, errors.New("connection reset"))
+	assert.Contains(t, buf.String(), "level=ERROR msg=\"query failed\"")
+	assert.Contains(t, buf.String(), "error=\"connection reset\"")
+
+	buf.Reset()
+	logger.LogMode(gormlogger.Silent).Trace(ctx, time.Now(), query, errors.New("connection reset"))
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,353 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is a versioned change of the schema with the SQL applying and reverting it
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// noTransaction marks a migration that can't run in a transaction, e.g. one creating
+// an index concurrently. Put it on the first line of the file.
+const noTransaction = "-- migrate:no-transaction"
+
+// InTransaction tells whether the SQL runs in a transaction
+func InTransaction(sql string) bool {
+	return !strings.HasPrefix(strings.TrimSpace(sql), noTransaction)
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// LoadMigrations reads the <version>_<name>.up.sql and <version>_<name>.down.sql files
+// in the root of fsys, ordered by version. Every migration needs both files.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Locker keeps concurrent runners, e.g. replicas starting together, from migrating at
+// the same time. It locks and unlocks on the connection the migrations run on.
+type Locker interface {
+	Lock(ctx context.Context, conn *sql.Conn) error
+	Unlock(ctx context.Context, conn *sql.Conn) error
+}
+
+// AdvisoryLock is a postgres session advisory lock, waiting for the holder to finish.
+// The statement timeout is lifted while it's held, migrations may take longer than
+// the queries of the service are allowed to.
+type AdvisoryLock struct {
+	Key int64
+}
+
+func (l AdvisoryLock) Lock(ctx context.Context, conn *sql.Conn) error {
+	if _, err := conn.ExecContext(ctx, "SET statement_timeout = 0"); err != nil {
+		return err
+	}
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", l.Key)
+	return err
+}
+
+func (l AdvisoryLock) Unlock(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.Key)
+	// the connection goes back to the pool, with the timeout it was opened with
+	if _, resetErr := conn.ExecContext(ctx, "RESET statement_timeout"); err == nil {
+		err = resetErr
+	}
+	return err
+}
+
+// NoLock is for databases only one runner uses, like the ones of tests
+type NoLock struct{}
+
+func (NoLock) Lock(context.Context, *sql.Conn) error   { return nil }
+func (NoLock) Unlock(context.Context, *sql.Conn) error { return nil }
+
+// migrationLockKey is the advisory lock key of migrations, each service has its own
+// database so the key doesn't need to tell them apart
+const migrationLockKey = 0x6d69677261746500
+
+// Migrator applies and reverts migrations, recording the applied ones in the history table
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	// Table is the history table, schema_migrations by default
+	Table  string
+	Locker Locker
+}
+
+// NewMigrator creates the migrator of a postgres database
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		Table:      "schema_migrations",
+		Locker:     AdvisoryLock{Key: migrationLockKey},
+	}
+}
+
+// MigrationStatus is a migration with when it was applied, AppliedAt is zero for
+// pending ones. Missing ones are applied but no longer known to the service.
+type MigrationStatus struct {
+	Migration
+	AppliedAt time.Time
+	Missing   bool
+}
+
+// Status returns the known migrations and the applied ones that aren't known, by version
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Migration: migration}
+			if record, ok := applied[migration.Version]; ok {
+				status.AppliedAt = record.AppliedAt
+				delete(applied, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for _, record := range applied {
+			record.Missing = true
+			statuses = append(statuses, record)
+		}
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, err
+}
+
+// Pending returns the migrations Up would apply
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	var pending []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		var err error
+		pending, err = m.pending(ctx, conn)
+		return err
+	})
+	return pending, err
+}
+
+// Up applies the pending migrations in version order and returns them. It stops at the
+// first failing one, the ones before it stay applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		pending, err := m.pending(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			insert := fmt.Sprintf("INSERT INTO %s (version, name, applied_at) VALUES ($1, $2, $3)", m.Table)
+			if err := m.run(ctx, conn, migration.Up, insert, migration.Version, migration.Name, time.Now().UTC()); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Applied returns the last steps applied migrations, newest first, the ones Down would revert
+func (m *Migrator) Applied(ctx context.Context, steps int) ([]Migration, error) {
+	var last []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		var err error
+		last, err = m.last(ctx, conn, steps)
+		return err
+	})
+	return last, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns them
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		last, err := m.last(ctx, conn, steps)
+		if err != nil {
+			return err
+		}
+		for _, migration := range last {
+			remove := fmt.Sprintf("DELETE FROM %s WHERE version = $1", m.Table)
+			if err := m.run(ctx, conn, migration.Down, remove, migration.Version); err != nil {
+				return fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// locked runs fn on a single connection holding the lock, with the history table in place
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := m.Locker.Lock(ctx, conn); err != nil {
+		return fmt.Errorf("locking migrations: %w", err)
+	}
+	defer func() {
+		// the lock would go with the session anyway, a failed unlock only delays the next runner
+		if unlockErr := m.Locker.Unlock(context.WithoutCancel(ctx), conn); unlockErr != nil && err == nil {
+			err = fmt.Errorf("unlocking migrations: %w", unlockErr)
+		}
+	}()
+
+	create := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`, m.Table)
+	if _, err := conn.ExecContext(ctx, create); err != nil {
+		return fmt.Errorf("creating %s: %w", m.Table, err)
+	}
+	return fn(conn)
+}
+
+// run executes the SQL of a migration and the statement recording it, in one transaction
+// unless the migration opts out
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, migrationSQL, record string, args ...any) error {
+	if !InTransaction(migrationSQL) {
+		if _, err := conn.ExecContext(ctx, migrationSQL); err != nil {
+			return err
+		}
+		_, err := conn.ExecContext(ctx, record, args...)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, migrationSQL); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]MigrationStatus, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version, name, applied_at FROM %s", m.Table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int64]MigrationStatus)
+	for rows.Next() {
+		var record MigrationStatus
+		if err := rows.Scan(&record.Version, &record.Name, &record.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[record.Version] = record
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) pending(ctx context.Context, conn *sql.Conn) ([]Migration, error) {
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// ErrUnknownMigration is returned when reverting a migration the service doesn't know
+var ErrUnknownMigration = errors.New("applied migration is not known")
+
+func (m *Migrator) last(ctx context.Context, conn *sql.Conn, steps int) ([]Migration, error) {
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+	var last []Migration
+	for _, version := range versions[:min(steps, len(versions))] {
+		migration, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d_%s", ErrUnknownMigration, version, applied[version].Name)
+		}
+		last = append(last, migration)
+	}
+	return last, nil
+}
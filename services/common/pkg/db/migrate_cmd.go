@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"text/tabwriter"
+	"time"
+)
+
+// MigrateUsage describes the migrate subcommand of the services
+const MigrateUsage = `usage: <service> [-config file] migrate <command>
+
+commands:
+  up [-dry-run]              apply the pending migrations
+  down [-steps n] [-dry-run] revert the last n applied migrations, 1 by default
+  status                     list the migrations and when they were applied
+  create [-dir dir] <name>   write empty up and down files of a new migration`
+
+// Command runs the migrate subcommand args, without the leading "migrate", writing what
+// it did to out. -dry-run prints the SQL that would run instead of running it.
+func (m *Migrator) Command(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no migrate command\n%s", MigrateUsage)
+	}
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	flags.SetOutput(out)
+	dryRun := flags.Bool("dry-run", false, "print the SQL instead of running it")
+	steps := flags.Int("steps", 1, "number of migrations to revert")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		if *dryRun {
+			pending, err := m.Pending(ctx)
+			if err != nil {
+				return err
+			}
+			printSQL(out, pending, "up")
+			return nil
+		}
+		done, err := m.Up(ctx)
+		for _, migration := range done {
+			fmt.Fprintf(out, "applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		return err
+
+	case "down":
+		if *steps < 1 {
+			return fmt.Errorf("-steps must be at least 1")
+		}
+		if *dryRun {
+			last, err := m.Applied(ctx, *steps)
+			if err != nil {
+				return err
+			}
+			printSQL(out, last, "down")
+			return nil
+		}
+		done, err := m.Down(ctx, *steps)
+		for _, migration := range done {
+			fmt.Fprintf(out, "reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, status := range statuses {
+			applied := "pending"
+			if !status.AppliedAt.IsZero() {
+				applied = status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Missing {
+				applied += " (no such migration in this build)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, applied)
+		}
+		return w.Flush()
+	}
+	return fmt.Errorf("unknown migrate command %q\n%s", args[0], MigrateUsage)
+}
+
+func printSQL(out io.Writer, migrations []Migration, direction string) {
+	if len(migrations) == 0 {
+		fmt.Fprintln(out, "-- nothing to run")
+	}
+	for _, migration := range migrations {
+		sql := migration.Up
+		if direction == "down" {
+			sql = migration.Down
+		}
+		fmt.Fprintf(out, "-- %04d_%s (%s)\n%s\n", migration.Version, migration.Name, direction, sql)
+	}
+}
+
+var migrationName = regexp.MustCompile(`^\w+$`)
+
+// CreateCommand runs `migrate create [-dir dir] <name>`, args without "migrate create".
+// It needs no database, the files are written to the migrations of the service source.
+func CreateCommand(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	flags.SetOutput(out)
+	dir := flags.String("dir", "internal/migrations", "directory of the migrations")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || !migrationName.MatchString(flags.Arg(0)) {
+		return fmt.Errorf("the migration needs a name of letters, digits and underscores\n%s", MigrateUsage)
+	}
+
+	version, err := NextVersion(os.DirFS(*dir))
+	if err != nil {
+		return err
+	}
+	up, down := MigrationFileNames(version, flags.Arg(0))
+	for _, name := range []string{up, down} {
+		file, err := os.OpenFile(filepath.Join(*dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(file, "-- %s\n", name)
+		if err := file.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "created %s\n", filepath.Join(*dir, name))
+	}
+	return nil
+}
+
+// MigrationFileNames returns the names of the up and down files of a migration
+func MigrationFileNames(version int64, name string) (string, string) {
+	base := fmt.Sprintf("%04d_%s", version, name)
+	return base + ".up.sql", base + ".down.sql"
+}
+
+// NextVersion returns the version following the last migration in fsys
+func NextVersion(fsys fs.FS) (int64, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 1, nil
+	}
+	return migrations[len(migrations)-1].Version + 1, nil
+}
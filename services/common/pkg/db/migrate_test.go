@@ -0,0 +1,153 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testMigrations = fstest.MapFS{
+	"0001_init.up.sql":       {Data: []byte("CREATE TABLE notes (id INTEGER PRIMARY KEY);")},
+	"0001_init.down.sql":     {Data: []byte("DROP TABLE notes;")},
+	"0002_add_body.up.sql":   {Data: []byte("ALTER TABLE notes ADD COLUMN body TEXT;")},
+	"0002_add_body.down.sql": {Data: []byte("ALTER TABLE notes DROP COLUMN body;")},
+	"README.md":              {Data: []byte("not a migration")},
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, *sql.DB) {
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := database.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrations, err := LoadMigrations(testMigrations)
+	require.NoError(t, err)
+	migrator := NewMigrator(sqlDB, migrations)
+	migrator.Locker = NoLock{}
+	return migrator, sqlDB
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(testMigrations)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "add_body", migrations[1].Name)
+
+	_, err = LoadMigrations(fstest.MapFS{"0001_init.up.sql": {Data: []byte("SELECT 1;")}})
+	assert.ErrorContains(t, err, "needs both an up and a down file")
+
+	assert.True(t, InTransaction("CREATE TABLE notes ();"))
+	assert.False(t, InTransaction("-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY ..."))
+}
+
+func TestMigrator_UpAndDown(t *testing.T) {
+	migrator, sqlDB := newTestMigrator(t)
+	ctx := context.Background()
+
+	done, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, done, 2)
+	_, err = sqlDB.Exec("INSERT INTO notes (id, body) VALUES (1, 'hi')")
+	require.NoError(t, err)
+
+	done, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, done, "applied migrations are not run again")
+
+	done, err = migrator.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, done, 1)
+	assert.Equal(t, int64(2), done[0].Version)
+	_, err = sqlDB.Exec("INSERT INTO notes (id, body) VALUES (2, 'hi')")
+	assert.Error(t, err, "the column is dropped")
+
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.False(t, statuses[0].AppliedAt.IsZero())
+	assert.True(t, statuses[1].AppliedAt.IsZero())
+}
+
+func TestMigrator_FailedMigrationIsNotRecorded(t *testing.T) {
+	migrator, _ := newTestMigrator(t)
+	migrator.migrations = append(migrator.migrations, Migration{Version: 3, Name: "broken", Up: "ALTER TABLE nope ADD COLUMN x TEXT;", Down: "SELECT 1;"})
+
+	done, err := migrator.Up(context.Background())
+	assert.ErrorContains(t, err, "migration 3_broken")
+	assert.Len(t, done, 2, "the migrations before the failing one stay applied")
+
+	pending, err := migrator.Pending(context.Background())
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, int64(3), pending[0].Version)
+}
+
+func TestMigrator_DownRefusesUnknownMigrations(t *testing.T) {
+	migrator, _ := newTestMigrator(t)
+	_, err := migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	migrator.migrations = migrator.migrations[:1]
+	_, err = migrator.Down(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrUnknownMigration)
+
+	statuses, err := migrator.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[1].Missing)
+}
+
+func TestCommand_DryRunPrintsPendingSQL(t *testing.T) {
+	migrator, _ := newTestMigrator(t)
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	require.NoError(t, migrator.Command(ctx, []string{"up", "-dry-run"}, &out))
+	assert.Contains(t, out.String(), "-- 0001_init (up)\nCREATE TABLE notes")
+	assert.Contains(t, out.String(), "-- 0002_add_body (up)")
+
+	pending, err := migrator.Pending(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pending, 2, "a dry run changes nothing")
+
+	out.Reset()
+	require.NoError(t, migrator.Command(ctx, []string{"up"}, &out))
+	assert.Equal(t, "applied 0001_init\napplied 0002_add_body\n", out.String())
+
+	out.Reset()
+	require.NoError(t, migrator.Command(ctx, []string{"down", "-steps", "2", "-dry-run"}, &out))
+	assert.Equal(t, "-- 0002_add_body (down)\nALTER TABLE notes DROP COLUMN body;\n-- 0001_init (down)\nDROP TABLE notes;\n", out.String())
+
+	out.Reset()
+	require.NoError(t, migrator.Command(ctx, []string{"status"}, &out))
+	assert.Contains(t, out.String(), "0002     add_body")
+
+	assert.Error(t, migrator.Command(ctx, []string{"sideways"}, &out))
+}
+
+func TestCreateCommand_WritesTheNextVersion(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+
+	require.NoError(t, CreateCommand([]string{"-dir", dir, "init"}, &out))
+	require.NoError(t, CreateCommand([]string{"-dir", dir, "add_index"}, &out))
+	assert.FileExists(t, filepath.Join(dir, "0002_add_index.up.sql"))
+	assert.FileExists(t, filepath.Join(dir, "0002_add_index.down.sql"))
+
+	migrations, err := LoadMigrations(os.DirFS(dir))
+	require.NoError(t, err)
+	assert.Len(t, migrations, 2)
+
+	assert.Error(t, CreateCommand([]string{"-dir", dir, "no spaces"}, &out))
+}
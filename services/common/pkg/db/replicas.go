@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type primaryKey struct{}
+
+// ReadYourWrites returns a context whose queries all go to the primary, for reads that
+// must see a write made just before, e.g. one checking a row and then updating it
+func ReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// UsesPrimary tells whether the queries with ctx read from the primary, see ReadYourWrites
+func UsesPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// Primary returns a session of db whose reads go to the primary
+func Primary(db *gorm.DB) *gorm.DB {
+	return db.WithContext(ReadYourWrites(db.Statement.Context))
+}
+
+// Replicas routes the reads of a gorm.DB to read replicas, round robin over the ones
+// that answered the last health check and to the primary when none did. Writes, reads
+// locking rows, transactions and the queries with ReadYourWrites stay on the primary.
+type Replicas struct {
+	pools   []*sql.DB
+	healthy []atomic.Bool
+	next    atomic.Uint64
+	log     *slog.Logger
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// replicaCheckTimeout bounds a health check of a replica
+const replicaCheckTimeout = 2 * time.Second
+
+// OpenReplicas opens the replicas at dsns like OpenWithCredentials, without waiting for
+// them, and routes the reads of primary to them. They are checked every checkInterval.
+func OpenReplicas(primary *gorm.DB, dsns []string, creds Credentials, opts Options, checkInterval time.Duration, log *slog.Logger) (*Replicas, error) {
+	pools := make([]*sql.DB, 0, len(dsns))
+	for _, dsn := range dsns {
+		pool, err := open(dsn, creds, opts)
+		if err != nil {
+			for _, opened := range pools {
+				opened.Close()
+			}
+			return nil, err
+		}
+		pools = append(pools, pool)
+	}
+	return UseReplicas(primary, pools, checkInterval, log)
+}
+
+// UseReplicas routes the reads of primary to pools, checking them every checkInterval
+// until Close. The replicas are checked once before it returns.
+func UseReplicas(primary *gorm.DB, pools []*sql.DB, checkInterval time.Duration, log *slog.Logger) (*Replicas, error) {
+	r := &Replicas{
+		pools:   pools,
+		healthy: make([]atomic.Bool, len(pools)),
+		log:     log,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := primary.Use(r); err != nil {
+		return nil, err
+	}
+	r.check(context.Background())
+	go r.checkEvery(checkInterval)
+	return r, nil
+}
+
+// Name is the name of the gorm plugin
+func (r *Replicas) Name() string {
+	return "db:replicas"
+}
+
+// Initialize registers the callbacks choosing the pool of the reads
+func (r *Replicas) Initialize(db *gorm.DB) error {
+	return errors.Join(
+		db.Callback().Query().Before("gorm:query").Register("db:replicas", r.route),
+		db.Callback().Row().Before("gorm:row").Register("db:replicas", r.route),
+	)
+}
+
+// Healthy returns the number of replicas reads go to
+func (r *Replicas) Healthy() int {
+	healthy := 0
+	for i := range r.healthy {
+		if r.healthy[i].Load() {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+// Close stops the health checks and closes the replicas
+func (r *Replicas) Close() error {
+	r.closeOnce.Do(func() { close(r.stop) })
+	<-r.done
+	var errs []error
+	for _, pool := range r.pools {
+		errs = append(errs, pool.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Replicas) route(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || UsesPrimary(stmt.Context) {
+		return
+	}
+	if _, inTransaction := stmt.ConnPool.(gorm.TxCommitter); inTransaction {
+		return
+	}
+	if _, locking := stmt.Clauses[clause.Locking{}.Name()]; locking {
+		return
+	}
+	if raw := stmt.SQL.String(); raw != "" && !readOnly(raw) {
+		return
+	}
+	if pool := r.pick(); pool != nil {
+		stmt.ConnPool = pool
+	}
+}
+
+// readOnly tells whether raw SQL can run on a replica
+func readOnly(raw string) bool {
+	sql := strings.ToLower(strings.TrimSpace(raw))
+	return strings.HasPrefix(sql, "select") && !strings.Contains(sql, " for update") && !strings.Contains(sql, " for share")
+}
+
+// pick returns the next healthy replica, nil when there is none
+func (r *Replicas) pick() *sql.DB {
+	start := r.next.Add(1)
+	for i := range uint64(len(r.pools)) {
+		index := (start + i) % uint64(len(r.pools))
+		if r.healthy[index].Load() {
+			return r.pools[index]
+		}
+	}
+	return nil
+}
+
+func (r *Replicas) checkEvery(interval time.Duration) {
+	defer close(r.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.check(context.Background())
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// check pings the replicas, logging the ones changing state
+func (r *Replicas) check(ctx context.Context) {
+	for i, pool := range r.pools {
+		pingCtx, cancel := context.WithTimeout(ctx, replicaCheckTimeout)
+		err := pool.PingContext(pingCtx)
+		cancel()
+		if was := r.healthy[i].Swap(err == nil); was == (err == nil) {
+			continue
+		}
+		if err != nil {
+			r.log.Warn("read replica is down, its reads go elsewhere", slog.Int("replica", i), slog.String("error", err.Error()))
+		} else {
+			r.log.Info("read replica is up", slog.Int("replica", i))
+		}
+	}
+}
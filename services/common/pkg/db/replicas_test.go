@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type note struct {
+	ID   uint `gorm:"primaryKey"`
+	Body string
+}
+
+// openNotes opens a sqlite database holding a single note with body
+func openNotes(t *testing.T, body string) *gorm.DB {
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "notes.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, database.AutoMigrate(&note{}))
+	require.NoError(t, database.Create(&note{ID: 1, Body: body}).Error)
+	sqlDB, err := database.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	return database
+}
+
+func useReplica(t *testing.T, primary *gorm.DB) (*Replicas, *sql.DB) {
+	replica, err := openNotes(t, "replica").DB()
+	require.NoError(t, err)
+	replicas, err := UseReplicas(primary, []*sql.DB{replica}, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	t.Cleanup(func() { replicas.Close() })
+	return replicas, replica
+}
+
+func body(t *testing.T, database *gorm.DB) string {
+	var n note
+	require.NoError(t, database.First(&n, 1).Error)
+	return n.Body
+}
+
+func TestReplicas_RouteReads(t *testing.T) {
+	primary := openNotes(t, "primary")
+	replicas, _ := useReplica(t, primary)
+	assert.Equal(t, 1, replicas.Healthy())
+
+	assert.Equal(t, "replica", body(t, primary))
+	var raw string
+	require.NoError(t, primary.Raw("SELECT body FROM notes WHERE id = 1").Scan(&raw).Error)
+	assert.Equal(t, "replica", raw)
+	var count int64
+	require.NoError(t, primary.Model(&note{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	assert.Equal(t, "primary", body(t, Primary(primary)), "read your writes")
+	assert.Equal(t, "primary", body(t, primary.WithContext(ReadYourWrites(context.Background()))))
+	assert.Equal(t, "primary", body(t, primary.Clauses(clause.Locking{Strength: "UPDATE"})), "locking reads are writes")
+
+	require.NoError(t, primary.Create(&note{ID: 2, Body: "written"}).Error)
+	var written note
+	assert.Error(t, primary.First(&written, 2).Error, "the replica doesn't have it")
+	err := primary.Transaction(func(tx *gorm.DB) error {
+		return tx.First(&written, 2).Error
+	})
+	assert.NoError(t, err, "transactions stay on the primary")
+}
+
+func TestReplicas_FailOverToThePrimary(t *testing.T) {
+	primary := openNotes(t, "primary")
+	replicas, replica := useReplica(t, primary)
+
+	replica.Close()
+	replicas.check(context.Background())
+	assert.Equal(t, 0, replicas.Healthy())
+	assert.Equal(t, "primary", body(t, primary))
+}
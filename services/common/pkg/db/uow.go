@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWork runs functions in a transaction carried by their context. Repositories
+// bound to the context with WithContext run their queries in it, so several typed
+// repositories write together without handing a *gorm.DB around.
+type UnitOfWork struct {
+	db *gorm.DB
+}
+
+// NewUnitOfWork creates the unit of work of the database, it must be the one of the
+// repositories joining it
+func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+type txKey struct{}
+
+// txScope is a transaction or a savepoint in it with the hooks registered in it
+type txScope struct {
+	tx *gorm.DB
+	// ctx is the one the outermost transaction was started with, given to the hooks
+	ctx           context.Context
+	afterCommit   []func(context.Context)
+	afterRollback []func(context.Context)
+}
+
+func scopeFrom(ctx context.Context) *txScope {
+	scope, _ := ctx.Value(txKey{}).(*txScope)
+	return scope
+}
+
+// Do runs fn in a transaction committed when it returns nil and rolled back otherwise.
+// Called in a transaction it joins it, an error of fn then rolls back the whole one.
+// The hooks registered with AfterCommit or AfterRollback run once it ended.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if scopeFrom(ctx) != nil {
+		return fn(ctx)
+	}
+	scope := &txScope{ctx: ctx}
+	err := u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		scope.tx = tx
+		return fn(context.WithValue(ctx, txKey{}, scope))
+	})
+	if err != nil {
+		scope.run(scope.afterRollback)
+		return err
+	}
+	scope.run(scope.afterCommit)
+	return nil
+}
+
+// Savepoint is Do running fn in a savepoint when called in a transaction, so an error
+// of fn only rolls back what fn wrote and the transaction goes on. The AfterCommit
+// hooks of a rolled back savepoint are dropped and its AfterRollback ones run.
+func (u *UnitOfWork) Savepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	parent := scopeFrom(ctx)
+	if parent == nil {
+		return u.Do(ctx, fn)
+	}
+	scope := &txScope{ctx: parent.ctx}
+	err := parent.tx.Transaction(func(tx *gorm.DB) error {
+		scope.tx = tx
+		return fn(context.WithValue(ctx, txKey{}, scope))
+	})
+	if err != nil {
+		scope.run(scope.afterRollback)
+		return err
+	}
+	parent.afterCommit = append(parent.afterCommit, scope.afterCommit...)
+	parent.afterRollback = append(parent.afterRollback, scope.afterRollback...)
+	return nil
+}
+
+func (s *txScope) run(hooks []func(context.Context)) {
+	for _, hook := range hooks {
+		hook(s.ctx)
+	}
+}
+
+// InUnitOfWork tells whether ctx carries the transaction of a unit of work
+func InUnitOfWork(ctx context.Context) bool {
+	return scopeFrom(ctx) != nil
+}
+
+// Conn returns the transaction of the unit of work in ctx bound to ctx, or fallback
+// bound to ctx outside of one
+func Conn(ctx context.Context, fallback *gorm.DB) *gorm.DB {
+	if scope := scopeFrom(ctx); scope != nil {
+		return scope.tx.WithContext(ctx)
+	}
+	return fallback.WithContext(ctx)
+}
+
+// AfterCommit runs fn once the transaction in ctx is committed, e.g. to publish what
+// it wrote. Outside a transaction there's nothing to wait for and fn runs now.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if scope := scopeFrom(ctx); scope != nil {
+		scope.afterCommit = append(scope.afterCommit, fn)
+		return
+	}
+	fn(ctx)
+}
+
+// AfterRollback runs fn if the transaction in ctx is rolled back, outside a transaction
+// it never runs
+func AfterRollback(ctx context.Context, fn func(ctx context.Context)) {
+	if scope := scopeFrom(ctx); scope != nil {
+		scope.afterRollback = append(scope.afterRollback, fn)
+	}
+}
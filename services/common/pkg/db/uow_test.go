@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func names(t *testing.T, database *gorm.DB) []string {
+	var bodies []string
+	require.NoError(t, database.Model(&note{}).Order("id").Pluck("body", &bodies).Error)
+	return bodies
+}
+
+func TestUnitOfWork_CommitsOrRollsBackTogether(t *testing.T) {
+	database := openNotes(t, "first")
+	uow := NewUnitOfWork(database)
+	var hooks []string
+
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		require.True(t, InUnitOfWork(ctx))
+		AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "committed") })
+		if err := Conn(ctx, database).Create(&note{ID: 2, Body: "second"}).Error; err != nil {
+			return err
+		}
+		// a nested call joins the transaction
+		return uow.Do(ctx, func(ctx context.Context) error {
+			assert.Empty(t, hooks, "nothing runs before the commit")
+			return Conn(ctx, database).Create(&note{ID: 3, Body: "third"}).Error
+		})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second", "third"}, names(t, database))
+	assert.Equal(t, []string{"committed"}, hooks)
+
+	failed := errors.New("failed")
+	err = uow.Do(context.Background(), func(ctx context.Context) error {
+		AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "not committed") })
+		AfterRollback(ctx, func(context.Context) { hooks = append(hooks, "rolled back") })
+		require.NoError(t, Conn(ctx, database).Create(&note{ID: 4, Body: "fourth"}).Error)
+		return uow.Do(ctx, func(context.Context) error { return failed })
+	})
+	assert.ErrorIs(t, err, failed)
+	assert.Equal(t, []string{"first", "second", "third"}, names(t, database))
+	assert.Equal(t, []string{"committed", "rolled back"}, hooks)
+}
+
+func TestUnitOfWork_SavepointRollsBackItsPart(t *testing.T) {
+	database := openNotes(t, "first")
+	uow := NewUnitOfWork(database)
+	var hooks []string
+
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, Conn(ctx, database).Create(&note{ID: 2, Body: "kept"}).Error)
+		err := uow.Savepoint(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "dropped") })
+			AfterRollback(ctx, func(context.Context) { hooks = append(hooks, "savepoint rolled back") })
+			require.NoError(t, Conn(ctx, database).Create(&note{ID: 3, Body: "undone"}).Error)
+			return errors.New("failed")
+		})
+		assert.Error(t, err)
+		return uow.Savepoint(ctx, func(ctx context.Context) error {
+			AfterCommit(ctx, func(context.Context) { hooks = append(hooks, "committed") })
+			return Conn(ctx, database).Create(&note{ID: 4, Body: "saved"}).Error
+		})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "kept", "saved"}, names(t, database))
+	assert.Equal(t, []string{"savepoint rolled back", "committed"}, hooks)
+}
+
+func TestAfterCommit_RunsNowOutsideATransaction(t *testing.T) {
+	ran := false
+	AfterCommit(context.Background(), func(context.Context) { ran = true })
+	assert.True(t, ran)
+	AfterRollback(context.Background(), func(context.Context) { t.Error("nothing to roll back") })
+}
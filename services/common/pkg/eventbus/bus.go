@@ -0,0 +1,142 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrUnsupportedSchema = errors.New("unsupported schema version")
+	ErrClosed            = errors.New("event bus is closed")
+)
+
+// Publisher publishes domain events
+type Publisher interface {
+	// Publish sends the envelope to the bus.
+	Publish(ctx context.Context, env Envelope) error
+	// Close flushes pending events and releases resources.
+	Close() error
+}
+
+// Handler processes a single domain event.
+// Returning an error causes redelivery, errors marked with Permanent are not retried.
+type Handler func(ctx context.Context, env Envelope) error
+
+// Consumer delivers domain events to a handler with at-least-once semantics
+type Consumer interface {
+	// Consume blocks, passing events to the handler until ctx is cancelled.
+	Consume(ctx context.Context, handler Handler) error
+	// Close releases resources.
+	Close() error
+}
+
+// RetryPolicy controls redelivery of events whose handler failed.
+// After MaxAttempts the event is moved to the dead-letter topic.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+}
+
+// DefaultRetryPolicy is used when no policy is configured
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 5, Backoff: time.Second}
+
+// Publish wraps payload into an envelope of eventType and publishes it.
+func Publish[T any](ctx context.Context, p Publisher, eventType string, payload T, opts ...EnvelopeOption) error {
+	env, err := NewEnvelope(ctx, eventType, payload, opts...)
+	if err != nil {
+		return err
+	}
+	return p.Publish(ctx, env)
+}
+
+// Handle adapts a typed function to a Handler, decoding the payload into T.
+func Handle[T any](fn func(ctx context.Context, env Envelope, payload T) error) Handler {
+	return func(ctx context.Context, env Envelope) error {
+		var payload T
+		if err := env.Decode(&payload); err != nil {
+			return err
+		}
+		return fn(ctx, env, payload)
+	}
+}
+
+// Mux dispatches events to handlers registered for their type.
+// Events without a handler are acknowledged and skipped.
+type Mux struct {
+	handlers map[string]Handler
+}
+
+// NewMux creates an empty Mux.
+func NewMux() *Mux {
+	return &Mux{handlers: make(map[string]Handler)}
+}
+
+// On registers the handler for the event type.
+func (m *Mux) On(eventType string, h Handler) *Mux {
+	m.handlers[eventType] = h
+	return m
+}
+
+// Handle dispatches env to the registered handler.
+func (m *Mux) Handle(ctx context.Context, env Envelope) error {
+	h, ok := m.handlers[env.Type]
+	if !ok {
+		return nil
+	}
+	return h(ctx, env)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not retryable, the event goes straight to the dead-letter topic.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// process runs the handler with retries. It returns the last handler error
+// if the event should be dead-lettered, or ctx.Err() if the context is done.
+func process(ctx context.Context, policy RetryPolicy, handler Handler, env Envelope) error {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	backoff := policy.Backoff
+	var err error
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		if err = handler(ctx, env); err == nil || IsPermanent(err) {
+			return err
+		}
+		if attempt == policy.MaxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return err
+}
+
+type discard struct{}
+
+func (discard) Publish(context.Context, Envelope) error { return nil }
+func (discard) Close() error                            { return nil }
+
+// Discard is a Publisher that drops every event. It is used when the bus is disabled.
+var Discard Publisher = discard{}
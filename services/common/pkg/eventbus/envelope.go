@@ -0,0 +1,97 @@
+package eventbus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion is the version of the envelope and payload schemas written by this package.
+const SchemaVersion = 1
+
+// Envelope wraps every domain event published to the bus
+type Envelope struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	SchemaVersion int             `json:"schema_version"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Payload       json.RawMessage `json:"payload"`
+	// Key is used for partitioning, events with the same key are delivered in order.
+	// It is not part of the serialized envelope.
+	Key string `json:"-"`
+}
+
+// EnvelopeOption customizes a new envelope
+type EnvelopeOption func(*Envelope)
+
+// WithKey sets the partitioning key of the envelope.
+func WithKey(key string) EnvelopeOption {
+	return func(e *Envelope) {
+		e.Key = key
+	}
+}
+
+// WithCorrelationID sets the correlation id of the envelope, overriding the one from the context.
+func WithCorrelationID(id string) EnvelopeOption {
+	return func(e *Envelope) {
+		e.CorrelationID = id
+	}
+}
+
+// NewEnvelope marshals payload to JSON and wraps it into an envelope.
+// The correlation id is taken from ctx when present.
+func NewEnvelope(ctx context.Context, eventType string, payload interface{}, opts ...EnvelopeOption) (Envelope, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+	env := Envelope{
+		ID:            newID(),
+		Type:          eventType,
+		SchemaVersion: SchemaVersion,
+		CorrelationID: CorrelationID(ctx),
+		OccurredAt:    time.Now().UTC(),
+		Payload:       raw,
+	}
+	for _, opt := range opts {
+		opt(&env)
+	}
+	return env, nil
+}
+
+// Decode unmarshals the payload of the envelope into v.
+func (e Envelope) Decode(v interface{}) error {
+	if e.SchemaVersion > SchemaVersion {
+		return Permanent(fmt.Errorf("%w: %d", ErrUnsupportedSchema, e.SchemaVersion))
+	}
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return Permanent(fmt.Errorf("invalid %s payload: %w", e.Type, err))
+	}
+	return nil
+}
+
+type correlationKey struct{}
+
+// ContextWithCorrelationID returns a copy of ctx carrying the correlation id.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation id stored in ctx or an empty string.
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
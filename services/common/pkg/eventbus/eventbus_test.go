@@ -0,0 +1,149 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublish_WrapsPayloadIntoEnvelope(t *testing.T) {
+	bus := NewMemoryBus(10, RetryPolicy{MaxAttempts: 1})
+	ctx := ContextWithCorrelationID(context.Background(), "req-1")
+
+	err := Publish(ctx, bus, TypeEventCreated, EventPayload{EventID: 7, Name: "Basketball"}, WithKey("7"))
+	require.NoError(t, err)
+
+	published := bus.Published()
+	require.Len(t, published, 1)
+	env := published[0]
+	assert.NotEmpty(t, env.ID)
+	assert.Equal(t, TypeEventCreated, env.Type)
+	assert.Equal(t, SchemaVersion, env.SchemaVersion)
+	assert.Equal(t, "req-1", env.CorrelationID)
+	assert.Equal(t, "7", env.Key)
+	assert.False(t, env.OccurredAt.IsZero())
+
+	var payload EventPayload
+	require.NoError(t, env.Decode(&payload))
+	assert.Equal(t, uint(7), payload.EventID)
+	assert.Equal(t, "Basketball", payload.Name)
+}
+
+func TestMux_DispatchesTypedHandlers(t *testing.T) {
+	bus := NewMemoryBus(10, RetryPolicy{MaxAttempts: 1})
+	ctx := context.Background()
+
+	var got []uint
+	mux := NewMux().
+		On(TypeRegistrationCreated, Handle(func(ctx context.Context, env Envelope, p RegistrationPayload) error {
+			got = append(got, p.UserID)
+			return nil
+		}))
+
+	require.NoError(t, Publish(ctx, bus, TypeRegistrationCreated, RegistrationPayload{EventID: 1, UserID: 42}))
+	require.NoError(t, Publish(ctx, bus, TypeEventCreated, EventPayload{EventID: 1}))
+	bus.Drain(ctx, mux.Handle)
+
+	assert.Equal(t, []uint{42}, got)
+	assert.Empty(t, bus.DeadLetters(), "events without a handler are skipped")
+}
+
+func TestMemoryBus_RetriesThenDeadLetters(t *testing.T) {
+	bus := NewMemoryBus(10, RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})
+	ctx := context.Background()
+
+	calls := 0
+	flaky := func(ctx context.Context, env Envelope) error {
+		calls++
+		if calls < 3 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	}
+	require.NoError(t, Publish(ctx, bus, TypeEventUpdated, EventPayload{EventID: 1}))
+	bus.Drain(ctx, flaky)
+	assert.Equal(t, 3, calls)
+	assert.Empty(t, bus.DeadLetters())
+
+	calls = 0
+	broken := func(ctx context.Context, env Envelope) error {
+		calls++
+		return errors.New("always fails")
+	}
+	require.NoError(t, Publish(ctx, bus, TypeEventUpdated, EventPayload{EventID: 2}))
+	bus.Drain(ctx, broken)
+	assert.Equal(t, 3, calls)
+	require.Len(t, bus.DeadLetters(), 1)
+	assert.EqualError(t, bus.DeadLetters()[0].Err, "always fails")
+}
+
+func TestPermanentErrors_AreNotRetried(t *testing.T) {
+	bus := NewMemoryBus(10, RetryPolicy{MaxAttempts: 5, Backoff: time.Millisecond})
+	ctx := context.Background()
+
+	calls := 0
+	handler := Handle(func(ctx context.Context, env Envelope, p EventPayload) error {
+		calls++
+		return nil
+	})
+	require.NoError(t, bus.Publish(ctx, Envelope{ID: "1", Type: TypeEventCreated, SchemaVersion: SchemaVersion + 1, Payload: []byte(`{}`)}))
+	require.NoError(t, bus.Publish(ctx, Envelope{ID: "2", Type: TypeEventCreated, SchemaVersion: SchemaVersion, Payload: []byte(`not json`)}))
+	bus.Drain(ctx, handler)
+
+	assert.Zero(t, calls)
+	dead := bus.DeadLetters()
+	require.Len(t, dead, 2)
+	assert.ErrorIs(t, dead[0].Err, ErrUnsupportedSchema)
+	assert.True(t, IsPermanent(dead[1].Err))
+}
+
+func TestMemoryBus_ConsumeStopsOnCancel(t *testing.T) {
+	bus := NewMemoryBus(10, RetryPolicy{MaxAttempts: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	handled := make(chan string, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.Consume(ctx, func(ctx context.Context, env Envelope) error {
+			handled <- env.ID
+			return nil
+		})
+	}()
+
+	require.NoError(t, bus.Publish(ctx, Envelope{ID: "1", Type: TypeEventCreated}))
+	assert.Equal(t, "1", <-handled)
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestKafkaConsumer_RetriesFailedCalls(t *testing.T) {
+	c := &KafkaConsumer{log: slog.New(slog.NewTextHandler(io.Discard, nil)), policy: RetryPolicy{Backoff: time.Millisecond}}
+
+	calls := 0
+	err := c.retry(context.Background(), "fetch", func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls, "the call is retried until it succeeds")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = c.retry(ctx, "commit", func(context.Context) error {
+		cancel()
+		return errors.New("connection refused")
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, c.stopped(ctx, err), "a cancelled consumer stops without an error")
+
+	err = c.retry(context.Background(), "fetch", func(context.Context) error { return io.EOF })
+	assert.ErrorIs(t, err, io.EOF, "a closed reader isn't retried")
+}
@@ -0,0 +1,42 @@
+package eventbus
+
+import "time"
+
+// Domain event types
+const (
+	TypeEventCreated          = "event.created"
+	TypeEventUpdated          = "event.updated"
+	TypeEventCancelled        = "event.cancelled"
+	TypeRegistrationCreated   = "registration.created"
+	TypeRegistrationCancelled = "registration.cancelled"
+	TypeRegistrationPromoted  = "registration.promoted"
+	TypeRegistrationAttended  = "registration.attended"
+)
+
+// EventPayload describes the state of an event after a change.
+// It is the payload of EventCreated, EventUpdated and EventCancelled.
+type EventPayload struct {
+	EventID         uint      `json:"event_id"`
+	Name            string    `json:"name"`
+	Category        string    `json:"category"`
+	City            string    `json:"city"`
+	Address         string    `json:"address"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	Status          string    `json:"status"`
+	MaxParticipants int       `json:"max_participants"`
+	CreatedBy       string    `json:"created_by"`
+}
+
+// RegistrationPayload describes a registration of a user for an event.
+// It is the payload of RegistrationCreated, RegistrationCancelled, RegistrationPromoted
+// and RegistrationAttended.
+type RegistrationPayload struct {
+	RegistrationID uint   `json:"registration_id"`
+	EventID        uint   `json:"event_id"`
+	UserID         uint   `json:"user_id"`
+	Username       string `json:"username"`
+	Email          string `json:"email,omitempty"`
+	// Status is the one of the registration, "waitlisted" when the event was full
+	Status string `json:"status,omitempty"`
+}
@@ -0,0 +1,206 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/logger"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	headerEventType     = "event-type"
+	headerSchemaVersion = "schema-version"
+	headerCorrelationID = "correlation-id"
+	headerError         = "dlq-error"
+	headerOrigin        = "dlq-origin"
+)
+
+// DeadLetterTopic returns the name of the dead-letter topic for topic.
+func DeadLetterTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+// KafkaPublisher publishes JSON envelopes to a Kafka topic
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher creates a publisher writing to topic. Publish blocks
+// until the brokers acknowledge the message.
+func NewKafkaPublisher(brokers []string, topic string) *KafkaPublisher {
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  topic,
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+			WriteTimeout:           5 * time.Second,
+		},
+	}
+}
+
+func (p *KafkaPublisher) Publish(ctx context.Context, env Envelope) error {
+	value, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(env.Key),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: headerEventType, Value: []byte(env.Type)},
+			{Key: headerSchemaVersion, Value: []byte(strconv.Itoa(env.SchemaVersion))},
+			{Key: headerCorrelationID, Value: []byte(env.CorrelationID)},
+		},
+	})
+}
+
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// KafkaConsumer reads envelopes from a topic as a member of a consumer group.
+// Offsets are committed only after the handler succeeded or the message was
+// moved to the dead-letter topic, which gives at-least-once delivery.
+type KafkaConsumer struct {
+	log    *slog.Logger
+	reader *kafka.Reader
+	dlq    *kafka.Writer
+	policy RetryPolicy
+}
+
+// NewKafkaConsumer creates a consumer of topic in the consumer group groupID.
+func NewKafkaConsumer(log *slog.Logger, brokers []string, groupID, topic string, policy RetryPolicy) *KafkaConsumer {
+	return &KafkaConsumer{
+		log: log.With(slog.String("component", "eventbus/kafka"), slog.String("topic", topic)),
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  brokers,
+			GroupID:  groupID,
+			Topic:    topic,
+			MaxWait:  time.Second,
+			MinBytes: 1,
+			MaxBytes: 10e6,
+		}),
+		dlq: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  DeadLetterTopic(topic),
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		},
+		policy: policy,
+	}
+}
+
+// Consume reads messages until ctx is cancelled. Offsets are committed only
+// after the handler succeeded or the message was moved to the dead-letter topic.
+// Failed calls to the brokers are retried, so they being unavailable for a while
+// doesn't stop the consumer.
+func (c *KafkaConsumer) Consume(ctx context.Context, handler Handler) error {
+	c.log.Info("starting kafka consumer")
+	for {
+		var msg kafka.Message
+		err := c.retry(ctx, "fetch", func(ctx context.Context) (err error) {
+			msg, err = c.reader.FetchMessage(ctx)
+			return err
+		})
+		if err != nil {
+			return c.stopped(ctx, err)
+		}
+
+		var env Envelope
+		if err = json.Unmarshal(msg.Value, &env); err == nil {
+			err = process(ctx, c.policy, handler, env)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			cause := err
+			err = c.retry(ctx, "dead-letter", func(ctx context.Context) error {
+				return c.deadLetter(ctx, msg, cause)
+			})
+			if err != nil {
+				return c.stopped(ctx, err)
+			}
+		}
+
+		err = c.retry(ctx, "commit", func(ctx context.Context) error {
+			return c.reader.CommitMessages(ctx, msg)
+		})
+		if err != nil {
+			return c.stopped(ctx, err)
+		}
+	}
+}
+
+// maxRetryBackoff caps the wait between the attempts of a call to the brokers
+const maxRetryBackoff = 30 * time.Second
+
+// retry calls fn until it succeeds, waiting the backoff of the policy between the attempts,
+// doubled every time up to maxRetryBackoff. It returns an error only when ctx is done or
+// the reader was closed.
+func (c *KafkaConsumer) retry(ctx context.Context, call string, fn func(ctx context.Context) error) error {
+	backoff := c.policy.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || errors.Is(err, io.EOF) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		c.log.Warn("kafka call failed, retrying",
+			slog.String("call", call),
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			logger.Err(err),
+		)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+// stopped is what Consume returns after retry failed: nothing when ctx is done or the
+// reader was closed, the error otherwise
+func (c *KafkaConsumer) stopped(ctx context.Context, err error) error {
+	if ctx.Err() != nil || errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+func (c *KafkaConsumer) deadLetter(ctx context.Context, msg kafka.Message, cause error) error {
+	c.log.Error("moving message to dead-letter topic",
+		slog.Int("partition", msg.Partition),
+		slog.Int64("offset", msg.Offset),
+		logger.Err(cause),
+	)
+	headers := append([]kafka.Header(nil), msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: headerError, Value: []byte(cause.Error())},
+		kafka.Header{Key: headerOrigin, Value: []byte(msg.Topic + "/" + strconv.Itoa(msg.Partition) + "/" + strconv.FormatInt(msg.Offset, 10))},
+	)
+	return c.dlq.WriteMessages(ctx, kafka.Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	})
+}
+
+func (c *KafkaConsumer) Close() error {
+	return errors.Join(c.reader.Close(), c.dlq.Close())
+}
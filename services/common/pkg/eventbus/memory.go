@@ -0,0 +1,106 @@
+package eventbus
+
+import (
+	"context"
+	"sync"
+)
+
+// DeadLetter is an event that could not be handled
+type DeadLetter struct {
+	Envelope Envelope
+	Err      error
+}
+
+// MemoryBus is an in-process Publisher and Consumer. It is used in tests
+// and follows the same retry and dead-letter rules as the Kafka implementation.
+type MemoryBus struct {
+	mu          sync.Mutex
+	queue       chan Envelope
+	published   []Envelope
+	deadLetters []DeadLetter
+	policy      RetryPolicy
+	closed      bool
+}
+
+// NewMemoryBus creates a bus that buffers up to size undelivered events.
+func NewMemoryBus(size int, policy RetryPolicy) *MemoryBus {
+	return &MemoryBus{
+		queue:  make(chan Envelope, size),
+		policy: policy,
+	}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, env Envelope) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+	b.published = append(b.published, env)
+	b.mu.Unlock()
+
+	select {
+	case b.queue <- env:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *MemoryBus) Consume(ctx context.Context, handler Handler) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case env, ok := <-b.queue:
+			if !ok {
+				return nil
+			}
+			b.handle(ctx, handler, env)
+		}
+	}
+}
+
+// Drain synchronously handles every queued event and returns.
+func (b *MemoryBus) Drain(ctx context.Context, handler Handler) {
+	for {
+		select {
+		case env := <-b.queue:
+			b.handle(ctx, handler, env)
+		default:
+			return
+		}
+	}
+}
+
+func (b *MemoryBus) handle(ctx context.Context, handler Handler, env Envelope) {
+	if err := process(ctx, b.policy, handler, env); err != nil && ctx.Err() == nil {
+		b.mu.Lock()
+		b.deadLetters = append(b.deadLetters, DeadLetter{Envelope: env, Err: err})
+		b.mu.Unlock()
+	}
+}
+
+// Published returns every event published so far.
+func (b *MemoryBus) Published() []Envelope {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Envelope(nil), b.published...)
+}
+
+// DeadLetters returns events whose handler gave up.
+func (b *MemoryBus) DeadLetters() []DeadLetter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]DeadLetter(nil), b.deadLetters...)
+}
+
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// OutboxMessage is an envelope in the outbox table waiting to be published
+type OutboxMessage struct {
+	ID            uint      `gorm:"primaryKey"`
+	EnvelopeID    string    `gorm:"type:varchar(64);not null"`
+	Type          string    `gorm:"type:varchar(100);not null"`
+	PartitionKey  string    `gorm:"type:varchar(255)"`
+	Envelope      []byte    `gorm:"not null"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null"`
+	LastError     string    `gorm:"type:text"`
+	CreatedAt     time.Time
+}
+
+// Outbox is a Publisher writing the envelopes to the outbox table of the service database.
+// Published in a db.UnitOfWork they're written in its transaction, so an event goes out
+// if and only if the change it tells about is committed. A Relay publishes them.
+type Outbox struct {
+	db *gorm.DB
+}
+
+// NewOutbox creates the outbox in the database of conn, it must be the one of the
+// unit of work the envelopes are published in
+func NewOutbox(conn *gorm.DB) *Outbox {
+	return &Outbox{db: conn}
+}
+
+func (o *Outbox) Publish(ctx context.Context, env Envelope) error {
+	value, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	msg := &OutboxMessage{
+		EnvelopeID:    env.ID,
+		Type:          env.Type,
+		PartitionKey:  env.Key,
+		Envelope:      value,
+		NextAttemptAt: time.Now(),
+	}
+	if err := db.Conn(ctx, o.db).Create(msg).Error; err != nil {
+		return fmt.Errorf("failed to write %s to the outbox: %w", env.Type, err)
+	}
+	return nil
+}
+
+func (o *Outbox) Close() error {
+	return nil
+}
+
+// RelayOptions configure a relay
+type RelayOptions struct {
+	// Interval is how often the outbox is checked for envelopes
+	Interval  time.Duration
+	BatchSize int
+	// Backoff is the wait after a failed publish, doubled on every failure up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Relay publishes the envelopes of the outbox in the order they were written and deletes
+// them once published. A failed envelope is retried after a backoff and the ones after
+// it wait, so the envelopes of a key stay in order. An envelope may go out more than
+// once, e.g. when several instances relay the same outbox, consumers are idempotent.
+type Relay struct {
+	log       *slog.Logger
+	db        *gorm.DB
+	publisher Publisher
+	opts      RelayOptions
+}
+
+// NewRelay creates the relay of the outbox in the database of conn to publisher
+func NewRelay(log *slog.Logger, conn *gorm.DB, publisher Publisher, opts RelayOptions) *Relay {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.BatchSize < 1 {
+		opts.BatchSize = 100
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.MaxBackoff < opts.Backoff {
+		opts.MaxBackoff = max(time.Minute, opts.Backoff)
+	}
+	return &Relay{
+		log:       log.With(slog.String("component", "eventbus/outbox")),
+		db:        conn,
+		publisher: publisher,
+		opts:      opts,
+	}
+}
+
+// Run relays the outbox every interval until ctx is cancelled, then once more so the
+// envelopes written by the requests drained on shutdown go out too.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+			r.log.Error("failed to relay the outbox", logger.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			if _, err := r.Flush(flushCtx); err != nil {
+				r.log.Warn("envelopes left in the outbox", logger.Err(err))
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush publishes the envelopes of the outbox up to the first one failing or waiting
+// for its retry, it returns how many went out
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		var batch []OutboxMessage
+		if err := r.db.WithContext(ctx).Order("id").Limit(r.opts.BatchSize).Find(&batch).Error; err != nil {
+			return published, fmt.Errorf("failed to read the outbox: %w", err)
+		}
+		for i := range batch {
+			msg := &batch[i]
+			if msg.NextAttemptAt.After(time.Now()) {
+				return published, nil
+			}
+			if err := r.publish(ctx, msg); err != nil {
+				return published, r.retryLater(ctx, msg, err)
+			}
+			published++
+		}
+		if len(batch) < r.opts.BatchSize {
+			return published, nil
+		}
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, msg *OutboxMessage) error {
+	var env Envelope
+	if err := json.Unmarshal(msg.Envelope, &env); err != nil {
+		return err
+	}
+	env.Key = msg.PartitionKey
+	if err := r.publisher.Publish(ctx, env); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Delete(&OutboxMessage{}, msg.ID).Error
+}
+
+// retryLater records the failed attempt and when the envelope is tried again
+func (r *Relay) retryLater(ctx context.Context, msg *OutboxMessage, cause error) error {
+	backoff := r.opts.Backoff
+	for i := 0; i < msg.Attempts && backoff < r.opts.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, r.opts.MaxBackoff)
+	err := r.db.WithContext(ctx).Model(&OutboxMessage{}).Where("id = ?", msg.ID).Updates(map[string]interface{}{
+		"attempts":        msg.Attempts + 1,
+		"next_attempt_at": time.Now().Add(backoff),
+		"last_error":      cause.Error(),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to publish %s %s: %w, and to record it: %v", msg.Type, msg.EnvelopeID, cause, err)
+	}
+	return fmt.Errorf("failed to publish %s %s, retrying in %s: %w", msg.Type, msg.EnvelopeID, backoff, cause)
+}
@@ -0,0 +1,95 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// flakyPublisher fails while down is set and records the envelopes otherwise
+type flakyPublisher struct {
+	down      bool
+	published []Envelope
+}
+
+func (p *flakyPublisher) Publish(_ context.Context, env Envelope) error {
+	if p.down {
+		return errors.New("kafka is down")
+	}
+	p.published = append(p.published, env)
+	return nil
+}
+
+func (p *flakyPublisher) Close() error { return nil }
+
+func setupOutbox(t *testing.T) (*gorm.DB, *Outbox) {
+	conn, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, conn.AutoMigrate(&OutboxMessage{}))
+	return conn, NewOutbox(conn)
+}
+
+func TestOutbox_WritesInTheUnitOfWork(t *testing.T) {
+	conn, outbox := setupOutbox(t)
+	uow := db.NewUnitOfWork(conn)
+
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		require.NoError(t, Publish(ctx, outbox, TypeEventCreated, EventPayload{EventID: 1}))
+		return errors.New("roll back")
+	})
+	assert.Error(t, err)
+	err = uow.Do(context.Background(), func(ctx context.Context) error {
+		return Publish(ctx, outbox, TypeEventCreated, EventPayload{EventID: 2}, WithKey("2"))
+	})
+	require.NoError(t, err)
+
+	var messages []OutboxMessage
+	require.NoError(t, conn.Find(&messages).Error)
+	require.Len(t, messages, 1, "the envelope of the rolled back change is gone with it")
+	assert.Equal(t, TypeEventCreated, messages[0].Type)
+	assert.Equal(t, "2", messages[0].PartitionKey)
+}
+
+func TestRelay_PublishesInOrderAndRetriesAfterBackoff(t *testing.T) {
+	conn, outbox := setupOutbox(t)
+	ctx := context.Background()
+	for _, key := range []string{"1", "2", "1"} {
+		require.NoError(t, Publish(ctx, outbox, TypeEventUpdated, EventPayload{}, WithKey(key)))
+	}
+	publisher := &flakyPublisher{down: true}
+	relay := NewRelay(slog.New(slog.NewTextHandler(io.Discard, nil)), conn, publisher, RelayOptions{BatchSize: 2, Backoff: time.Hour})
+
+	published, err := relay.Flush(ctx)
+	assert.ErrorContains(t, err, "kafka is down")
+	assert.Zero(t, published)
+	var failed OutboxMessage
+	require.NoError(t, conn.Order("id").First(&failed).Error)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Equal(t, "kafka is down", failed.LastError)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), failed.NextAttemptAt, time.Minute)
+
+	publisher.down = false
+	published, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, published, "the envelopes wait behind the one retried later")
+
+	require.NoError(t, conn.Model(&OutboxMessage{}).Where("id = ?", failed.ID).Update("next_attempt_at", time.Now()).Error)
+	published, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, published)
+	require.Len(t, publisher.published, 3)
+	assert.Equal(t, []string{"1", "2", "1"}, []string{publisher.published[0].Key, publisher.published[1].Key, publisher.published[2].Key})
+
+	var left int64
+	require.NoError(t, conn.Model(&OutboxMessage{}).Count(&left).Error)
+	assert.Zero(t, left, "published envelopes are deleted")
+}
@@ -0,0 +1,278 @@
+// Package eventsclient is the client of the gRPC API of event-service, shared by the
+// services that reserve seats or need the details of events.
+package eventsclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/breaker"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/grpcerr"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/health"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/metrics"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/protos/events"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/tracing"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MaxBatchSize is the number of events event-service returns by one BatchGetEvents call,
+// BatchGetEvents of the client splits larger requests.
+const MaxBatchSize = 500
+
+// RewatchDelay is the pause before WatchEvent watches again after the stream broke
+var RewatchDelay = time.Second
+
+type Client struct {
+	api    events.EventServiceClient
+	health health.Check
+	log    *slog.Logger
+}
+
+// Options tune how calls are retried and when the client stops calling
+type Options struct {
+	// Timeout is the deadline of each try
+	Timeout time.Duration
+	// Retries is how many times a call failing with a transient code is tried again
+	Retries int
+	// Backoff is the base of the exponential pause between tries, jittered by 20%
+	Backoff time.Duration
+	// BreakerFailures consecutive failed calls make the client fail calls at once for
+	// BreakerCooldown, zero keeps the breaker closed
+	BreakerFailures int
+	BreakerCooldown time.Duration
+}
+
+// New dials event-service at addr. Calls are logged, measured, retried on transient
+// failures and cut off while event-service keeps failing.
+func New(
+	ctx context.Context,
+	log *slog.Logger,
+	addr string,
+	dialOpts []grpc.DialOption,
+	opts Options,
+) (*Client, error) {
+	retryOpts := []retry.CallOption{
+		retry.WithCodes(transientCodes...),
+		retry.WithMax(uint(opts.Retries)),
+		retry.WithPerRetryTimeout(opts.Timeout),
+		retry.WithBackoff(retry.BackoffExponentialWithJitter(opts.Backoff, 0.2)),
+	}
+
+	logOpts := []logging.Option{
+		logging.WithLogOnEvents(logging.PayloadReceived, logging.PayloadSent),
+	}
+
+	cc, err := grpc.DialContext(ctx, addr, append(dialOpts,
+		tracing.DialOption(),
+		grpc.WithChainUnaryInterceptor(
+			metrics.UnaryClientInterceptor(),
+			logging.UnaryClientInterceptor(InterceptorLogger(log), logOpts...),
+			breaker.UnaryClientInterceptor(breaker.New(opts.BreakerFailures, opts.BreakerCooldown)),
+			retry.UnaryClientInterceptor(retryOpts...),
+		),
+	)...)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromConn(cc, log), nil
+}
+
+// NewFromConn creates the client on an already dialed connection
+func NewFromConn(cc grpc.ClientConnInterface, log *slog.Logger) *Client {
+	return &Client{
+		api:    events.NewEventServiceClient(cc),
+		health: health.GRPC(cc, events.EventService_ServiceDesc.ServiceName),
+		log:    log,
+	}
+}
+
+func InterceptorLogger(l *slog.Logger) logging.Logger {
+	return logging.LoggerFunc(func(ctx context.Context, lvl logging.Level, msg string, fields ...any) {
+		l.Log(ctx, slog.Level(lvl), msg, fields...)
+	})
+}
+
+// transientCodes are retried, the others won't change by trying again. A try running
+// out of its own timeout is DeadlineExceeded too.
+var transientCodes = []codes.Code{codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded}
+
+// noRepeat keeps a call that changes the seat count from being repeated after it may
+// have reached the server, only calls refused before that are retried.
+var noRepeat = retry.WithCodes(codes.Unavailable)
+
+// Outcome returns the reason a call failed with, as set by event-service in the ErrorInfo
+// of the error, RESERVE_STATUS_UNSPECIFIED for errors without one.
+func Outcome(err error) events.ReserveStatus {
+	reason := grpcerr.Reason(err, events.EventService_ServiceDesc.ServiceName)
+	return events.ReserveStatus(events.ReserveStatus_value[reason])
+}
+
+// CheckAndReserve takes a seat of the event for the user whose token is in ctx, see
+// Outcome for why it failed
+func (c *Client) CheckAndReserve(ctx context.Context, eventID uint32) (*events.CheckAndReserveResponse, error) {
+	resp, err := c.api.CheckAndReserve(ctx, &events.CheckAndReserveRequest{
+		EventId: eventID,
+	}, noRepeat)
+	if err != nil {
+		c.log.Error("failed to call CheckAndReserve", "error", err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RemoveRegistration frees the seat of the user whose token is in ctx
+func (c *Client) RemoveRegistration(ctx context.Context, eventID uint32) (*events.RemoveRegistrationResponse, error) {
+	resp, err := c.api.RemoveRegistration(ctx, &events.RemoveRegistrationRequest{
+		EventId: eventID,
+	}, noRepeat)
+	if err != nil {
+		c.log.Error("failed to call RemoveRegistration", "error", err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) GetEvent(ctx context.Context, eventID uint32) (*events.GetEventResponse, error) {
+	resp, err := c.api.GetEvent(ctx, &events.GetEventRequest{
+		EventId: eventID,
+	})
+	if err != nil {
+		c.log.Error("failed to call GetEvent", "error", err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// BatchGetEvents returns the events of eventIDs that exist, in no particular order.
+// More than MaxBatchSize ids are requested in several calls.
+func (c *Client) BatchGetEvents(ctx context.Context, eventIDs []uint32) (*events.BatchGetEventsResponse, error) {
+	found := &events.BatchGetEventsResponse{}
+	for start := 0; start < len(eventIDs); start += MaxBatchSize {
+		end := min(start+MaxBatchSize, len(eventIDs))
+		resp, err := c.api.BatchGetEvents(ctx, &events.BatchGetEventsRequest{
+			EventIds: eventIDs[start:end],
+		})
+		if err != nil {
+			c.log.Error("failed to call BatchGetEvents", "error", err)
+			return nil, err
+		}
+		found.Events = append(found.Events, resp.Events...)
+	}
+	return found, nil
+}
+
+// ListEvents returns one page of the events matching the filters of req
+func (c *Client) ListEvents(ctx context.Context, req *events.ListEventsRequest) (*events.ListEventsResponse, error) {
+	resp, err := c.api.ListEvents(ctx, req)
+	if err != nil {
+		c.log.Error("failed to call ListEvents", "error", err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// EachEvent calls fn for every event matching the filters of req, page by page starting
+// at req.PageToken, and stops at the first error of fn.
+func (c *Client) EachEvent(ctx context.Context, req *events.ListEventsRequest, fn func(*events.Event) error) error {
+	page := &events.ListEventsRequest{
+		Category:     req.Category,
+		City:         req.City,
+		Status:       req.Status,
+		CreatedBy:    req.CreatedBy,
+		StartsAfter:  req.StartsAfter,
+		StartsBefore: req.StartsBefore,
+		PageSize:     req.PageSize,
+		PageToken:    req.PageToken,
+	}
+	for {
+		resp, err := c.ListEvents(ctx, page)
+		if err != nil {
+			return err
+		}
+		for _, event := range resp.Events {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		if resp.NextPageToken == "" {
+			return nil
+		}
+		page.PageToken = resp.NextPageToken
+	}
+}
+
+// WatchEvent calls fn with the current state of the event and then with each of its
+// changes until ctx is done, the event is deleted or fn fails. When the stream breaks
+// or falls behind the event is watched again, fn gets its current state once more and
+// should treat it as a replacement of everything seen before.
+func (c *Client) WatchEvent(ctx context.Context, eventID uint32, fn func(*events.EventChange) error) error {
+	for {
+		deleted, err := c.watch(ctx, eventID, fn)
+		var stop errStop
+		switch {
+		case deleted:
+			return nil
+		case errors.As(err, &stop):
+			return stop.err
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case !rewatch(err):
+			return err
+		}
+		c.log.Warn("event watch broke, watching again", slog.Uint64("event_id", uint64(eventID)), "error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(RewatchDelay):
+		}
+	}
+}
+
+// errStop tells apart errors of fn from errors of the stream
+type errStop struct{ err error }
+
+func (e errStop) Error() string { return e.err.Error() }
+
+func (c *Client) watch(ctx context.Context, eventID uint32, fn func(*events.EventChange) error) (bool, error) {
+	stream, err := c.api.WatchEvent(ctx, &events.WatchEventRequest{EventId: eventID})
+	if err != nil {
+		return false, err
+	}
+	for {
+		change, err := stream.Recv()
+		if err != nil {
+			return false, err
+		}
+		if err := fn(change); err != nil {
+			return false, errStop{err}
+		}
+		if change.Type == events.ChangeType_CHANGE_TYPE_DELETED {
+			return true, nil
+		}
+	}
+}
+
+// rewatch tells whether the watch may continue on a new stream after err
+func rewatch(err error) bool {
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Aborted, codes.Internal, codes.Unknown:
+		return true
+	}
+	return false
+}
+
+// Health checks that the upstream reports the service as serving
+func (c *Client) Health(ctx context.Context) error {
+	return c.health(ctx)
+}
@@ -0,0 +1,213 @@
+package eventsclient
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/grpcerr"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/protos/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeEvents struct {
+	events.UnimplementedEventServiceServer
+	batches  [][]uint32
+	listed   []*events.Event
+	watches  int
+	watchErr error
+	// getErrs are returned by GetEvent one by one before it succeeds
+	getErrs []error
+	gets    int
+}
+
+func (f *fakeEvents) GetEvent(_ context.Context, req *events.GetEventRequest) (*events.GetEventResponse, error) {
+	f.gets++
+	if len(f.getErrs) > 0 {
+		err := f.getErrs[0]
+		f.getErrs = f.getErrs[1:]
+		return nil, err
+	}
+	return &events.GetEventResponse{Status: events.ReserveStatus_SUCCESS, Event: &events.Event{Id: req.EventId}}, nil
+}
+
+func (f *fakeEvents) BatchGetEvents(_ context.Context, req *events.BatchGetEventsRequest) (*events.BatchGetEventsResponse, error) {
+	f.batches = append(f.batches, req.EventIds)
+	resp := &events.BatchGetEventsResponse{}
+	for _, id := range req.EventIds {
+		resp.Events = append(resp.Events, &events.Event{Id: id})
+	}
+	return resp, nil
+}
+
+// ListEvents pages through listed two at a time, the token is the next index
+func (f *fakeEvents) ListEvents(_ context.Context, req *events.ListEventsRequest) (*events.ListEventsResponse, error) {
+	start, _ := strconv.Atoi(req.PageToken)
+	end := min(start+2, len(f.listed))
+	resp := &events.ListEventsResponse{Events: f.listed[start:end]}
+	if end < len(f.listed) {
+		resp.NextPageToken = strconv.Itoa(end)
+	}
+	return resp, nil
+}
+
+// WatchEvent breaks the first stream with watchErr after the current state and deletes
+// the event on the second one
+func (f *fakeEvents) WatchEvent(req *events.WatchEventRequest, stream events.EventService_WatchEventServer) error {
+	f.watches++
+	event := &events.Event{Id: req.EventId, Participants: uint32(f.watches)}
+	if err := stream.Send(&events.EventChange{Type: events.ChangeType_CHANGE_TYPE_CURRENT, Event: event}); err != nil {
+		return err
+	}
+	if f.watches == 1 {
+		return f.watchErr
+	}
+	return stream.Send(&events.EventChange{Type: events.ChangeType_CHANGE_TYPE_DELETED, Event: event})
+}
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// serve starts fake and returns the dial options reaching it
+func serve(t *testing.T, fake *fakeEvents) []grpc.DialOption {
+	listener := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	events.RegisterEventServiceServer(srv, fake)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+}
+
+func newClient(t *testing.T, fake *fakeEvents) *Client {
+	cc, err := grpc.NewClient("passthrough:///bufnet", serve(t, fake)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { cc.Close() })
+	return NewFromConn(cc, discard)
+}
+
+func dialClient(t *testing.T, fake *fakeEvents, opts Options) *Client {
+	client, err := New(context.Background(), discard, "passthrough:///bufnet", serve(t, fake), opts)
+	require.NoError(t, err)
+	return client
+}
+
+func notFound(eventID string) error {
+	return grpcerr.WithInfo(codes.NotFound, events.EventService_ServiceDesc.ServiceName,
+		events.ReserveStatus_EVENT_NOT_FOUND.String(), "event not found", map[string]string{"event_id": eventID})
+}
+
+func TestGetEvent_RetriesOnlyTransientFailures(t *testing.T) {
+	fake := &fakeEvents{getErrs: []error{status.Error(codes.Unavailable, "restarting")}}
+	client := dialClient(t, fake, Options{Timeout: time.Second, Retries: 2, Backoff: time.Millisecond})
+
+	resp, err := client.GetEvent(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(7), resp.Event.Id)
+	assert.Equal(t, 2, fake.gets)
+
+	fake.gets = 0
+	fake.getErrs = []error{notFound("8")}
+	_, err = client.GetEvent(context.Background(), 8)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, events.ReserveStatus_EVENT_NOT_FOUND, Outcome(err))
+	assert.Equal(t, 1, fake.gets, "a missing event stays missing")
+
+	assert.Equal(t, events.ReserveStatus_RESERVE_STATUS_UNSPECIFIED, Outcome(status.Error(codes.Internal, "no details")))
+}
+
+func TestGetEvent_BreakerFailsFastAfterFailures(t *testing.T) {
+	fake := &fakeEvents{getErrs: []error{
+		status.Error(codes.Unavailable, "down"),
+		status.Error(codes.Unavailable, "down"),
+	}}
+	client := dialClient(t, fake, Options{Timeout: time.Second, BreakerFailures: 2, BreakerCooldown: time.Hour})
+
+	for range 2 {
+		_, err := client.GetEvent(context.Background(), 7)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	_, err := client.GetEvent(context.Background(), 7)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 2, fake.gets, "the open breaker keeps calls from reaching the server")
+
+	// an answer like NOT_FOUND means event-service works
+	fake.gets = 0
+	client = dialClient(t, fake, Options{Timeout: time.Second, BreakerFailures: 1, BreakerCooldown: time.Hour})
+	fake.getErrs = []error{notFound("7")}
+	client.GetEvent(context.Background(), 7)
+	_, err = client.GetEvent(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fake.gets)
+}
+
+func TestBatchGetEvents_SplitsLargeRequests(t *testing.T) {
+	fake := &fakeEvents{}
+	client := newClient(t, fake)
+
+	ids := make([]uint32, MaxBatchSize+1)
+	for i := range ids {
+		ids[i] = uint32(i + 1)
+	}
+	resp, err := client.BatchGetEvents(context.Background(), ids)
+	require.NoError(t, err)
+	assert.Len(t, resp.Events, len(ids))
+	require.Len(t, fake.batches, 2)
+	assert.Len(t, fake.batches[0], MaxBatchSize)
+	assert.Equal(t, []uint32{MaxBatchSize + 1}, fake.batches[1])
+
+	_, err = client.BatchGetEvents(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Len(t, fake.batches, 2, "nothing to ask for")
+}
+
+func TestEachEvent_WalksAllPages(t *testing.T) {
+	fake := &fakeEvents{}
+	for id := uint32(1); id <= 5; id++ {
+		fake.listed = append(fake.listed, &events.Event{Id: id})
+	}
+	client := newClient(t, fake)
+
+	var seen []uint32
+	err := client.EachEvent(context.Background(), &events.ListEventsRequest{}, func(event *events.Event) error {
+		seen = append(seen, event.Id)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{1, 2, 3, 4, 5}, seen)
+}
+
+func TestWatchEvent_WatchesAgainAfterFallingBehind(t *testing.T) {
+	RewatchDelay = time.Millisecond
+	fake := &fakeEvents{watchErr: status.Error(codes.Aborted, "fell behind")}
+	client := newClient(t, fake)
+
+	var changes []events.ChangeType
+	err := client.WatchEvent(context.Background(), 7, func(change *events.EventChange) error {
+		changes = append(changes, change.Type)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []events.ChangeType{
+		events.ChangeType_CHANGE_TYPE_CURRENT,
+		events.ChangeType_CHANGE_TYPE_CURRENT,
+		events.ChangeType_CHANGE_TYPE_DELETED,
+	}, changes)
+
+	fake.watches = 0
+	fake.watchErr = status.Error(codes.PermissionDenied, "no")
+	err = client.WatchEvent(context.Background(), 7, func(*events.EventChange) error { return nil })
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, 1, fake.watches, "only passing failures are retried")
+}
@@ -0,0 +1,106 @@
+package grpcauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ServiceTokens gets the service token of the service from auth-service and keeps it
+// until most of its lifetime has passed.
+type ServiceTokens struct {
+	url    string
+	name   string
+	secret string
+	client *http.Client
+
+	mu        sync.Mutex
+	token     string
+	refreshAt time.Time
+}
+
+// NewServiceTokens creates the token source of the service name authenticated by secret,
+// url is the service-token endpoint of auth-service.
+func NewServiceTokens(url, name, secret string) *ServiceTokens {
+	return &ServiceTokens{
+		url:    url,
+		name:   name,
+		secret: secret,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// TokenResponse is what the service-token endpoint of auth-service returns
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	// ExpiresIn is the lifetime of the token in seconds
+	ExpiresIn int `json:"expires_in"`
+}
+
+// Token returns the cached token or gets a new one
+func (s *ServiceTokens) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Before(s.refreshAt) {
+		return s.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(s.name, s.secret)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("service token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("service token: auth-service answered %s", resp.Status)
+	}
+	var body TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("service token: %w", err)
+	}
+
+	lifetime := time.Duration(body.ExpiresIn) * time.Second
+	s.token = body.AccessToken
+	s.refreshAt = time.Now().Add(lifetime * 4 / 5)
+	return s.token, nil
+}
+
+// Credentials attach the tokens to the calls of a client connection,
+// pass them with grpc.WithPerRPCCredentials.
+type Credentials struct {
+	// Services is nil for a service that doesn't call others on its own
+	Services *ServiceTokens
+	// Plaintext lets the tokens go over channels without TLS, for local runs only
+	Plaintext bool
+}
+
+func (c Credentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	md := make(map[string]string, 2)
+	if token, ok := UserToken(ctx); ok {
+		md[UserTokenKey] = "Bearer " + token
+	}
+	if c.Services != nil {
+		token, err := c.Services.Token(ctx)
+		if err != nil && len(md) == 0 {
+			return nil, err
+		}
+		// with a user token the call may still be allowed, the server decides
+		if err == nil {
+			md[ServiceTokenKey] = token
+		}
+	}
+	return md, nil
+}
+
+// RequireTransportSecurity keeps the tokens off channels without TLS unless Plaintext is set.
+func (c Credentials) RequireTransportSecurity() bool {
+	return !c.Plaintext
+}
@@ -0,0 +1,84 @@
+// Package grpcauth authenticates calls between services. A call carries the access token
+// of the end user it is made for, forwarded from the HTTP request that caused it, and the
+// service token the calling service got from auth-service. The server verifies both,
+// authorizes the method and passes the acting user to the handler.
+package grpcauth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Metadata keys of the tokens
+const (
+	UserTokenKey    = "authorization"
+	ServiceTokenKey = "x-service-token"
+)
+
+type userTokenKey struct{}
+
+// WithUserToken returns ctx carrying the access token of the end user, calls made with it
+// through Credentials forward the token.
+func WithUserToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, userTokenKey{}, token)
+}
+
+// UserToken returns the access token put by WithUserToken
+func UserToken(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(userTokenKey{}).(string)
+	return token, ok && token != ""
+}
+
+// ForwardUserToken is HTTP middleware putting the access token of the request, from the
+// access_token cookie or the Authorization header, into its context, so the gRPC calls
+// made while handling it are made for the user.
+func ForwardUserToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := ""
+		if cookie, err := r.Cookie("access_token"); err == nil {
+			token = cookie.Value
+		} else if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token = bearer
+		}
+		if token != "" {
+			r = r.WithContext(WithUserToken(r.Context(), token))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Principal is who a call is made by and for
+type Principal struct {
+	// Service is the name of the calling service, empty when it sent no service token
+	Service string
+	// User holds the claims of the end user the call is made for, nil when the service
+	// calls on its own, e.g. from a background job
+	User jwt.MapClaims
+}
+
+type principalKey struct{}
+
+func withPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal verified by the server interceptors
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Username returns the name of the acting user, empty without one
+func (p Principal) Username() string {
+	username, _ := p.User["username"].(string)
+	return username
+}
+
+// UserID returns the id of the acting user
+func (p Principal) UserID() (uint, bool) {
+	id, ok := p.User["userID"].(float64)
+	return uint(id), ok
+}
@@ -0,0 +1,134 @@
+package grpcauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const reserveMethod = "/events.EventService/CheckAndReserve"
+
+func newSigner(t *testing.T) *auth.Signer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	signer, err := auth.NewSigner(base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})))
+	require.NoError(t, err)
+	return signer
+}
+
+func sign(t *testing.T, signer *auth.Signer, claims jwt.MapClaims) string {
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	token, err := signer.Sign(claims)
+	require.NoError(t, err)
+	return token
+}
+
+// call runs the interceptor for the method with the tokens as incoming metadata and
+// returns the principal the handler got
+func call(verifier *auth.Verifier, policy Policy, method string, md map[string]string) (Principal, error) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.New(md))
+	var got Principal
+	_, err := UnaryServerInterceptor(verifier, policy)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+		func(ctx context.Context, _ any) (any, error) {
+			got, _ = FromContext(ctx)
+			return nil, nil
+		})
+	return got, err
+}
+
+func TestServerInterceptor_AuthorizesByRule(t *testing.T) {
+	signer := newSigner(t)
+	verifier := signer.Verifier()
+	userToken := "Bearer " + sign(t, signer, jwt.MapClaims{"userID": 7, "username": "alice"})
+	registration := sign(t, signer, jwt.MapClaims{"sub": "registration-service", "aud": []string{auth.ServiceAudience}})
+	chat := sign(t, signer, jwt.MapClaims{"sub": "chat-service", "aud": []string{auth.ServiceAudience}})
+
+	policy := Policy{
+		reserveMethod:             {User: true, Services: []string{"registration-service"}},
+		"/grpc.health.v1.Health/": {Public: true},
+	}
+
+	principal, err := call(verifier, policy, reserveMethod, map[string]string{UserTokenKey: userToken, ServiceTokenKey: registration})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", principal.Username())
+	assert.Equal(t, "registration-service", principal.Service)
+	userID, ok := principal.UserID()
+	assert.True(t, ok)
+	assert.Equal(t, uint(7), userID)
+
+	_, err = call(verifier, policy, reserveMethod, map[string]string{UserTokenKey: userToken, ServiceTokenKey: chat})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "only registration-service may reserve")
+
+	_, err = call(verifier, policy, reserveMethod, map[string]string{ServiceTokenKey: registration})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "a reservation is made for a user")
+
+	_, err = call(verifier, policy, reserveMethod, nil)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = call(verifier, policy, reserveMethod, map[string]string{UserTokenKey: "Bearer " + registration, ServiceTokenKey: registration})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "a service token is not a user token")
+
+	principal, err = call(verifier, policy, "/events.EventService/GetEvent", map[string]string{ServiceTokenKey: chat})
+	assert.NoError(t, err, "methods without a rule are open to services")
+	assert.Nil(t, principal.User)
+
+	_, err = call(verifier, policy, "/grpc.health.v1.Health/Check", nil)
+	assert.NoError(t, err)
+}
+
+func TestCredentials_ForwardUserAndCacheServiceToken(t *testing.T) {
+	requests := 0
+	authService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		name, secret, ok := r.BasicAuth()
+		if !ok || name != "registration-service" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(TokenResponse{AccessToken: "service-token", TokenType: "Bearer", ExpiresIn: 3600})
+	}))
+	defer authService.Close()
+
+	creds := Credentials{Services: NewServiceTokens(authService.URL, "registration-service", "s3cret")}
+	assert.True(t, creds.RequireTransportSecurity(), "tokens should need TLS unless plaintext is allowed")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/registrations", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: "user-token"})
+	var ctx context.Context
+	ForwardUserToken(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	md, err := creds.GetRequestMetadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{UserTokenKey: "Bearer user-token", ServiceTokenKey: "service-token"}, md)
+
+	md, err = creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{ServiceTokenKey: "service-token"}, md)
+	assert.Equal(t, 1, requests, "the token should be cached")
+
+	wrong := Credentials{Services: NewServiceTokens(authService.URL, "registration-service", "guess")}
+	_, err = wrong.GetRequestMetadata(context.Background())
+	assert.ErrorContains(t, err, "401")
+}
@@ -0,0 +1,106 @@
+package grpcauth
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Rule tells who may call a method. Methods without one may be called by any
+// verified user or service.
+type Rule struct {
+	// Public methods need no tokens, like the health checks
+	Public bool
+	// User requires the token of the end user the call is made for
+	User bool
+	// Services requires a service token of one of the services
+	Services []string
+}
+
+// Policy maps full method names, e.g. events.EventService_CheckAndReserve_FullMethodName,
+// or "/package.Service/" for all methods of a service, to their rule.
+type Policy map[string]Rule
+
+func (p Policy) rule(method string) Rule {
+	if rule, ok := p[method]; ok {
+		return rule
+	}
+	if i := strings.LastIndex(method, "/"); i > 0 {
+		return p[method[:i+1]]
+	}
+	return Rule{}
+}
+
+// authenticate verifies the tokens in the metadata of the call and checks them against
+// the rule of the method, returning ctx with the principal.
+func authenticate(ctx context.Context, verifier *auth.Verifier, policy Policy, method string) (context.Context, error) {
+	rule := policy.rule(method)
+	if rule.Public {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var principal Principal
+	if values := md.Get(UserTokenKey); len(values) > 0 {
+		token, _ := strings.CutPrefix(values[0], "Bearer ")
+		claims, err := verifier.VerifyJWTToken(token)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "user token: %v", err)
+		}
+		principal.User = claims
+	}
+	if values := md.Get(ServiceTokenKey); len(values) > 0 {
+		claims, err := verifier.VerifyAudience(values[0], auth.ServiceAudience)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "service token: %v", err)
+		}
+		principal.Service, _ = claims.GetSubject()
+	}
+
+	switch {
+	case principal.User == nil && principal.Service == "":
+		return nil, status.Errorf(codes.Unauthenticated, "%s requires a user or service token", method)
+	case rule.User && principal.User == nil:
+		return nil, status.Errorf(codes.PermissionDenied, "%s is only called for a user", method)
+	case len(rule.Services) > 0 && !slices.Contains(rule.Services, principal.Service):
+		return nil, status.Errorf(codes.PermissionDenied, "service %q is not allowed to call %s", principal.Service, method)
+	}
+	return withPrincipal(ctx, principal), nil
+}
+
+// UnaryServerInterceptor authenticates and authorizes calls by the policy
+func UnaryServerInterceptor(verifier *auth.Verifier, policy Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, verifier, policy, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streams
+func StreamServerInterceptor(verifier *auth.Verifier, policy Policy) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), verifier, policy, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
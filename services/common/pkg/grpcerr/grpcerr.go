@@ -0,0 +1,88 @@
+// Package grpcerr builds gRPC errors carrying google.rpc error details and reads them
+// back, so callers branch on a stable reason instead of parsing messages.
+package grpcerr
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// WithInfo returns an error of code carrying an ErrorInfo with the reason of the failure
+// in domain, metadata may hold the ids it's about.
+func WithInfo(code codes.Code, domain, reason, msg string, metadata map[string]string) error {
+	return withDetails(status.New(code, msg), &errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   domain,
+		Metadata: metadata,
+	})
+}
+
+// Violation is a field of a request that isn't valid
+type Violation struct {
+	Field       string
+	Description string
+}
+
+// BadRequest returns an InvalidArgument error carrying the violations as BadRequest
+func BadRequest(msg string, violations ...Violation) error {
+	details := &errdetails.BadRequest{}
+	for _, v := range violations {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	return withDetails(status.New(codes.InvalidArgument, msg), details)
+}
+
+// withDetails attaches the details to st, they are dropped only if st is OK, which the
+// callers never pass
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) error {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// Info returns the ErrorInfo of err, nil when it carries none
+func Info(err error) *errdetails.ErrorInfo {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	return nil
+}
+
+// Reason returns the reason of the ErrorInfo of err if it is in domain, empty otherwise
+func Reason(err error, domain string) string {
+	info := Info(err)
+	if info == nil || info.Domain != domain {
+		return ""
+	}
+	return info.Reason
+}
+
+// Violations returns the field violations of the BadRequest of err
+func Violations(err error) []Violation {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil
+	}
+	var violations []Violation
+	for _, detail := range st.Details() {
+		if bad, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range bad.FieldViolations {
+				violations = append(violations, Violation{Field: v.Field, Description: v.Description})
+			}
+		}
+	}
+	return violations
+}
@@ -2,12 +2,15 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/service"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -16,6 +19,18 @@ import (
 type GenericHandler[T any] struct {
 	Service  service.Interface[T]
 	Verifier *auth.Verifier
+	// CacheMaxAge lets the browser reuse the reads of GetByIDHandler and GetAllHandler
+	// for that long, they aren't cached when it's zero
+	CacheMaxAge time.Duration
+	// StatsFields are the fields StatsHandler groups, aggregates and filters by, by the
+	// name used in the query. Fields of personal data are better left out.
+	StatsFields map[string]repository.Column[T]
+}
+
+// LastModifier is an entity telling when it was changed, GetByIDHandler sends it as
+// Last-Modified and answers a request with If-Modified-Since with Not Modified
+type LastModifier interface {
+	LastModified() time.Time
 }
 
 // NewGenericHandler creates a new GenericHandler with the provided service and verifier.
@@ -103,9 +118,13 @@ func (h *GenericHandler[T]) CreateHandler() http.HandlerFunc {
 			return
 		}
 
-		// Pass the claims to the service.
-		created, err := h.Service.Create(claims, &entity)
+		// Pass the claims to the service, the request context carries its trace and deadline.
+		created, err := h.Service.CreateContext(r.Context(), claims, &entity)
 		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+				return
+			}
 			http.Error(w, "Error creating entity: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -144,6 +163,10 @@ func (h *GenericHandler[T]) GetByIDHandler() http.HandlerFunc {
 			return
 		}
 
+		h.setCacheControl(w)
+		if modifier, ok := any(entity).(LastModifier); ok && notModified(w, r, modifier.LastModified()) {
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(entity)
 	}
@@ -166,8 +189,12 @@ func (h *GenericHandler[T]) UpdateHandler() http.HandlerFunc {
 		}
 
 		// Pass the claims to the service.
-		updated, err := h.Service.Update(claims, &entity)
+		updated, err := h.Service.UpdateContext(r.Context(), claims, &entity)
 		if err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+				return
+			}
 			http.Error(w, "Error updating entity: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -200,7 +227,11 @@ func (h *GenericHandler[T]) DeleteHandler() http.HandlerFunc {
 		}
 
 		// Pass the claims to the service.
-		if err := h.Service.Delete(claims, id); err != nil {
+		if err := h.Service.DeleteContext(r.Context(), claims, id); err != nil {
+			if errors.Is(err, auth.ErrForbidden) {
+				http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+				return
+			}
 			http.Error(w, "Error deleting entity: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -226,6 +257,8 @@ func (h *GenericHandler[T]) GetAllHandler() http.HandlerFunc {
 			return
 		}
 
+		// no Last-Modified, the newest change doesn't tell when an entity was deleted
+		h.setCacheControl(w)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(entities)
 	}
@@ -394,6 +427,8 @@ func (h *GenericHandler[T]) GetPageHandler() http.HandlerFunc {
 }
 
 // BulkInsertHandler handles HTTP POST requests to bulk insert multiple entities.
+// The entities that can't be inserted don't stop the others, the response reports
+// each of them and is Multi-Status when some failed.
 func (h *GenericHandler[T]) BulkInsertHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Verify the JWT token and get claims.
@@ -416,12 +451,18 @@ func (h *GenericHandler[T]) BulkInsertHandler() http.HandlerFunc {
 		}
 
 		// Pass the claims to the service.
-		if err := h.Service.BulkInsert(claims, entityPtrs); err != nil {
+		result, err := h.Service.InsertEach(claims, entityPtrs)
+		if err != nil {
 			http.Error(w, "Error bulk inserting entities: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		w.WriteHeader(http.StatusCreated)
-		w.Write([]byte("Bulk insert successful"))
+		w.Header().Set("Content-Type", "application/json")
+		if result.Failed > 0 {
+			w.WriteHeader(http.StatusMultiStatus)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(result)
 	}
 }
 
@@ -456,3 +497,27 @@ func (h *GenericHandler[T]) BulkUpdateHandler() http.HandlerFunc {
 		w.Write([]byte("Bulk update successful"))
 	}
 }
+
+// setCacheControl lets the response be reused for CacheMaxAge by the browser only,
+// it depends on the token of the user
+func (h *GenericHandler[T]) setCacheControl(w http.ResponseWriter) {
+	if h.CacheMaxAge > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(h.CacheMaxAge.Seconds())))
+	}
+}
+
+// notModified sets Last-Modified and answers Not Modified when the client has the
+// version of modified, HTTP dates have no fractions of a second
+func notModified(w http.ResponseWriter, r *http.Request, modified time.Time) bool {
+	if modified.IsZero() {
+		return false
+	}
+	modified = modified.Truncate(time.Second)
+	w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modified.After(since) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
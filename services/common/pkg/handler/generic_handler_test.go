@@ -2,6 +2,7 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -467,9 +468,47 @@ func TestBulkInsertHandlerIntegration(t *testing.T) {
 	if res.StatusCode != http.StatusCreated {
 		t.Fatalf("expected status 201, got %d", res.StatusCode)
 	}
-	body, _ := io.ReadAll(res.Body)
-	if string(body) != "Bulk insert successful" {
-		t.Errorf("unexpected response body: %s", string(body))
+	var result repository.BatchResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if result.Created != 2 || result.Failed != 0 || len(result.Items) != 2 {
+		t.Errorf("unexpected report: %+v", result)
+	}
+	var count int64
+	if err := db.Model(&TestEntity{}).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count entities: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 entities, got %d", count)
+	}
+}
+
+// TestBulkInsertHandlerPartialFailure tests that a failing entity is reported and doesn't stop the others.
+func TestBulkInsertHandlerPartialFailure(t *testing.T) {
+	h, db, priv := newTestHandler(t)
+
+	reqBody := `[{"id": 1, "name": "bulk1"}, {"id": 1, "name": "duplicate"}, {"name": "bulk3"}]`
+	req := httptest.NewRequest(http.MethodPost, "/bulkInsert", bytes.NewBufferString(reqBody))
+	token := generateValidToken(t, priv)
+	addValidCookie(req, token)
+	rec := httptest.NewRecorder()
+
+	h.BulkInsertHandler()(rec, req)
+	res := rec.Result()
+	if res.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("expected status 207, got %d", res.StatusCode)
+	}
+	var result repository.BatchResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if result.Created != 2 || result.Failed != 1 {
+		t.Fatalf("unexpected report: %+v", result)
+	}
+	failed := result.Items[1]
+	if failed.Index != 1 || failed.Status != repository.ItemFailed || failed.Error == "" {
+		t.Errorf("expected the duplicate to be reported failed, got %+v", failed)
 	}
 	var count int64
 	if err := db.Model(&TestEntity{}).Count(&count).Error; err != nil {
@@ -531,3 +570,139 @@ func TestMissingTokenIntegration(t *testing.T) {
 	}
 }
 
+
+// StampedEntity tells when it was changed.
+type StampedEntity struct {
+	ID        int       `gorm:"primaryKey" json:"id"`
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (e StampedEntity) LastModified() time.Time {
+	return e.UpdatedAt
+}
+
+// TestGetByIDHandlerNotModified tests the caching headers of GetByIDHandler.
+func TestGetByIDHandlerNotModified(t *testing.T) {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(&StampedEntity{}); err != nil {
+		t.Fatalf("failed to migrate StampedEntity: %v", err)
+	}
+	entity := StampedEntity{Name: "stamped"}
+	if err := db.Create(&entity).Error; err != nil {
+		t.Fatalf("failed to create entity: %v", err)
+	}
+	priv, _, pubKeyStr := generateTestKey(t)
+	verif, err := auth.NewVerifier(pubKeyStr)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	svc := service.NewGenericService[StampedEntity](repository.NewGenericRepository[StampedEntity](db))
+	h := handler.NewGenericHandler[StampedEntity](svc, verif)
+	h.CacheMaxAge = time.Minute
+	token := generateValidToken(t, priv)
+
+	get := func(ifModifiedSince string) *http.Response {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/get?id=%d", entity.ID), nil)
+		addValidCookie(req, token)
+		if ifModifiedSince != "" {
+			req.Header.Set("If-Modified-Since", ifModifiedSince)
+		}
+		rec := httptest.NewRecorder()
+		h.GetByIDHandler()(rec, req)
+		return rec.Result()
+	}
+
+	res := get("")
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+	if got := res.Header.Get("Cache-Control"); got != "private, max-age=60" {
+		t.Errorf("unexpected Cache-Control %q", got)
+	}
+	lastModified := res.Header.Get("Last-Modified")
+	if lastModified != entity.UpdatedAt.UTC().Format(http.TimeFormat) {
+		t.Errorf("unexpected Last-Modified %q", lastModified)
+	}
+
+	if res := get(lastModified); res.StatusCode != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", res.StatusCode)
+	}
+	earlier := entity.UpdatedAt.Add(-time.Hour).UTC().Format(http.TimeFormat)
+	if res := get(earlier); res.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200 for a changed entity, got %d", res.StatusCode)
+	}
+}
+
+// TestStatsHandlerIntegration tests the StatsHandler.
+func TestStatsHandlerIntegration(t *testing.T) {
+	h, db, priv := newTestHandler(t)
+	h.StatsFields = map[string]repository.Column[TestEntity]{
+		"name": repository.FieldOf[TestEntity, string]("Name"),
+		"id":   repository.FieldOf[TestEntity, int]("ID"),
+	}
+	for _, name := range []string{"a", "a", "b", "c", "c", "c"} {
+		if err := db.Create(&TestEntity{Name: name}).Error; err != nil {
+			t.Fatalf("failed to create entity: %v", err)
+		}
+	}
+	token := generateValidToken(t, priv)
+
+	req := httptest.NewRequest(http.MethodGet, "/stats?group_by=name&metrics=count,max:id&having=count>=2&name=!=a", nil)
+	addValidCookie(req, token)
+	rec := httptest.NewRecorder()
+	h.StatsHandler()(rec, req)
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		t.Fatalf("expected status 200, got %d: %s", res.StatusCode, body)
+	}
+	var rows []repository.StatsRow
+	if err := json.NewDecoder(res.Body).Decode(&rows); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Group["name"] != "c" || rows[0].Values["count"] != 3 || rows[0].Values["max_id"] != 6 {
+		t.Errorf("unexpected stats: %+v", rows)
+	}
+
+	for _, query := range []string{"group_by=secret", "metrics=sum:name", "having=count", "secret=1"} {
+		req := httptest.NewRequest(http.MethodGet, "/stats?"+query, nil)
+		addValidCookie(req, token)
+		rec := httptest.NewRecorder()
+		h.StatsHandler()(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+// ownerOnlyService refuses to delete the entities of others
+type ownerOnlyService struct {
+	*service.GenericService[TestEntity]
+}
+
+func (s ownerOnlyService) DeleteContext(ctx context.Context, claims jwt.MapClaims, id int) error {
+	return fmt.Errorf("%w: entity %d belongs to someone else", auth.ErrForbidden, id)
+}
+
+func TestDeleteHandlerForbidden(t *testing.T) {
+	h, db, priv := newTestHandler(t)
+	h.Service = ownerOnlyService{service.NewGenericService[TestEntity](repository.NewGenericRepository[TestEntity](db))}
+
+	entity := TestEntity{Name: "someone else's"}
+	if err := db.Create(&entity).Error; err != nil {
+		t.Fatalf("failed to create entity: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/delete?id=%d", entity.ID), nil)
+	addValidCookie(req, generateValidToken(t, priv))
+	rec := httptest.NewRecorder()
+
+	h.DeleteHandler()(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rec.Code)
+	}
+	if err := db.First(&TestEntity{}, entity.ID).Error; err != nil {
+		t.Errorf("entity was deleted: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+)
+
+// statsParams are the query parameters of StatsHandler that aren't filters
+var statsParams = []string{"group_by", "metrics", "having"}
+
+// StatsHandler handles HTTP GET requests aggregating the entities, e.g.
+//
+//	?group_by=category,start_time:month&metrics=count,avg:participants&having=count>=5&status=active
+//
+// group_by takes fields of StatsFields, time ones optionally truncated to an hour,
+// day, week, month or year. metrics takes count and count, sum, avg, min or max of a
+// field, count alone by default. having filters the groups by a metric. The other
+// parameters filter the entities like FindHandler, by fields of StatsFields only.
+func (h *GenericHandler[T]) StatsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Verify the JWT token and get claims.
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		stats, err := h.parseStats(r.URL.Query())
+		if err != nil {
+			http.Error(w, "Invalid query parameters: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rows, err := h.Service.Aggregate(claims, stats)
+		if errors.Is(err, repository.ErrInvalidStats) {
+			http.Error(w, "Invalid query parameters: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error aggregating entities: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rows)
+	}
+}
+
+func (h *GenericHandler[T]) parseStats(q url.Values) (repository.Stats[T], error) {
+	var stats repository.Stats[T]
+	field := func(name string) (repository.Column[T], error) {
+		column, ok := h.StatsFields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		return column, nil
+	}
+
+	for _, item := range listParam(q, "group_by") {
+		name, unit, _ := strings.Cut(item, ":")
+		column, err := field(name)
+		if err != nil {
+			return stats, err
+		}
+		group := repository.GroupBy(column)
+		if unit != "" {
+			group = group.Truncate(repository.TimeUnit(unit))
+		}
+		stats.GroupBy = append(stats.GroupBy, group)
+	}
+
+	metric := func(item string) (repository.Measure[T], error) {
+		fn, name, _ := strings.Cut(item, ":")
+		if name == "" {
+			if fn != string(repository.Count) {
+				return repository.Measure[T]{}, fmt.Errorf("metric %q needs a field", item)
+			}
+			return repository.CountOf[T](), nil
+		}
+		column, err := field(name)
+		if err != nil {
+			return repository.Measure[T]{}, err
+		}
+		return repository.MeasureOf(repository.AggregateFunc(fn), column), nil
+	}
+	for _, item := range listParam(q, "metrics") {
+		measure, err := metric(item)
+		if err != nil {
+			return stats, err
+		}
+		stats.Measures = append(stats.Measures, measure)
+	}
+
+	for _, item := range listParam(q, "having") {
+		i := strings.IndexAny(item, "<>=!")
+		if i <= 0 {
+			return stats, fmt.Errorf("having %q has no operator", item)
+		}
+		op, value := splitOperator(item[i:])
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return stats, fmt.Errorf("having %q doesn't compare to a number", item)
+		}
+		measure, err := metric(item[:i])
+		if err != nil {
+			return stats, err
+		}
+		stats.Having = append(stats.Having, measure.Having(op, number))
+	}
+
+	var filters []repository.Spec[T]
+	for key, values := range q {
+		if isStatsParam(key) {
+			continue
+		}
+		column, err := field(key)
+		if err != nil {
+			return stats, err
+		}
+		for _, value := range values {
+			op, value := splitOperator(value)
+			filters = append(filters, repository.Raw[T](fmt.Sprintf("%s %s ?", column.Column(), op), value))
+		}
+	}
+	stats.Where = repository.Where(filters...)
+	return stats, nil
+}
+
+// splitOperator splits the comparison operator leading value off like
+// ParseQueryCondition, = when there's none
+func splitOperator(value string) (string, string) {
+	for _, op := range []string{"<=", ">=", "!=", "<", ">", "="} {
+		if strings.HasPrefix(value, op) {
+			return op, value[len(op):]
+		}
+	}
+	return "=", value
+}
+
+// listParam returns the comma separated items of the parameter
+func listParam(q url.Values, key string) []string {
+	var items []string
+	for _, value := range q[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+func isStatsParam(key string) bool {
+	for _, param := range statsParams {
+		if key == param {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// GRPCServer is the standard grpc.health.v1 service reporting the readiness of a Checker
+// for the whole server and for each of its services.
+type GRPCServer struct {
+	server   *grpchealth.Server
+	checker  *Checker
+	services []string
+}
+
+// NewGRPCServer creates the health service for the given gRPC service names.
+// Until Run updates it, everything is reported as serving.
+func NewGRPCServer(checker *Checker, services ...string) *GRPCServer {
+	return &GRPCServer{
+		server:   grpchealth.NewServer(),
+		checker:  checker,
+		services: services,
+	}
+}
+
+// Register adds the health service to the gRPC server.
+func (s *GRPCServer) Register(gRPC *grpc.Server) {
+	healthpb.RegisterHealthServer(gRPC, s.server)
+}
+
+// Run updates the statuses from the checker every interval until ctx is done.
+func (s *GRPCServer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.update(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown reports every service as not serving, any later updates are ignored.
+func (s *GRPCServer) Shutdown() {
+	s.server.Shutdown()
+}
+
+func (s *GRPCServer) update(ctx context.Context) {
+	status := healthpb.HealthCheckResponse_SERVING
+	if _, ready := s.checker.Ready(ctx); !ready {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	s.server.SetServingStatus("", status)
+	for _, service := range s.services {
+		s.server.SetServingStatus(service, status)
+	}
+}
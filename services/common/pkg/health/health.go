@@ -0,0 +1,179 @@
+// Package health runs dependency checks of a service and serves them as
+// liveness and readiness endpoints over HTTP and grpc.health.v1.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports whether a dependency is usable
+type Check func(ctx context.Context) error
+
+// Statuses of checks and of the service
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDegraded = "degraded"
+	// StatusShuttingDown is reported once Shutdown was called
+	StatusShuttingDown = "shutting_down"
+)
+
+// Result is the outcome of a single check
+type Result struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the outcome of all checks
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+type namedCheck struct {
+	name     string
+	check    Check
+	optional bool
+}
+
+// Checker holds the checks of a service. A service is ready when all required
+// checks pass and Shutdown was not called; failing optional checks only degrade it.
+type Checker struct {
+	mu           sync.RWMutex
+	checks       []namedCheck
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// New creates a Checker running every check with the timeout.
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a required check.
+func (c *Checker) Add(name string, check Check) *Checker {
+	return c.add(namedCheck{name: name, check: check})
+}
+
+// AddOptional registers a check whose failure is reported but doesn't make the service unready,
+// e.g. an upstream service that only some requests need.
+func (c *Checker) AddOptional(name string, check Check) *Checker {
+	return c.add(namedCheck{name: name, check: check, optional: true})
+}
+
+func (c *Checker) add(check namedCheck) *Checker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check)
+	return c
+}
+
+// Shutdown makes the service unready, so load balancers stop sending new requests
+// while the in-flight ones are drained.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// Ready runs all checks concurrently and reports whether the service can serve requests.
+func (c *Checker) Ready(ctx context.Context) (Report, bool) {
+	if c.shuttingDown.Load() {
+		return Report{Status: StatusShuttingDown}, false
+	}
+
+	c.mu.RLock()
+	checks := append([]namedCheck(nil), c.checks...)
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+	ready := true
+	for i, check := range checks {
+		report.Checks[check.name] = results[i]
+		if results[i].Status == StatusUp {
+			continue
+		}
+		if check.optional {
+			if ready {
+				report.Status = StatusDegraded
+			}
+			continue
+		}
+		ready = false
+		report.Status = StatusDown
+	}
+	return report, ready
+}
+
+func (c *Checker) run(ctx context.Context, check namedCheck) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.check(ctx)
+	result := Result{
+		Status:   StatusUp,
+		Optional: check.optional,
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler serves /healthz. It only tells that the process is able to
+// handle requests, dependencies are not checked, so a restart can't fix their failures.
+func (c *Checker) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(Report{Status: StatusUp})
+	}
+}
+
+// ReadinessHandler serves /readyz with the report of all checks,
+// responding 503 when the service is not ready.
+func (c *Checker) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, ready := c.Ready(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// Probe requests url and returns the exit code for a container health check:
+// 0 if it responded 200 and 1 otherwise. It lets images without curl check themselves.
+func Probe(url string) int {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return 1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 1
+	}
+	return 0
+}
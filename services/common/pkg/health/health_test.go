@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func up(context.Context) error   { return nil }
+func down(context.Context) error { return errors.New("connection refused") }
+
+// TestReady_FailingOptionalCheckDegrades проверяет, что необязательная проверка не делает сервис неготовым
+func TestReady_FailingOptionalCheckDegrades(t *testing.T) {
+	checker := New(time.Second).Add("postgres", up).AddOptional("event-service", down)
+
+	report, ready := checker.Ready(context.Background())
+	assert.True(t, ready)
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, StatusDown, report.Checks["event-service"].Status)
+	assert.Equal(t, "connection refused", report.Checks["event-service"].Error)
+
+	checker.Add("kafka", down)
+	report, ready = checker.Ready(context.Background())
+	assert.False(t, ready)
+	assert.Equal(t, StatusDown, report.Status)
+}
+
+// TestReady_Timeout проверяет, что зависшая проверка обрывается по таймауту
+func TestReady_Timeout(t *testing.T) {
+	checker := New(20*time.Millisecond).Add("postgres", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	_, ready := checker.Ready(context.Background())
+	assert.False(t, ready)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// TestHandlers_Shutdown проверяет, что после Shutdown /readyz отвечает 503, а /healthz — 200
+func TestHandlers_Shutdown(t *testing.T) {
+	checker := New(time.Second).Add("postgres", up)
+
+	rec := httptest.NewRecorder()
+	checker.ReadinessHandler()(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	checker.Shutdown()
+
+	rec = httptest.NewRecorder()
+	checker.ReadinessHandler()(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var report Report
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	assert.Equal(t, StatusShuttingDown, report.Status)
+
+	rec = httptest.NewRecorder()
+	checker.LivenessHandler()(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+// TestGRPC проверяет grpc.health.v1 сервер вместе с проверкой GRPC на клиенте
+func TestGRPC(t *testing.T) {
+	var failing bool
+	checker := New(time.Second).Add("postgres", func(context.Context) error {
+		if failing {
+			return errors.New("down")
+		}
+		return nil
+	})
+	healthServer := NewGRPCServer(checker, "events.EventService")
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	healthServer.Register(server)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	check := GRPC(conn, "events.EventService")
+
+	ctx := context.Background()
+	healthServer.update(ctx)
+	assert.NoError(t, check(ctx))
+
+	failing = true
+	healthServer.update(ctx)
+	assert.ErrorContains(t, check(ctx), healthpb.HealthCheckResponse_NOT_SERVING.String())
+
+	failing = false
+	healthServer.Shutdown()
+	healthServer.update(ctx)
+	assert.Error(t, check(ctx))
+}
@@ -0,0 +1,340 @@
+// Package ical renders events as iCalendar (RFC 5545) documents.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	_ "time/tzdata" // services run from scratch images without a zoneinfo database
+	"unicode/utf8"
+)
+
+// ContentType is the media type of iCalendar documents
+const ContentType = "text/calendar; charset=utf-8"
+
+const (
+	prodID       = "-//Event Planner//Events//RU"
+	utcLayout    = "20060102T150405Z"
+	localLayout  = "20060102T150405"
+	maxLineBytes = 75
+)
+
+// Event statuses
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// EventUID returns the UID of an event. Every service must use it, so that the
+// same event imported from a single .ics and from a feed is not duplicated.
+func EventUID(eventID uint, publicURL string) string {
+	host := "event-planner"
+	if u, err := url.Parse(publicURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return fmt.Sprintf("event-%d@%s", eventID, host)
+}
+
+// EventStatus maps the status of an event to the iCalendar one.
+func EventStatus(status string) string {
+	if strings.EqualFold(status, "cancelled") {
+		return StatusCancelled
+	}
+	return StatusConfirmed
+}
+
+// JoinLocation joins non-empty parts of an address into a LOCATION value.
+func JoinLocation(parts ...string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, ", ")
+}
+
+// Event is a single VEVENT.
+type Event struct {
+	// UID must stay the same for every version of the event,
+	// while Sequence grows on each change visible to attendees.
+	UID          string
+	Sequence     int
+	Summary      string
+	Description  string
+	Location     string
+	Categories   []string
+	Latitude     float64
+	Longitude    float64
+	Start        time.Time
+	End          time.Time
+	Status       string
+	Created      time.Time
+	LastModified time.Time
+	URL          string
+}
+
+// Calendar is a VCALENDAR with its events. Start and End of the events are written
+// in their own location with a matching VTIMEZONE; UTC times are written as is.
+type Calendar struct {
+	Name            string
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Write renders the calendar to w.
+func (c *Calendar) Write(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	out := &writer{w: b}
+
+	out.line("BEGIN:VCALENDAR")
+	out.line("VERSION:2.0")
+	out.line("PRODID:" + prodID)
+	out.line("CALSCALE:GREGORIAN")
+	out.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		out.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+	if c.RefreshInterval > 0 {
+		out.line("REFRESH-INTERVAL;VALUE=DURATION:" + duration(c.RefreshInterval))
+		out.line("X-PUBLISHED-TTL:" + duration(c.RefreshInterval))
+	}
+
+	for _, tz := range zones(c.Events) {
+		tz.write(out)
+	}
+
+	stamp := time.Now().UTC().Format(utcLayout)
+	for _, e := range c.Events {
+		out.line("BEGIN:VEVENT")
+		out.line("UID:" + escape(e.UID))
+		out.line("DTSTAMP:" + stamp)
+		// a cancelled event nothing is known about has no times, METHOD makes them optional
+		if !e.Start.IsZero() {
+			out.line(dateTime("DTSTART", e.Start))
+		}
+		if !e.End.IsZero() {
+			out.line(dateTime("DTEND", e.End))
+		}
+		out.line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+		out.line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			out.line("DESCRIPTION:" + escape(e.Description))
+		}
+		if e.Location != "" {
+			out.line("LOCATION:" + escape(e.Location))
+		}
+		if len(e.Categories) > 0 {
+			escaped := make([]string, len(e.Categories))
+			for i, category := range e.Categories {
+				escaped[i] = escape(category)
+			}
+			out.line("CATEGORIES:" + strings.Join(escaped, ","))
+		}
+		if e.Latitude != 0 || e.Longitude != 0 {
+			out.line(fmt.Sprintf("GEO:%f;%f", e.Latitude, e.Longitude))
+		}
+		if e.URL != "" {
+			out.line("URL:" + e.URL)
+		}
+		status := e.Status
+		if status == "" {
+			status = StatusConfirmed
+		}
+		out.line("STATUS:" + status)
+		if !e.Created.IsZero() {
+			out.line("CREATED:" + e.Created.UTC().Format(utcLayout))
+		}
+		if !e.LastModified.IsZero() {
+			out.line("LAST-MODIFIED:" + e.LastModified.UTC().Format(utcLayout))
+		}
+		out.line("END:VEVENT")
+	}
+
+	out.line("END:VCALENDAR")
+	if out.err != nil {
+		return out.err
+	}
+	return b.Flush()
+}
+
+func dateTime(name string, t time.Time) string {
+	if t.Location() == time.UTC {
+		return name + ":" + t.Format(utcLayout)
+	}
+	return name + ";TZID=" + t.Location().String() + ":" + t.Format(localLayout)
+}
+
+// duration formats d as an RFC 5545 duration, e.g. PT1H30M
+func duration(d time.Duration) string {
+	d = d.Round(time.Second)
+	var sb strings.Builder
+	sb.WriteString("PT")
+	if h := d / time.Hour; h > 0 {
+		fmt.Fprintf(&sb, "%dH", h)
+		d -= h * time.Hour
+	}
+	if m := d / time.Minute; m > 0 {
+		fmt.Fprintf(&sb, "%dM", m)
+		d -= m * time.Minute
+	}
+	if s := d / time.Second; s > 0 || sb.Len() == 2 {
+		fmt.Fprintf(&sb, "%dS", s)
+	}
+	return sb.String()
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escape escapes a TEXT value
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// writer writes content lines folded at 75 octets, as required by RFC 5545.
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (w *writer) line(s string) {
+	if w.err != nil {
+		return
+	}
+	limit := maxLineBytes
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if _, w.err = w.w.WriteString(s[:cut] + "\r\n "); w.err != nil {
+			return
+		}
+		s = s[cut:]
+		// continuation lines start with a space, which counts toward the limit
+		limit = maxLineBytes - 1
+	}
+	_, w.err = w.w.WriteString(s + "\r\n")
+}
+
+// zone collects the span of event times in a single location.
+type zone struct {
+	loc      *time.Location
+	from, to time.Time
+}
+
+func zones(events []Event) []zone {
+	byName := make(map[string]*zone)
+	add := func(t time.Time) {
+		if t.IsZero() || t.Location() == time.UTC {
+			return
+		}
+		name := t.Location().String()
+		z, ok := byName[name]
+		if !ok {
+			byName[name] = &zone{loc: t.Location(), from: t, to: t}
+			return
+		}
+		if t.Before(z.from) {
+			z.from = t
+		}
+		if t.After(z.to) {
+			z.to = t
+		}
+	}
+	for _, e := range events {
+		add(e.Start)
+		add(e.End)
+	}
+
+	result := make([]zone, 0, len(byName))
+	for _, z := range byName {
+		result = append(result, *z)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].loc.String() < result[j].loc.String() })
+	return result
+}
+
+// transition is a change of the UTC offset of a location.
+type transition struct {
+	at         time.Time
+	offsetFrom int
+	offsetTo   int
+	name       string
+	dst        bool
+}
+
+// write renders a VTIMEZONE with every observance in effect between a year
+// before the first event and a year after the last one.
+func (z zone) write(out *writer) {
+	from := z.from.AddDate(-1, 0, 0)
+	to := z.to.AddDate(1, 0, 0)
+
+	out.line("BEGIN:VTIMEZONE")
+	out.line("TZID:" + z.loc.String())
+
+	start := from.In(z.loc)
+	name, offset := start.Zone()
+	observance(out, transition{at: start, offsetFrom: offset, offsetTo: offset, name: name, dst: start.IsDST()})
+	for _, t := range transitions(z.loc, from, to) {
+		observance(out, t)
+	}
+
+	out.line("END:VTIMEZONE")
+}
+
+func observance(out *writer, t transition) {
+	kind := "STANDARD"
+	if t.dst {
+		kind = "DAYLIGHT"
+	}
+	out.line("BEGIN:" + kind)
+	// DTSTART of an observance is the local time before the change
+	out.line("DTSTART:" + t.at.In(time.FixedZone("", t.offsetFrom)).Format(localLayout))
+	out.line("TZOFFSETFROM:" + utcOffset(t.offsetFrom))
+	out.line("TZOFFSETTO:" + utcOffset(t.offsetTo))
+	out.line("TZNAME:" + escape(t.name))
+	out.line("END:" + kind)
+}
+
+// transitions finds offset changes of loc in [from, to) by scanning day by day
+// and narrowing every change down to the second.
+func transitions(loc *time.Location, from, to time.Time) []transition {
+	var result []transition
+	from = from.Truncate(time.Second)
+	_, offset := from.In(loc).Zone()
+	for day := from; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		if _, nextOffset := next.In(loc).Zone(); nextOffset == offset {
+			continue
+		}
+		lo, hi := day, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add((hi.Sub(lo) / 2).Truncate(time.Second))
+			if _, o := mid.In(loc).Zone(); o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		at := hi.In(loc)
+		name, newOffset := at.Zone()
+		result = append(result, transition{at: at, offsetFrom: offset, offsetTo: newOffset, name: name, dst: at.IsDST()})
+		offset = newOffset
+	}
+	return result
+}
+
+// utcOffset formats seconds east of UTC as +hhmm
+func utcOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
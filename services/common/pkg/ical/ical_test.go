@@ -0,0 +1,93 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func render(t *testing.T, c *Calendar) string {
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	return buf.String()
+}
+
+// unfold joins folded content lines back
+func unfold(s string) []string {
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(s, "\r\n ", ""), "\r\n"), "\r\n")
+}
+
+func TestWrite_EventInLocalTimeZone(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Novosibirsk")
+	require.NoError(t, err)
+	start := time.Date(2025, 5, 15, 17, 0, 0, 0, loc)
+
+	out := render(t, &Calendar{
+		Name:            "Мои события",
+		RefreshInterval: time.Hour,
+		Events: []Event{{
+			UID:         EventUID(7, "https://events-planner.ru"),
+			Sequence:    3,
+			Summary:     "Баскетбол; вечер, улица",
+			Description: "Играем баскет\nна улице",
+			Location:    "Карла Маркса 37, Новосибирск",
+			Latitude:    54.989688,
+			Longitude:   82.902014,
+			Start:       start,
+			End:         start.Add(6 * time.Hour),
+			Status:      EventStatus("cancelled"),
+		}},
+	})
+	lines := unfold(out)
+
+	assert.Contains(t, lines, "UID:event-7@events-planner.ru")
+	assert.Contains(t, lines, "SEQUENCE:3")
+	assert.Contains(t, lines, "DTSTART;TZID=Asia/Novosibirsk:20250515T170000")
+	assert.Contains(t, lines, "DTEND;TZID=Asia/Novosibirsk:20250515T230000")
+	assert.Contains(t, lines, `SUMMARY:Баскетбол\; вечер\, улица`)
+	assert.Contains(t, lines, `DESCRIPTION:Играем баскет\nна улице`)
+	assert.Contains(t, lines, "GEO:54.989688;82.902014")
+	assert.Contains(t, lines, "STATUS:CANCELLED")
+	assert.Contains(t, lines, "REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	assert.Contains(t, lines, "TZID:Asia/Novosibirsk")
+	// no daylight saving time in Novosibirsk
+	assert.NotContains(t, out, "BEGIN:DAYLIGHT")
+	assert.Contains(t, lines, "TZOFFSETTO:+0700")
+}
+
+func TestWrite_DaylightSavingTransitions(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	start := time.Date(2025, 7, 1, 18, 0, 0, 0, loc)
+
+	out := render(t, &Calendar{Events: []Event{{UID: "1", Summary: "x", Start: start, End: start.Add(time.Hour)}}})
+	lines := unfold(out)
+
+	assert.Equal(t, 1, strings.Count(out, "BEGIN:VTIMEZONE"))
+	// spring forward on the last Sunday of March at 02:00 local time
+	assert.Contains(t, lines, "DTSTART:20250330T020000")
+	assert.Contains(t, lines, "TZOFFSETFROM:+0100")
+	assert.Contains(t, lines, "TZOFFSETTO:+0200")
+	assert.Contains(t, lines, "TZNAME:CEST")
+	// fall back on the last Sunday of October at 03:00 local time
+	assert.Contains(t, lines, "DTSTART:20251026T030000")
+	assert.Contains(t, out, "BEGIN:DAYLIGHT")
+}
+
+func TestWrite_UTCAndFolding(t *testing.T) {
+	start := time.Date(2025, 5, 15, 10, 0, 0, 0, time.UTC)
+	description := strings.Repeat("Очень длинное описание события. ", 10)
+
+	out := render(t, &Calendar{Events: []Event{{UID: "1", Summary: "x", Description: description, Start: start, End: start}}})
+
+	assert.NotContains(t, out, "VTIMEZONE")
+	assert.Contains(t, unfold(out), "DTSTART:20250515T100000Z")
+	assert.Contains(t, unfold(out), "DESCRIPTION:"+description)
+	for _, line := range strings.Split(out, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+	}
+}
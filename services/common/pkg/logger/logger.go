@@ -5,16 +5,25 @@ import (
 	"os"
 )
 
+// level is shared by loggers from SetupLogger, so SetLevel changes it at runtime
+var level = new(slog.LevelVar)
+
 // SetupLogger get env var and configure returns configured loggers that depends on env var
 func SetupLogger(env string) *slog.Logger {
     var log *slog.Logger
     switch env {
     case "local":
+        level.Set(slog.LevelDebug)
         log = setupPrettySlog()
     case "dev":
-        log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+        level.Set(slog.LevelDebug)
+        log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
     case "prod":
-        log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+        level.Set(slog.LevelInfo)
+        log = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+    }
+    if log != nil {
+        log = slog.New(WithTrace(log.Handler()))
     }
     return log
 }
@@ -22,9 +31,23 @@ func SetupLogger(env string) *slog.Logger {
 func setupPrettySlog() *slog.Logger {
     opts := PrettyHandlerOptions{
         SlogOpts: &slog.HandlerOptions{
-            Level: slog.LevelDebug,
+            Level: level,
         },
     }
     handler := opts.NewPrettyHandler(os.Stdout)
     return slog.New(handler)
 }
+
+// SetLevel sets the level of loggers from SetupLogger by name: debug, info, warn or error.
+// An empty name keeps the current level.
+func SetLevel(name string) error {
+    if name == "" {
+        return nil
+    }
+    var l slog.Level
+    if err := l.UnmarshalText([]byte(name)); err != nil {
+        return err
+    }
+    level.Set(l)
+    return nil
+}
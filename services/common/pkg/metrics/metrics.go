@@ -0,0 +1,64 @@
+// Package metrics exposes Prometheus metrics shared by all services:
+// HTTP and gRPC RED metrics, database metrics and business counters.
+// Everything is registered in the default registry next to the Go runtime metrics.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes the business metrics
+const Namespace = "event_planner"
+
+// Results of reservations and logins
+const (
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+	ResultFull      = "full"
+)
+
+var (
+	// EventsCreated counts created events
+	EventsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "events_created_total",
+		Help:      "Number of created events.",
+	})
+
+	// Reservations counts attempts to reserve a place at an event by result:
+	// succeeded, full or failed
+	Reservations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "reservations_total",
+		Help:      "Number of attempts to reserve a place at an event by result.",
+	}, []string{"result"})
+
+	// Logins counts login attempts by result: succeeded or failed
+	Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "logins_total",
+		Help:      "Number of login attempts by result.",
+	}, []string{"result"})
+)
+
+// Handler serves the metrics of the default registry.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve exposes the metrics on addr at /metrics. It blocks until the server fails.
+func Serve(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return srv.ListenAndServe()
+}
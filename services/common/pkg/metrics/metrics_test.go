@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMiddleware_LabelsRoutePattern(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/api/v1/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	for _, path := range []string{"/api/v1/items/1", "/api/v1/items/2", "/nope"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(httpRequests.WithLabelValues("/api/v1/items/{id}", "GET", "418")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues(unmatchedRoute, "GET", "404")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(httpInFlight))
+}
+
+func TestMiddleware_LabelsOtherMethods(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, method := range []string{"PROPFIND", "GARBAGE1", "GARBAGE2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/nope", nil))
+	}
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(httpRequests.WithLabelValues(unmatchedRoute, otherMethod, "405")))
+}
+
+func TestUnaryServerInterceptor_RecordsCode(t *testing.T) {
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.TestService/Do"}
+
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "missing")
+	})
+	require.Error(t, err)
+	_, err = interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(grpcServerHandled.WithLabelValues("test.TestService", "Do", "NotFound")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(grpcServerHandled.WithLabelValues("test.TestService", "Do", "OK")))
+}
+
+func TestSplitMethod(t *testing.T) {
+	service, method := splitMethod("/events.EventService/GetEvent")
+	assert.Equal(t, "events.EventService", service)
+	assert.Equal(t, "GetEvent", method)
+}
+
+type metricsModel struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+func TestInstrumentDB(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&metricsModel{}))
+	require.NoError(t, InstrumentDB(db, "testdb"))
+
+	require.NoError(t, db.Create(&metricsModel{Name: "a"}).Error)
+	var found metricsModel
+	err = db.First(&found, 100).Error
+	require.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	require.Error(t, db.Exec("SELECT * FROM missing_table").Error)
+
+	// create, query and raw
+	assert.Equal(t, 3, testutil.CollectAndCount(dbQueryDuration))
+	assert.Equal(t, 0.0, testutil.ToFloat64(dbQueryErrors.WithLabelValues("testdb", "query", "metrics_models")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(dbQueryErrors.WithLabelValues("testdb", "raw", "")))
+
+	// The pool collector is registered once per database name
+	assert.Error(t, InstrumentDB(db, "testdb"))
+}
@@ -0,0 +1,123 @@
+package mtls
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/mtls/devca"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const checkMethod = "/grpc.health.v1.Health/Check"
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func writeCerts(t *testing.T, ca *devca.CA, dir string, names ...string) {
+	for _, name := range names {
+		require.NoError(t, ca.WriteFiles(dir, name, "localhost"))
+	}
+}
+
+func reloader(t *testing.T, dir, name string) *Reloader {
+	r, err := NewReloader(discard, filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key"), filepath.Join(dir, "ca.crt"))
+	require.NoError(t, err)
+	return r
+}
+
+func serve(t *testing.T, certs *Reloader, policy Policy) string {
+	srv := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(certs.ServerConfig())),
+		grpc.ChainUnaryInterceptor(UnaryServerInterceptor(policy)),
+	)
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(l)
+	t.Cleanup(srv.Stop)
+	return l.Addr().String()
+}
+
+func check(t *testing.T, addr string, certs *Reloader) error {
+	conn, err := grpc.NewClient("passthrough:///"+addr,
+		grpc.WithTransportCredentials(credentials.NewTLS(certs.ClientConfig("event-service"))))
+	require.NoError(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err = grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	return err
+}
+
+func TestPolicy_AllowsOnlyListedPeers(t *testing.T) {
+	ca, err := devca.New()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	writeCerts(t, ca, dir, "event-service", "registration-service", "chat-service")
+
+	policy := Policy{}
+	policy.Allow(checkMethod, "registration-service")
+	addr := serve(t, reloader(t, dir, "event-service"), policy)
+
+	assert.NoError(t, check(t, addr, reloader(t, dir, "registration-service")))
+	assert.Equal(t, codes.PermissionDenied, status.Code(check(t, addr, reloader(t, dir, "chat-service"))))
+}
+
+func TestReloader_RejectsForeignCAUntilRotated(t *testing.T) {
+	serverCA, err := devca.New()
+	require.NoError(t, err)
+	otherCA, err := devca.New()
+	require.NoError(t, err)
+
+	serverDir, clientDir := t.TempDir(), t.TempDir()
+	writeCerts(t, serverCA, serverDir, "event-service")
+	writeCerts(t, otherCA, clientDir, "registration-service")
+	serverCerts := reloader(t, serverDir, "event-service")
+	addr := serve(t, serverCerts, Policy{})
+
+	// the client neither trusts the server nor is trusted by it
+	assert.Equal(t, codes.Unavailable, status.Code(check(t, addr, reloader(t, clientDir, "registration-service"))))
+
+	reloaded, err := serverCerts.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded, "unchanged files should not be loaded again")
+
+	// rotate everything onto the other CA, the server picks it up without a restart
+	writeCerts(t, otherCA, serverDir, "event-service")
+	later := time.Now().Add(time.Second)
+	for _, name := range []string{"ca.crt", "event-service.crt", "event-service.key"} {
+		require.NoError(t, os.Chtimes(filepath.Join(serverDir, name), later, later))
+	}
+	reloaded, err = serverCerts.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+
+	assert.NoError(t, check(t, addr, reloader(t, clientDir, "registration-service")))
+}
+
+func TestReloader_KeepsCertificatesOnBrokenRotation(t *testing.T) {
+	ca, err := devca.New()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	writeCerts(t, ca, dir, "event-service", "registration-service")
+	serverCerts := reloader(t, dir, "event-service")
+	addr := serve(t, serverCerts, Policy{})
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "event-service.key"), []byte("half written"), 0o600))
+	_, err = serverCerts.Reload()
+	assert.Error(t, err)
+
+	assert.NoError(t, check(t, addr, reloader(t, dir, "registration-service")))
+}
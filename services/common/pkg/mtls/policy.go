@@ -0,0 +1,78 @@
+package mtls
+
+import (
+	"context"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// PeerNames returns the DNS and URI names of the verified client certificate of the call,
+// nil when the call didn't come over mutual TLS.
+func PeerNames(ctx context.Context) []string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	leaf := info.State.VerifiedChains[0][0]
+	names := append([]string(nil), leaf.DNSNames...)
+	for _, uri := range leaf.URIs {
+		names = append(names, uri.String())
+	}
+	return names
+}
+
+// Policy maps full gRPC method names, e.g. events.EventService_CheckAndReserve_FullMethodName,
+// to the names of the peers allowed to call them. Methods not in the policy are open to
+// every peer with a certificate signed by the CA.
+type Policy map[string][]string
+
+// Allow lets the peers call the method
+func (p Policy) Allow(method string, peers ...string) {
+	p[method] = append(p[method], peers...)
+}
+
+func (p Policy) authorize(ctx context.Context, method string) error {
+	allowed, ok := p[method]
+	if !ok {
+		return nil
+	}
+	names := PeerNames(ctx)
+	for _, name := range names {
+		if slices.Contains(allowed, name) {
+			return nil
+		}
+	}
+	if len(names) == 0 {
+		return status.Errorf(codes.Unauthenticated, "%s requires a client certificate", method)
+	}
+	return status.Errorf(codes.PermissionDenied, "%v is not allowed to call %s", names, method)
+}
+
+// UnaryServerInterceptor rejects calls from peers the policy doesn't allow
+func UnaryServerInterceptor(p Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := p.authorize(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streams
+func StreamServerInterceptor(p Policy) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := p.authorize(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
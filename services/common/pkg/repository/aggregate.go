@@ -0,0 +1,335 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// TimeUnit is what a time field is truncated to when grouping by it
+type TimeUnit string
+
+const (
+	Hour  TimeUnit = "hour"
+	Day   TimeUnit = "day"
+	Week  TimeUnit = "week"
+	Month TimeUnit = "month"
+	Year  TimeUnit = "year"
+)
+
+var timeUnits = map[TimeUnit]bool{Hour: true, Day: true, Week: true, Month: true, Year: true}
+
+// sqliteTruncations are the strftime formats truncating a time, weeks start on Monday
+var sqliteTruncations = map[TimeUnit]string{
+	Hour:  "strftime('%%Y-%%m-%%d %%H:00:00', %s)",
+	Day:   "strftime('%%Y-%%m-%%d 00:00:00', %s)",
+	Week:  "strftime('%%Y-%%m-%%d 00:00:00', %s, 'weekday 0', '-6 days')",
+	Month: "strftime('%%Y-%%m-01 00:00:00', %s)",
+	Year:  "strftime('%%Y-01-01 00:00:00', %s)",
+}
+
+// Group is a field the entities are grouped by
+type Group[T any] struct {
+	column string
+	unit   TimeUnit
+}
+
+// GroupBy groups the entities by the values of column
+func GroupBy[T any](column Column[T]) Group[T] {
+	return Group[T]{column: column.Column()}
+}
+
+// Truncate groups the entities by the unit of time of the field instead, e.g. the
+// month of the start time. The field must be a time.
+func (g Group[T]) Truncate(unit TimeUnit) Group[T] {
+	g.unit = unit
+	return g
+}
+
+// Name is the key of the group in the rows of the result: the column, followed by
+// the unit when truncated, e.g. start_time_month
+func (g Group[T]) Name() string {
+	if g.unit != "" {
+		return g.column + "_" + string(g.unit)
+	}
+	return g.column
+}
+
+// AggregateFunc is an aggregate function of a measure
+type AggregateFunc string
+
+const (
+	Count AggregateFunc = "count"
+	Sum   AggregateFunc = "sum"
+	Avg   AggregateFunc = "avg"
+	Min   AggregateFunc = "min"
+	Max   AggregateFunc = "max"
+)
+
+// Measure is a value computed over the entities of a group
+type Measure[T any] struct {
+	fn     AggregateFunc
+	column string
+}
+
+// CountOf counts the entities of the group
+func CountOf[T any]() Measure[T] {
+	return Measure[T]{fn: Count}
+}
+
+// MeasureOf applies fn to the values of column, a number field. Count counts the
+// entities whose column isn't null.
+func MeasureOf[T any](fn AggregateFunc, column Column[T]) Measure[T] {
+	return Measure[T]{fn: fn, column: column.Column()}
+}
+
+// Name is the key of the measure in the rows of the result: count for CountOf, the
+// function followed by the column otherwise, e.g. sum_participants
+func (m Measure[T]) Name() string {
+	if m.column == "" {
+		return string(m.fn)
+	}
+	return string(m.fn) + "_" + m.column
+}
+
+// Having keeps the groups whose measure compares to value with op, one of =, !=, <,
+// <=, > and >=
+func (m Measure[T]) Having(op string, value float64) Having[T] {
+	return Having[T]{measure: m, op: op, value: value}
+}
+
+// Having is a condition on a measure of the groups
+type Having[T any] struct {
+	measure Measure[T]
+	op      string
+	value   float64
+}
+
+// Stats is an aggregation of the entities selected by Where. Without groups it has a
+// single row over all of them, without measures it counts them.
+type Stats[T any] struct {
+	// Where selects the entities, its order, paging and preloads are ignored
+	Where    Query[T]
+	GroupBy  []Group[T]
+	Measures []Measure[T]
+	Having   []Having[T]
+}
+
+// StatsRow is a group of the aggregation with its measures
+type StatsRow struct {
+	Group  map[string]interface{} `json:"group"`
+	Values map[string]float64     `json:"values"`
+}
+
+var havingOps = map[string]bool{"=": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// Aggregate returns the rows of the aggregation ordered by their groups. Invalid stats,
+// like a sum of a text field, fail with ErrInvalidStats.
+func (repo *GenericRepository[T]) Aggregate(stats Stats[T]) ([]StatsRow, error) {
+	s, err := repo.schema()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAggregate, err)
+	}
+	tx := stats.Where.filter(repo.Db)
+	quote := tx.Statement.Quote
+
+	measures := stats.Measures
+	if len(measures) == 0 {
+		measures = []Measure[T]{CountOf[T]()}
+	}
+
+	selects := make([]string, 0, len(stats.GroupBy)+len(measures))
+	groups := make([]string, 0, len(stats.GroupBy))
+	for _, group := range stats.GroupBy {
+		expr, err := groupExpression(tx, s, group)
+		if err != nil {
+			return nil, err
+		}
+		selects = append(selects, expr+" AS "+quote(group.Name()))
+		groups = append(groups, expr)
+	}
+	measureExprs := make(map[Measure[T]]string, len(measures))
+	for _, measure := range append(measures, havingMeasures(stats.Having)...) {
+		if _, ok := measureExprs[measure]; ok {
+			continue
+		}
+		expr, err := measureExpression(tx, s, measure)
+		if err != nil {
+			return nil, err
+		}
+		measureExprs[measure] = expr
+	}
+	for _, measure := range measures {
+		selects = append(selects, measureExprs[measure]+" AS "+quote(measure.Name()))
+	}
+
+	tx = tx.Select(strings.Join(selects, ", "))
+	if len(groups) > 0 {
+		tx = tx.Group(strings.Join(groups, ", ")).Order(strings.Join(groups, ", "))
+	}
+	for _, having := range stats.Having {
+		if !havingOps[having.op] {
+			return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidStats, having.op)
+		}
+		tx = tx.Having(measureExprs[having.measure]+" "+having.op+" ?", having.value)
+	}
+
+	scanned, err := scanMaps(tx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAggregate, err)
+	}
+	rows := make([]StatsRow, 0, len(scanned))
+	for _, values := range scanned {
+		row := StatsRow{Group: make(map[string]interface{}, len(stats.GroupBy)), Values: make(map[string]float64, len(measures))}
+		for _, group := range stats.GroupBy {
+			row.Group[group.Name()] = groupValue(values[group.Name()], group.unit != "")
+		}
+		for _, measure := range measures {
+			value, err := toFloat(values[measure.Name()])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrAggregate, measure.Name(), err)
+			}
+			row.Values[measure.Name()] = value
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// scanMaps runs the query of tx and returns its rows by column name, with the values
+// as returned by the driver
+func scanMaps(tx *gorm.DB) ([]map[string]interface{}, error) {
+	rows, err := tx.Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var result []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			row[column] = values[i]
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+func havingMeasures[T any](havings []Having[T]) []Measure[T] {
+	measures := make([]Measure[T], len(havings))
+	for i, having := range havings {
+		measures[i] = having.measure
+	}
+	return measures
+}
+
+func groupExpression[T any](tx *gorm.DB, s *schema.Schema, group Group[T]) (string, error) {
+	field := s.LookUpField(group.column)
+	if field == nil {
+		return "", fmt.Errorf("%w: %s has no column %s", ErrInvalidStats, s.Name, group.column)
+	}
+	column := tx.Statement.Quote(field.DBName)
+	if group.unit == "" {
+		return column, nil
+	}
+	if !isTime(field) {
+		return "", fmt.Errorf("%w: %s isn't a time to truncate", ErrInvalidStats, group.column)
+	}
+	if !timeUnits[group.unit] {
+		return "", fmt.Errorf("%w: unknown time unit %q", ErrInvalidStats, group.unit)
+	}
+	switch tx.Dialector.Name() {
+	case "postgres":
+		return fmt.Sprintf("date_trunc('%s', %s)", group.unit, column), nil
+	case "sqlite":
+		return fmt.Sprintf(sqliteTruncations[group.unit], column), nil
+	}
+	return "", fmt.Errorf("%w: truncating times isn't supported on %s", ErrInvalidStats, tx.Dialector.Name())
+}
+
+func measureExpression[T any](tx *gorm.DB, s *schema.Schema, measure Measure[T]) (string, error) {
+	if measure.column == "" {
+		if measure.fn != Count {
+			return "", fmt.Errorf("%w: %s needs a field", ErrInvalidStats, measure.fn)
+		}
+		return "COUNT(*)", nil
+	}
+	field := s.LookUpField(measure.column)
+	if field == nil {
+		return "", fmt.Errorf("%w: %s has no column %s", ErrInvalidStats, s.Name, measure.column)
+	}
+	column := tx.Statement.Quote(field.DBName)
+	switch measure.fn {
+	case Count:
+		return "COUNT(" + column + ")", nil
+	case Sum, Avg, Min, Max:
+		if field.DataType != schema.Int && field.DataType != schema.Uint && field.DataType != schema.Float {
+			return "", fmt.Errorf("%w: %s isn't a number", ErrInvalidStats, measure.column)
+		}
+		// avg of integers is numeric on postgres, a double scans the same everywhere
+		return fmt.Sprintf("CAST(%s(%s) AS DOUBLE PRECISION)", strings.ToUpper(string(measure.fn)), column), nil
+	}
+	return "", fmt.Errorf("%w: unknown function %q", ErrInvalidStats, measure.fn)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func isTime(field *schema.Field) bool {
+	return field.DataType == schema.Time || field.FieldType == timeType || field.FieldType == reflect.PointerTo(timeType)
+}
+
+// groupValue returns the value of a group as scanned, a truncated time is a time
+// whatever the driver returned
+func groupValue(value interface{}, truncated bool) interface{} {
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+	if s, ok := value.(string); ok && truncated {
+		if t, err := time.ParseInLocation(time.DateTime, s, time.UTC); err == nil {
+			return t
+		}
+	}
+	return value
+}
+
+func toFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case nil:
+		// sum and avg of no values
+		return 0, nil
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case []byte:
+		return strconv.ParseFloat(string(v), 64)
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("unexpected %T", value)
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type statsEvent struct {
+	ID           uint `gorm:"primaryKey"`
+	Category     string
+	Participants int
+	StartTime    time.Time
+}
+
+var (
+	statsCategory     = FieldOf[statsEvent, string]("Category")
+	statsParticipants = FieldOf[statsEvent, int]("Participants")
+	statsStartTime    = FieldOf[statsEvent, time.Time]("StartTime")
+)
+
+func setupStatsRepo(t *testing.T) *GenericRepository[statsEvent] {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&statsEvent{}))
+	at := func(month time.Month, day int) time.Time {
+		return time.Date(2025, month, day, 18, 30, 0, 0, time.UTC)
+	}
+	require.NoError(t, db.Create(&[]statsEvent{
+		{Category: "music", Participants: 10, StartTime: at(1, 5)},
+		{Category: "music", Participants: 20, StartTime: at(1, 20)},
+		{Category: "music", Participants: 60, StartTime: at(2, 3)},
+		{Category: "sport", Participants: 5, StartTime: at(1, 7)},
+		{Category: "sport", Participants: 7, StartTime: at(3, 1)},
+	}).Error)
+	return NewGenericRepository[statsEvent](db)
+}
+
+func TestAggregate_CountWithoutGroups(t *testing.T) {
+	repo := setupStatsRepo(t)
+
+	rows, err := repo.Aggregate(Stats[statsEvent]{})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Empty(t, rows[0].Group)
+	assert.Equal(t, map[string]float64{"count": 5}, rows[0].Values)
+}
+
+func TestAggregate_GroupsAndMeasures(t *testing.T) {
+	repo := setupStatsRepo(t)
+
+	rows, err := repo.Aggregate(Stats[statsEvent]{
+		GroupBy: []Group[statsEvent]{GroupBy(statsCategory)},
+		Measures: []Measure[statsEvent]{
+			CountOf[statsEvent](),
+			MeasureOf(Sum, statsParticipants),
+			MeasureOf(Avg, statsParticipants),
+			MeasureOf(Max, statsParticipants),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []StatsRow{
+		{Group: map[string]interface{}{"category": "music"}, Values: map[string]float64{"count": 3, "sum_participants": 90, "avg_participants": 30, "max_participants": 60}},
+		{Group: map[string]interface{}{"category": "sport"}, Values: map[string]float64{"count": 2, "sum_participants": 12, "avg_participants": 6, "max_participants": 7}},
+	}, rows)
+}
+
+func TestAggregate_TruncatedTimeWhereAndHaving(t *testing.T) {
+	repo := setupStatsRepo(t)
+
+	rows, err := repo.Aggregate(Stats[statsEvent]{
+		Where:   Where(statsParticipants.Gte(7)),
+		GroupBy: []Group[statsEvent]{GroupBy(statsStartTime).Truncate(Month), GroupBy(statsCategory)},
+		Having:  []Having[statsEvent]{MeasureOf(Sum, statsParticipants).Having(">", 10)},
+	})
+	require.NoError(t, err)
+	month := func(m time.Month) time.Time { return time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC) }
+	assert.Equal(t, []StatsRow{
+		{Group: map[string]interface{}{"start_time_month": month(1), "category": "music"}, Values: map[string]float64{"count": 2}},
+		{Group: map[string]interface{}{"start_time_month": month(2), "category": "music"}, Values: map[string]float64{"count": 1}},
+	}, rows)
+}
+
+func TestAggregate_Weeks(t *testing.T) {
+	repo := setupStatsRepo(t)
+
+	rows, err := repo.Aggregate(Stats[statsEvent]{
+		Where:   Where(statsStartTime.Lt(time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC))),
+		GroupBy: []Group[statsEvent]{GroupBy(statsStartTime).Truncate(Week)},
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	// January 5th 2025 is a Sunday, the 7th the Tuesday after
+	assert.Equal(t, time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC), rows[0].Group["start_time_week"])
+	assert.Equal(t, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), rows[1].Group["start_time_week"])
+}
+
+func TestAggregate_Invalid(t *testing.T) {
+	repo := setupStatsRepo(t)
+
+	cases := map[string]Stats[statsEvent]{
+		"sum of text":         {Measures: []Measure[statsEvent]{MeasureOf(Sum, statsCategory)}},
+		"truncated text":      {GroupBy: []Group[statsEvent]{GroupBy(statsCategory).Truncate(Day)}},
+		"unknown unit":        {GroupBy: []Group[statsEvent]{GroupBy(statsStartTime).Truncate("decade")}},
+		"unknown function":    {Measures: []Measure[statsEvent]{MeasureOf("median", statsParticipants)}},
+		"unknown operator":    {Having: []Having[statsEvent]{CountOf[statsEvent]().Having("; DROP", 1)}},
+		"sum without a field": {Measures: []Measure[statsEvent]{{fn: Sum}}},
+	}
+	for name, stats := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := repo.Aggregate(stats)
+			assert.ErrorIs(t, err, ErrInvalidStats)
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultBatchSize is the number of rows written or read per statement by the batched
+// methods of a repository when neither its BatchSize nor the one of the database is set
+const DefaultBatchSize = 100
+
+// ItemStatus is what a batched write did with an entity
+type ItemStatus string
+
+const (
+	ItemCreated ItemStatus = "created"
+	ItemUpdated ItemStatus = "updated"
+	ItemFailed  ItemStatus = "failed"
+)
+
+// ItemResult is the outcome of the write of the entity at Index in the written slice
+type ItemResult struct {
+	Index  int        `json:"index"`
+	Status ItemStatus `json:"status"`
+	// Error is the reason of the failure of a failed entity
+	Error string `json:"error,omitempty"`
+}
+
+// BatchResult reports a batched write entity by entity
+type BatchResult struct {
+	Created int          `json:"created"`
+	Updated int          `json:"updated"`
+	Failed  int          `json:"failed"`
+	Items   []ItemResult `json:"items"`
+}
+
+func (r *BatchResult) add(index int, status ItemStatus, err error) {
+	item := ItemResult{Index: index, Status: status}
+	switch status {
+	case ItemCreated:
+		r.Created++
+	case ItemUpdated:
+		r.Updated++
+	case ItemFailed:
+		r.Failed++
+		item.Error = err.Error()
+	}
+	r.Items = append(r.Items, item)
+}
+
+// OnConflict tells Upsert what to do with an entity conflicting with a stored one
+type OnConflict[T any] struct {
+	// Target are the columns of the unique constraint, the primary key when empty
+	Target []Column[T]
+	// Update are the columns overwritten in the stored entity, all of them when empty
+	Update []Column[T]
+}
+
+func (repo *GenericRepository[T]) batchSize() int {
+	if repo.BatchSize > 0 {
+		return repo.BatchSize
+	}
+	if repo.Db.CreateBatchSize > 0 {
+		return repo.Db.CreateBatchSize
+	}
+	return DefaultBatchSize
+}
+
+// InsertEach inserts the entities batch by batch, an entity that can't be inserted is
+// reported failed and the others are inserted anyway. Each batch is written in its
+// own transaction, or savepoint when the repository is in one. The error is only
+// for the failures stopping the whole write, like a cancelled context.
+func (repo *GenericRepository[T]) InsertEach(entities []*T) (*BatchResult, error) {
+	return repo.writeEach(entities, nil, func(tx *gorm.DB, batch []*T) error {
+		return tx.Create(batch).Error
+	})
+}
+
+// Upsert inserts the entities or updates the stored ones they conflict with, batch by
+// batch like InsertEach. Entities are reported created or updated by looking up the
+// conflicting ones before writing, in the same transaction.
+func (repo *GenericRepository[T]) Upsert(entities []*T, conflict OnConflict[T]) (*BatchResult, error) {
+	s, err := repo.schema()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBulkInsert, err)
+	}
+	target := make([]*schema.Field, 0, len(conflict.Target))
+	for _, column := range conflict.Target {
+		target = append(target, s.LookUpField(column.Column()))
+	}
+	if len(target) == 0 {
+		target = s.PrimaryFields
+	}
+	if len(target) == 0 {
+		return nil, fmt.Errorf("%w: %s has no primary key to detect conflicts", ErrBulkInsert, s.Name)
+	}
+
+	onConflict := clause.OnConflict{UpdateAll: len(conflict.Update) == 0}
+	for _, field := range target {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: field.DBName})
+	}
+	if !onConflict.UpdateAll {
+		columns := make([]string, 0, len(conflict.Update))
+		for _, column := range conflict.Update {
+			columns = append(columns, column.Column())
+		}
+		onConflict.DoUpdates = clause.AssignmentColumns(columns)
+	}
+
+	classify := func(tx *gorm.DB, batch []*T) ([]ItemStatus, error) {
+		return existing(tx, batch, target)
+	}
+	return repo.writeEach(entities, classify, func(tx *gorm.DB, batch []*T) error {
+		return tx.Clauses(onConflict).Create(batch).Error
+	})
+}
+
+// writeEach writes the entities with write batch by batch. A failing batch is written
+// again entity by entity to tell the failing ones from the others. classify returns
+// the status of the entities of a batch before it's written, they are all created
+// without it.
+func (repo *GenericRepository[T]) writeEach(entities []*T, classify func(tx *gorm.DB, batch []*T) ([]ItemStatus, error), write func(tx *gorm.DB, batch []*T) error) (*BatchResult, error) {
+	result := &BatchResult{Items: make([]ItemResult, 0, len(entities))}
+	writeBatch := func(batch []*T) ([]ItemStatus, error) {
+		var statuses []ItemStatus
+		err := repo.Db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if classify != nil {
+				if statuses, err = classify(tx, batch); err != nil {
+					return err
+				}
+			} else {
+				statuses = make([]ItemStatus, len(batch))
+				for i := range statuses {
+					statuses[i] = ItemCreated
+				}
+			}
+			return write(tx, batch)
+		})
+		return statuses, err
+	}
+
+	size := repo.batchSize()
+	for start := 0; start < len(entities); start += size {
+		batch := entities[start:min(start+size, len(entities))]
+		statuses, err := writeBatch(batch)
+		if err == nil {
+			for i, status := range statuses {
+				result.add(start+i, status, nil)
+			}
+			continue
+		}
+		for i := range batch {
+			if ctxErr := repo.Db.Statement.Context.Err(); ctxErr != nil {
+				return result, fmt.Errorf("%w: %v", ErrBulkInsert, ctxErr)
+			}
+			statuses, err := writeBatch(batch[i : i+1])
+			if err != nil {
+				result.add(start+i, ItemFailed, err)
+				continue
+			}
+			result.add(start+i, statuses[0], nil)
+		}
+	}
+	return result, nil
+}
+
+// existing tells for each entity of batch whether it conflicts with a stored one on
+// the target columns, entities with a zero target are new
+func existing[T any](tx *gorm.DB, batch []*T, target []*schema.Field) ([]ItemStatus, error) {
+	ctx := tx.Statement.Context
+	keyOf := func(entity reflect.Value) (string, bool) {
+		parts := make([]string, len(target))
+		for i, field := range target {
+			value, zero := field.ValueOf(ctx, entity)
+			if zero {
+				return "", false
+			}
+			parts[i] = fmt.Sprint(value)
+		}
+		return strings.Join(parts, "\x00"), true
+	}
+
+	keys := make([]string, len(batch))
+	conditions := make([]clause.Expression, 0, len(batch))
+	for i, entity := range batch {
+		value := reflect.ValueOf(entity).Elem()
+		key, ok := keyOf(value)
+		if !ok {
+			continue
+		}
+		keys[i] = key
+		eqs := make([]clause.Expression, len(target))
+		for j, field := range target {
+			v, _ := field.ValueOf(ctx, value)
+			eqs[j] = clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: v}
+		}
+		conditions = append(conditions, junction(" AND ", eqs))
+	}
+
+	found := make(map[string]bool)
+	if len(conditions) > 0 {
+		columns := make([]string, len(target))
+		for i, field := range target {
+			columns[i] = field.DBName
+		}
+		var stored []T
+		if err := tx.Model(new(T)).Select(columns).Where(junction(" OR ", conditions)).Find(&stored).Error; err != nil {
+			return nil, err
+		}
+		for i := range stored {
+			key, _ := keyOf(reflect.ValueOf(&stored[i]).Elem())
+			found[key] = true
+		}
+	}
+
+	statuses := make([]ItemStatus, len(batch))
+	for i, key := range keys {
+		statuses[i] = ItemCreated
+		if key != "" && found[key] {
+			statuses[i] = ItemUpdated
+		}
+	}
+	return statuses, nil
+}
+
+func (repo *GenericRepository[T]) schema() (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: repo.Db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// FindInBatches calls fn with the entities selected by the query batch by batch, so a
+// large table is read without holding it in memory. Entities come in primary key
+// order, the order and paging of the query are ignored and a projection gets the
+// primary key too. A batch is reused for the
+// next one, fn must copy what it keeps. An error of fn stops the reading and is
+// returned as it is.
+func (repo *GenericRepository[T]) FindInBatches(query Query[T], fn func(batch []T) error) error {
+	var (
+		batch []T
+		fnErr error
+	)
+	tx := query.filter(repo.Db)
+	for _, association := range query.preloads {
+		tx = tx.Preload(association)
+	}
+	if len(query.columns) > 0 {
+		// the batches go on from the last primary key read
+		s, err := repo.schema()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrFindEntities, err)
+		}
+		columns := slices.Clone(query.columns)
+		for _, field := range s.PrimaryFields {
+			if !slices.Contains(columns, field.DBName) {
+				columns = append(columns, field.DBName)
+			}
+		}
+		tx = tx.Select(columns)
+	}
+	result := tx.FindInBatches(&batch, repo.batchSize(), func(*gorm.DB, int) error {
+		fnErr = fn(batch)
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if result.Error != nil {
+		return fmt.Errorf("%w: %v", ErrFindEntities, result.Error)
+	}
+	return nil
+}
+
+// All returns an iterator over the entities selected by the query, read batch by batch
+// with FindInBatches. A failed read is yielded as the error of a last zero entity.
+func (repo *GenericRepository[T]) All(query Query[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		stopped := false
+		err := repo.FindInBatches(query, func(batch []T) error {
+			for _, entity := range batch {
+				if !yield(entity, nil) {
+					stopped = true
+					return errStopIteration
+				}
+			}
+			return nil
+		})
+		if err != nil && !stopped {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+var errStopIteration = errors.New("iteration stopped")
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type batchItem struct {
+	ID    uint   `gorm:"primaryKey"`
+	Code  string `gorm:"uniqueIndex"`
+	Name  string
+	Stock int
+}
+
+var (
+	batchCode  = FieldOf[batchItem, string]("Code")
+	batchName  = FieldOf[batchItem, string]("Name")
+	batchStock = FieldOf[batchItem, int]("Stock")
+)
+
+// statementCounter counts the inserts run on the database
+type statementCounter struct {
+	inserts int
+}
+
+func setupBatchRepo(t *testing.T) (*GenericRepository[batchItem], *statementCounter) {
+	counter := &statementCounter{}
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&batchItem{}))
+	require.NoError(t, db.Callback().Create().Before("gorm:create").Register("test:count", func(*gorm.DB) {
+		counter.inserts++
+	}))
+	return NewGenericRepository[batchItem](db), counter
+}
+
+func newItems(n int) []*batchItem {
+	items := make([]*batchItem, n)
+	for i := range items {
+		items[i] = &batchItem{Code: fmt.Sprintf("c%02d", i), Name: "item", Stock: i}
+	}
+	return items
+}
+
+func TestBulkInsert_Batches(t *testing.T) {
+	repo, counter := setupBatchRepo(t)
+	repo.BatchSize = 4
+
+	require.NoError(t, repo.BulkInsert(newItems(10)))
+	assert.Equal(t, 3, counter.inserts, "10 entities are inserted 4 by 4")
+	count, err := repo.CountBy(Query[batchItem]{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), count)
+}
+
+func TestBulkInsert_AllOrNothing(t *testing.T) {
+	repo, _ := setupBatchRepo(t)
+	repo.BatchSize = 2
+
+	items := newItems(5)
+	items[3].Code = items[0].Code
+	assert.ErrorIs(t, repo.BulkInsert(items), ErrBulkInsert)
+	count, err := repo.CountBy(Query[batchItem]{})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestInsertEach_ReportsFailures(t *testing.T) {
+	repo, _ := setupBatchRepo(t)
+	repo.BatchSize = 3
+
+	items := newItems(7)
+	items[1].Code = items[0].Code
+	items[5].Code = items[4].Code
+	result, err := repo.InsertEach(items)
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, result.Created)
+	assert.Equal(t, 2, result.Failed)
+	require.Len(t, result.Items, 7)
+	for i, item := range result.Items {
+		assert.Equal(t, i, item.Index)
+		if i == 1 || i == 5 {
+			assert.Equal(t, ItemFailed, item.Status)
+			assert.NotEmpty(t, item.Error)
+		} else {
+			assert.Equal(t, ItemCreated, item.Status)
+			assert.NotZero(t, items[i].ID)
+		}
+	}
+	count, err := repo.CountBy(Query[batchItem]{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+}
+
+func TestInsertEach_CancelledContext(t *testing.T) {
+	repo, _ := setupBatchRepo(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.WithContext(ctx).InsertEach(newItems(3))
+	assert.ErrorIs(t, err, ErrBulkInsert)
+}
+
+func TestUpsert(t *testing.T) {
+	repo, _ := setupBatchRepo(t)
+	repo.BatchSize = 2
+	require.NoError(t, repo.BulkInsert(newItems(3)))
+
+	items := []*batchItem{
+		{Code: "c00", Name: "renamed", Stock: 100},
+		{Code: "c09", Name: "new", Stock: 9},
+		{Code: "c02", Name: "renamed", Stock: 200},
+	}
+	result, err := repo.Upsert(items, OnConflict[batchItem]{
+		Target: []Column[batchItem]{batchCode},
+		Update: []Column[batchItem]{batchStock},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Updated)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, []ItemStatus{ItemUpdated, ItemCreated, ItemUpdated},
+		[]ItemStatus{result.Items[0].Status, result.Items[1].Status, result.Items[2].Status})
+
+	stored, err := repo.FirstBy(Where(batchCode.Eq("c02")))
+	require.NoError(t, err)
+	assert.Equal(t, 200, stored.Stock)
+	assert.Equal(t, "item", stored.Name, "only the update columns are overwritten")
+
+	count, err := repo.CountBy(Query[batchItem]{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+}
+
+func TestUpsert_PrimaryKeyUpdatesAll(t *testing.T) {
+	repo, _ := setupBatchRepo(t)
+	items := newItems(2)
+	require.NoError(t, repo.BulkInsert(items))
+
+	result, err := repo.Upsert([]*batchItem{
+		{ID: items[1].ID, Code: "c01", Name: "renamed", Stock: 5},
+		{Code: "c05", Name: "new"},
+	}, OnConflict[batchItem]{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 1, result.Created)
+
+	stored, err := repo.GetByID(int(items[1].ID))
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", stored.Name)
+	assert.Equal(t, 5, stored.Stock)
+}
+
+func TestFindInBatches(t *testing.T) {
+	repo, _ := setupBatchRepo(t)
+	require.NoError(t, repo.BulkInsert(newItems(10)))
+	repo.BatchSize = 3
+
+	var sizes []int
+	var stocks []int
+	err := repo.FindInBatches(Where(batchStock.Gte(1)).Select(batchStock), func(batch []batchItem) error {
+		sizes = append(sizes, len(batch))
+		for _, item := range batch {
+			stocks = append(stocks, item.Stock)
+			assert.Empty(t, item.Name, "only the selected columns are read")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 3, 3}, sizes)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9}, stocks)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = repo.FindInBatches(Query[batchItem]{}, func([]batchItem) error {
+		calls++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestAll(t *testing.T) {
+	repo, _ := setupBatchRepo(t)
+	require.NoError(t, repo.BulkInsert(newItems(7)))
+	repo.BatchSize = 2
+
+	var codes []string
+	for item, err := range repo.All(Where(batchName.Eq("item"))) {
+		require.NoError(t, err)
+		codes = append(codes, item.Code)
+		if len(codes) == 5 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"c00", "c01", "c02", "c03", "c04"}, codes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range repo.WithContext(ctx).All(Query[batchItem]{}) {
+		assert.ErrorIs(t, err, ErrFindEntities)
+	}
+}
@@ -16,5 +16,7 @@ var (
 	ErrBulkInsert      = errors.New("unable to bulk insert entities")
 	ErrBulkUpdate      = errors.New("unable to bulk update entities")
 	ErrTransaction     = errors.New("unable to begin transaction")
+	ErrAggregate       = errors.New("unable to aggregate entities")
+	ErrInvalidStats    = errors.New("invalid aggregation")
 )
 
@@ -1,13 +1,19 @@
 package repository
 
 import (
+	"context"
 	"fmt"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
 	"gorm.io/gorm"
 )
 
 // GenericRepository - generic struct, that provides all necessary methods to work with GORM
 type GenericRepository[T any] struct {
 	Db *gorm.DB
+	// BatchSize is the number of rows per statement of the batched methods. When zero
+	// it's the CreateBatchSize of the database, or DefaultBatchSize.
+	BatchSize int
 }
 
 // NewGenericRepository - constructor for NewGenericRepository
@@ -15,6 +21,20 @@ func NewGenericRepository[T any](db *gorm.DB) *GenericRepository[T] {
 	return &GenericRepository[T]{Db: db}
 }
 
+// WithContext returns a copy of the repository whose queries run with ctx,
+// so they are cancelled together with it and traced as its children.
+// In a db.UnitOfWork they run in its transaction.
+func (repo *GenericRepository[T]) WithContext(ctx context.Context) *GenericRepository[T] {
+	return &GenericRepository[T]{Db: db.Conn(ctx, repo.Db), BatchSize: repo.BatchSize}
+}
+
+// Primary returns a copy of the repository reading from the primary database, for
+// reads that must see a write made just before. Without read replicas it's the same.
+// Writes and transactions always go to the primary, reads go to a replica otherwise.
+func (repo *GenericRepository[T]) Primary() *GenericRepository[T] {
+	return &GenericRepository[T]{Db: db.Primary(repo.Db), BatchSize: repo.BatchSize}
+}
+
 // Create creates a new entity and returns the created entity.
 func (repo *GenericRepository[T]) Create(entity *T) (*T, error) {
 	result := repo.Db.Create(entity)
@@ -67,27 +87,49 @@ func (repo *GenericRepository[T]) GetAll() ([]T, error) {
 
 // DeleteWhere deletes entities matching the given condition.
 func (repo *GenericRepository[T]) DeleteWhere(condition interface{}, args ...interface{}) error {
-	result := repo.Db.Where(condition, args...).Delete(new(T))
-	if result.Error != nil {
-		return fmt.Errorf("%w: %v", ErrDeleteWithCond, result.Error)
-	}
-	return nil
+	return repo.DeleteBy(Where(Raw[T](condition, args...)))
 }
 
 // Find returns all entities matching the given condition.
 func (repo *GenericRepository[T]) Find(condition interface{}, args ...interface{}) ([]T, error) {
+	return repo.FindBy(Where(Raw[T](condition, args...)))
+}
+
+// FindFirst returns a single entity matching the given condition.
+func (repo *GenericRepository[T]) FindFirst(condition interface{}, args ...interface{}) (*T, error) {
+	return repo.FirstBy(Where(Raw[T](condition, args...)))
+}
+
+// Count returns the count of entities matching the given condition.
+func (repo *GenericRepository[T]) Count(condition interface{}, args ...interface{}) (int64, error) {
+	return repo.CountBy(Where(Raw[T](condition, args...)))
+}
+
+// GetPage returns a paginated list of entities matching the given condition.
+func (repo *GenericRepository[T]) GetPage(page int, pageSize int, condition interface{}, args ...interface{}) ([]T, error) {
+	var entities []T
+	result := Where(Raw[T](condition, args...)).Page(page, pageSize).apply(repo.Db).Find(&entities)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%w: %v", ErrGetPageEntities, result.Error)
+	}
+	return entities, nil
+}
+
+// FindBy returns the entities selected by the query.
+func (repo *GenericRepository[T]) FindBy(query Query[T]) ([]T, error) {
 	var entities []T
-	result := repo.Db.Where(condition, args...).Find(&entities)
+	result := query.apply(repo.Db).Find(&entities)
 	if result.Error != nil {
 		return nil, fmt.Errorf("%w: %v", ErrFindEntities, result.Error)
 	}
 	return entities, nil
 }
 
-// FindFirst returns a single entity matching the given condition.
-func (repo *GenericRepository[T]) FindFirst(condition interface{}, args ...interface{}) (*T, error) {
+// FirstBy returns the first entity selected by the query, in primary key order unless
+// the query orders them.
+func (repo *GenericRepository[T]) FirstBy(query Query[T]) (*T, error) {
 	var entity T
-	result := repo.Db.Where(condition, args...).First(&entity)
+	result := query.apply(repo.Db).First(&entity)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
 			return nil, gorm.ErrRecordNotFound
@@ -97,47 +139,88 @@ func (repo *GenericRepository[T]) FindFirst(condition interface{}, args ...inter
 	return &entity, nil
 }
 
-// Count returns the count of entities matching the given condition.
-func (repo *GenericRepository[T]) Count(condition interface{}, args ...interface{}) (int64, error) {
+// CountBy returns the count of entities matching the query, its order and paging are ignored.
+func (repo *GenericRepository[T]) CountBy(query Query[T]) (int64, error) {
 	var count int64
-	result := repo.Db.Model(new(T)).Where(condition, args...).Count(&count)
+	result := query.filter(repo.Db).Count(&count)
 	if result.Error != nil {
 		return 0, fmt.Errorf("%w: %v", ErrCountEntities, result.Error)
 	}
 	return count, nil
 }
 
-// GetPage returns a paginated list of entities matching the given condition.
-func (repo *GenericRepository[T]) GetPage(page int, pageSize int, condition interface{}, args ...interface{}) ([]T, error) {
-	var entities []T
-	offset := (page - 1) * pageSize
-	result := repo.Db.Where(condition, args...).Offset(offset).Limit(pageSize).Find(&entities)
+// ExistsBy tells whether an entity matches the query.
+func (repo *GenericRepository[T]) ExistsBy(query Query[T]) (bool, error) {
+	var found []int
+	result := query.filter(repo.Db).Select("1").Limit(1).Find(&found)
 	if result.Error != nil {
-		return nil, fmt.Errorf("%w: %v", ErrGetPageEntities, result.Error)
+		return false, fmt.Errorf("%w: %v", ErrFindEntity, result.Error)
 	}
-	return entities, nil
+	return len(found) > 0, nil
 }
 
-// BulkInsert inserts multiple entities at once.
-func (repo *GenericRepository[T]) BulkInsert(entities []*T) error {
-	result := repo.Db.Create(&entities)
+// DeleteBy deletes the entities matching the query. A query without condition is
+// refused rather than deleting everything.
+func (repo *GenericRepository[T]) DeleteBy(query Query[T]) error {
+	result := query.filter(repo.Db).Delete(new(T))
 	if result.Error != nil {
-		return fmt.Errorf("%w: %v", ErrBulkInsert, result.Error)
+		return fmt.Errorf("%w: %v", ErrDeleteWithCond, result.Error)
 	}
 	return nil
 }
 
-// BulkUpdate updates multiple entities based on the given condition with provided update data.
-func (repo *GenericRepository[T]) BulkUpdate(condition interface{}, args []interface{}, updateData interface{}) error {
-	result := repo.Db.Model(new(T)).Where(condition, args...).Updates(updateData)
+// UpdateBy updates the entities matching the query with updateData, a struct or a map
+// of column names to values.
+func (repo *GenericRepository[T]) UpdateBy(query Query[T], updateData interface{}) error {
+	result := query.filter(repo.Db).Updates(updateData)
 	if result.Error != nil {
 		return fmt.Errorf("%w: %v", ErrBulkUpdate, result.Error)
 	}
 	return nil
 }
 
+// Pluck returns the values of field of the entities selected by the query.
+func Pluck[T, V any](repo *GenericRepository[T], query Query[T], field Field[T, V]) ([]V, error) {
+	var values []V
+	result := query.apply(repo.Db).Pluck(field.Column(), &values)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFindEntities, result.Error)
+	}
+	return values, nil
+}
+
+// Project returns the entities selected by the query as P, a struct with a subset of
+// the fields of T, only their columns are read.
+func Project[T, P any](repo *GenericRepository[T], query Query[T]) ([]P, error) {
+	var projections []P
+	result := query.apply(repo.Db).Find(&projections)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFindEntities, result.Error)
+	}
+	return projections, nil
+}
+
+// BulkInsert inserts multiple entities at once, BatchSize of them per statement.
+// Nothing is inserted if one of them fails, see InsertEach to insert the others anyway.
+func (repo *GenericRepository[T]) BulkInsert(entities []*T) error {
+	result := repo.Db.CreateInBatches(entities, repo.batchSize())
+	if result.Error != nil {
+		return fmt.Errorf("%w: %v", ErrBulkInsert, result.Error)
+	}
+	return nil
+}
+
+// BulkUpdate updates multiple entities based on the given condition with provided update data.
+func (repo *GenericRepository[T]) BulkUpdate(condition interface{}, args []interface{}, updateData interface{}) error {
+	return repo.UpdateBy(Where(Raw[T](condition, args...)), updateData)
+}
+
 // ExecuteInTransaction executes the provided function within a transaction.
+// A repository bound to a transaction runs fn in a savepoint of it.
 func (repo *GenericRepository[T]) ExecuteInTransaction(fn func(tx *gorm.DB) error) error {
+	if _, inTransaction := repo.Db.Statement.ConnPool.(gorm.TxCommitter); inTransaction {
+		return repo.Db.Transaction(fn)
+	}
 	tx := repo.Db.Begin()
 	if tx.Error != nil {
 		return fmt.Errorf("%w: %v", ErrTransaction, tx.Error)
@@ -1,10 +1,12 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -324,3 +326,39 @@ func TestExecuteInTransaction_MultipleMethods(t *testing.T) {
 	assert.Len(t, all, 3, "expected 3 total entities after transaction")
 }
 
+// OtherEntity is written together with TestEntity in a unit of work
+type OtherEntity struct {
+	ID    int `gorm:"primaryKey"`
+	Owner int
+}
+
+func TestUnitOfWork_TypedRepositoriesShareTheTransaction(t *testing.T) {
+	database := setupTestDB(t)
+	assert.NoError(t, database.AutoMigrate(&OtherEntity{}))
+	entities := NewGenericRepository[TestEntity](database)
+	others := NewGenericRepository[OtherEntity](database)
+	uow := db.NewUnitOfWork(database)
+
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		entity, err := entities.WithContext(ctx).Create(&TestEntity{Name: "owner"})
+		if err != nil {
+			return err
+		}
+		if _, err := others.WithContext(ctx).Create(&OtherEntity{Owner: entity.ID}); err != nil {
+			return err
+		}
+		// ExecuteInTransaction of a bound repository runs in a savepoint
+		err = entities.WithContext(ctx).ExecuteInTransaction(func(tx *gorm.DB) error {
+			tx.Create(&TestEntity{Name: "undone"})
+			return errors.New("failed")
+		})
+		assert.Error(t, err)
+		return errors.New("roll back everything")
+	})
+	assert.Error(t, err)
+
+	count, _ := entities.Count("1 = 1")
+	assert.Zero(t, count)
+	count, _ = others.Count("1 = 1")
+	assert.Zero(t, count)
+}
@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// schemas caches the parsed models of FieldOf
+var schemas sync.Map
+
+// Field is a column of the model T holding values of type V. Its methods build the
+// specifications comparing it, so the compiler checks the values they're given.
+type Field[T, V any] struct {
+	column string
+}
+
+// FieldOf returns the field of T with the Go name name, e.g. FieldOf[models.Event, uint]("CreatorID").
+// Its column is the one gorm maps the field to. It panics when T has no such field or
+// its type isn't V, fields are meant to be declared once as package variables.
+func FieldOf[T, V any](name string) Field[T, V] {
+	s, err := schema.Parse(new(T), &schemas, schema.NamingStrategy{})
+	if err != nil {
+		panic(fmt.Sprintf("repository: parsing %T: %v", *new(T), err))
+	}
+	field := s.LookUpField(name)
+	if field == nil || field.DBName == "" {
+		panic(fmt.Sprintf("repository: %s has no column %s", s.Name, name))
+	}
+	want := reflect.TypeOf((*V)(nil)).Elem()
+	if field.FieldType != want && !(field.FieldType.Kind() == reflect.Pointer && field.FieldType.Elem() == want) {
+		panic(fmt.Sprintf("repository: %s.%s is %s, not %s", s.Name, name, field.FieldType, want))
+	}
+	return Field[T, V]{column: field.DBName}
+}
+
+// Column returns the name of the column of the field
+func (f Field[T, V]) Column() string {
+	return f.column
+}
+
+func (f Field[T, V]) ref() clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: f.column}
+}
+
+func (f Field[T, V]) of(T) {}
+
+func (f Field[T, V]) Eq(value V) Spec[T] {
+	return expr[T](clause.Eq{Column: f.ref(), Value: value})
+}
+
+func (f Field[T, V]) Ne(value V) Spec[T] {
+	return expr[T](clause.Neq{Column: f.ref(), Value: value})
+}
+
+func (f Field[T, V]) Gt(value V) Spec[T] {
+	return expr[T](clause.Gt{Column: f.ref(), Value: value})
+}
+
+func (f Field[T, V]) Gte(value V) Spec[T] {
+	return expr[T](clause.Gte{Column: f.ref(), Value: value})
+}
+
+func (f Field[T, V]) Lt(value V) Spec[T] {
+	return expr[T](clause.Lt{Column: f.ref(), Value: value})
+}
+
+func (f Field[T, V]) Lte(value V) Spec[T] {
+	return expr[T](clause.Lte{Column: f.ref(), Value: value})
+}
+
+// In matches the entities whose field is one of values, none matches no entity
+func (f Field[T, V]) In(values ...V) Spec[T] {
+	vars := make([]interface{}, len(values))
+	for i, value := range values {
+		vars[i] = value
+	}
+	return expr[T](clause.IN{Column: f.ref(), Values: vars})
+}
+
+// Like matches the field against an SQL LIKE pattern
+func (f Field[T, V]) Like(pattern string) Spec[T] {
+	return expr[T](clause.Like{Column: f.ref(), Value: pattern})
+}
+
+func (f Field[T, V]) IsNull() Spec[T] {
+	return expr[T](clause.Eq{Column: f.ref(), Value: nil})
+}
+
+func (f Field[T, V]) NotNull() Spec[T] {
+	return expr[T](clause.Neq{Column: f.ref(), Value: nil})
+}
+
+func (f Field[T, V]) Asc() Order[T] {
+	return Order[T]{column: f.ref()}
+}
+
+func (f Field[T, V]) Desc() Order[T] {
+	return Order[T]{column: f.ref(), desc: true}
+}
+
+// Column is a field of T of any type, for the projections
+type Column[T any] interface {
+	Column() string
+	of(T)
+}
+
+// Order is the ordering of the entities by a field
+type Order[T any] struct {
+	column clause.Column
+	desc   bool
+}
+
+// Spec is a condition on the entities of T. Build them with the methods of the fields
+// of T and combine them with And, Or and Not. The zero Spec matches every entity.
+type Spec[T any] struct {
+	build func(stmt *gorm.Statement) clause.Expression
+}
+
+func expr[T any](e clause.Expression) Spec[T] {
+	return Spec[T]{build: func(*gorm.Statement) clause.Expression { return e }}
+}
+
+// Raw is the condition of the untyped methods: a condition with its args as taken by
+// gorm's Where, e.g. Raw[models.Event]("start_time > ?", now)
+func Raw[T any](condition interface{}, args ...interface{}) Spec[T] {
+	return Spec[T]{build: func(stmt *gorm.Statement) clause.Expression {
+		return junction(" AND ", stmt.BuildCondition(condition, args...))
+	}}
+}
+
+func (s Spec[T]) expression(stmt *gorm.Statement) clause.Expression {
+	if s.build == nil {
+		return nil
+	}
+	return s.build(stmt)
+}
+
+func expressions[T any](stmt *gorm.Statement, specs []Spec[T]) []clause.Expression {
+	exprs := make([]clause.Expression, 0, len(specs))
+	for _, spec := range specs {
+		if e := spec.expression(stmt); e != nil {
+			exprs = append(exprs, e)
+		}
+	}
+	return exprs
+}
+
+// And matches the entities matching all specs
+func And[T any](specs ...Spec[T]) Spec[T] {
+	return Spec[T]{build: func(stmt *gorm.Statement) clause.Expression {
+		return junction(" AND ", expressions(stmt, specs))
+	}}
+}
+
+// Or matches the entities matching any of specs
+func Or[T any](specs ...Spec[T]) Spec[T] {
+	return Spec[T]{build: func(stmt *gorm.Statement) clause.Expression {
+		exprs := expressions(stmt, specs)
+		if len(exprs) < len(specs) {
+			// one of them matches everything
+			return nil
+		}
+		if len(exprs) == 0 {
+			return clause.Expr{SQL: "1 = 0"}
+		}
+		return junction(" OR ", exprs)
+	}}
+}
+
+// Not matches the entities not matching spec
+func Not[T any](spec Spec[T]) Spec[T] {
+	return Spec[T]{build: func(stmt *gorm.Statement) clause.Expression {
+		e := spec.expression(stmt)
+		if e == nil {
+			return clause.Expr{SQL: "1 = 0"}
+		}
+		return negation{expr: e}
+	}}
+}
+
+// junctionExpr joins conditions with AND or OR. gorm's own ones leave out parentheses
+// in some nestings and negate an AND term by term, these are always parenthesized.
+type junctionExpr struct {
+	op    string
+	exprs []clause.Expression
+}
+
+func junction(op string, exprs []clause.Expression) clause.Expression {
+	switch len(exprs) {
+	case 0:
+		return nil
+	case 1:
+		return exprs[0]
+	}
+	return junctionExpr{op: op, exprs: exprs}
+}
+
+func (j junctionExpr) Build(builder clause.Builder) {
+	builder.WriteByte('(')
+	for i, e := range j.exprs {
+		if i > 0 {
+			builder.WriteString(j.op)
+		}
+		buildGrouped(builder, e)
+	}
+	builder.WriteByte(')')
+}
+
+type negation struct {
+	expr clause.Expression
+}
+
+func (n negation) Build(builder clause.Builder) {
+	builder.WriteString("NOT ")
+	if _, grouped := n.expr.(junctionExpr); grouped {
+		n.expr.Build(builder)
+		return
+	}
+	builder.WriteByte('(')
+	n.expr.Build(builder)
+	builder.WriteByte(')')
+}
+
+// buildGrouped builds e in parentheses when it's raw SQL, which may hold an OR
+func buildGrouped(builder clause.Builder, e clause.Expression) {
+	switch e.(type) {
+	case clause.Expr, clause.NamedExpr:
+		builder.WriteByte('(')
+		e.Build(builder)
+		builder.WriteByte(')')
+	default:
+		e.Build(builder)
+	}
+}
+
+// Query selects entities of T: the ones matching all its specs, in its order, with
+// its associations preloaded. Its methods return a modified copy, so a query can be
+// shared and extended. The zero Query selects every entity.
+type Query[T any] struct {
+	where    []Spec[T]
+	orders   []Order[T]
+	limit    int
+	offset   int
+	preloads []string
+	columns  []string
+}
+
+// Where returns the query of the entities matching all specs
+func Where[T any](specs ...Spec[T]) Query[T] {
+	return Query[T]{}.Where(specs...)
+}
+
+// Where narrows the query to the entities matching all specs too
+func (q Query[T]) Where(specs ...Spec[T]) Query[T] {
+	q.where = append(slices.Clip(q.where), specs...)
+	return q
+}
+
+// OrderBy orders the entities by orders after the ones already given
+func (q Query[T]) OrderBy(orders ...Order[T]) Query[T] {
+	q.orders = append(slices.Clip(q.orders), orders...)
+	return q
+}
+
+// Limit returns at most n entities, 0 removes the limit
+func (q Query[T]) Limit(n int) Query[T] {
+	q.limit = n
+	return q
+}
+
+// Offset skips the first n entities
+func (q Query[T]) Offset(n int) Query[T] {
+	q.offset = n
+	return q
+}
+
+// Page returns the page-th page of size entities, pages start at 1
+func (q Query[T]) Page(page, size int) Query[T] {
+	return q.Offset((page - 1) * size).Limit(size)
+}
+
+// Preload loads the associations of the entities, e.g. "Creator" or "Creator.Profile"
+func (q Query[T]) Preload(associations ...string) Query[T] {
+	q.preloads = append(slices.Clip(q.preloads), associations...)
+	return q
+}
+
+// Select loads only columns into the entities, the other fields are left zero
+func (q Query[T]) Select(columns ...Column[T]) Query[T] {
+	names := slices.Clip(q.columns)
+	for _, column := range columns {
+		names = append(names, column.Column())
+	}
+	q.columns = names
+	return q
+}
+
+// filter returns tx narrowed to the entities of the query, without its order and paging
+func (q Query[T]) filter(tx *gorm.DB) *gorm.DB {
+	tx = tx.Model(new(T))
+	if e := junction(" AND ", expressions(tx.Statement, q.where)); e != nil {
+		tx = tx.Where(e)
+	}
+	return tx
+}
+
+// apply returns tx running the query
+func (q Query[T]) apply(tx *gorm.DB) *gorm.DB {
+	tx = q.filter(tx)
+	for _, order := range q.orders {
+		tx = tx.Order(clause.OrderByColumn{Column: order.column, Desc: order.desc})
+	}
+	if q.limit > 0 {
+		tx = tx.Limit(q.limit)
+	}
+	if q.offset > 0 {
+		tx = tx.Offset(q.offset)
+	}
+	for _, association := range q.preloads {
+		tx = tx.Preload(association)
+	}
+	if len(q.columns) > 0 {
+		tx = tx.Select(q.columns)
+	}
+	return tx
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type queryOwner struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+type queryItem struct {
+	ID        uint `gorm:"primaryKey"`
+	Title     string
+	Seats     int
+	OwnerID   uint
+	Owner     queryOwner
+	DeletedAt *time.Time
+}
+
+var (
+	itemID        = FieldOf[queryItem, uint]("ID")
+	itemTitle     = FieldOf[queryItem, string]("Title")
+	itemSeats     = FieldOf[queryItem, int]("Seats")
+	itemOwnerID   = FieldOf[queryItem, uint]("OwnerID")
+	itemDeletedAt = FieldOf[queryItem, time.Time]("DeletedAt")
+)
+
+func setupQueryRepo(t *testing.T) *GenericRepository[queryItem] {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&queryOwner{}, &queryItem{}))
+	require.NoError(t, db.Create(&[]queryOwner{{ID: 1, Name: "alice"}, {ID: 2, Name: "bob"}}).Error)
+	deleted := time.Now()
+	require.NoError(t, db.Create(&[]queryItem{
+		{ID: 1, Title: "go meetup", Seats: 10, OwnerID: 1},
+		{ID: 2, Title: "rust meetup", Seats: 30, OwnerID: 1},
+		{ID: 3, Title: "go conference", Seats: 300, OwnerID: 2},
+		{ID: 4, Title: "cancelled", Seats: 5, OwnerID: 2, DeletedAt: &deleted},
+	}).Error)
+	return NewGenericRepository[queryItem](db)
+}
+
+func ids(items []queryItem) []uint {
+	var result []uint
+	for _, item := range items {
+		result = append(result, item.ID)
+	}
+	return result
+}
+
+func TestFieldOf(t *testing.T) {
+	assert.Equal(t, "owner_id", itemOwnerID.Column())
+	assert.Equal(t, "deleted_at", itemDeletedAt.Column())
+	assert.Panics(t, func() { FieldOf[queryItem, string]("Missing") })
+	assert.Panics(t, func() { FieldOf[queryItem, string]("Seats") }, "the type of the field is checked")
+	assert.Panics(t, func() { FieldOf[queryItem, queryOwner]("Owner") }, "associations have no column")
+}
+
+func TestFindBy_Specs(t *testing.T) {
+	repo := setupQueryRepo(t)
+	cases := []struct {
+		name  string
+		query Query[queryItem]
+		want  []uint
+	}{
+		{"zero query", Query[queryItem]{}, []uint{1, 2, 3, 4}},
+		{"eq", Where(itemOwnerID.Eq(2)), []uint{3, 4}},
+		{"and", Where(itemOwnerID.Eq(1), itemSeats.Gt(10)), []uint{2}},
+		{"or", Where(Or(itemSeats.Lt(10), itemSeats.Gte(300))), []uint{3, 4}},
+		{"or and", Where(Or(itemSeats.Lt(10), itemSeats.Gte(300)), itemOwnerID.Eq(2), itemDeletedAt.IsNull()), []uint{3}},
+		{"not and", Where(Not(And(itemOwnerID.Eq(1), itemSeats.Lte(10)))), []uint{2, 3, 4}},
+		{"not or", Where(Not(Or(itemOwnerID.Eq(1), itemSeats.Ne(5)))), []uint{4}},
+		{"in", Where(itemID.In(1, 3, 9)), []uint{1, 3}},
+		{"in none", Where(itemID.In()), nil},
+		{"like", Where(itemTitle.Like("go %")), []uint{1, 3}},
+		{"not null", Where(itemDeletedAt.NotNull()), []uint{4}},
+		{"raw", Where(Raw[queryItem]("seats > ? OR title = ?", 100, "go meetup"), itemOwnerID.Eq(2)), []uint{3}},
+		{"or with everything", Where(Or(Spec[queryItem]{}, itemID.Eq(1))), []uint{1, 2, 3, 4}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			items, err := repo.FindBy(tc.query.OrderBy(itemID.Asc()))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, ids(items))
+		})
+	}
+}
+
+func TestFindBy_OrderPagingPreloadSelect(t *testing.T) {
+	repo := setupQueryRepo(t)
+
+	items, err := repo.FindBy(Query[queryItem]{}.OrderBy(itemOwnerID.Desc(), itemSeats.Asc()).Page(2, 2))
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 2}, ids(items))
+
+	items, err = repo.FindBy(Where(itemID.Eq(3)).Preload("Owner"))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "bob", items[0].Owner.Name)
+
+	items, err = repo.FindBy(Where(itemID.Eq(3)).Select(itemID, itemTitle))
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "go conference", items[0].Title)
+	assert.Zero(t, items[0].Seats, "columns not selected are left zero")
+}
+
+func TestQuery_IsCopiedOnChange(t *testing.T) {
+	repo := setupQueryRepo(t)
+	base := Where(itemOwnerID.Eq(1))
+	narrowed := base.Where(itemSeats.Gt(10))
+
+	all, err := repo.FindBy(base)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	some, err := repo.FindBy(narrowed)
+	require.NoError(t, err)
+	assert.Len(t, some, 1)
+}
+
+func TestFirstCountExistsBy(t *testing.T) {
+	repo := setupQueryRepo(t)
+
+	first, err := repo.FirstBy(Where(itemOwnerID.Eq(1)).OrderBy(itemSeats.Desc()))
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), first.ID)
+
+	_, err = repo.FirstBy(Where(itemOwnerID.Eq(3)))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	count, err := repo.CountBy(Where(itemOwnerID.Eq(1)).Limit(1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count, "paging doesn't apply to the count")
+
+	exists, err := repo.ExistsBy(Where(itemTitle.Eq("rust meetup")))
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = repo.ExistsBy(Where(itemTitle.Eq("java meetup")))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestUpdateDeleteBy(t *testing.T) {
+	repo := setupQueryRepo(t)
+
+	require.NoError(t, repo.UpdateBy(Where(itemOwnerID.Eq(2)), map[string]interface{}{"seats": 1}))
+	count, err := repo.CountBy(Where(itemSeats.Eq(1)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	require.NoError(t, repo.DeleteBy(Where(itemDeletedAt.NotNull())))
+	count, err = repo.CountBy(Query[queryItem]{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	assert.ErrorIs(t, repo.DeleteBy(Query[queryItem]{}), ErrDeleteWithCond, "deleting everything is refused")
+}
+
+func TestPluckAndProject(t *testing.T) {
+	repo := setupQueryRepo(t)
+
+	titles, err := Pluck(repo, Where(itemOwnerID.Eq(1)).OrderBy(itemTitle.Desc()), itemTitle)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rust meetup", "go meetup"}, titles)
+
+	type summary struct {
+		ID    uint
+		Seats int
+	}
+	summaries, err := Project[queryItem, summary](repo, Where(itemSeats.Gte(30)).OrderBy(itemID.Asc()))
+	require.NoError(t, err)
+	assert.Equal(t, []summary{{ID: 2, Seats: 30}, {ID: 3, Seats: 300}}, summaries)
+}
@@ -1,6 +1,10 @@
 package repository
 
-import "gorm.io/gorm"
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
 
 // Interface defines all generic repository operations for type T.
 type Interface[T any] interface {
@@ -26,9 +30,29 @@ type Interface[T any] interface {
 	GetPage(page int, pageSize int, condition interface{}, args ...interface{}) ([]T, error)
 	// BulkInsert inserts multiple entities at once.
 	BulkInsert(entities []*T) error
+	// InsertEach inserts the entities that can be inserted and reports each of them.
+	InsertEach(entities []*T) (*BatchResult, error)
 	// BulkUpdate updates multiple entities based on the given condition with provided update data.
 	BulkUpdate(condition interface{}, args []interface{}, updateData interface{}) error
+	// Aggregate groups the entities and computes the measures of the groups.
+	Aggregate(stats Stats[T]) ([]StatsRow, error)
 	// ExecuteInTransaction executes the provided function within a transaction.
 	ExecuteInTransaction(fn func(tx *gorm.DB) error) error
 }
 
+
+// WithContext binds repo to ctx when it can be bound, see GenericRepository.WithContext.
+// Repositories that can't are returned as they are.
+func WithContext[T any](repo Interface[T], ctx context.Context) Interface[T] {
+	switch repo := repo.(type) {
+	case interface {
+		WithContext(ctx context.Context) *GenericRepository[T]
+	}:
+		return repo.WithContext(ctx)
+	case interface {
+		WithContext(ctx context.Context) Interface[T]
+	}:
+		return repo.WithContext(ctx)
+	}
+	return repo
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -19,12 +21,26 @@ func NewGenericService[T any](repo repository.Interface[T]) *GenericService[T] {
 	}
 }
 
+// WithContext returns a copy of the service whose repository runs queries with ctx.
+// Repositories that can't be bound to a context are used as they are.
+func (s *GenericService[T]) WithContext(ctx context.Context) *GenericService[T] {
+	return &GenericService[T]{
+		Repo: repository.WithContext(s.Repo, ctx),
+	}
+}
+
 // Create creates a new entity using the underlying repository.
 // It ignores the claims parameter.
 func (s *GenericService[T]) Create(claims jwt.MapClaims, entity *T) (*T, error) {
 	return s.Repo.Create(entity)
 }
 
+// CreateContext creates a new entity with the repository bound to ctx.
+// Services overriding Create override it too, the generic handler calls it.
+func (s *GenericService[T]) CreateContext(ctx context.Context, claims jwt.MapClaims, entity *T) (*T, error) {
+	return s.WithContext(ctx).Create(claims, entity)
+}
+
 // GetByID retrieves an entity by its unique identifier using the underlying repository.
 // It ignores the claims parameter.
 func (s *GenericService[T]) GetByID(claims jwt.MapClaims, id int) (*T, error) {
@@ -37,12 +53,22 @@ func (s *GenericService[T]) Update(claims jwt.MapClaims, entity *T) (*T, error)
 	return s.Repo.Update(entity)
 }
 
+// UpdateContext updates an existing entity with the repository bound to ctx.
+func (s *GenericService[T]) UpdateContext(ctx context.Context, claims jwt.MapClaims, entity *T) (*T, error) {
+	return s.WithContext(ctx).Update(claims, entity)
+}
+
 // Delete removes an entity identified by its unique identifier using the underlying repository.
 // It ignores the claims parameter.
 func (s *GenericService[T]) Delete(claims jwt.MapClaims, id int) error {
 	return s.Repo.Delete(id)
 }
 
+// DeleteContext removes an entity by its identifier with the repository bound to ctx.
+func (s *GenericService[T]) DeleteContext(ctx context.Context, claims jwt.MapClaims, id int) error {
+	return s.WithContext(ctx).Delete(claims, id)
+}
+
 // GetAll retrieves all entities from the underlying repository.
 // It ignores the claims parameter.
 func (s *GenericService[T]) GetAll(claims jwt.MapClaims) ([]T, error) {
@@ -85,6 +111,18 @@ func (s *GenericService[T]) BulkInsert(claims jwt.MapClaims, entities []*T) erro
 	return s.Repo.BulkInsert(entities)
 }
 
+// InsertEach inserts the entities that can be inserted and reports each of them using the underlying repository.
+// It ignores the claims parameter.
+func (s *GenericService[T]) InsertEach(claims jwt.MapClaims, entities []*T) (*repository.BatchResult, error) {
+	return s.Repo.InsertEach(entities)
+}
+
+// Aggregate groups the entities and computes the measures of the groups using the underlying repository.
+// It ignores the claims parameter.
+func (s *GenericService[T]) Aggregate(claims jwt.MapClaims, stats repository.Stats[T]) ([]repository.StatsRow, error) {
+	return s.Repo.Aggregate(stats)
+}
+
 // BulkUpdate updates multiple entities that match the specified condition using the underlying repository.
 // It ignores the claims parameter.
 func (s *GenericService[T]) BulkUpdate(claims jwt.MapClaims, condition interface{}, args []interface{}, updateData interface{}) error {
@@ -1,17 +1,28 @@
 package service
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"context"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+	"github.com/golang-jwt/jwt/v5"
+)
 
 // Interface defines all the generic service operations for type T.
 type Interface[T any] interface {
 	// Create creates a new entity.
 	Create(claims jwt.MapClaims, entity *T) (*T, error)
+	// CreateContext creates a new entity as part of the request in ctx.
+	CreateContext(ctx context.Context, claims jwt.MapClaims, entity *T) (*T, error)
 	// GetByID retrieves an entity by its id.
 	GetByID(claims jwt.MapClaims, id int) (*T, error)
 	// Update updates an existing entity.
 	Update(claims jwt.MapClaims, entity *T) (*T, error)
+	// UpdateContext updates an existing entity as part of the request in ctx.
+	UpdateContext(ctx context.Context, claims jwt.MapClaims, entity *T) (*T, error)
 	// Delete deletes an entity by its id.
 	Delete(claims jwt.MapClaims, id int) error
+	// DeleteContext deletes an entity by its id as part of the request in ctx.
+	DeleteContext(ctx context.Context, claims jwt.MapClaims, id int) error
 	// GetAll retrieves all entities.
 	GetAll(claims jwt.MapClaims, ) ([]T, error)
 	// DeleteWhere deletes entities matching the given condition.
@@ -26,6 +37,10 @@ type Interface[T any] interface {
 	GetPage(claims jwt.MapClaims, page int, pageSize int, condition interface{}, args ...interface{}) ([]T, error)
 	// BulkInsert inserts multiple entities at once.
 	BulkInsert(claims jwt.MapClaims, entities []*T) error
+	// InsertEach inserts the entities that can be inserted and reports each of them.
+	InsertEach(claims jwt.MapClaims, entities []*T) (*repository.BatchResult, error)
+	// Aggregate groups the entities and computes the measures of the groups.
+	Aggregate(claims jwt.MapClaims, stats repository.Stats[T]) ([]repository.StatsRow, error)
 	// BulkUpdate updates multiple entities based on the given condition.
 	BulkUpdate(claims jwt.MapClaims, condition interface{}, args []interface{}, updateData interface{}) error
 }
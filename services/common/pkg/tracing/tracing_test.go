@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/logger"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+func TestMiddleware_NamesSpanAfterRoute(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	var logs bytes.Buffer
+	log := slog.New(logger.WithTrace(slog.NewJSONHandler(&logs, nil)))
+
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/api/v1/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		log.InfoContext(r.Context(), "handling")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/items/42", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /api/v1/items/{id}", spans[0].Name())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", spans[0].SpanContext().TraceID().String())
+	assert.Contains(t, logs.String(), `"trace_id":"0af7651916cd43dd8448eb211c80319c"`)
+	assert.Contains(t, logs.String(), `"span_id":"`+spans[0].SpanContext().SpanID().String()+`"`)
+}
+
+type tracedModel struct {
+	ID   uint `gorm:"primaryKey"`
+	Name string
+}
+
+func TestInstrumentDB_RecordsQueriesInTrace(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&tracedModel{}))
+	require.NoError(t, InstrumentDB(db))
+
+	// Outside of a trace nothing is recorded
+	require.NoError(t, db.Create(&tracedModel{Name: "a"}).Error)
+	assert.Empty(t, recorder.Ended())
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	var found tracedModel
+	require.NoError(t, db.WithContext(ctx).First(&found, "name = ?", "a").Error)
+	require.Error(t, db.WithContext(ctx).Exec("SELECT * FROM missing_table").Error)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	query, raw := spans[0], spans[1]
+	assert.Equal(t, "gorm.query", query.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+	assert.Contains(t, fmt.Sprint(query.Attributes()), "traced_models")
+	assert.Equal(t, "gorm.raw", raw.Name())
+	assert.Len(t, raw.Events(), 1, "the error should be recorded")
+}
@@ -1,40 +1,32 @@
 package main
 
 import (
-	"context"
+	"event-service/internal/config"
 	grpcserver "event-service/internal/grpc-server"
 	"event-service/internal/handler"
-	"event-service/internal/models"
+	"event-service/internal/migrations"
 	"event-service/internal/repository"
 	"event-service/internal/service"
-	"fmt"
-	"log/slog"
-	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-    "github.com/prometheus/client_golang/prometheus/promhttp"
 	"time"
 
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/app"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
-	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/config"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/cache"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/grpcauth"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/logger"
-	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/middlewarelogger"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/protos/events"
 )
 
 
 func main(){
-    cfg := config.MustLoadConfig()
-    log := logger.SetupLogger(cfg.Env)
-    log.Info("Connecting to db with params")
-    log.Info("Database: ", slog.String("host", cfg.Database.Host), slog.String("port", cfg.Database.Port))
+    a := app.New("event-service")
+    cfg, log := a.Config, a.Log
+    var eventCfg config.Config
+    a.MustLoadSection("events", &eventCfg)
 
-    dsn := fmt.Sprintf("user=%s password=%s dbname=%s host=%s port=%s sslmode=%s",cfg.Database.User, cfg.Database.Password, cfg.Database.Name, cfg.Database.Host, cfg.Database.Port, "disable")
-    dbConnection := db.SetupDB(dsn, &models.Event{})
+    dbConnection := a.MustConnectDB(migrations.FS)
     eventRepo := repository.NewEventRepository(dbConnection)
     verifier, err := auth.NewVerifier(cfg.PublicKey)
     if err != nil {
@@ -42,76 +34,62 @@ func main(){
         panic("failed to init JWT verifier")
     }
 
-    eventService := service.NewEventService(eventRepo)
+    eventService := service.NewEventService(eventRepo, db.NewUnitOfWork(dbConnection), a.Outbox(dbConnection))
+    switch eventCfg.Cache.Backend {
+    case "memory":
+        eventService = eventService.WithCache(cache.NewLRU(eventCfg.Cache.Size), cache.Options{Prefix: "events", TTL: eventCfg.Cache.TTL, Log: log})
+    case "redis":
+        redis := cache.DialRedis(eventCfg.Cache.RedisAddr, eventCfg.Cache.RedisPassword, 0)
+        a.OnClose("cache", redis)
+        eventService = eventService.WithCache(redis, cache.Options{Prefix: "events", TTL: eventCfg.Cache.TTL, Log: log})
+    }
 
 
     // ---------------GRPC SERVER------------------------
 
-    grpcApp := grpcserver.New(eventService, log, cfg.GRPC.Server.Port) 
+    grpcserver.Register(a.GRPC(), eventService)
+    // seats are only reserved and released by registration-service for the user it forwards the token of
+    reservations := grpcauth.Rule{User: true, Services: eventCfg.ReservationClients}
+    for _, method := range []string{events.EventService_CheckAndReserve_FullMethodName, events.EventService_RemoveRegistration_FullMethodName} {
+        a.AllowGRPC(method, eventCfg.ReservationClients...)
+        a.AuthorizeGRPC(method, reservations)
+    }
 
-    go grpcApp.MustRun()
-    
 
-    // go prometheus metrics
-    go func (){
-        http.Handle("/metrics", promhttp.Handler())
-        http.ListenAndServe(":9100", nil)
-    }()
    // -----------------HTTP SERVER------------------------ 
 
-    handler := handler.NewEventHandler(eventService, verifier)
+    defaultTimeZone, err := time.LoadLocation(eventCfg.Calendar.DefaultTimeZone)
+    if err != nil {
+        log.Error("failed to load default time zone", logger.Err(err))
+        panic("failed to load default time zone")
+    }
+    calendarOpts := handler.CalendarOptions{
+        PublicURL:       eventCfg.Calendar.PublicURL,
+        DefaultTimeZone: defaultTimeZone,
+    }
 
-    router := chi.NewRouter()
-    router.Use(middleware.RequestID)
-    router.Use(middleware.RealIP)
-    router.Use(middlewarelogger.New(log))
-    router.Use(middleware.Recoverer)
-    router.Use(middleware.URLFormat)
+    handler := handler.NewEventHandler(eventService, verifier)
+    handler.CacheMaxAge = eventCfg.Cache.MaxAge
 
+    // the writes of many events at once skip the checks of the service on each of them
+    router := a.Router
     router.Post("/api/v1/events", handler.CreateHandler())
     router.Get("/api/v1/events", handler.GetAllHandler())
-    router.Get("/api/v1/events/{id}", handler.GetByIDHandler())
+    router.Get("/api/v1/events/{id}", handler.WithICal(handler.GetByIDHandler(), calendarOpts))
     router.Put("/api/v1/events", handler.UpdateHandler())
     router.Delete("/api/v1/events/{id}", handler.DeleteHandler())
-    router.Delete("/api/v1/events", handler.DeleteWhereHandler())
+    router.With(verifier.RequirePermission(auth.PermEventsDeleteAny)).Delete("/api/v1/events", handler.DeleteWhereHandler())
     router.Get("/api/v1/events/search", handler.FindHandler())
     router.Get("/api/v1/events/search/first", handler.FindFirstHandler())
     router.Get("/api/v1/events/count", handler.CountHandler())
+    router.Get("/api/v1/events/stats", handler.StatsHandler())
     router.Get("/api/v1/events/page", handler.GetPageHandler())
-    router.Post("/api/v1/events/bulk", handler.BulkInsertHandler())
-    router.Put("/api/v1/events/bulk", handler.BulkUpdateHandler())
+    router.Get("/api/v1/events/organizations/{orgID}", handler.OrganizationEventsHandler())
+    router.With(verifier.RequirePermission(auth.PermEventsBulk)).Post("/api/v1/events/bulk", handler.BulkInsertHandler())
+    router.With(verifier.RequirePermission(auth.PermEventsUpdateAny)).Put("/api/v1/events/bulk", handler.BulkUpdateHandler())
 
 
-
-    srv := &http.Server{
-        Addr: fmt.Sprintf("%s:%d",cfg.Server.Addr, cfg.Server.Port),
-        Handler: router,
-        ReadTimeout: cfg.Server.ReadTimeout,
-        WriteTimeout: cfg.Server.WriteTimeout,
-        IdleTimeout: cfg.Server.IdleTimeout,
+    if err := a.Run(); err != nil {
+        os.Exit(1)
     }
-
-    go func() {
-        log.Info(fmt.Sprintf("Server listening on port %d", cfg.Server.Port))
-        if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-            log.Error("failed to start server", logger.Err(err))
-        }
-    }()
-
-    // ----------- STOP PROGRAM ---------------
-
-    stop := make(chan os.Signal, 1)
-    signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
-    <-stop
-    ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Error("HTTP server Shutdown error", logger.Err(err))
-	} else {
-		log.Info("HTTP server gracefully stopped")
-	}
-
-	grpcApp.Stop()
-	log.Info("gRPC server gracefully stopped")
-   
 }
@@ -0,0 +1,26 @@
+package config
+
+import "time"
+
+// Config is the event-service section of the config file, under the `events` key.
+// Shared settings like the server and database are in the common config.
+type Config struct {
+    Calendar struct {
+        // PublicURL is the external address of the site, used in event UIDs
+        PublicURL       string `yaml:"public_url" envconfig:"CALENDAR_PUBLIC_URL" default:"https://localhost" validate:"required"`
+        DefaultTimeZone string `yaml:"default_time_zone" envconfig:"CALENDAR_DEFAULT_TIME_ZONE" default:"Asia/Novosibirsk" validate:"required"`
+    } `yaml:"calendar"`
+    // Cache keeps event reads for TTL, in every instance with memory or shared by all with redis
+    Cache struct {
+        Backend       string        `yaml:"backend" envconfig:"CACHE_BACKEND" default:"memory" validate:"oneof=none memory redis"`
+        Size          int           `yaml:"size" envconfig:"CACHE_SIZE" default:"10000" validate:"min=1"`
+        TTL           time.Duration `yaml:"ttl" envconfig:"CACHE_TTL" default:"30s" validate:"min=1s"`
+        RedisAddr     string        `yaml:"redis_addr" envconfig:"CACHE_REDIS_ADDR" default:"localhost:6379"`
+        RedisPassword string        `yaml:"redis_password" envconfig:"CACHE_REDIS_PASSWORD" secret:"true"`
+        // MaxAge is how long browsers may reuse an event or the list, 0 doesn't let them
+        MaxAge        time.Duration `yaml:"max_age" envconfig:"CACHE_MAX_AGE" default:"10s"`
+    } `yaml:"cache"`
+    // ReservationClients are the services allowed to reserve and release seats over gRPC,
+    // by their service tokens and, when GRPC_SERVER_USE_TLS is set, their certificate names
+    ReservationClients []string `yaml:"reservation_clients" envconfig:"GRPC_RESERVATION_CLIENTS" default:"registration-service" validate:"required"`
+}
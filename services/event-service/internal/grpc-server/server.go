@@ -2,12 +2,26 @@ package grpcserver
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"event-service/internal/models"
+	"event-service/internal/repository"
 	"event-service/internal/service"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/grpcauth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/grpcerr"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/metrics"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/protos/events"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
 )
 
 type serverAPI struct {
@@ -21,33 +35,82 @@ func Register(gRPC *grpc.Server, service *service.EventService) {
 
 
 
-func (s *serverAPI) CheckAndReserve(ctx context.Context, req *events.CheckAndReserveRequest) (*events.CheckAndReserveResponse, error) {
-    event, err := s.service.GetByID(nil, int(req.EventId))
+// actingUser returns the user the call is made for, taken from the access token verified
+// by the grpcauth interceptor rather than from the request, which anyone can fill in.
+func actingUser(ctx context.Context) (string, error) {
+    principal, _ := grpcauth.FromContext(ctx)
+    username := strings.TrimSpace(principal.Username())
+    if username == "" {
+        return "", status.Error(codes.PermissionDenied, "the call is not made for a user")
+    }
+    return username, nil
+}
+
+// errorDomain is the domain of the ErrorInfo of failed calls
+var errorDomain = events.EventService_ServiceDesc.ServiceName
+
+// failure returns the error of a call on the event that failed for reason
+func failure(code codes.Code, reason events.ReserveStatus, eventID uint32, msg string) error {
+    return grpcerr.WithInfo(code, errorDomain, reason.String(), msg, map[string]string{
+        "event_id": strconv.FormatUint(uint64(eventID), 10),
+    })
+}
+
+// getEvent loads the event, a missing one is NOT_FOUND and a failed query INTERNAL
+func (s *serverAPI) getEvent(ctx context.Context, eventID uint32) (*models.Event, error) {
+    event, err := s.service.WithContext(ctx).GetByID(nil, int(eventID))
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, failure(codes.NotFound, events.ReserveStatus_EVENT_NOT_FOUND, eventID, fmt.Sprintf("event %d not found", eventID))
+    }
     if err != nil {
-        return &events.CheckAndReserveResponse{
-            Status: events.ReserveStatus_EVENT_NOT_FOUND,
-        }, nil
+        return nil, failure(codes.Internal, events.ReserveStatus_INTERNAL_ERROR, eventID, "failed to get the event")
     }
+    return event, nil
+}
 
-    if strings.TrimSpace(req.Username) == strings.TrimSpace(event.CreatedBy) {
-        fmt.Println("HELLLLOO")
-        return &events.CheckAndReserveResponse{
-            Status: events.ReserveStatus_RESERVE_STATUS_UNSPECIFIED,
-        }, nil
+func (s *serverAPI) CheckAndReserve(ctx context.Context, req *events.CheckAndReserveRequest) (*events.CheckAndReserveResponse, error) {
+    resp, err := s.checkAndReserve(ctx, req)
+    switch status.Code(err) {
+    case codes.OK:
+        metrics.Reservations.WithLabelValues(metrics.ResultSucceeded).Inc()
+    case codes.FailedPrecondition:
+        if grpcerr.Reason(err, errorDomain) == events.ReserveStatus_EVENT_FULL.String() {
+            metrics.Reservations.WithLabelValues(metrics.ResultFull).Inc()
+            break
+        }
+        fallthrough
+    default:
+        metrics.Reservations.WithLabelValues(metrics.ResultFailed).Inc()
     }
+    return resp, err
+}
 
+func (s *serverAPI) checkAndReserve(ctx context.Context, req *events.CheckAndReserveRequest) (*events.CheckAndReserveResponse, error) {
+    // the seats are counted from the stored event, which may have been created just before
+    ctx = db.ReadYourWrites(ctx)
+    username, err := actingUser(ctx)
+    if err != nil {
+        return nil, err
+    }
+    event, err := s.getEvent(ctx, req.EventId)
+    if err != nil {
+        return nil, err
+    }
+
+    if username == strings.TrimSpace(event.CreatedBy) {
+        return nil, failure(codes.FailedPrecondition, events.ReserveStatus_ORGANIZER_CANNOT_REGISTER, req.EventId, "the organizer cannot register for their own event")
+    }
+    if event.Status == "cancelled" {
+        return nil, failure(codes.FailedPrecondition, events.ReserveStatus_EVENT_CANCELLED, req.EventId, fmt.Sprintf("event %d is cancelled", req.EventId))
+    }
     if event.Participants >= event.MaxParticipants {
-        return &events.CheckAndReserveResponse{
-            Status: events.ReserveStatus_EVENT_FULL,
-        }, nil
+        return nil, failure(codes.FailedPrecondition, events.ReserveStatus_EVENT_FULL, req.EventId, fmt.Sprintf("event %d is full", req.EventId))
     }
 
     event.Participants = event.Participants + 1
-    updatedEvent, err := s.service.Update(nil, event)
+    updatedEvent, err := s.service.WithContext(ctx).UpdateParticipants(event)
     if err != nil {
-        return &events.CheckAndReserveResponse {
-            Status: events.ReserveStatus_INTERNAL_ERROR,
-        }, nil
+        return nil, failure(codes.Internal, events.ReserveStatus_INTERNAL_ERROR, req.EventId, "failed to reserve the seat")
     }
 
     return &events.CheckAndReserveResponse{
@@ -57,34 +120,233 @@ func (s *serverAPI) CheckAndReserve(ctx context.Context, req *events.CheckAndRes
 }
 
 func (s *serverAPI) RemoveRegistration(ctx context.Context, req *events.RemoveRegistrationRequest) (*events.RemoveRegistrationResponse, error) {
-    event, err := s.service.GetByID(nil, int(req.EventId))
+    ctx = db.ReadYourWrites(ctx)
+    username, err := actingUser(ctx)
     if err != nil {
-        return &events.RemoveRegistrationResponse{
-            Status: events.ReserveStatus_EVENT_NOT_FOUND,
-        }, nil
+        return nil, err
     }
-
-    if strings.TrimSpace(req.Username) == strings.TrimSpace(event.CreatedBy) {
-        return &events.RemoveRegistrationResponse{
-            Status: events.ReserveStatus_RESERVE_STATUS_UNSPECIFIED,
-        }, nil
+    event, err := s.getEvent(ctx, req.EventId)
+    if err != nil {
+        return nil, err
     }
 
+    if username == strings.TrimSpace(event.CreatedBy) {
+        return nil, failure(codes.FailedPrecondition, events.ReserveStatus_ORGANIZER_CANNOT_REGISTER, req.EventId, "the organizer has no registration to remove")
+    }
     if event.Participants < 1 {
-        return &events.RemoveRegistrationResponse{
-            Status: events.ReserveStatus_RESERVE_STATUS_UNSPECIFIED,
-        }, nil
+        return nil, failure(codes.FailedPrecondition, events.ReserveStatus_NO_PARTICIPANTS, req.EventId, fmt.Sprintf("event %d has no participants", req.EventId))
     }
 
     event.Participants = event.Participants - 1
-    _, err = s.service.Update(nil, event)
+    _, err = s.service.WithContext(ctx).UpdateParticipants(event)
     if err != nil {
-        return &events.RemoveRegistrationResponse {
-            Status: events.ReserveStatus_INTERNAL_ERROR,
-        }, nil
+        return nil, failure(codes.Internal, events.ReserveStatus_INTERNAL_ERROR, req.EventId, "failed to free the seat")
     }
 
     return &events.RemoveRegistrationResponse{
         Status: events.ReserveStatus_SUCCESS,
     }, nil
 }
+
+func (s *serverAPI) GetEvent(ctx context.Context, req *events.GetEventRequest) (*events.GetEventResponse, error) {
+    event, err := s.getEvent(ctx, req.EventId)
+    if err != nil {
+        return nil, err
+    }
+
+    return &events.GetEventResponse{
+        Status: events.ReserveStatus_SUCCESS,
+        Event:  toProto(event),
+    }, nil
+}
+
+// maxBatchSize limits the number of events requested at once
+const maxBatchSize = 500
+
+// BatchGetEvents returns the requested events that exist, in no particular order.
+// Deleted events are returned cancelled, for the calendars showing them.
+func (s *serverAPI) BatchGetEvents(ctx context.Context, req *events.BatchGetEventsRequest) (*events.BatchGetEventsResponse, error) {
+    if len(req.EventIds) > maxBatchSize {
+        return nil, grpcerr.BadRequest("too many events requested", grpcerr.Violation{
+            Field:       "event_ids",
+            Description: fmt.Sprintf("at most %d events can be requested at once", maxBatchSize),
+        })
+    }
+    if len(req.EventIds) == 0 {
+        return &events.BatchGetEventsResponse{}, nil
+    }
+
+    ids := make([]uint, len(req.EventIds))
+    for i, id := range req.EventIds {
+        ids[i] = uint(id)
+    }
+    found, err := s.service.WithContext(ctx).FindWithDeleted(ids)
+    if err != nil {
+        return nil, status.Error(codes.Internal, "failed to get events")
+    }
+
+    resp := &events.BatchGetEventsResponse{Events: make([]*events.Event, 0, len(found))}
+    for i := range found {
+        resp.Events = append(resp.Events, toProto(&found[i]))
+    }
+    return resp, nil
+}
+
+// Page sizes of ListEvents
+const (
+    defaultPageSize = 50
+    maxPageSize     = 500
+)
+
+// ListEvents returns a page of the events matching the filters in start time order.
+func (s *serverAPI) ListEvents(ctx context.Context, req *events.ListEventsRequest) (*events.ListEventsResponse, error) {
+    var violations []grpcerr.Violation
+    pageSize := int(req.PageSize)
+    switch {
+    case pageSize == 0:
+        pageSize = defaultPageSize
+    case pageSize > maxPageSize:
+        violations = append(violations, grpcerr.Violation{Field: "page_size", Description: fmt.Sprintf("must be at most %d", maxPageSize)})
+    }
+    after, err := decodePageToken(req.PageToken)
+    if err != nil {
+        violations = append(violations, grpcerr.Violation{Field: "page_token", Description: "not a token returned by ListEvents"})
+    }
+    if req.StartsAfter != nil && req.StartsBefore != nil && !req.StartsAfter.AsTime().Before(req.StartsBefore.AsTime()) {
+        violations = append(violations, grpcerr.Violation{Field: "starts_before", Description: "must be after starts_after"})
+    }
+    if len(violations) > 0 {
+        return nil, grpcerr.BadRequest("invalid ListEvents request", violations...)
+    }
+
+    filter := repository.ListFilter{
+        Category:  req.Category,
+        City:      req.City,
+        Status:    req.Status,
+        CreatedBy: req.CreatedBy,
+    }
+    if req.StartsAfter != nil {
+        filter.StartsAfter = req.StartsAfter.AsTime()
+    }
+    if req.StartsBefore != nil {
+        filter.StartsBefore = req.StartsBefore.AsTime()
+    }
+
+    // one more than asked tells whether there is a next page
+    found, err := s.service.WithContext(ctx).List(filter, after, pageSize+1)
+    if err != nil {
+        return nil, status.Error(codes.Internal, "failed to list events")
+    }
+
+    resp := &events.ListEventsResponse{}
+    if len(found) > pageSize {
+        found = found[:pageSize]
+        last := found[pageSize-1]
+        resp.NextPageToken = encodePageToken(repository.Cursor{StartTime: last.StartTime, ID: last.ID})
+    }
+    resp.Events = make([]*events.Event, 0, len(found))
+    for i := range found {
+        resp.Events = append(resp.Events, toProto(&found[i]))
+    }
+    return resp, nil
+}
+
+// encodePageToken makes the opaque page token of the cursor
+func encodePageToken(cursor repository.Cursor) string {
+    raw := strconv.FormatInt(cursor.StartTime.UnixNano(), 10) + ":" + strconv.FormatUint(uint64(cursor.ID), 10)
+    return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodePageToken returns the cursor of the token, nil for the first page
+func decodePageToken(token string) (*repository.Cursor, error) {
+    if token == "" {
+        return nil, nil
+    }
+    raw, err := base64.RawURLEncoding.DecodeString(token)
+    if err != nil {
+        return nil, err
+    }
+    startTime, id, ok := strings.Cut(string(raw), ":")
+    if !ok {
+        return nil, fmt.Errorf("no id in page token")
+    }
+    nanos, err := strconv.ParseInt(startTime, 10, 64)
+    if err != nil {
+        return nil, err
+    }
+    eventID, err := strconv.ParseUint(id, 10, 32)
+    if err != nil {
+        return nil, err
+    }
+    return &repository.Cursor{StartTime: time.Unix(0, nanos), ID: uint(eventID)}, nil
+}
+
+// WatchEvent sends the current state of the event and then its changes until the event
+// is deleted or the caller goes away. A watcher that can't keep up gets Aborted and
+// should watch again.
+func (s *serverAPI) WatchEvent(req *events.WatchEventRequest, stream events.EventService_WatchEventServer) error {
+    ctx := stream.Context()
+    // watch before reading the event, so no change made in between is missed
+    changes, stop := s.service.Watch(uint(req.EventId))
+    defer stop()
+
+    event, err := s.getEvent(ctx, req.EventId)
+    if err != nil {
+        return err
+    }
+    if err := stream.Send(&events.EventChange{Type: events.ChangeType_CHANGE_TYPE_CURRENT, Event: toProto(event)}); err != nil {
+        return err
+    }
+
+    for {
+        select {
+        case <-ctx.Done():
+            return nil
+        case change, ok := <-changes:
+            if !ok {
+                return grpcerr.WithInfo(codes.Aborted, errorDomain, "WATCHER_FELL_BEHIND", "the watcher fell behind the changes, watch again", nil)
+            }
+            if err := stream.Send(&events.EventChange{Type: changeTypes[change.Type], Event: toProto(&change.Event)}); err != nil {
+                return err
+            }
+            if change.Type == service.ChangeDeleted {
+                return nil
+            }
+        }
+    }
+}
+
+var changeTypes = map[service.ChangeType]events.ChangeType{
+    service.ChangeUpdated:      events.ChangeType_CHANGE_TYPE_UPDATED,
+    service.ChangeParticipants: events.ChangeType_CHANGE_TYPE_PARTICIPANTS,
+    service.ChangeCancelled:    events.ChangeType_CHANGE_TYPE_CANCELLED,
+    service.ChangeDeleted:      events.ChangeType_CHANGE_TYPE_DELETED,
+}
+
+func toProto(event *models.Event) *events.Event {
+    var orgID uint32
+    if event.OrganizationID != nil {
+        orgID = uint32(*event.OrganizationID)
+    }
+    return &events.Event{
+        Id:              uint32(event.ID),
+        Name:            event.Name,
+        CreatedBy:       event.CreatedBy,
+        Status:          event.Status,
+        Participants:    uint32(event.Participants),
+        MaxParticipants: uint32(event.MaxParticipants),
+        Description:     event.Description,
+        Category:        event.Category,
+        City:            event.City,
+        Address:         event.Address,
+        Latitude:        event.Latitude,
+        Longitude:       event.Longitude,
+        StartTime:       timestamppb.New(event.StartTime),
+        EndTime:         timestamppb.New(event.EndTime),
+        TimeZone:        event.TimeZone,
+        Sequence:        uint32(event.Sequence),
+        CreatedAt:       timestamppb.New(event.CreatedAt),
+        UpdatedAt:       timestamppb.New(event.UpdatedAt),
+        OrganizationId:  orgID,
+    }
+}
@@ -1,19 +1,68 @@
 package handler
 
 import (
+	"encoding/json"
 	"event-service/internal/models"
+	"event-service/internal/repository"
 	"event-service/internal/service"
+	"net/http"
+	"strconv"
 
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/handler"
+	"github.com/go-chi/chi/v5"
 )
 
 type EventHandler struct {
     *handler.GenericHandler[models.Event]
+    events *service.EventService
 }
 
 func NewEventHandler(service *service.EventService, verifier *auth.Verifier) *EventHandler {
+    generic := handler.NewGenericHandler[models.Event](service, verifier)
+    generic.StatsFields = repository.EventStatsFields
     return &EventHandler{
-        GenericHandler: handler.NewGenericHandler[models.Event](service, verifier),
+        GenericHandler: generic,
+        events:         service,
+    }
+}
+
+// OrganizationEventsHandler returns a page of the events of an organization in start
+// time order, page and pageSize default to the first 20 events
+func (h *EventHandler) OrganizationEventsHandler() http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        if _, err := h.CheckToken(r); err != nil {
+            http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+            return
+        }
+
+        orgID, err := strconv.ParseUint(chi.URLParam(r, "orgID"), 10, 0)
+        if err != nil || orgID == 0 {
+            http.Error(w, "Invalid orgID parameter", http.StatusBadRequest)
+            return
+        }
+        page, pageSize := 1, 20
+        q := r.URL.Query()
+        if value := q.Get("page"); value != "" {
+            if page, err = strconv.Atoi(value); err != nil || page < 1 {
+                http.Error(w, "Invalid page parameter", http.StatusBadRequest)
+                return
+            }
+        }
+        if value := q.Get("pageSize"); value != "" {
+            if pageSize, err = strconv.Atoi(value); err != nil || pageSize < 1 || pageSize > 100 {
+                http.Error(w, "Invalid pageSize parameter", http.StatusBadRequest)
+                return
+            }
+        }
+
+        events, err := h.events.ListByOrganization(uint(orgID), page, pageSize)
+        if err != nil {
+            http.Error(w, "Error retrieving events: "+err.Error(), http.StatusInternalServerError)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(events)
     }
 }
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"event-service/internal/models"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/ical"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"gorm.io/gorm"
+)
+
+// CalendarOptions configures iCalendar export of events
+type CalendarOptions struct {
+	PublicURL       string
+	DefaultTimeZone *time.Location
+}
+
+// WithICal serves "{id}.ics" requests with the iCalendar export and the rest with next.
+func (h *EventHandler) WithICal(next http.HandlerFunc, opts CalendarOptions) http.HandlerFunc {
+	export := h.ICalHandler(opts)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string); format == "ics" {
+			export(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// ICalHandler returns a single event as an iCalendar file.
+func (h *EventHandler) ICalHandler(opts CalendarOptions) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid id parameter", http.StatusBadRequest)
+			return
+		}
+
+		event, err := h.Service.GetByID(claims, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Event not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error retrieving entity: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		calendar := &ical.Calendar{Events: []ical.Event{ICalEvent(event, opts)}}
+		w.Header().Set("Content-Type", ical.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="event-%d.ics"`, event.ID))
+		if err := calendar.Write(w); err != nil {
+			http.Error(w, "Error writing calendar: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// ICalEvent converts the event to a VEVENT in the time zone of the event.
+func ICalEvent(event *models.Event, opts CalendarOptions) ical.Event {
+	loc := opts.DefaultTimeZone
+	if event.TimeZone != "" {
+		if l, err := time.LoadLocation(event.TimeZone); err == nil {
+			loc = l
+		}
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var categories []string
+	if event.Category != "" {
+		categories = []string{event.Category}
+	}
+
+	return ical.Event{
+		UID:          ical.EventUID(event.ID, opts.PublicURL),
+		Sequence:     event.Sequence,
+		Summary:      event.Name,
+		Description:  event.Description,
+		Location:     ical.JoinLocation(event.Address, event.City),
+		Categories:   categories,
+		Latitude:     event.Latitude,
+		Longitude:    event.Longitude,
+		Start:        event.StartTime.In(loc),
+		End:          event.EndTime.In(loc),
+		Status:       ical.EventStatus(event.Status),
+		Created:      event.CreatedAt,
+		LastModified: event.UpdatedAt,
+	}
+}
@@ -0,0 +1,28 @@
+-- the schema AutoMigrate created, existing databases are left as they are
+CREATE TABLE IF NOT EXISTS events (
+    id bigserial PRIMARY KEY,
+    name varchar(255) NOT NULL,
+    description text,
+    category varchar(100),
+    participants bigint DEFAULT 0 CONSTRAINT chk_events_participants CHECK (participants >= 0),
+    max_participants bigint DEFAULT 100 CONSTRAINT chk_events_max_participants CHECK (max_participants >= 1),
+    image_data text,
+    city varchar(100) NOT NULL,
+    address varchar(255),
+    latitude double precision CONSTRAINT chk_events_latitude CHECK (latitude >= -90 AND latitude <= 90),
+    longitude double precision CONSTRAINT chk_events_longitude CHECK (longitude >= -180 AND longitude <= 180),
+    start_time timestamptz NOT NULL,
+    end_time timestamptz NOT NULL,
+    status varchar(50) NOT NULL DEFAULT 'active',
+    time_zone varchar(64),
+    sequence bigint NOT NULL DEFAULT 0,
+    created_by text NOT NULL,
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_events_name ON events (name);
+CREATE INDEX IF NOT EXISTS idx_events_category ON events (category);
+CREATE INDEX IF NOT EXISTS idx_events_city ON events (city);
+CREATE INDEX IF NOT EXISTS idx_events_start_time ON events (start_time);
+CREATE INDEX IF NOT EXISTS idx_events_end_time ON events (end_time);
+CREATE INDEX IF NOT EXISTS idx_events_created_by ON events (created_by);
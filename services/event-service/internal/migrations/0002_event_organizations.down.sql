@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_events_organization_id;
+ALTER TABLE events DROP COLUMN IF EXISTS organization_id;
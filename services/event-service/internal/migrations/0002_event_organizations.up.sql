@@ -0,0 +1,2 @@
+ALTER TABLE events ADD COLUMN IF NOT EXISTS organization_id bigint;
+CREATE INDEX IF NOT EXISTS idx_events_organization_id ON events (organization_id);
@@ -0,0 +1,12 @@
+-- events published with the changes they tell about, relayed to kafka by eventbus.Relay
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id bigserial PRIMARY KEY,
+    envelope_id varchar(64) NOT NULL,
+    type varchar(100) NOT NULL,
+    partition_key varchar(255),
+    envelope bytea NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    last_error text,
+    created_at timestamptz
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_events_deleted_at;
+ALTER TABLE events DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,2 @@
+ALTER TABLE events ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_events_deleted_at ON events (deleted_at);
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Event is a struct desribing an event 
@@ -24,10 +26,21 @@ type Event struct {
     StartTime       time.Time `gorm:"not null;index" json:"start_time"`
     EndTime         time.Time `gorm:"not null;index" json:"end_time"`
     Status          string    `gorm:"type:varchar(50);not null;default:'active'" json:"status"`
+    // IANA time zone of the event, e.g. Asia/Novosibirsk. Empty means the default one
+    TimeZone        string    `gorm:"type:varchar(64)" json:"time_zone,omitempty"`
+    // Sequence grows on every update, so that calendar apps pick up changes
+    Sequence        int       `gorm:"not null;default:0" json:"sequence"`
 
     CreatedBy       string    `gorm:"not null;index" json:"created_by"`
+    // OrganizationID is the organization owning the event, whose owners and organizers
+    // manage it instead of its creator. Nil for an event of its creator alone.
+    // An update without it keeps the stored one, 0 gives the event back to its creator
+    OrganizationID  *uint     `gorm:"index" json:"organization_id,omitempty"`
     CreatedAt       time.Time `gorm:"autoCreateTime;default:CURRENT_TIMESTAMP" json:"created_at"`
     UpdatedAt       time.Time `gorm:"autoUpdateTime;default:CURRENT_TIMESTAMP" json:"updated_at"`
+    // DeletedAt soft-deletes the event, the calendars of its participants still
+    // need it to show the event cancelled
+    DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // JSON EXAMPLE
@@ -45,7 +58,12 @@ type Event struct {
 //   "start_time": "2025-05-15T17:00:00+07:00",
 //   "end_time": "2025-05-15T23:00:00+07:00",
 //   "status": "active",
+//   "time_zone": "Asia/Novosibirsk",
+//   "organization_id": 3,
 //   "created_by": "evgeniyfimushkin"
 // }
 
-
+// LastModified is when the event was changed, sent as Last-Modified
+func (e Event) LastModified() time.Time {
+    return e.UpdatedAt
+}
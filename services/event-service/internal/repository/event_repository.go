@@ -2,11 +2,45 @@ package repository
 
 import (
 	"event-service/internal/models"
+	"fmt"
+	"time"
 
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
 	"gorm.io/gorm"
 )
 
+// Fields of Event for the typed queries
+var (
+    EventID              = repository.FieldOf[models.Event, uint]("ID")
+    EventCategory        = repository.FieldOf[models.Event, string]("Category")
+    EventCity            = repository.FieldOf[models.Event, string]("City")
+    EventStatus          = repository.FieldOf[models.Event, string]("Status")
+    EventCreatedBy       = repository.FieldOf[models.Event, string]("CreatedBy")
+    EventOrganizationID  = repository.FieldOf[models.Event, uint]("OrganizationID")
+    EventParticipants    = repository.FieldOf[models.Event, int]("Participants")
+    EventMaxParticipants = repository.FieldOf[models.Event, int]("MaxParticipants")
+    EventStartTime       = repository.FieldOf[models.Event, time.Time]("StartTime")
+    EventCreatedAt       = repository.FieldOf[models.Event, time.Time]("CreatedAt")
+)
+
+// EventStatsFields are the fields of the event stats, by query parameter
+var EventStatsFields = map[string]repository.Column[models.Event]{
+    "category":         EventCategory,
+    "city":             EventCity,
+    "status":           EventStatus,
+    "created_by":       EventCreatedBy,
+    "organization_id":  EventOrganizationID,
+    "participants":     EventParticipants,
+    "max_participants": EventMaxParticipants,
+    "start_time":       EventStartTime,
+    "created_at":       EventCreatedAt,
+}
+
+// OfOrganization is the query of the events of the organization in start time order
+func OfOrganization(orgID uint) repository.Query[models.Event] {
+    return repository.Where(EventOrganizationID.Eq(orgID)).OrderBy(EventStartTime.Asc(), EventID.Asc())
+}
+
 type EventRepository struct {
     *repository.GenericRepository[models.Event]
 }
@@ -17,6 +51,72 @@ func NewEventRepository(db *gorm.DB) *EventRepository {
 	}
 }
 
+// Cancel marks the event cancelled and bumps its sequence, so that calendar apps
+// still showing it cancel it once it's deleted
+func (er *EventRepository) Cancel(id uint) error {
+    return er.UpdateBy(repository.Where(EventID.Eq(id)), map[string]interface{}{
+        "status":   "cancelled",
+        "sequence": gorm.Expr("sequence + 1"),
+    })
+}
+
+// FindWithDeleted returns the events with the ids including the deleted ones
+func (er *EventRepository) FindWithDeleted(ids []uint) ([]models.Event, error) {
+    var events []models.Event
+    if err := er.Db.Unscoped().Where("id IN ?", ids).Find(&events).Error; err != nil {
+        return nil, err
+    }
+    return events, nil
+}
+
+// ListFilter selects the events returned by List, zero fields match every event
+type ListFilter struct {
+	Category     string
+	City         string
+	Status       string
+	CreatedBy    string
+	StartsAfter  time.Time
+	StartsBefore time.Time
+}
+
+// Cursor is the position of the last event of a page in the start time order
+type Cursor struct {
+	StartTime time.Time
+	ID        uint
+}
+
+// List returns up to limit events matching the filter ordered by start time and id,
+// starting after the cursor when it's given.
+func (er *EventRepository) List(filter ListFilter, after *Cursor, limit int) ([]models.Event, error) {
+	query := er.Db.Model(&models.Event{})
+	for _, field := range []struct{ column, value string }{
+		{"category", filter.Category},
+		{"city", filter.City},
+		{"status", filter.Status},
+		{"created_by", filter.CreatedBy},
+	} {
+		if field.value != "" {
+			query = query.Where(field.column+" = ?", field.value)
+		}
+	}
+	if !filter.StartsAfter.IsZero() {
+		query = query.Where("start_time >= ?", filter.StartsAfter)
+	}
+	if !filter.StartsBefore.IsZero() {
+		query = query.Where("start_time < ?", filter.StartsBefore)
+	}
+	if after != nil {
+		query = query.Where("start_time > ? OR (start_time = ? AND id > ?)", after.StartTime, after.StartTime, after.ID)
+	}
+
+	var events []models.Event
+	result := query.Order("start_time, id").Limit(limit).Find(&events)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list events: %w", result.Error)
+	}
+	return events, nil
+}
+
 // // GetByCategory returns all events that belong to the specified category.
 // func (er *EventRepository) GetByCategory(category string) ([]models.Event, error) {
 // 	var events []models.Event
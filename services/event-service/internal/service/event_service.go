@@ -1,36 +1,100 @@
 package service
 
 import (
+	"context"
 	"event-service/internal/models"
 	"event-service/internal/repository"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/cache"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventbus"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/metrics"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/service"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 // EventService specializes in handling business logic for Event entities.
-// It embeds GenericService for basic CRUD operations and adds additional dependencies (e.g., a verifier).
+// It embeds GenericService for basic CRUD operations and publishes domain events on changes.
 type EventService struct {
 	*service.GenericService[models.Event]
+	repo     *repository.EventRepository
+	uow      *db.UnitOfWork
+	bus      eventbus.Publisher
+	watchers *watchers
 }
 
-// NewEventService creates a new instance of EventService using the provided verifier and repository.
+// NewEventService creates a new instance of EventService using the provided repository and publisher.
 // It initializes the underlying GenericService using the given repository.
-func NewEventService(repo *repository.EventRepository) *EventService {
+// The changes are stored and published in units of work of uow, so bus should be
+// the outbox of its database.
+func NewEventService(repo *repository.EventRepository, uow *db.UnitOfWork, bus eventbus.Publisher) *EventService {
 	return &EventService{
 		GenericService: service.NewGenericService[models.Event](repo),
+		repo:           repo,
+		uow:            uow,
+		bus:            bus,
+		watchers:       newWatchers(),
 	}
 }
 
+// WithCache returns a copy of the service reading the events through the cache in
+// backend, the writes made by the service invalidate it.
+func (s *EventService) WithCache(backend cache.Backend, opts cache.Options) *EventService {
+    cached := *s
+    cached.GenericService = service.NewGenericService[models.Event](cache.NewRepository[models.Event](s.repo, backend, opts))
+    return &cached
+}
+
+// WithContext returns a copy of the service whose queries run with ctx.
+func (s *EventService) WithContext(ctx context.Context) *EventService {
+    scoped := *s
+    scoped.GenericService = s.GenericService.WithContext(ctx)
+    scoped.repo = &repository.EventRepository{GenericRepository: s.repo.GenericRepository.WithContext(ctx)}
+    return &scoped
+}
+
+// List returns a page of events matching the filter in start time order, see EventRepository.List
+func (s *EventService) List(filter repository.ListFilter, after *repository.Cursor, limit int) ([]models.Event, error) {
+    return s.repo.List(filter, after, limit)
+}
+
+// FindWithDeleted returns the events with the ids, the deleted ones being cancelled
+func (s *EventService) FindWithDeleted(ids []uint) ([]models.Event, error) {
+    return s.repo.FindWithDeleted(ids)
+}
+
+// ListByOrganization returns a page of the events of the organization in start time order
+func (s *EventService) ListByOrganization(orgID uint, page, pageSize int) ([]models.Event, error) {
+    return s.repo.FindBy(repository.OfOrganization(orgID).Page(page, pageSize))
+}
+
+// Watch returns the channel getting the changes of the event made from now on and the
+// function to stop watching. The channel is closed when the watcher falls behind.
+func (s *EventService) Watch(eventID uint) (<-chan Change, func()) {
+    ch := s.watchers.add(eventID)
+    return ch, func() { s.watchers.remove(eventID, ch) }
+}
+
 func (s *EventService) Create(claims jwt.MapClaims, entity *models.Event) (*models.Event, error) {
+    return s.CreateContext(context.Background(), claims, entity)
+}
+
+// CreateContext creates the event with the queries and the published EventCreated
+// being part of the request in ctx.
+func (s *EventService) CreateContext(ctx context.Context, claims jwt.MapClaims, entity *models.Event) (*models.Event, error) {
     username, ok := claims["username"].(string)
     if !ok {
         return nil, fmt.Errorf("invalid token: username not found or not a string")
     }
     entity.CreatedBy = username
+    if entity.OrganizationID != nil && !auth.ManagesOrgEvents(claims, *entity.OrganizationID) {
+        return nil, fmt.Errorf("%w: only owners and organizers create the events of organization %d", auth.ErrForbidden, *entity.OrganizationID)
+    }
     now := time.Now()
     oneYearLater := now.AddDate(1, 0, 0)
 
@@ -68,10 +132,126 @@ func (s *EventService) Create(claims jwt.MapClaims, entity *models.Event) (*mode
         return nil, fmt.Errorf("longitude must be between -180 and 180")
     }
 
-    return s.GenericService.Create(claims, entity)
+    if entity.TimeZone != "" {
+        if _, err := time.LoadLocation(entity.TimeZone); err != nil {
+            return nil, fmt.Errorf("unknown time zone %q", entity.TimeZone)
+        }
+    }
+
+    var created *models.Event
+    err := s.uow.Do(ctx, func(ctx context.Context) error {
+        var err error
+        if created, err = s.GenericService.WithContext(ctx).Create(claims, entity); err != nil {
+            return err
+        }
+        db.AfterCommit(ctx, func(context.Context) { metrics.EventsCreated.Inc() })
+        return s.publish(ctx, eventbus.TypeEventCreated, created)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return created, nil
 }
 
 func (s *EventService) Update(claims jwt.MapClaims, entity *models.Event) (*models.Event, error) {
+    return s.UpdateContext(context.Background(), claims, entity)
+}
+
+// UpdateContext updates the event with the queries and the published event being part
+// of the request in ctx.
+func (s *EventService) UpdateContext(ctx context.Context, claims jwt.MapClaims, entity *models.Event) (*models.Event, error) {
+    var updated *models.Event
+    err := s.uow.Do(ctx, func(ctx context.Context) error {
+        var err error
+        updated, err = s.updateStored(ctx, claims, entity)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    return updated, nil
+}
+
+// updateStored updates and publishes the event in the unit of work in ctx
+func (s *EventService) updateStored(ctx context.Context, claims jwt.MapClaims, entity *models.Event) (*models.Event, error) {
+    scoped := s.WithContext(ctx)
+    // the sequence must follow the stored one, a lagging replica would repeat it
+    current, err := scoped.repo.Primary().GetByID(int(entity.ID))
+    if err != nil {
+        return nil, err
+    }
+    if err := authorize(claims, current, auth.PermEventsUpdateAny); err != nil {
+        return nil, err
+    }
+    // the organization is only changed when one is given, 0 gives the event back to its
+    // creator and moving it to an organization takes managing its events
+    switch {
+    case entity.OrganizationID == nil:
+        entity.OrganizationID = current.OrganizationID
+    case *entity.OrganizationID == 0:
+        entity.OrganizationID = nil
+    case (current.OrganizationID == nil || *entity.OrganizationID != *current.OrganizationID) &&
+        !auth.ManagesOrgEvents(claims, *entity.OrganizationID) && !auth.HasPermission(claims, auth.PermEventsUpdateAny):
+        return nil, fmt.Errorf("%w: only owners and organizers move events to organization %d", auth.ErrForbidden, *entity.OrganizationID)
+    }
+    entity.CreatedBy = current.CreatedBy
+    entity.Sequence = current.Sequence + 1
+
+    updated, err := scoped.update(claims, entity)
+    if err != nil {
+        return nil, err
+    }
+    eventType, change := eventbus.TypeEventUpdated, ChangeUpdated
+    if updated.Status == "cancelled" {
+        eventType, change = eventbus.TypeEventCancelled, ChangeCancelled
+    }
+    db.AfterCommit(ctx, func(context.Context) { s.watchers.notify(Change{Type: change, Event: *updated}) })
+    return updated, s.publish(ctx, eventType, updated)
+}
+
+// UpdateParticipants stores a changed participants counter.
+// Unlike Update it does not publish EventUpdated, since subscribers are not interested in seat counts,
+// only the watchers of the event are told.
+func (s *EventService) UpdateParticipants(entity *models.Event) (*models.Event, error) {
+    updated, err := s.update(nil, entity)
+    if err != nil {
+        return nil, err
+    }
+    s.watchers.notify(Change{Type: ChangeParticipants, Event: *updated})
+    return updated, nil
+}
+
+func (s *EventService) Delete(claims jwt.MapClaims, id int) error {
+    return s.DeleteContext(context.Background(), claims, id)
+}
+
+// DeleteContext deletes the event with the queries and the published EventCancelled
+// being part of the request in ctx.
+func (s *EventService) DeleteContext(ctx context.Context, claims jwt.MapClaims, id int) error {
+    return s.uow.Do(ctx, func(ctx context.Context) error {
+        scoped := s.WithContext(ctx)
+        event, err := scoped.repo.Primary().GetByID(id)
+        if err != nil {
+            return err
+        }
+        if err := authorize(claims, event, auth.PermEventsDeleteAny); err != nil {
+            return err
+        }
+        // the event is soft-deleted and cancelled, see EventRepository.FindWithDeleted
+        if err := scoped.repo.Cancel(event.ID); err != nil {
+            return err
+        }
+        if err := scoped.GenericService.Delete(claims, id); err != nil {
+            return err
+        }
+        event.Status = "cancelled"
+        event.Sequence++
+        db.AfterCommit(ctx, func(context.Context) { s.watchers.notify(Change{Type: ChangeDeleted, Event: *event}) })
+        return s.publish(ctx, eventbus.TypeEventCancelled, event)
+    })
+}
+
+func (s *EventService) update(claims jwt.MapClaims, entity *models.Event) (*models.Event, error) {
     now := time.Now()
     oneYearLater := now.AddDate(1, 0, 0)
 
@@ -107,5 +287,53 @@ func (s *EventService) Update(claims jwt.MapClaims, entity *models.Event) (*mode
         return nil, fmt.Errorf("longitude must be between -180 and 180")
     }
 
+    if entity.TimeZone != "" {
+        if _, err := time.LoadLocation(entity.TimeZone); err != nil {
+            return nil, fmt.Errorf("unknown time zone %q", entity.TimeZone)
+        }
+    }
+
     return s.GenericService.Update(claims, entity)
 }
+
+// authorize fails with auth.ErrForbidden unless the user of the token manages the event.
+// The events of an organization are managed by its owners and organizers, as told by
+// the token, the others by their creator. Users granted permission, like moderators,
+// manage every event.
+func authorize(claims jwt.MapClaims, event *models.Event, permission string) error {
+    if auth.HasPermission(claims, permission) {
+        return nil
+    }
+    if event.OrganizationID != nil {
+        if !auth.ManagesOrgEvents(claims, *event.OrganizationID) {
+            return fmt.Errorf("%w: only owners and organizers of organization %d manage event %d", auth.ErrForbidden, *event.OrganizationID, event.ID)
+        }
+        return nil
+    }
+    username, ok := claims["username"].(string)
+    if !ok {
+        return fmt.Errorf("invalid token: username not found or not a string")
+    }
+    if strings.TrimSpace(event.CreatedBy) != strings.TrimSpace(username) {
+        return fmt.Errorf("%w: only the organizer manages event %d", auth.ErrForbidden, event.ID)
+    }
+    return nil
+}
+
+// publish sends the event state to the bus in the unit of work of the change,
+// failing to publish it rolls the change back.
+func (s *EventService) publish(ctx context.Context, eventType string, event *models.Event) error {
+    payload := eventbus.EventPayload{
+        EventID:         event.ID,
+        Name:            event.Name,
+        Category:        event.Category,
+        City:            event.City,
+        Address:         event.Address,
+        StartTime:       event.StartTime,
+        EndTime:         event.EndTime,
+        Status:          event.Status,
+        MaxParticipants: event.MaxParticipants,
+        CreatedBy:       event.CreatedBy,
+    }
+    return eventbus.Publish(ctx, s.bus, eventType, payload, eventbus.WithKey(strconv.FormatUint(uint64(event.ID), 10)))
+}
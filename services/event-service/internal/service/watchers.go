@@ -0,0 +1,80 @@
+package service
+
+import (
+	"event-service/internal/models"
+	"sync"
+)
+
+// ChangeType tells what happened to a watched event
+type ChangeType int
+
+const (
+	ChangeUpdated ChangeType = iota + 1
+	ChangeParticipants
+	ChangeCancelled
+	ChangeDeleted
+)
+
+// Change is a stored change of an event as its watchers get it
+type Change struct {
+	Type  ChangeType
+	Event models.Event
+}
+
+// watchBuffer is how many changes a watcher may lag behind before it is dropped
+const watchBuffer = 16
+
+// watchers fans the changes made through this instance out to the watchers of the
+// events. Changes made by other instances of event-service aren't seen, watchers that
+// need them should run against a single instance or follow the domain events on Kafka.
+type watchers struct {
+	mu      sync.Mutex
+	byEvent map[uint]map[chan Change]struct{}
+}
+
+func newWatchers() *watchers {
+	return &watchers{byEvent: make(map[uint]map[chan Change]struct{})}
+}
+
+func (w *watchers) add(eventID uint) chan Change {
+	ch := make(chan Change, watchBuffer)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.byEvent[eventID] == nil {
+		w.byEvent[eventID] = make(map[chan Change]struct{})
+	}
+	w.byEvent[eventID][ch] = struct{}{}
+	return ch
+}
+
+// remove closes ch unless notify already did
+func (w *watchers) remove(eventID uint, ch chan Change) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.byEvent[eventID][ch]; !ok {
+		return
+	}
+	delete(w.byEvent[eventID], ch)
+	if len(w.byEvent[eventID]) == 0 {
+		delete(w.byEvent, eventID)
+	}
+	close(ch)
+}
+
+// notify sends the change without blocking, a watcher whose buffer is full is dropped by
+// closing its channel, so it notices it missed changes.
+func (w *watchers) notify(change Change) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for ch := range w.byEvent[change.Event.ID] {
+		select {
+		case ch <- change:
+		default:
+			delete(w.byEvent[change.Event.ID], ch)
+			close(ch)
+		}
+	}
+	if len(w.byEvent[change.Event.ID]) == 0 {
+		delete(w.byEvent, change.Event.ID)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"notification-service/internal/config"
+	"notification-service/internal/handler"
+	"notification-service/internal/migrations"
+	"notification-service/internal/repository"
+	"notification-service/internal/sender"
+	"notification-service/internal/service"
+	"notification-service/internal/templates"
+	"os"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/app"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventbus"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/health"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/logger"
+)
+
+func main() {
+	a := app.New("notification-service")
+	cfg, log := a.Config, a.Log
+	var notifyCfg config.Config
+	a.MustLoadSection("notification", &notifyCfg)
+
+	dbConnection := a.MustConnectDB(migrations.FS)
+	// notifications come only from kafka, without it the service has nothing to do
+	a.Health.Add("kafka", health.Kafka(cfg.Kafka.Brokers))
+
+	prefRepo := repository.NewPreferenceRepository(dbConnection)
+	deliveryRepo := repository.NewDeliveryRepository(dbConnection)
+	subscriptionRepo := repository.NewSubscriptionRepository(dbConnection)
+	eventInfoRepo := repository.NewEventInfoRepository(dbConnection)
+
+	verifier, err := auth.NewVerifier(cfg.PublicKey)
+	if err != nil {
+		log.Error("failed to init JWT verifier", logger.Err(err))
+		panic("failed to init JWT verifier")
+	}
+
+	renderer, err := templates.NewRenderer(notifyCfg.DefaultLocale)
+	if err != nil {
+		log.Error("failed to load templates", logger.Err(err))
+		panic("failed to load templates")
+	}
+
+	// ---------------SENDERS------------------------
+
+	senders := []sender.Sender{
+		sender.NewSMTPSender(notifyCfg.SMTP.Host, notifyCfg.SMTP.Port, notifyCfg.SMTP.Username, notifyCfg.SMTP.Password, notifyCfg.SMTP.From),
+	}
+	if notifyCfg.Telegram.BotToken != "" {
+		senders = append(senders, sender.NewTelegramSender(notifyCfg.Telegram.APIURL, notifyCfg.Telegram.BotToken))
+	} else {
+		log.Warn("TELEGRAM_BOT_TOKEN is not set, telegram notifications are disabled")
+	}
+
+	notificationService := service.NewNotificationService(
+		log,
+		prefRepo,
+		deliveryRepo,
+		subscriptionRepo,
+		eventInfoRepo,
+		renderer,
+		senders,
+		service.Options{
+			DefaultLocale:       notifyCfg.DefaultLocale,
+			ReminderMaxAttempts: notifyCfg.ReminderMaxAttempts,
+			ReminderBackoff:     notifyCfg.ReminderBackoff,
+			ReminderMaxBackoff:  notifyCfg.ReminderMaxBackoff,
+		},
+	)
+	preferenceService := service.NewPreferenceService(prefRepo, notifyCfg.DefaultLocale, renderer.Locales())
+	deliveryService := service.NewDeliveryService(deliveryRepo)
+
+	// ---------------KAFKA CONSUMER------------------------
+
+	kafkaConsumer := eventbus.NewKafkaConsumer(log, cfg.Kafka.Brokers, cfg.Kafka.GroupID, cfg.Kafka.Topic, eventbus.RetryPolicy{
+		MaxAttempts: cfg.Kafka.MaxAttempts,
+		Backoff:     cfg.Kafka.RetryBackoff,
+	})
+	a.Go("kafka consumer", func(ctx context.Context) error {
+		return kafkaConsumer.Consume(ctx, notificationService.Handle)
+	})
+	a.OnClose("kafka consumer", kafkaConsumer)
+
+	a.Go("reminders", func(ctx context.Context) error {
+		notificationService.RunReminders(ctx, notifyCfg.ReminderInterval, notifyCfg.ReminderBefore)
+		return nil
+	})
+
+	// -----------------HTTP SERVER------------------------
+
+	handler := handler.NewNotificationHandler(deliveryService, preferenceService, verifier)
+
+	a.Router.Get("/api/v1/notifications/my", handler.GetMyHandler())
+	a.Router.Get("/api/v1/notifications/preferences", handler.GetPreferencesHandler())
+	a.Router.Put("/api/v1/notifications/preferences", handler.UpdatePreferencesHandler())
+
+	if err := a.Run(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,30 @@
+package config
+
+import "time"
+
+// Config is the notification-service section of the config file, under the `notification` key.
+// Shared settings like the server, database and kafka are in the common config.
+type Config struct {
+	DefaultLocale    string        `yaml:"default_locale" envconfig:"NOTIFICATION_DEFAULT_LOCALE" default:"ru" validate:"required"`
+	ReminderBefore   time.Duration `yaml:"reminder_before" envconfig:"NOTIFICATION_REMINDER_BEFORE" default:"24h" validate:"min=1m"`
+	ReminderInterval time.Duration `yaml:"reminder_interval" envconfig:"NOTIFICATION_REMINDER_INTERVAL" default:"1m" validate:"min=1s"`
+	// failed reminders of an event are tried again after ReminderBackoff, doubled on every
+	// failure up to ReminderMaxBackoff, until ReminderMaxAttempts attempts were made
+	ReminderMaxAttempts int           `yaml:"reminder_max_attempts" envconfig:"NOTIFICATION_REMINDER_MAX_ATTEMPTS" default:"5" validate:"min=1"`
+	ReminderBackoff     time.Duration `yaml:"reminder_backoff" envconfig:"NOTIFICATION_REMINDER_BACKOFF" default:"1m" validate:"min=1s"`
+	ReminderMaxBackoff  time.Duration `yaml:"reminder_max_backoff" envconfig:"NOTIFICATION_REMINDER_MAX_BACKOFF" default:"30m" validate:"min=1s"`
+
+	SMTP struct {
+		Host     string `yaml:"host" envconfig:"SMTP_HOST" default:"localhost" validate:"required"`
+		Port     int    `yaml:"port" envconfig:"SMTP_PORT" default:"587" validate:"port"`
+		Username string `yaml:"username" envconfig:"SMTP_USERNAME"`
+		Password string `yaml:"password" envconfig:"SMTP_PASSWORD" secret:"true"`
+		From     string `yaml:"from" envconfig:"SMTP_FROM" default:"noreply@events-planner.ru" validate:"required"`
+	} `yaml:"smtp"`
+
+	// Telegram notifications are disabled without BotToken
+	Telegram struct {
+		BotToken string `yaml:"bot_token" envconfig:"TELEGRAM_BOT_TOKEN" secret:"true"`
+		APIURL   string `yaml:"api_url" envconfig:"TELEGRAM_API_URL" default:"https://api.telegram.org"`
+	} `yaml:"telegram"`
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"notification-service/internal/models"
+	"notification-service/internal/service"
+	"strconv"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/handler"
+)
+
+type NotificationHandler struct {
+	*handler.GenericHandler[models.Delivery]
+	deliveries  *service.DeliveryService
+	preferences *service.PreferenceService
+}
+
+func NewNotificationHandler(deliveries *service.DeliveryService, preferences *service.PreferenceService, verifier *auth.Verifier) *NotificationHandler {
+	return &NotificationHandler{
+		GenericHandler: handler.NewGenericHandler[models.Delivery](deliveries, verifier),
+		deliveries:     deliveries,
+		preferences:    preferences,
+	}
+}
+
+// GetMyHandler returns a page of notifications delivered to the current user.
+func (h *NotificationHandler) GetMyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		page, pageSize := 1, 20
+		if v := r.URL.Query().Get("page"); v != "" {
+			if page, err = strconv.Atoi(v); err != nil || page < 1 {
+				http.Error(w, "Invalid page parameter", http.StatusBadRequest)
+				return
+			}
+		}
+		if v := r.URL.Query().Get("pageSize"); v != "" {
+			if pageSize, err = strconv.Atoi(v); err != nil || pageSize < 1 || pageSize > 100 {
+				http.Error(w, "Invalid pageSize parameter", http.StatusBadRequest)
+				return
+			}
+		}
+
+		deliveries, err := h.deliveries.GetMy(claims, page, pageSize)
+		if err != nil {
+			http.Error(w, "Error retrieving notifications: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(deliveries)
+	}
+}
+
+// GetPreferencesHandler returns notification preferences of the current user.
+func (h *NotificationHandler) GetPreferencesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		pref, err := h.preferences.GetMy(claims)
+		if err != nil {
+			http.Error(w, "Error retrieving preferences: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(pref)
+	}
+}
+
+// UpdatePreferencesHandler replaces notification preferences of the current user.
+func (h *NotificationHandler) UpdatePreferencesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		var pref models.Preference
+		if err := json.NewDecoder(r.Body).Decode(&pref); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		updated, err := h.preferences.UpdateMy(claims, &pref)
+		if err != nil {
+			http.Error(w, "Error updating preferences: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(updated)
+	}
+}
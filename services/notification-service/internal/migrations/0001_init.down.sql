@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS event_infos;
+DROP TABLE IF EXISTS subscriptions;
+DROP TABLE IF EXISTS deliveries;
+DROP TABLE IF EXISTS opt_outs;
+DROP TABLE IF EXISTS preferences;
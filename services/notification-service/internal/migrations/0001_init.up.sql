@@ -0,0 +1,63 @@
+-- the schema AutoMigrate created, existing databases are left as they are
+CREATE TABLE IF NOT EXISTS preferences (
+    user_id bigint PRIMARY KEY,
+    locale varchar(10) NOT NULL,
+    email varchar(255),
+    email_enabled boolean NOT NULL,
+    telegram_chat_id varchar(64),
+    telegram_enabled boolean NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS opt_outs (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    kind varchar(50) NOT NULL,
+    channel varchar(20) NOT NULL DEFAULT '',
+    CONSTRAINT fk_preferences_opt_outs FOREIGN KEY (user_id) REFERENCES preferences (user_id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_opt_out ON opt_outs (user_id, kind, channel);
+
+CREATE TABLE IF NOT EXISTS deliveries (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    event_id bigint NOT NULL,
+    kind varchar(50) NOT NULL,
+    channel varchar(20) NOT NULL,
+    recipient varchar(255) NOT NULL,
+    locale varchar(10) NOT NULL,
+    subject varchar(255),
+    body text,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    attempts bigint NOT NULL DEFAULT 0,
+    last_error text,
+    dedup_key varchar(255) NOT NULL,
+    sent_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_deliveries_user_id ON deliveries (user_id);
+CREATE INDEX IF NOT EXISTS idx_deliveries_event_id ON deliveries (event_id);
+CREATE INDEX IF NOT EXISTS idx_deliveries_status ON deliveries (status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deliveries_dedup_key ON deliveries (dedup_key);
+
+CREATE TABLE IF NOT EXISTS subscriptions (
+    id bigserial PRIMARY KEY,
+    event_id bigint NOT NULL,
+    user_id bigint NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription ON subscriptions (event_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_subscriptions_user_id ON subscriptions (user_id);
+
+CREATE TABLE IF NOT EXISTS event_infos (
+    event_id bigint PRIMARY KEY,
+    name varchar(255) NOT NULL,
+    city varchar(100),
+    address varchar(255),
+    start_time timestamptz NOT NULL,
+    status varchar(50) NOT NULL,
+    reminder_sent_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_event_infos_start_time ON event_infos (start_time);
@@ -0,0 +1,2 @@
+ALTER TABLE event_infos DROP COLUMN IF EXISTS reminder_retry_at;
+ALTER TABLE event_infos DROP COLUMN IF EXISTS reminder_attempts;
@@ -0,0 +1,2 @@
+ALTER TABLE event_infos ADD COLUMN IF NOT EXISTS reminder_attempts integer NOT NULL DEFAULT 0;
+ALTER TABLE event_infos ADD COLUMN IF NOT EXISTS reminder_retry_at timestamptz;
@@ -0,0 +1,10 @@
+// Package migrations holds the versioned SQL migrations of the notification-service schema, see
+// `notification-service migrate` for applying them
+package migrations
+
+import "embed"
+
+// FS holds the <version>_<name>.up.sql and <version>_<name>.down.sql files
+//
+//go:embed *.sql
+var FS embed.FS
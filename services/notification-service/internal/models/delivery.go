@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Notification kinds
+const (
+	KindAll                   = "all"
+	KindRegistrationConfirmed = "registration_confirmed"
+	KindWaitlistPromoted      = "waitlist_promoted"
+	KindEventChanged          = "event_changed"
+	KindEventCancelled        = "event_cancelled"
+	KindEventReminder         = "event_reminder"
+)
+
+// Delivery statuses
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// Delivery is a record of a single notification sent to a user through one channel
+type Delivery struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	EventID   uint       `gorm:"not null;index" json:"event_id"`
+	Kind      string     `gorm:"type:varchar(50);not null" json:"kind"`
+	Channel   string     `gorm:"type:varchar(20);not null" json:"channel"`
+	Recipient string     `gorm:"type:varchar(255);not null" json:"-"`
+	Locale    string     `gorm:"type:varchar(10);not null" json:"locale"`
+	Subject   string     `gorm:"type:varchar(255)" json:"subject"`
+	Body      string     `gorm:"type:text" json:"body"`
+	Status    string     `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Attempts  int        `gorm:"not null;default:0" json:"attempts"`
+	LastError string     `gorm:"type:text" json:"last_error,omitempty"`
+	DedupKey  string     `gorm:"type:varchar(255);not null;uniqueIndex" json:"-"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// EventInfo is a local projection of an event, built from domain events.
+// It is used to render templates and to schedule reminders.
+type EventInfo struct {
+	EventID        uint       `gorm:"primaryKey;autoIncrement:false" json:"event_id"`
+	Name           string     `gorm:"type:varchar(255);not null" json:"name"`
+	City           string     `gorm:"type:varchar(100)" json:"city"`
+	Address        string     `gorm:"type:varchar(255)" json:"address"`
+	StartTime      time.Time  `gorm:"not null;index" json:"start_time"`
+	Status         string     `gorm:"type:varchar(50);not null" json:"status"`
+	ReminderSentAt *time.Time `json:"reminder_sent_at,omitempty"`
+	// ReminderAttempts counts the failed attempts at the reminders, retried at ReminderRetryAt
+	ReminderAttempts int        `gorm:"not null;default:0" json:"reminder_attempts"`
+	ReminderRetryAt  *time.Time `json:"reminder_retry_at,omitempty"`
+	UpdatedAt        time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Subscription links a user with an event they are registered for
+type Subscription struct {
+	ID      uint `gorm:"primaryKey" json:"id"`
+	EventID uint `gorm:"not null;uniqueIndex:idx_subscription" json:"event_id"`
+	UserID  uint `gorm:"not null;uniqueIndex:idx_subscription;index" json:"user_id"`
+}
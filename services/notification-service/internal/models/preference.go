@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Preference holds per-user delivery settings for notifications
+type Preference struct {
+	UserID          uint      `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Locale          string    `gorm:"type:varchar(10);not null" json:"locale"`
+	Email           string    `gorm:"type:varchar(255)" json:"email"`
+	EmailEnabled    bool      `gorm:"not null" json:"email_enabled"`
+	TelegramChatID  string    `gorm:"type:varchar(64)" json:"telegram_chat_id"`
+	TelegramEnabled bool      `gorm:"not null" json:"telegram_enabled"`
+	OptOuts         []OptOut  `gorm:"foreignKey:UserID;references:UserID;constraint:OnDelete:CASCADE" json:"opt_outs"`
+	CreatedAt       time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// OptOut disables a notification kind for a user.
+// Empty Channel means the kind is disabled on every channel.
+type OptOut struct {
+	ID      uint   `gorm:"primaryKey" json:"-"`
+	UserID  uint   `gorm:"not null;uniqueIndex:idx_opt_out" json:"-"`
+	Kind    string `gorm:"type:varchar(50);not null;uniqueIndex:idx_opt_out" json:"kind"`
+	Channel string `gorm:"type:varchar(20);not null;default:'';uniqueIndex:idx_opt_out" json:"channel,omitempty"`
+}
+
+// Covers reports whether the opt-out disables kind on channel
+func (o OptOut) Covers(kind, channel string) bool {
+	if o.Kind != kind && o.Kind != KindAll {
+		return false
+	}
+	return o.Channel == "" || o.Channel == channel
+}
+
+// JSON EXAMPLE
+
+// {
+//   "locale": "en",
+//   "email": "ivan@gmail.com",
+//   "email_enabled": true,
+//   "telegram_chat_id": "123456789",
+//   "telegram_enabled": false,
+//   "opt_outs": [{"kind": "event_reminder", "channel": "email"}]
+// }
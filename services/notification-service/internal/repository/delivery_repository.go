@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"fmt"
+	"notification-service/internal/models"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DeliveryRepository struct {
+	*repository.GenericRepository[models.Delivery]
+}
+
+func NewDeliveryRepository(db *gorm.DB) *DeliveryRepository {
+	return &DeliveryRepository{
+		GenericRepository: repository.NewGenericRepository[models.Delivery](db),
+	}
+}
+
+// Claim inserts the delivery unless one with the same DedupKey already exists.
+// It returns the stored delivery, which may have been created by an earlier attempt.
+func (repo *DeliveryRepository) Claim(delivery *models.Delivery) (*models.Delivery, error) {
+	result := repo.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "dedup_key"}},
+		DoNothing: true,
+	}).Create(delivery)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%w: %v", repository.ErrCreateEntity, result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return delivery, nil
+	}
+	return repo.FindFirst("dedup_key = ?", delivery.DedupKey)
+}
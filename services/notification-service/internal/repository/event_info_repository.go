@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"fmt"
+	"notification-service/internal/models"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EventInfoRepository struct {
+	*repository.GenericRepository[models.EventInfo]
+}
+
+func NewEventInfoRepository(db *gorm.DB) *EventInfoRepository {
+	return &EventInfoRepository{
+		GenericRepository: repository.NewGenericRepository[models.EventInfo](db),
+	}
+}
+
+// Upsert stores the projection of the event, overwriting the previous state.
+func (repo *EventInfoRepository) Upsert(info *models.EventInfo) error {
+	result := repo.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "city", "address", "start_time", "status", "reminder_sent_at", "reminder_attempts", "reminder_retry_at", "updated_at"}),
+	}).Create(info)
+	if result.Error != nil {
+		return fmt.Errorf("%w: %v", repository.ErrUpdateEntity, result.Error)
+	}
+	return nil
+}
+
+// DueReminders returns active events starting in (now, to] that have not been reminded yet,
+// the earliest first. Events whose reminders failed maxAttempts times or are retried after
+// now are left out.
+func (repo *EventInfoRepository) DueReminders(now, to time.Time, maxAttempts int) ([]models.EventInfo, error) {
+	var infos []models.EventInfo
+	result := repo.Db.
+		Where("status = ? AND reminder_sent_at IS NULL AND start_time > ? AND start_time <= ?", "active", now, to).
+		Where("reminder_attempts < ? AND (reminder_retry_at IS NULL OR reminder_retry_at <= ?)", maxAttempts, now).
+		Order("start_time").
+		Find(&infos)
+	if result.Error != nil {
+		return nil, fmt.Errorf("%w: %v", repository.ErrFindEntities, result.Error)
+	}
+	return infos, nil
+}
+
+// DeferReminder records a failed attempt at the reminders of the event, retried at retryAt.
+func (repo *EventInfoRepository) DeferReminder(eventID uint, attempts int, retryAt time.Time) error {
+	return repo.BulkUpdate("event_id = ?", []interface{}{eventID}, map[string]interface{}{
+		"reminder_attempts": attempts,
+		"reminder_retry_at": retryAt,
+	})
+}
+
+// MarkReminded records that reminders for the event have been sent.
+func (repo *EventInfoRepository) MarkReminded(eventID uint, at time.Time) error {
+	return repo.BulkUpdate("event_id = ?", []interface{}{eventID}, map[string]interface{}{"reminder_sent_at": at})
+}
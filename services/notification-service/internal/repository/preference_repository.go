@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"fmt"
+	"notification-service/internal/models"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+	"gorm.io/gorm"
+)
+
+type PreferenceRepository struct {
+	*repository.GenericRepository[models.Preference]
+}
+
+func NewPreferenceRepository(db *gorm.DB) *PreferenceRepository {
+	return &PreferenceRepository{
+		GenericRepository: repository.NewGenericRepository[models.Preference](db),
+	}
+}
+
+// GetByUserID returns preferences of the user together with opt-outs.
+func (repo *PreferenceRepository) GetByUserID(userID uint) (*models.Preference, error) {
+	var pref models.Preference
+	result := repo.Db.Preload("OptOuts").First(&pref, "user_id = ?", userID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("%w: %v", repository.ErrGetEntityByID, result.Error)
+	}
+	return &pref, nil
+}
+
+// Save stores preferences and replaces the set of opt-outs in one transaction.
+func (repo *PreferenceRepository) Save(pref *models.Preference) (*models.Preference, error) {
+	err := repo.ExecuteInTransaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("OptOuts").Save(pref).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", pref.UserID).Delete(&models.OptOut{}).Error; err != nil {
+			return err
+		}
+		for i := range pref.OptOuts {
+			pref.OptOuts[i].ID = 0
+			pref.OptOuts[i].UserID = pref.UserID
+		}
+		if len(pref.OptOuts) > 0 {
+			return tx.Create(&pref.OptOuts).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", repository.ErrUpdateEntity, err)
+	}
+	return pref, nil
+}
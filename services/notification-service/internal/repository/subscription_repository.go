@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"fmt"
+	"notification-service/internal/models"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Fields of Subscription for the typed queries
+var (
+	SubscriptionEventID = repository.FieldOf[models.Subscription, uint]("EventID")
+	SubscriptionUserID  = repository.FieldOf[models.Subscription, uint]("UserID")
+)
+
+type SubscriptionRepository struct {
+	*repository.GenericRepository[models.Subscription]
+}
+
+func NewSubscriptionRepository(db *gorm.DB) *SubscriptionRepository {
+	return &SubscriptionRepository{
+		GenericRepository: repository.NewGenericRepository[models.Subscription](db),
+	}
+}
+
+// Subscribe links the user with the event, doing nothing if the link exists.
+func (repo *SubscriptionRepository) Subscribe(eventID, userID uint) error {
+	result := repo.Db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Subscription{EventID: eventID, UserID: userID})
+	if result.Error != nil {
+		return fmt.Errorf("%w: %v", repository.ErrCreateEntity, result.Error)
+	}
+	return nil
+}
+
+// Unsubscribe removes the link between the user and the event.
+func (repo *SubscriptionRepository) Unsubscribe(eventID, userID uint) error {
+	return repo.DeleteBy(repository.Where(SubscriptionEventID.Eq(eventID), SubscriptionUserID.Eq(userID)))
+}
+
+// UserIDs returns ids of all users subscribed to the event.
+func (repo *SubscriptionRepository) UserIDs(eventID uint) ([]uint, error) {
+	return repository.Pluck(repo.GenericRepository, repository.Where(SubscriptionEventID.Eq(eventID)), SubscriptionUserID)
+}
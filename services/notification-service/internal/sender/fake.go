@@ -0,0 +1,44 @@
+package sender
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeSender records messages instead of sending them. It is used in tests.
+type FakeSender struct {
+	mu      sync.Mutex
+	channel string
+	// FailTimes is the number of Send calls that fail before the first success.
+	FailTimes int
+	// Err is returned by failing calls.
+	Err  error
+	sent []Message
+}
+
+// NewFakeSender creates a fake sender for the channel.
+func NewFakeSender(channel string) *FakeSender {
+	return &FakeSender{channel: channel}
+}
+
+func (s *FakeSender) Channel() string {
+	return s.channel
+}
+
+func (s *FakeSender) Send(_ context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.FailTimes > 0 {
+		s.FailTimes--
+		return s.Err
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+// Sent returns a copy of all delivered messages.
+func (s *FakeSender) Sent() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.sent...)
+}
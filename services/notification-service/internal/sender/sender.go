@@ -0,0 +1,32 @@
+package sender
+
+import (
+	"context"
+	"errors"
+)
+
+// Channels supported by notification-service
+const (
+	ChannelEmail    = "email"
+	ChannelTelegram = "telegram"
+)
+
+// ErrPermanent marks delivery errors that must not be retried,
+// e.g. an invalid address or a bot blocked by the user.
+var ErrPermanent = errors.New("permanent delivery error")
+
+// Message is a rendered notification addressed to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages through one channel
+type Sender interface {
+	// Channel returns the name of the channel the sender delivers to.
+	Channel() string
+	// Send delivers the message or returns an error.
+	// Errors wrapping ErrPermanent are not retried.
+	Send(ctx context.Context, msg Message) error
+}
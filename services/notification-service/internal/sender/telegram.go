@@ -0,0 +1,74 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TelegramSender delivers messages through the Telegram Bot API
+type TelegramSender struct {
+	apiURL string
+	token  string
+	client *http.Client
+}
+
+// NewTelegramSender creates a sender for the bot with the given token.
+func NewTelegramSender(apiURL, token string) *TelegramSender {
+	return &TelegramSender{
+		apiURL: strings.TrimRight(apiURL, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *TelegramSender) Channel() string {
+	return ChannelTelegram
+}
+
+func (s *TelegramSender) Send(ctx context.Context, msg Message) error {
+	text := msg.Body
+	if msg.Subject != "" {
+		text = msg.Subject + "\n\n" + msg.Body
+	}
+	body, err := json.Marshal(map[string]string{
+		"chat_id": msg.To,
+		"text":    text,
+	})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/bot%s/sendMessage", s.apiURL, s.token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call telegram api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+
+	switch {
+	case resp.StatusCode == http.StatusOK && result.OK:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("telegram api returned %d: %s", resp.StatusCode, result.Description)
+	default:
+		// 400 (chat not found), 403 (bot blocked) and similar will not succeed on retry
+		return fmt.Errorf("%w: telegram api returned %d: %s", ErrPermanent, resp.StatusCode, result.Description)
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"notification-service/internal/models"
+	"notification-service/internal/repository"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/service"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DeliveryService exposes delivery history to users.
+type DeliveryService struct {
+	*service.GenericService[models.Delivery]
+}
+
+// NewDeliveryService creates a new instance of DeliveryService.
+func NewDeliveryService(repo *repository.DeliveryRepository) *DeliveryService {
+	return &DeliveryService{
+		GenericService: service.NewGenericService[models.Delivery](repo),
+	}
+}
+
+// GetMy returns the latest deliveries of the user from the token.
+func (s *DeliveryService) GetMy(claims jwt.MapClaims, page, pageSize int) ([]models.Delivery, error) {
+	userID, err := userIDFromClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.GetPage(page, pageSize, "user_id = ?", userID)
+}
@@ -0,0 +1,408 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"notification-service/internal/models"
+	"notification-service/internal/repository"
+	"notification-service/internal/sender"
+	"notification-service/internal/templates"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventbus"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// NotificationService turns domain events into notifications and delivers them
+// through the configured channels.
+type NotificationService struct {
+	log           *slog.Logger
+	prefs         *repository.PreferenceRepository
+	deliveries    *repository.DeliveryRepository
+	subscriptions *repository.SubscriptionRepository
+	events        *repository.EventInfoRepository
+	renderer      *templates.Renderer
+	senders       map[string]sender.Sender
+	defaultLocale string
+	opts          Options
+}
+
+// Options configures delivery of notifications
+type Options struct {
+	DefaultLocale string
+	// ReminderMaxAttempts is how many times the reminders of an event are tried
+	ReminderMaxAttempts int
+	// ReminderBackoff is the wait before failed reminders of an event are tried again,
+	// doubled on every failure up to ReminderMaxBackoff
+	ReminderBackoff    time.Duration
+	ReminderMaxBackoff time.Duration
+}
+
+// NewNotificationService creates a new instance of NotificationService.
+// Only channels with a sender in senders are used for delivery.
+func NewNotificationService(
+	log *slog.Logger,
+	prefs *repository.PreferenceRepository,
+	deliveries *repository.DeliveryRepository,
+	subscriptions *repository.SubscriptionRepository,
+	events *repository.EventInfoRepository,
+	renderer *templates.Renderer,
+	senders []sender.Sender,
+	opts Options,
+) *NotificationService {
+	if opts.ReminderMaxAttempts < 1 {
+		opts.ReminderMaxAttempts = 5
+	}
+	if opts.ReminderBackoff <= 0 {
+		opts.ReminderBackoff = time.Minute
+	}
+	if opts.ReminderMaxBackoff < opts.ReminderBackoff {
+		opts.ReminderMaxBackoff = max(30*time.Minute, opts.ReminderBackoff)
+	}
+	byChannel := make(map[string]sender.Sender, len(senders))
+	for _, s := range senders {
+		byChannel[s.Channel()] = s
+	}
+	return &NotificationService{
+		log:           log.With(slog.String("component", "service/notification")),
+		prefs:         prefs,
+		deliveries:    deliveries,
+		subscriptions: subscriptions,
+		events:        events,
+		renderer:      renderer,
+		senders:       byChannel,
+		defaultLocale: opts.DefaultLocale,
+		opts:          opts,
+	}
+}
+
+// Handle processes a single domain event. A returned error means the event
+// should be redelivered, unless it is permanent. Transient delivery errors are returned
+// so that the retry policy of the bus, then its dead-letter topic, deals with them;
+// notifications already sent are skipped on redelivery.
+func (s *NotificationService) Handle(ctx context.Context, env eventbus.Envelope) error {
+	switch env.Type {
+	case eventbus.TypeEventCreated:
+		var p eventbus.EventPayload
+		if err := env.Decode(&p); err != nil {
+			return err
+		}
+		_, err := s.saveEvent(p)
+		return err
+
+	case eventbus.TypeEventUpdated, eventbus.TypeEventCancelled:
+		var p eventbus.EventPayload
+		if err := env.Decode(&p); err != nil {
+			return err
+		}
+		if env.Type == eventbus.TypeEventCancelled {
+			p.Status = "cancelled"
+		}
+		info, err := s.saveEvent(p)
+		if err != nil {
+			return err
+		}
+		kind := models.KindEventChanged
+		if info.Status == "cancelled" {
+			kind = models.KindEventCancelled
+		}
+		return s.notifySubscribers(ctx, info, kind, env.ID)
+
+	case eventbus.TypeRegistrationCreated:
+		var p eventbus.RegistrationPayload
+		if err := env.Decode(&p); err != nil {
+			return err
+		}
+		if err := s.subscriptions.Subscribe(p.EventID, p.UserID); err != nil {
+			return err
+		}
+		if err := s.ensurePreference(p.UserID, p.Email); err != nil {
+			return err
+		}
+		// a waitlisted user hears from us once the registration is promoted
+		if p.Status == "waitlisted" {
+			return nil
+		}
+		info := s.eventInfo(p.EventID)
+		data := templateData(info)
+		data.Username = p.Username
+		return s.notify(ctx, p.UserID, p.EventID, models.KindRegistrationConfirmed, data, env.ID)
+
+	case eventbus.TypeRegistrationPromoted:
+		var p eventbus.RegistrationPayload
+		if err := env.Decode(&p); err != nil {
+			return err
+		}
+		if err := s.subscriptions.Subscribe(p.EventID, p.UserID); err != nil {
+			return err
+		}
+		info := s.eventInfo(p.EventID)
+		return s.notify(ctx, p.UserID, p.EventID, models.KindWaitlistPromoted, templateData(info), env.ID)
+
+	case eventbus.TypeRegistrationCancelled:
+		var p eventbus.RegistrationPayload
+		if err := env.Decode(&p); err != nil {
+			return err
+		}
+		return s.subscriptions.Unsubscribe(p.EventID, p.UserID)
+
+	default:
+		s.log.Debug("skipping unknown event type", slog.String("type", env.Type))
+		return nil
+	}
+}
+
+// SendDueReminders notifies subscribers of events starting within the next `before`.
+// The reminders of an event that failed are tried again after a backoff while the other
+// events are reminded anyway, the failures are returned together.
+func (s *NotificationService) SendDueReminders(ctx context.Context, now time.Time, before time.Duration) error {
+	due, err := s.events.DueReminders(now, now.Add(before), s.opts.ReminderMaxAttempts)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range due {
+		if err := s.remind(ctx, &due[i], now); err != nil {
+			errs = append(errs, fmt.Errorf("reminders of event %d: %w", due[i].EventID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// remind notifies the subscribers of the event and marks it reminded, or records the
+// failed attempt and when to try again. Reminders already sent aren't sent twice.
+func (s *NotificationService) remind(ctx context.Context, info *models.EventInfo, now time.Time) error {
+	key := fmt.Sprintf("reminder-%d", info.StartTime.Unix())
+	err := s.notifySubscribers(ctx, info, models.KindEventReminder, key)
+	if err == nil {
+		return s.events.MarkReminded(info.EventID, now)
+	}
+
+	attempts := info.ReminderAttempts + 1
+	if attempts >= s.opts.ReminderMaxAttempts {
+		s.log.Error("giving up on the reminders of the event",
+			slog.Uint64("event_id", uint64(info.EventID)),
+			slog.Int("attempts", attempts),
+			logger.Err(err),
+		)
+	}
+	if deferErr := s.events.DeferReminder(info.EventID, attempts, now.Add(s.reminderBackoff(attempts))); deferErr != nil {
+		return errors.Join(err, deferErr)
+	}
+	return err
+}
+
+// reminderBackoff is the wait after the attempts-th failure, doubled on every one
+func (s *NotificationService) reminderBackoff(attempts int) time.Duration {
+	backoff := s.opts.ReminderBackoff
+	for i := 1; i < attempts && backoff < s.opts.ReminderMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, s.opts.ReminderMaxBackoff)
+}
+
+// RunReminders calls SendDueReminders every interval until ctx is cancelled.
+func (s *NotificationService) RunReminders(ctx context.Context, interval, before time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.SendDueReminders(ctx, time.Now(), before); err != nil {
+			s.log.Error("failed to send reminders", logger.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *NotificationService) saveEvent(p eventbus.EventPayload) (*models.EventInfo, error) {
+	info := &models.EventInfo{
+		EventID:   p.EventID,
+		Name:      p.Name,
+		City:      p.City,
+		Address:   p.Address,
+		StartTime: p.StartTime,
+		Status:    p.Status,
+	}
+	if info.Status == "" {
+		info.Status = "active"
+	}
+	// Keep the reminder mark and attempts only while the start time stays the same
+	if old, err := s.events.GetByID(int(p.EventID)); err == nil && old.StartTime.Equal(p.StartTime) {
+		info.ReminderSentAt = old.ReminderSentAt
+		info.ReminderAttempts = old.ReminderAttempts
+		info.ReminderRetryAt = old.ReminderRetryAt
+	}
+	if err := s.events.Upsert(info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// eventInfo returns the local projection of the event or a stub if it is unknown.
+func (s *NotificationService) eventInfo(eventID uint) *models.EventInfo {
+	info, err := s.events.GetByID(int(eventID))
+	if err != nil {
+		return &models.EventInfo{EventID: eventID, Name: fmt.Sprintf("#%d", eventID)}
+	}
+	return info
+}
+
+func (s *NotificationService) ensurePreference(userID uint, email string) error {
+	_, err := s.prefs.GetByUserID(userID)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	_, err = s.prefs.Create(&models.Preference{
+		UserID:          userID,
+		Locale:          s.defaultLocale,
+		Email:           email,
+		EmailEnabled:    email != "",
+		TelegramEnabled: true,
+	})
+	return err
+}
+
+// notifySubscribers notifies every subscriber of the event, one failing doesn't keep the
+// others from being notified. The failures are returned together, on a retry the
+// subscribers already notified are skipped.
+func (s *NotificationService) notifySubscribers(ctx context.Context, info *models.EventInfo, kind, key string) error {
+	userIDs, err := s.subscriptions.UserIDs(info.EventID)
+	if err != nil {
+		return err
+	}
+	data := templateData(info)
+	var errs []error
+	for _, userID := range userIDs {
+		if err := s.notify(ctx, userID, info.EventID, kind, data, key); err != nil {
+			errs = append(errs, fmt.Errorf("user %d: %w", userID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// notify delivers a notification to every enabled channel of the user, a failing channel
+// doesn't keep the others from being used. key identifies the cause of the notification
+// and makes redelivery idempotent for every recipient.
+func (s *NotificationService) notify(ctx context.Context, userID, eventID uint, kind string, data templates.Data, key string) error {
+	pref, err := s.prefs.GetByUserID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.log.Debug("user has no notification preferences", slog.Uint64("user_id", uint64(userID)))
+			return nil
+		}
+		return err
+	}
+
+	subject, body, err := s.renderer.Render(pref.Locale, kind, data)
+	if err != nil {
+		s.log.Error("failed to render notification", slog.String("kind", kind), logger.Err(err))
+		return nil
+	}
+
+	var errs []error
+	for _, channel := range []string{sender.ChannelEmail, sender.ChannelTelegram} {
+		recipient, ok := recipientFor(pref, channel)
+		if !ok || optedOut(pref, kind, channel) {
+			continue
+		}
+		snd, ok := s.senders[channel]
+		if !ok {
+			continue
+		}
+
+		delivery, err := s.deliveries.Claim(&models.Delivery{
+			UserID:    userID,
+			EventID:   eventID,
+			Kind:      kind,
+			Channel:   channel,
+			Recipient: recipient,
+			Locale:    pref.Locale,
+			Subject:   subject,
+			Body:      body,
+			Status:    models.StatusPending,
+			DedupKey:  fmt.Sprintf("%s:%s:%d:%d:%s", key, kind, eventID, userID, channel),
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if delivery.Status != models.StatusPending {
+			continue
+		}
+		if err := s.deliver(ctx, snd, delivery); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver sends the message once and records the outcome. A transient error leaves
+// the delivery pending and is returned, the event is then redelivered by the bus.
+func (s *NotificationService) deliver(ctx context.Context, snd sender.Sender, d *models.Delivery) error {
+	d.Attempts++
+	sendErr := snd.Send(ctx, sender.Message{To: d.Recipient, Subject: d.Subject, Body: d.Body})
+
+	switch {
+	case sendErr == nil:
+		now := time.Now()
+		d.Status = models.StatusSent
+		d.LastError = ""
+		d.SentAt = &now
+	case errors.Is(sendErr, sender.ErrPermanent):
+		d.Status = models.StatusFailed
+		d.LastError = sendErr.Error()
+		s.log.Warn("failed to deliver notification",
+			slog.Uint64("delivery_id", uint64(d.ID)),
+			slog.String("channel", d.Channel),
+			slog.Int("attempts", d.Attempts),
+			logger.Err(sendErr),
+		)
+	default:
+		d.LastError = sendErr.Error()
+	}
+	if _, err := s.deliveries.Update(d); err != nil {
+		return err
+	}
+	if d.Status == models.StatusPending {
+		return fmt.Errorf("delivery %d over %s: %w", d.ID, d.Channel, sendErr)
+	}
+	return nil
+}
+
+func templateData(info *models.EventInfo) templates.Data {
+	return templates.Data{
+		EventID:   info.EventID,
+		EventName: info.Name,
+		City:      info.City,
+		Address:   info.Address,
+		StartTime: info.StartTime,
+	}
+}
+
+func recipientFor(pref *models.Preference, channel string) (string, bool) {
+	switch channel {
+	case sender.ChannelEmail:
+		return pref.Email, pref.EmailEnabled && pref.Email != ""
+	case sender.ChannelTelegram:
+		return pref.TelegramChatID, pref.TelegramEnabled && pref.TelegramChatID != ""
+	}
+	return "", false
+}
+
+func optedOut(pref *models.Preference, kind, channel string) bool {
+	for _, o := range pref.OptOuts {
+		if o.Covers(kind, channel) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"notification-service/internal/models"
+	"notification-service/internal/repository"
+	"notification-service/internal/sender"
+	"notification-service/internal/templates"
+	"testing"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventbus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type testEnv struct {
+	svc      *NotificationService
+	email    *sender.FakeSender
+	telegram *sender.FakeSender
+	prefs    *repository.PreferenceRepository
+	events   *repository.EventInfoRepository
+	db       *gorm.DB
+}
+
+func setupTestEnv(t *testing.T) *testEnv {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Preference{}, &models.OptOut{}, &models.Delivery{}, &models.Subscription{}, &models.EventInfo{}))
+
+	renderer, err := templates.NewRenderer("ru")
+	require.NoError(t, err)
+
+	env := &testEnv{
+		email:    sender.NewFakeSender(sender.ChannelEmail),
+		telegram: sender.NewFakeSender(sender.ChannelTelegram),
+		prefs:    repository.NewPreferenceRepository(db),
+		events:   repository.NewEventInfoRepository(db),
+		db:       db,
+	}
+	env.svc = NewNotificationService(
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+		env.prefs,
+		repository.NewDeliveryRepository(db),
+		repository.NewSubscriptionRepository(db),
+		env.events,
+		renderer,
+		[]sender.Sender{env.email, env.telegram},
+		Options{DefaultLocale: "ru"},
+	)
+	return env
+}
+
+func envelope(t *testing.T, id, typ string, payload interface{}) eventbus.Envelope {
+	raw, err := json.Marshal(payload)
+	require.NoError(t, err)
+	return eventbus.Envelope{ID: id, Type: typ, SchemaVersion: eventbus.SchemaVersion, OccurredAt: time.Now(), Payload: raw}
+}
+
+func TestHandle_RegistrationCreated_SendsConfirmation(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+	start := time.Now().Add(72 * time.Hour)
+
+	require.NoError(t, env.svc.Handle(ctx, envelope(t, "1", eventbus.TypeEventCreated, eventbus.EventPayload{
+		EventID: 7, Name: "Баскетбол", City: "Новосибирск", StartTime: start, Status: "active",
+	})))
+	require.NoError(t, env.svc.Handle(ctx, envelope(t, "2", eventbus.TypeRegistrationCreated, eventbus.RegistrationPayload{
+		RegistrationID: 1, EventID: 7, UserID: 42, Username: "ivan", Email: "ivan@example.com",
+	})))
+
+	sent := env.email.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "ivan@example.com", sent[0].To)
+	assert.Contains(t, sent[0].Subject, "Баскетбол")
+	assert.Empty(t, env.telegram.Sent(), "telegram chat id is not known yet")
+
+	// Redelivery of the same message must not send a duplicate
+	require.NoError(t, env.svc.Handle(ctx, envelope(t, "2", eventbus.TypeRegistrationCreated, eventbus.RegistrationPayload{
+		RegistrationID: 1, EventID: 7, UserID: 42, Username: "ivan", Email: "ivan@example.com",
+	})))
+	assert.Len(t, env.email.Sent(), 1)
+}
+
+func TestHandle_WaitlistPromoted(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+
+	require.NoError(t, env.svc.Handle(ctx, envelope(t, "1", eventbus.TypeEventCreated, eventbus.EventPayload{
+		EventID: 7, Name: "Баскетбол", StartTime: time.Now().Add(72 * time.Hour), Status: "active",
+	})))
+	require.NoError(t, env.svc.Handle(ctx, envelope(t, "2", eventbus.TypeRegistrationCreated, eventbus.RegistrationPayload{
+		RegistrationID: 1, EventID: 7, UserID: 42, Username: "ivan", Email: "ivan@example.com", Status: "waitlisted",
+	})))
+	assert.Empty(t, env.email.Sent(), "a waitlisted registration isn't confirmed")
+
+	require.NoError(t, env.svc.Handle(ctx, envelope(t, "3", eventbus.TypeRegistrationPromoted, eventbus.RegistrationPayload{
+		RegistrationID: 1, EventID: 7, UserID: 42, Status: "registered",
+	})))
+	sent := env.email.Sent()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "ivan@example.com", sent[0].To)
+	assert.Contains(t, sent[0].Subject, "Освободилось место")
+}
+
+func TestHandle_EventCancelled_RespectsOptOuts(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+	start := time.Now().Add(72 * time.Hour)
+
+	for _, userID := range []uint{1, 2} {
+		require.NoError(t, env.svc.Handle(ctx, envelope(t, fmt.Sprintf("reg-%d", userID), eventbus.TypeRegistrationCreated, eventbus.RegistrationPayload{
+			EventID: 7, UserID: userID, Email: fmt.Sprintf("user%d@example.com", userID),
+		})))
+	}
+	_, err := env.prefs.Save(&models.Preference{
+		UserID: 2, Locale: "en", Email: "user2@example.com", EmailEnabled: true,
+		TelegramChatID: "100", TelegramEnabled: true,
+		OptOuts: []models.OptOut{{Kind: models.KindEventCancelled, Channel: sender.ChannelEmail}},
+	})
+	require.NoError(t, err)
+	sentBefore := len(env.email.Sent())
+
+	require.NoError(t, env.svc.Handle(ctx, envelope(t, "3", eventbus.TypeEventCancelled, eventbus.EventPayload{
+		EventID: 7, Name: "Basketball", StartTime: start,
+	})))
+
+	emails := env.email.Sent()[sentBefore:]
+	require.Len(t, emails, 1)
+	assert.Equal(t, "user1@example.com", emails[0].To)
+
+	tg := env.telegram.Sent()
+	require.Len(t, tg, 1)
+	assert.Equal(t, "100", tg[0].To)
+	assert.Contains(t, tg[0].Subject, "cancelled", "user 2 prefers english")
+}
+
+func TestHandle_EventChanged_NotifiesEverySubscriberDespiteFailures(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+	start := time.Now().Add(72 * time.Hour)
+
+	for _, userID := range []uint{1, 2, 3} {
+		require.NoError(t, env.svc.Handle(ctx, envelope(t, fmt.Sprintf("reg-%d", userID), eventbus.TypeRegistrationCreated, eventbus.RegistrationPayload{
+			EventID: 7, UserID: userID, Email: fmt.Sprintf("user%d@example.com", userID),
+		})))
+	}
+	sentBefore := len(env.email.Sent())
+
+	changed := envelope(t, "changed", eventbus.TypeEventUpdated, eventbus.EventPayload{
+		EventID: 7, Name: "Basketball", StartTime: start, Status: "active",
+	})
+	env.email.FailTimes = 1
+	env.email.Err = errors.New("smtp is down")
+	assert.Error(t, env.svc.Handle(ctx, changed))
+	assert.Len(t, env.email.Sent()[sentBefore:], 2, "the other subscribers are notified anyway")
+
+	// the redelivery reaches the one that failed and nobody twice
+	require.NoError(t, env.svc.Handle(ctx, changed))
+	recipients := map[string]int{}
+	for _, msg := range env.email.Sent()[sentBefore:] {
+		recipients[msg.To]++
+	}
+	assert.Equal(t, map[string]int{"user1@example.com": 1, "user2@example.com": 1, "user3@example.com": 1}, recipients)
+}
+
+func TestDeliver_RetriesAndRecordsStatus(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+	bus := eventbus.NewMemoryBus(10, eventbus.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})
+
+	env.email.FailTimes = 2
+	env.email.Err = errors.New("smtp is down")
+	require.NoError(t, bus.Publish(ctx, envelope(t, "1", eventbus.TypeRegistrationCreated, eventbus.RegistrationPayload{
+		EventID: 1, UserID: 1, Email: "a@example.com",
+	})))
+	bus.Drain(ctx, env.svc.Handle)
+	require.Len(t, env.email.Sent(), 1)
+	assert.Empty(t, bus.DeadLetters())
+
+	var d models.Delivery
+	require.NoError(t, env.db.First(&d, "user_id = ?", 1).Error)
+	assert.Equal(t, models.StatusSent, d.Status)
+	assert.Equal(t, 3, d.Attempts, "the bus redelivers the event, each delivery sends once")
+
+	env.email.FailTimes = 5
+	require.NoError(t, bus.Publish(ctx, envelope(t, "2", eventbus.TypeRegistrationCreated, eventbus.RegistrationPayload{
+		EventID: 1, UserID: 2, Email: "b@example.com",
+	})))
+	bus.Drain(ctx, env.svc.Handle)
+	assert.Len(t, bus.DeadLetters(), 1, "the event is dead-lettered once the bus gives up")
+
+	var pending models.Delivery
+	require.NoError(t, env.db.First(&pending, "user_id = ?", 2).Error)
+	assert.Equal(t, models.StatusPending, pending.Status)
+	assert.Equal(t, 3, pending.Attempts)
+	assert.Equal(t, "smtp is down", pending.LastError)
+
+	env.email.FailTimes = 1
+	env.email.Err = fmt.Errorf("%w: mailbox does not exist", sender.ErrPermanent)
+	require.NoError(t, env.svc.Handle(ctx, envelope(t, "3", eventbus.TypeRegistrationCreated, eventbus.RegistrationPayload{
+		EventID: 1, UserID: 3, Email: "c@example.com",
+	})))
+
+	var failed models.Delivery
+	require.NoError(t, env.db.First(&failed, "user_id = ?", 3).Error)
+	assert.Equal(t, models.StatusFailed, failed.Status)
+	assert.Equal(t, 1, failed.Attempts, "permanent errors are not retried")
+}
+
+func TestSendDueReminders(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, env.svc.Handle(ctx, envelope(t, "1", eventbus.TypeEventCreated, eventbus.EventPayload{
+		EventID: 1, Name: "Soon", StartTime: now.Add(20 * time.Hour), Status: "active",
+	})))
+	require.NoError(t, env.svc.Handle(ctx, envelope(t, "2", eventbus.TypeEventCreated, eventbus.EventPayload{
+		EventID: 2, Name: "Later", StartTime: now.Add(48 * time.Hour), Status: "active",
+	})))
+	for _, eventID := range []uint{1, 2} {
+		require.NoError(t, env.svc.Handle(ctx, envelope(t, fmt.Sprintf("reg-%d", eventID), eventbus.TypeRegistrationCreated, eventbus.RegistrationPayload{
+			EventID: eventID, UserID: 5, Email: "u@example.com",
+		})))
+	}
+	sentBefore := len(env.email.Sent())
+
+	require.NoError(t, env.svc.SendDueReminders(ctx, now, 24*time.Hour))
+	require.NoError(t, env.svc.SendDueReminders(ctx, now, 24*time.Hour))
+
+	reminders := env.email.Sent()[sentBefore:]
+	require.Len(t, reminders, 1, "only the event within 24h is reminded, and only once")
+	assert.Contains(t, reminders[0].Subject, "Soon")
+
+	info, err := env.events.GetByID(1)
+	require.NoError(t, err)
+	assert.NotNil(t, info.ReminderSentAt)
+}
+
+func TestSendDueReminders_RetriesFailedEventsAfterBackoff(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for eventID, start := range map[uint]time.Duration{1: 20 * time.Hour, 2: 22 * time.Hour} {
+		require.NoError(t, env.svc.Handle(ctx, envelope(t, fmt.Sprintf("event-%d", eventID), eventbus.TypeEventCreated, eventbus.EventPayload{
+			EventID: eventID, Name: fmt.Sprintf("Event %d", eventID), StartTime: now.Add(start), Status: "active",
+		})))
+		require.NoError(t, env.svc.Handle(ctx, envelope(t, fmt.Sprintf("reg-%d", eventID), eventbus.TypeRegistrationCreated, eventbus.RegistrationPayload{
+			EventID: eventID, UserID: eventID, Email: fmt.Sprintf("user%d@example.com", eventID),
+		})))
+	}
+	sentBefore := len(env.email.Sent())
+
+	// the reminder of the earliest event fails, the other one is sent anyway
+	env.email.FailTimes = 1
+	env.email.Err = errors.New("smtp is down")
+	assert.ErrorContains(t, env.svc.SendDueReminders(ctx, now, 24*time.Hour), "reminders of event 1")
+	reminders := env.email.Sent()[sentBefore:]
+	require.Len(t, reminders, 1)
+	assert.Equal(t, "user2@example.com", reminders[0].To)
+
+	failed, err := env.events.GetByID(1)
+	require.NoError(t, err)
+	assert.Nil(t, failed.ReminderSentAt)
+	assert.Equal(t, 1, failed.ReminderAttempts)
+	require.NotNil(t, failed.ReminderRetryAt)
+	assert.WithinDuration(t, now.Add(time.Minute), *failed.ReminderRetryAt, time.Second)
+
+	require.NoError(t, env.svc.SendDueReminders(ctx, now.Add(30*time.Second), 24*time.Hour))
+	assert.Len(t, env.email.Sent()[sentBefore:], 1, "the event waits for its backoff")
+
+	require.NoError(t, env.svc.SendDueReminders(ctx, now.Add(time.Minute), 24*time.Hour))
+	reminders = env.email.Sent()[sentBefore:]
+	require.Len(t, reminders, 2)
+	assert.Equal(t, "user1@example.com", reminders[1].To)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"notification-service/internal/models"
+	"notification-service/internal/repository"
+	"notification-service/internal/sender"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/service"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// PreferenceService manages notification settings of the current user.
+type PreferenceService struct {
+	*service.GenericService[models.Preference]
+	repo          *repository.PreferenceRepository
+	defaultLocale string
+	locales       []string
+}
+
+// NewPreferenceService creates a new instance of PreferenceService.
+// locales lists the locales that users may choose.
+func NewPreferenceService(repo *repository.PreferenceRepository, defaultLocale string, locales []string) *PreferenceService {
+	return &PreferenceService{
+		GenericService: service.NewGenericService[models.Preference](repo),
+		repo:           repo,
+		defaultLocale:  defaultLocale,
+		locales:        locales,
+	}
+}
+
+// GetMy returns preferences of the user from the token, creating defaults on first access.
+func (s *PreferenceService) GetMy(claims jwt.MapClaims) (*models.Preference, error) {
+	userID, err := userIDFromClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+	pref, err := s.repo.GetByUserID(userID)
+	if err == nil {
+		return pref, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	email, _ := claims["email"].(string)
+	return s.repo.Create(&models.Preference{
+		UserID:          userID,
+		Locale:          s.defaultLocale,
+		Email:           email,
+		EmailEnabled:    email != "",
+		TelegramEnabled: true,
+	})
+}
+
+// UpdateMy replaces preferences of the user from the token.
+func (s *PreferenceService) UpdateMy(claims jwt.MapClaims, pref *models.Preference) (*models.Preference, error) {
+	current, err := s.GetMy(claims)
+	if err != nil {
+		return nil, err
+	}
+	pref.UserID = current.UserID
+	pref.CreatedAt = current.CreatedAt
+
+	if pref.Locale == "" {
+		pref.Locale = s.defaultLocale
+	}
+	if !s.supportedLocale(pref.Locale) {
+		return nil, fmt.Errorf("unsupported locale %q", pref.Locale)
+	}
+	for _, o := range pref.OptOuts {
+		if !validKind(o.Kind) {
+			return nil, fmt.Errorf("unknown notification kind %q", o.Kind)
+		}
+		if o.Channel != "" && o.Channel != sender.ChannelEmail && o.Channel != sender.ChannelTelegram {
+			return nil, fmt.Errorf("unknown channel %q", o.Channel)
+		}
+	}
+	return s.repo.Save(pref)
+}
+
+func (s *PreferenceService) supportedLocale(locale string) bool {
+	for _, l := range s.locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+func validKind(kind string) bool {
+	switch kind {
+	case models.KindAll, models.KindRegistrationConfirmed, models.KindWaitlistPromoted,
+		models.KindEventChanged, models.KindEventCancelled, models.KindEventReminder:
+		return true
+	}
+	return false
+}
+
+func userIDFromClaims(claims jwt.MapClaims) (uint, error) {
+	userIDFloat, ok := claims["userID"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("UserID is not a number")
+	}
+	return uint(userIDFloat), nil
+}
@@ -0,0 +1,94 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed ru en
+var files embed.FS
+
+// Data is passed to every notification template
+type Data struct {
+	Username  string
+	EventID   uint
+	EventName string
+	City      string
+	Address   string
+	StartTime time.Time
+}
+
+// Renderer renders notification subjects and bodies from per-locale templates.
+// Each template file defines "subject" and "body" blocks and is named after the notification kind.
+type Renderer struct {
+	defaultLocale string
+	// templates[locale][kind]
+	templates map[string]map[string]*template.Template
+}
+
+// NewRenderer parses the embedded templates. Messages for unknown locales are
+// rendered with defaultLocale.
+func NewRenderer(defaultLocale string) (*Renderer, error) {
+	r := &Renderer{
+		defaultLocale: defaultLocale,
+		templates:     make(map[string]map[string]*template.Template),
+	}
+	err := fs.WalkDir(files, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ".tmpl" {
+			return err
+		}
+		locale := path.Dir(p)
+		kind := strings.TrimSuffix(path.Base(p), ".tmpl")
+		tmpl, err := template.New(kind).ParseFS(files, p)
+		if err != nil {
+			return fmt.Errorf("failed to parse template %s: %w", p, err)
+		}
+		if r.templates[locale] == nil {
+			r.templates[locale] = make(map[string]*template.Template)
+		}
+		r.templates[locale][kind] = tmpl
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := r.templates[defaultLocale]; !ok {
+		return nil, fmt.Errorf("no templates for default locale %q", defaultLocale)
+	}
+	return r, nil
+}
+
+// Render returns the subject and body of the notification kind in the locale.
+func (r *Renderer) Render(locale, kind string, data Data) (string, string, error) {
+	tmpl, ok := r.templates[locale][kind]
+	if !ok {
+		tmpl, ok = r.templates[r.defaultLocale][kind]
+		if !ok {
+			return "", "", fmt.Errorf("no template for notification kind %q", kind)
+		}
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return "", "", fmt.Errorf("failed to render body: %w", err)
+	}
+	return strings.TrimSpace(subject.String()), strings.TrimSpace(body.String()), nil
+}
+
+// Locales returns the list of supported locales.
+func (r *Renderer) Locales() []string {
+	locales := make([]string, 0, len(r.templates))
+	for l := range r.templates {
+		locales = append(locales, l)
+	}
+	return locales
+}
@@ -0,0 +1,8 @@
+# generate the keys with ../scripts/gen-secrets.sh ../secrets first
+export PRIVATE_KEY_FILE="$(pwd)/../secrets/private_key"
+export PUBLIC_KEY_FILE="$(pwd)/../secrets/public_key"
+export SERVER_PORT=8087
+export DB_NAME=notifications_db
+export ENV=local
+export GRPC_SERVER_USE_TLS=false
+export GRPC_CLIENT_USE_TLS=false
@@ -3,37 +3,32 @@ package main
 import (
 	"context"
 	"fmt"
-	"log/slog"
-	"net/http"
 	"os"
-	grpcclient "registration-service/internal/client/grpc-client"
+	"time"
+	"registration-service/internal/config"
+	grpcserver "registration-service/internal/grpc-server"
 	"registration-service/internal/handler"
-	"registration-service/internal/models"
+	"registration-service/internal/migrations"
 	"registration-service/internal/repository"
 	"registration-service/internal/service"
-    "github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/app"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
-	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/config"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventsclient"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/logger"
-	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/middlewarelogger"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 )
 
 
 func main(){
-    cfg := config.MustLoadConfig()
-    log := logger.SetupLogger(cfg.Env)
-
-    log.Info("Connecting to db with params")
-    log.Info("Database: ", slog.String("host", cfg.Database.Host), slog.String("port", cfg.Database.Port))
+    a := app.New("registration-service")
+    cfg, log := a.Config, a.Log
+    var registrationCfg config.Config
+    a.MustLoadSection("registrations", &registrationCfg)
 
-    dsn := fmt.Sprintf("user=%s password=%s dbname=%s host=%s port=%s sslmode=%s",cfg.Database.User, cfg.Database.Password, cfg.Database.Name, cfg.Database.Host, cfg.Database.Port, "disable")
-    dbConnection := db.SetupDB(dsn, &models.Registration{})
+    dbConnection := a.MustConnectDB(migrations.FS)
     registrationRepo := repository.NewRegistrationRepository(dbConnection)
+    calendarTokenRepo := repository.NewCalendarTokenRepository(dbConnection)
     verifier, err := auth.NewVerifier(cfg.PublicKey)
     if err != nil {
         log.Error("failed to init JWT verifier", logger.Err(err))
@@ -44,32 +39,64 @@ func main(){
 
     // ----------------INIT GRPC CLIENT---------------------
 
-    eventClient, err := grpcclient.NewEventClient(
+    eventClient, err := eventsclient.New(
         context.Background(),
         log,
         fmt.Sprintf("%s:%d", cfg.GRPC.Client.Host, cfg.GRPC.Client.Port),
-        cfg.GRPC.Client.RetryTimeout,
-        cfg.GRPC.Client.RetryCount,
+        a.GRPCDialOptions(),
+        eventsclient.Options{
+            Timeout:         cfg.GRPC.Client.RetryTimeout,
+            Retries:         cfg.GRPC.Client.RetryCount,
+            Backoff:         cfg.GRPC.Client.RetryBackoff,
+            BreakerFailures: cfg.GRPC.Client.BreakerFailures,
+            BreakerCooldown: cfg.GRPC.Client.BreakerCooldown,
+        },
     )
     if err != nil {
         log.Error("failed to init event client", logger.Err(err))
         os.Exit(1)
     }
-    
-    registrationservice := service.NewRegistrationService(registrationRepo, eventClient)
+    // registrations can still be listed and cancelled while event-service is down
+    a.Health.AddOptional("event-service", eventClient.Health)
 
+    // registrations are stored with the domain events in units of work, relayed from the outbox
+    uow, bus := db.NewUnitOfWork(dbConnection), a.Outbox(dbConnection)
 
-    // -------------------INIT HTTP SERVER---------------
+    registrationservice := service.NewRegistrationService(registrationRepo, uow, eventClient, bus)
+
+
+    // ---------------GRPC SERVER------------------------
+
+    grpcserver.Register(a.GRPC(), registrationservice)
+
+
+    defaultTimeZone, err := time.LoadLocation(registrationCfg.Calendar.DefaultTimeZone)
+    if err != nil {
+        log.Error("failed to load default time zone", logger.Err(err))
+        panic("failed to load default time zone")
+    }
+    calendarService := service.NewCalendarService(calendarTokenRepo, registrationRepo, eventClient, service.CalendarOptions{
+        PublicURL:       registrationCfg.Calendar.PublicURL,
+        DefaultTimeZone: defaultTimeZone,
+        RefreshInterval: registrationCfg.Calendar.RefreshInterval,
+    })
 
-    handler := handler.NewRegistrationHandler(registrationservice, verifier)
 
-    router := chi.NewRouter()
-    router.Use(middleware.RequestID)
-    router.Use(middleware.RealIP)
-    router.Use(middlewarelogger.New(log))
-    router.Use(middleware.Recoverer)
-    router.Use(middleware.URLFormat)
+    ticketSigner, err := auth.NewSigner(registrationCfg.Ticket.PrivateKey)
+    if err != nil {
+        log.Error("failed to init ticket signer", logger.Err(err))
+        panic("failed to init ticket signer")
+    }
+    ticketService := service.NewTicketService(registrationRepo, eventClient, ticketSigner, uow, bus, service.TicketOptions{
+        ValidAfterEnd: registrationCfg.Ticket.ValidAfterEnd,
+    })
+
+
+    // -------------------INIT HTTP SERVER---------------
 
+    handler := handler.NewRegistrationHandler(registrationservice, calendarService, ticketService, verifier)
+
+    router := a.Router
     router.Post("/api/v1/registrations", handler.CreateHandler())
     router.Get("/api/v1/registrations", handler.GetAllHandler())
     router.Get("/api/v1/registrations/{id}", handler.GetByIDHandler())
@@ -78,31 +105,22 @@ func main(){
     //router.Delete("/api/v1/registrations/where", handler.DeleteWhereHandler())
     router.Get("/api/v1/registrations/search", handler.FindHandler())
     router.Get("/api/v1/registrations/my", handler.GetMyHandler())
+    router.Get("/api/v1/registrations/calendar", handler.GetMyCalendarHandler())
+    router.Post("/api/v1/registrations/calendar/rotate", handler.RotateMyCalendarHandler())
+    router.Get("/api/v1/registrations/calendar/{token}", handler.CalendarFeedHandler())
+    router.Get("/api/v1/registrations/{id}/ticket", handler.TicketHandler())
+    router.Post("/api/v1/registrations/checkin", handler.CheckInHandler())
+    router.Get("/api/v1/registrations/events/{eventID}/attendance", handler.AttendanceHandler())
+    router.Get("/api/v1/registrations/tickets/keys", handler.TicketKeysHandler())
     router.Get("/api/v1/registrations/search/first", handler.FindFirstHandler())
     router.Get("/api/v1/registrations/count", handler.CountHandler())
+    router.Get("/api/v1/registrations/stats", handler.StatsHandler())
     router.Get("/api/v1/registrations/page", handler.GetPageHandler())
     //router.Post("/api/v1/registrations/bulk", handler.BulkInsertHandler())
     //router.Put("/api/v1/registrations/bulk", handler.BulkUpdateHandler())
 
 
-    // go prometheus metrics
-    go func (){
-        http.Handle("/metrics", promhttp.Handler())
-        http.ListenAndServe(":9100", nil)
-    }()
-
-    srv := &http.Server{
-        Addr: fmt.Sprintf("%s:%d",cfg.Server.Addr, cfg.Server.Port),
-        Handler: router,
-        ReadTimeout: cfg.Server.ReadTimeout,
-        WriteTimeout: cfg.Server.WriteTimeout,
-        IdleTimeout: cfg.Server.IdleTimeout,
-    }
-
-    log.Info(fmt.Sprintf("Server listening on port %d", cfg.Server.Port))
-    if err := srv.ListenAndServe(); err != nil {
-        log.Error("failed to start server", logger.Err(err))
+    if err := a.Run(); err != nil {
+        os.Exit(1)
     }
-    log.Info("server stopped")
-   
 }
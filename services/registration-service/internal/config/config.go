@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+// Config is the registration-service section of the config file, under the `registrations` key.
+// Shared settings like the server, database and keys are in the common config.
+type Config struct {
+    Calendar struct {
+        // PublicURL is the external address of the site, used in feed links and event UIDs
+        PublicURL       string        `yaml:"public_url" envconfig:"CALENDAR_PUBLIC_URL" default:"https://localhost" validate:"required"`
+        DefaultTimeZone string        `yaml:"default_time_zone" envconfig:"CALENDAR_DEFAULT_TIME_ZONE" default:"Asia/Novosibirsk" validate:"required"`
+        RefreshInterval time.Duration `yaml:"refresh_interval" envconfig:"CALENDAR_REFRESH_INTERVAL" default:"1h" validate:"min=1m"`
+    } `yaml:"calendar"`
+
+    Ticket struct {
+        // PrivateKey signs event tickets. It is a key of its own, a leaked ticket key
+        // must not be able to sign access tokens.
+        PrivateKey    string        `yaml:"private_key" envconfig:"TICKET_PRIVATE_KEY" secret:"true" validate:"required"`
+        // ValidAfterEnd is how long a ticket stays valid after the end of the event
+        ValidAfterEnd time.Duration `yaml:"valid_after_end" envconfig:"TICKET_VALID_AFTER_END" default:"24h" validate:"min=0s"`
+    } `yaml:"ticket"`
+}
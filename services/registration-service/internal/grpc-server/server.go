@@ -0,0 +1,47 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"registration-service/internal/models"
+	"registration-service/internal/service"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/grpcauth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/protos/registrations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type serverAPI struct {
+    registrations.RegistrationServiceServer
+    service *service.RegistrationService
+}
+
+func Register(gRPC *grpc.Server, service *service.RegistrationService) {
+    registrations.RegisterRegistrationServiceServer(gRPC, &serverAPI{service: service})
+}
+
+// IsRegistered reports whether the user holds a registration for the event.
+// A call made for a user may only ask about that user, a service may ask about anyone.
+func (s *serverAPI) IsRegistered(ctx context.Context, req *registrations.IsRegisteredRequest) (*registrations.IsRegisteredResponse, error) {
+    if principal, _ := grpcauth.FromContext(ctx); principal.User != nil {
+        if userID, ok := principal.UserID(); !ok || uint32(userID) != req.UserId {
+            return nil, status.Error(codes.PermissionDenied, "registrations of other users are not visible")
+        }
+    }
+    registration, err := s.service.WithContext(ctx).FindOfUser(uint(req.EventId), uint(req.UserId))
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return &registrations.IsRegisteredResponse{Registered: false}, nil
+    }
+    if err != nil {
+        return nil, status.Error(codes.Internal, "failed to check registration")
+    }
+
+    // a registration on the waitlist isn't one yet, the status tells it apart
+    return &registrations.IsRegisteredResponse{
+        Registered: registration.Status != models.StatusWaitlisted,
+        Status:     registration.Status,
+    }, nil
+}
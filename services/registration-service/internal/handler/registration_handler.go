@@ -2,21 +2,33 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"registration-service/internal/models"
+	"registration-service/internal/repository"
 	"registration-service/internal/service"
 
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/handler"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/ical"
+	"github.com/go-chi/chi/v5"
 )
 
 type RegistrationHandler struct {
     *handler.GenericHandler[models.Registration]
+    registrations *service.RegistrationService
+    calendar *service.CalendarService
+    tickets  *service.TicketService
 }
 
-func NewRegistrationHandler(service *service.RegistrationService, verifier *auth.Verifier) *RegistrationHandler {
+func NewRegistrationHandler(service *service.RegistrationService, calendar *service.CalendarService, tickets *service.TicketService, verifier *auth.Verifier) *RegistrationHandler {
+    generic := handler.NewGenericHandler[models.Registration](service, verifier)
+    generic.StatsFields = repository.RegistrationStatsFields
     return &RegistrationHandler{
-        GenericHandler: handler.NewGenericHandler[models.Registration](service, verifier),
+        GenericHandler: generic,
+        registrations:  service,
+        calendar:       calendar,
+        tickets:        tickets,
     }
 }
 
@@ -69,7 +81,7 @@ func (h *RegistrationHandler) DeleteHandler() http.HandlerFunc {
 			return
 		}
 
-		err = h.Service.Delete(claims, int(req.EventID))
+		err = h.registrations.DeleteContext(r.Context(), claims, int(req.EventID))
 		if err != nil {
 			http.Error(w, "Failed to delete registration: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -79,3 +91,65 @@ func (h *RegistrationHandler) DeleteHandler() http.HandlerFunc {
 	}
 }
 
+// GetMyCalendarHandler returns the links to the calendar feed of the current user.
+func (h *RegistrationHandler) GetMyCalendarHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		feed, err := h.calendar.GetMyFeed(claims)
+		if err != nil {
+			http.Error(w, "Error retrieving calendar feed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(feed)
+	}
+}
+
+// RotateMyCalendarHandler issues new links to the calendar feed; the old ones stop working.
+func (h *RegistrationHandler) RotateMyCalendarHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		feed, err := h.calendar.RotateMyFeed(claims)
+		if err != nil {
+			http.Error(w, "Error rotating calendar feed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(feed)
+	}
+}
+
+// CalendarFeedHandler serves the calendar feed. It is authorized by the token
+// in the path instead of the cookie, so calendar apps can subscribe to it.
+func (h *RegistrationHandler) CalendarFeedHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		calendar, err := h.calendar.Calendar(r.Context(), chi.URLParam(r, "token"))
+		if errors.Is(err, service.ErrFeedNotFound) {
+			http.Error(w, "Calendar feed not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Error building calendar feed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", ical.ContentType)
+		w.Header().Set("Content-Disposition", `inline; filename="events.ics"`)
+		if err := calendar.Write(w); err != nil {
+			http.Error(w, "Error writing calendar: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"registration-service/internal/service"
+	"strconv"
+	"strings"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/skip2/go-qrcode"
+)
+
+// qrSize is the width of PNG tickets in pixels
+const qrSize = 512
+
+// TicketHandler returns the ticket of a registration as JSON,
+// or as a QR code for "{id}/ticket.png" and "{id}/ticket.svg".
+func (h *RegistrationHandler) TicketHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil || id <= 0 {
+			http.Error(w, "Invalid id parameter", http.StatusBadRequest)
+			return
+		}
+
+		ticket, err := h.tickets.Issue(r.Context(), claims, uint(id))
+		if err != nil {
+			ticketError(w, "Error issuing ticket", err)
+			return
+		}
+
+		// Tickets are personal, they must not end up in shared caches
+		w.Header().Set("Cache-Control", "private, no-store")
+
+		format, _ := r.Context().Value(middleware.URLFormatCtxKey).(string)
+		switch format {
+		case "", "json":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(ticket)
+		case "png":
+			png, err := qrcode.Encode(ticket.Token, qrcode.Medium, qrSize)
+			if err != nil {
+				http.Error(w, "Error rendering ticket: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(png)
+		case "svg":
+			code, err := qrcode.New(ticket.Token, qrcode.Medium)
+			if err != nil {
+				http.Error(w, "Error rendering ticket: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "image/svg+xml")
+			writeSVG(w, code.Bitmap())
+		default:
+			http.Error(w, "Unsupported ticket format: "+format, http.StatusNotFound)
+		}
+	}
+}
+
+// CheckInHandler checks the holder of a scanned ticket in.
+// Only the organizer of the event can call it.
+func (h *RegistrationHandler) CheckInHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Ticket string `json:"ticket"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Ticket == "" {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		checkIn, err := h.tickets.CheckIn(r.Context(), claims, req.Ticket)
+		if err != nil {
+			ticketError(w, "Check-in failed", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(checkIn)
+	}
+}
+
+// AttendanceHandler returns the attendance statistics of an event to its organizer.
+func (h *RegistrationHandler) AttendanceHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := h.CheckToken(r)
+		if err != nil {
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		eventID, err := strconv.Atoi(chi.URLParam(r, "eventID"))
+		if err != nil || eventID <= 0 {
+			http.Error(w, "Invalid eventID parameter", http.StatusBadRequest)
+			return
+		}
+
+		stats, err := h.tickets.Stats(r.Context(), claims, uint(eventID))
+		if err != nil {
+			ticketError(w, "Error retrieving attendance", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	}
+}
+
+// TicketKeysHandler publishes the public key of tickets as a JWK set,
+// so scanner apps can verify tickets offline.
+func (h *RegistrationHandler) TicketKeysHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(struct {
+			Keys []auth.JWK `json:"keys"`
+		}{
+			Keys: []auth.JWK{h.tickets.PublicKey()},
+		})
+	}
+}
+
+func ticketError(w http.ResponseWriter, msg string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrRegistrationNotFound), errors.Is(err, service.ErrEventNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrNotOrganizer):
+		status = http.StatusForbidden
+	case errors.Is(err, service.ErrAlreadyCheckedIn), errors.Is(err, service.ErrWaitlisted):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrInvalidTicket):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrEventEnded):
+		status = http.StatusGone
+	}
+	http.Error(w, msg+": "+err.Error(), status)
+}
+
+// writeSVG draws the QR code as one path of unit squares, the bitmap already includes the quiet zone.
+func writeSVG(w io.Writer, bitmap [][]bool) {
+	size := len(bitmap)
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size)
+	fmt.Fprintf(w, `<rect width="%d" height="%d" fill="#fff"/><path d="%s" fill="#000"/></svg>`, size, size, path.String())
+}
@@ -0,0 +1,22 @@
+-- the schema AutoMigrate created, existing databases are left as they are
+CREATE TABLE IF NOT EXISTS registrations (
+    id bigserial PRIMARY KEY,
+    event_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    registration_time timestamptz,
+    status varchar(50) NOT NULL DEFAULT 'registered',
+    updated_at timestamptz,
+    comment text,
+    checked_in_at timestamptz,
+    checked_in_by varchar(255)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_user ON registrations (event_id, user_id);
+
+CREATE TABLE IF NOT EXISTS calendar_tokens (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    token varchar(64) NOT NULL,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_tokens_user_id ON calendar_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_tokens_token ON calendar_tokens (token);
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarToken is a secret that gives access to the calendar feed of a user
+// without a session, since calendar apps cannot log in.
+type CalendarToken struct {
+    ID        uint      `gorm:"primaryKey" json:"-"`
+    UserID    uint      `gorm:"not null;uniqueIndex" json:"user_id"`
+    Token     string    `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+    CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
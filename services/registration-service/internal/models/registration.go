@@ -13,8 +13,19 @@ type Registration struct {
     Status           string         `gorm:"type:varchar(50);not null;default:'registered'" json:"status"`
     UpdatedAt        time.Time      `gorm:"autoUpdateTime" json:"updated_at"`                   
     Comment          string         `gorm:"type:text" json:"comment,omitempty"`                 
+    CheckedInAt      *time.Time     `json:"checked_in_at,omitempty"`
+    CheckedInBy      string         `gorm:"type:varchar(255)" json:"checked_in_by,omitempty"`
 }
 
+// Registration statuses
+const (
+    StatusRegistered = "registered"
+    StatusAttended   = "attended"
+    // StatusWaitlisted is a registration for a full event, it holds no seat until a
+    // registration cancelled before it passes its seat on
+    StatusWaitlisted = "waitlisted"
+)
+
 
 // JSON EXAMPLE
 
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"fmt"
+	"registration-service/internal/models"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CalendarTokenRepository struct {
+    *repository.GenericRepository[models.CalendarToken]
+}
+
+func NewCalendarTokenRepository(db *gorm.DB) *CalendarTokenRepository {
+	return &CalendarTokenRepository{
+		GenericRepository: repository.NewGenericRepository[models.CalendarToken](db),
+	}
+}
+
+// Save stores the token of the user, replacing the previous one.
+func (repo *CalendarTokenRepository) Save(token *models.CalendarToken) (*models.CalendarToken, error) {
+    result := repo.Db.Clauses(clause.OnConflict{
+        Columns:   []clause.Column{{Name: "user_id"}},
+        DoUpdates: clause.AssignmentColumns([]string{"token", "created_at"}),
+    }).Create(token)
+    if result.Error != nil {
+        return nil, fmt.Errorf("%w: %v", repository.ErrCreateEntity, result.Error)
+    }
+    return repo.FindFirst("user_id = ?", token.UserID)
+}
@@ -1,12 +1,38 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
 	"registration-service/internal/models"
+	"time"
 
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/repository"
 	"gorm.io/gorm"
 )
 
+// Fields of Registration for the typed queries
+var (
+    RegistrationEventID     = repository.FieldOf[models.Registration, uint]("EventID")
+    RegistrationUserID      = repository.FieldOf[models.Registration, uint]("UserID")
+    RegistrationStatus      = repository.FieldOf[models.Registration, string]("Status")
+    RegistrationTime        = repository.FieldOf[models.Registration, time.Time]("RegistrationTime")
+    RegistrationCheckedInAt = repository.FieldOf[models.Registration, time.Time]("CheckedInAt")
+)
+
+// OfUser is the query of the registration of the user for the event
+func OfUser(eventID, userID uint) repository.Query[models.Registration] {
+    return repository.Where(RegistrationEventID.Eq(eventID), RegistrationUserID.Eq(userID))
+}
+
+// RegistrationStatsFields are the fields of the registration stats, by query parameter.
+// The user isn't one of them, the stats don't tell who registered.
+var RegistrationStatsFields = map[string]repository.Column[models.Registration]{
+    "event_id":          RegistrationEventID,
+    "status":            RegistrationStatus,
+    "registration_time": RegistrationTime,
+    "checked_in_at":     RegistrationCheckedInAt,
+}
+
 type RegistrationRepository struct {
     *repository.GenericRepository[models.Registration]
 }
@@ -17,6 +43,84 @@ func NewRegistrationRepository(db *gorm.DB) *RegistrationRepository {
 	}
 }
 
+// CheckIn marks the registration attended unless it is already checked in or cancelled.
+// It reports whether the registration was changed, so concurrent scans check in only once.
+func (repo *RegistrationRepository) CheckIn(id uint, checkedInBy string, at time.Time) (bool, error) {
+    result := repo.Db.Model(&models.Registration{}).
+        Where("id = ? AND status = ?", id, models.StatusRegistered).
+        Updates(map[string]interface{}{
+            "status":        models.StatusAttended,
+            "checked_in_at": at,
+            "checked_in_by": checkedInBy,
+        })
+    if result.Error != nil {
+        return false, fmt.Errorf("%w: %v", repository.ErrUpdateEntity, result.Error)
+    }
+    return result.RowsAffected == 1, nil
+}
+
+// CancelAndPromote deletes the registration and passes its seat to the registration
+// waitlisted first for the event, in one transaction. With nobody on the waitlist free
+// is called to give the seat back, an error of it rolls the deletion back. It returns
+// the promoted registration, nil when the seat was freed.
+func (repo *RegistrationRepository) CancelAndPromote(registration *models.Registration, free func() error) (*models.Registration, error) {
+    var promoted *models.Registration
+    err := repo.Db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Delete(&models.Registration{}, registration.ID).Error; err != nil {
+            return fmt.Errorf("%w: %v", repository.ErrDeleteEntity, err)
+        }
+        for {
+            var next models.Registration
+            err := tx.Where("event_id = ? AND status = ?", registration.EventID, models.StatusWaitlisted).
+                Order("registration_time, id").
+                First(&next).Error
+            if errors.Is(err, gorm.ErrRecordNotFound) {
+                return free()
+            }
+            if err != nil {
+                return fmt.Errorf("%w: %v", repository.ErrFindEntity, err)
+            }
+            // a concurrent cancellation may have promoted it already, then the next one is tried
+            result := tx.Model(&models.Registration{}).
+                Where("id = ? AND status = ?", next.ID, models.StatusWaitlisted).
+                Update("status", models.StatusRegistered)
+            if result.Error != nil {
+                return fmt.Errorf("%w: %v", repository.ErrUpdateEntity, result.Error)
+            }
+            if result.RowsAffected == 1 {
+                next.Status = models.StatusRegistered
+                promoted = &next
+                return nil
+            }
+        }
+    })
+    if err != nil {
+        return nil, err
+    }
+    return promoted, nil
+}
+
+// CountByStatus returns the number of registrations for the event grouped by status.
+func (repo *RegistrationRepository) CountByStatus(eventID uint) (map[string]int64, error) {
+    var rows []struct {
+        Status string
+        Count  int64
+    }
+    result := repo.Db.Model(&models.Registration{}).
+        Select("status, COUNT(*) AS count").
+        Where("event_id = ?", eventID).
+        Group("status").
+        Scan(&rows)
+    if result.Error != nil {
+        return nil, fmt.Errorf("%w: %v", repository.ErrCountEntities, result.Error)
+    }
+    counts := make(map[string]int64, len(rows))
+    for _, row := range rows {
+        counts[row.Status] = row.Count
+    }
+    return counts, nil
+}
+
 // // GetByCategory returns all events that belong to the specified category.
 // func (er *RegistrationRepository) GetByCategory(category string) ([]models.Registration, error) {
 // 	var events []models.Registration
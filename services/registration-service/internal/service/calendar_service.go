@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"registration-service/internal/models"
+	"registration-service/internal/repository"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/ical"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/protos/events"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// ErrFeedNotFound is returned for unknown or rotated feed tokens
+var ErrFeedNotFound = errors.New("calendar feed not found")
+
+// batchSize matches the limit of BatchGetEvents in event-service
+const batchSize = 500
+
+// EventBatchGetter is implemented by the event-service gRPC client.
+type EventBatchGetter interface {
+    BatchGetEvents(ctx context.Context, eventIDs []uint32) (*events.BatchGetEventsResponse, error)
+}
+
+// CalendarOptions configures calendar feeds
+type CalendarOptions struct {
+    PublicURL       string
+    DefaultTimeZone *time.Location
+    RefreshInterval time.Duration
+}
+
+// Feed holds the links to the calendar feed of a user
+type Feed struct {
+    URL       string `json:"url"`
+    WebcalURL string `json:"webcal_url"`
+}
+
+// CalendarService builds subscribable calendar feeds of the events a user is registered for.
+type CalendarService struct {
+    tokens        *repository.CalendarTokenRepository
+    registrations *repository.RegistrationRepository
+    events        EventBatchGetter
+    opts          CalendarOptions
+}
+
+func NewCalendarService(
+    tokens *repository.CalendarTokenRepository,
+    registrations *repository.RegistrationRepository,
+    events EventBatchGetter,
+    opts CalendarOptions,
+) *CalendarService {
+    if opts.DefaultTimeZone == nil {
+        opts.DefaultTimeZone = time.UTC
+    }
+    return &CalendarService{
+        tokens:        tokens,
+        registrations: registrations,
+        events:        events,
+        opts:          opts,
+    }
+}
+
+// GetMyFeed returns the feed links of the current user, creating the feed on first use.
+func (s *CalendarService) GetMyFeed(claims jwt.MapClaims) (*Feed, error) {
+    userID, err := userIDFromClaims(claims)
+    if err != nil {
+        return nil, err
+    }
+    token, err := s.tokens.FindFirst("user_id = ?", userID)
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return s.issue(userID)
+    }
+    if err != nil {
+        return nil, err
+    }
+    return s.feed(token.Token), nil
+}
+
+// RotateMyFeed replaces the feed token of the current user; the old links stop working.
+func (s *CalendarService) RotateMyFeed(claims jwt.MapClaims) (*Feed, error) {
+    userID, err := userIDFromClaims(claims)
+    if err != nil {
+        return nil, err
+    }
+    return s.issue(userID)
+}
+
+// Calendar returns the calendar of the user owning the feed token.
+func (s *CalendarService) Calendar(ctx context.Context, token string) (*ical.Calendar, error) {
+    feedToken, err := s.tokens.FindFirst("token = ?", token)
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrFeedNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    // the waitlist holds no seat, its events aren't on the calendar until a promotion
+    registrations, err := s.registrations.Find("user_id = ? AND status <> ?", feedToken.UserID, models.StatusWaitlisted)
+    if err != nil {
+        return nil, err
+    }
+    ids := make([]uint32, 0, len(registrations))
+    for _, registration := range registrations {
+        ids = append(ids, uint32(registration.EventID))
+    }
+
+    calendar := &ical.Calendar{
+        Name:            "Event Planner",
+        RefreshInterval: s.opts.RefreshInterval,
+    }
+    for start := 0; start < len(ids); start += batchSize {
+        end := min(start+batchSize, len(ids))
+        resp, err := s.events.BatchGetEvents(ctx, ids[start:end])
+        if err != nil {
+            return nil, fmt.Errorf("failed to get events: %w", err)
+        }
+        found := make(map[uint32]bool, len(resp.Events))
+        for _, event := range resp.Events {
+            found[event.Id] = true
+            calendar.Events = append(calendar.Events, s.icalEvent(event))
+        }
+        // deleted events come back cancelled, the ones purged since are cancelled here
+        // so that calendar apps drop the copies they still show
+        for _, id := range ids[start:end] {
+            if !found[id] {
+                calendar.Events = append(calendar.Events, s.cancelledEvent(id))
+            }
+        }
+    }
+    sort.Slice(calendar.Events, func(i, j int) bool {
+        return calendar.Events[i].Start.Before(calendar.Events[j].Start)
+    })
+    return calendar, nil
+}
+
+func (s *CalendarService) issue(userID uint) (*Feed, error) {
+    secret := make([]byte, 32)
+    if _, err := rand.Read(secret); err != nil {
+        return nil, fmt.Errorf("failed to generate feed token: %w", err)
+    }
+    token, err := s.tokens.Save(&models.CalendarToken{
+        UserID: userID,
+        Token:  base64.RawURLEncoding.EncodeToString(secret),
+    })
+    if err != nil {
+        return nil, err
+    }
+    return s.feed(token.Token), nil
+}
+
+func (s *CalendarService) feed(token string) *Feed {
+    url := strings.TrimRight(s.opts.PublicURL, "/") + "/api/v1/registrations/calendar/" + token + ".ics"
+    webcal := url
+    if i := strings.Index(url, "://"); i >= 0 {
+        webcal = "webcal" + url[i:]
+    }
+    return &Feed{URL: url, WebcalURL: webcal}
+}
+
+// icalEvent converts the event to a VEVENT in the time zone of the event.
+func (s *CalendarService) icalEvent(event *events.Event) ical.Event {
+    loc := s.opts.DefaultTimeZone
+    if event.TimeZone != "" {
+        if l, err := time.LoadLocation(event.TimeZone); err == nil {
+            loc = l
+        }
+    }
+
+    var categories []string
+    if event.Category != "" {
+        categories = []string{event.Category}
+    }
+
+    return ical.Event{
+        UID:          ical.EventUID(uint(event.Id), s.opts.PublicURL),
+        Sequence:     int(event.Sequence),
+        Summary:      event.Name,
+        Description:  event.Description,
+        Location:     ical.JoinLocation(event.Address, event.City),
+        Categories:   categories,
+        Latitude:     event.Latitude,
+        Longitude:    event.Longitude,
+        Start:        event.StartTime.AsTime().In(loc),
+        End:          event.EndTime.AsTime().In(loc),
+        Status:       ical.EventStatus(event.Status),
+        Created:      event.CreatedAt.AsTime(),
+        LastModified: event.UpdatedAt.AsTime(),
+    }
+}
+
+// cancelledEvent is the VEVENT cancelling an event event-service no longer has
+func (s *CalendarService) cancelledEvent(eventID uint32) ical.Event {
+    return ical.Event{
+        UID:     ical.EventUID(uint(eventID), s.opts.PublicURL),
+        Summary: "Cancelled event",
+        Status:  ical.StatusCancelled,
+    }
+}
+
+func userIDFromClaims(claims jwt.MapClaims) (uint, error) {
+    userIDFloat, ok := claims["userID"].(float64)
+    if !ok {
+        return 0, fmt.Errorf("UserID is not a number")
+    }
+    return uint(userIDFloat), nil
+}
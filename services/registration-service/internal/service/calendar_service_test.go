@@ -0,0 +1,66 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"registration-service/internal/models"
+	"registration-service/internal/repository"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/ical"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/protos/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (f fakeEventGetter) BatchGetEvents(_ context.Context, eventIDs []uint32) (*events.BatchGetEventsResponse, error) {
+	resp := &events.BatchGetEventsResponse{}
+	for _, id := range eventIDs {
+		if event, ok := f[id]; ok {
+			resp.Events = append(resp.Events, event)
+		}
+	}
+	return resp, nil
+}
+
+func TestCalendar_CancelsDeletedAndMissingEvents(t *testing.T) {
+	env := setupTestEnv(t)
+	require.NoError(t, env.repo.Db.AutoMigrate(&models.CalendarToken{}))
+	tokens := repository.NewCalendarTokenRepository(env.repo.Db)
+	for eventID, status := range map[uint]string{1: models.StatusRegistered, 2: models.StatusRegistered, 3: models.StatusAttended, 4: models.StatusWaitlisted} {
+		_, err := env.repo.Create(&models.Registration{EventID: eventID, UserID: 7, Status: status})
+		require.NoError(t, err)
+	}
+
+	start := time.Date(2025, 5, 15, 10, 0, 0, 0, time.UTC)
+	getter := fakeEventGetter{
+		1: {Id: 1, Name: "Баскетбол", Status: "active", StartTime: timestamppb.New(start), EndTime: timestamppb.New(start.Add(time.Hour))},
+		// deleted in event-service, which returns it cancelled
+		2: {Id: 2, Name: "Волейбол", Status: "cancelled", Sequence: 3, StartTime: timestamppb.New(start), EndTime: timestamppb.New(start.Add(time.Hour))},
+		4: {Id: 4, Name: "Футбол", Status: "active", StartTime: timestamppb.New(start), EndTime: timestamppb.New(start.Add(time.Hour))},
+	}
+	svc := NewCalendarService(tokens, env.repo, getter, CalendarOptions{PublicURL: "https://events.example.com"})
+	feed, err := svc.GetMyFeed(userClaims(7, "ivan"))
+	require.NoError(t, err)
+	token := strings.TrimSuffix(feed.URL[strings.LastIndex(feed.URL, "/")+1:], ".ics")
+
+	calendar, err := svc.Calendar(context.Background(), token)
+	require.NoError(t, err)
+	statuses := make(map[string]string)
+	for _, event := range calendar.Events {
+		statuses[event.UID] = event.Status
+	}
+	assert.Equal(t, map[string]string{
+		ical.EventUID(1, "https://events.example.com"): ical.StatusConfirmed,
+		ical.EventUID(2, "https://events.example.com"): ical.StatusCancelled,
+		ical.EventUID(3, "https://events.example.com"): ical.StatusCancelled,
+	}, statuses, "the waitlisted event is left out, the deleted and the missing ones are cancelled")
+
+	var out bytes.Buffer
+	require.NoError(t, calendar.Write(&out))
+	assert.Equal(t, 2, strings.Count(out.String(), "DTSTART"), "the missing event has no times")
+	assert.Contains(t, out.String(), "SEQUENCE:3")
+}
@@ -3,10 +3,13 @@ package service
 import (
 	"context"
 	"fmt"
-	grpcclient "registration-service/internal/client/grpc-client"
+	"strconv"
 	"registration-service/internal/models"
 	"registration-service/internal/repository"
 
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventbus"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventsclient"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/protos/events"
 	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/service"
 	"github.com/golang-jwt/jwt/v5"
@@ -17,27 +20,60 @@ import (
 // It embeds GenericService for basic CRUD operations and adds additional dependencies (e.g., a verifier).
 type RegistrationService struct {
 	*service.GenericService[models.Registration]
-    eventClient *grpcclient.EventClient
+    repo        *repository.RegistrationRepository
+    uow         *db.UnitOfWork
+    eventClient *eventsclient.Client
+    bus         eventbus.Publisher
 }
 
 // NewRegistrationService creates a new instance of RegistrationService using the provided verifier and repository.
 // It initializes the underlying GenericService using the given repository.
-func NewRegistrationService(repo *repository.RegistrationRepository, grpcclient *grpcclient.EventClient) *RegistrationService {
+// The registrations are stored and published in units of work of uow, so bus should
+// be the outbox of its database.
+func NewRegistrationService(repo *repository.RegistrationRepository, uow *db.UnitOfWork, eventClient *eventsclient.Client, bus eventbus.Publisher) *RegistrationService {
 	return &RegistrationService{
 		GenericService: service.NewGenericService[models.Registration](repo),
-        eventClient: grpcclient,
+        repo:        repo,
+        uow:         uow,
+        eventClient: eventClient,
+        bus:         bus,
 	}
 }
 
+// WithContext returns a copy of the service whose queries run with ctx.
+func (s *RegistrationService) WithContext(ctx context.Context) *RegistrationService {
+    scoped := *s
+    scoped.GenericService = s.GenericService.WithContext(ctx)
+    scoped.repo = &repository.RegistrationRepository{GenericRepository: s.repo.WithContext(ctx)}
+    return &scoped
+}
+
+// FindOfUser returns the registration of the user for the event, gorm.ErrRecordNotFound if there is none.
+func (s *RegistrationService) FindOfUser(eventID, userID uint) (*models.Registration, error) {
+    return s.repo.FirstBy(repository.OfUser(eventID, userID))
+}
+
 func (s *RegistrationService) Create(claims jwt.MapClaims, entity *models.Registration) (*models.Registration, error) {
+    return s.CreateContext(context.Background(), claims, entity)
+}
+
+// CreateContext registers the user for the event, reserving a place in event-service,
+// or puts them on the waitlist when the event is full.
+// Calls to event-service and the database are made with ctx.
+func (s *RegistrationService) CreateContext(ctx context.Context, claims jwt.MapClaims, entity *models.Registration) (*models.Registration, error) {
     userIDFloat, ok := claims["userID"].(float64)
     if !ok {
         return nil, fmt.Errorf("UserID is not a number")
     }
 
-    entity.UserID = uint(userIDFloat) 
+    entity.UserID = uint(userIDFloat)
+    // the status and the check-in are the service's to set, not the client's
+    entity.ID = 0
+    entity.Status = models.StatusRegistered
+    entity.CheckedInAt = nil
+    entity.CheckedInBy = ""
 
-    existing, err := s.FindFirst(claims, "event_id = ? AND user_id = ?", entity.EventID, entity.UserID)
+    existing, err := s.WithContext(ctx).FindOfUser(entity.EventID, entity.UserID)
     if err == nil && existing != nil {
         return nil, fmt.Errorf("user is already registered for this event")
     }
@@ -51,36 +87,49 @@ func (s *RegistrationService) Create(claims jwt.MapClaims, entity *models.Regist
         return nil, fmt.Errorf("error checking existing registration: %w", err)
     }
     
-    resp, err := s.eventClient.CheckAndReserve(context.Background(), uint32(entity.EventID), username)
-    if err != nil {
-        return nil, err
-    }
-    if resp.Status == events.ReserveStatus_RESERVE_STATUS_UNSPECIFIED {
+    _, err = s.eventClient.CheckAndReserve(ctx, uint32(entity.EventID))
+    switch eventsclient.Outcome(err) {
+    case events.ReserveStatus_ORGANIZER_CANNOT_REGISTER:
         return nil, fmt.Errorf("Event creator cannot register for their own event")
+    case events.ReserveStatus_EVENT_NOT_FOUND:
+        return nil, fmt.Errorf("Event with id %d not found", entity.EventID)
+    case events.ReserveStatus_EVENT_FULL:
+        // the user waits for a seat to be passed on by a cancellation
+        entity.Status = models.StatusWaitlisted
+        err = nil
+    case events.ReserveStatus_EVENT_CANCELLED:
+        return nil, fmt.Errorf("Event with id %d is cancelled", entity.EventID)
     }
-
-    if resp.Status == events.ReserveStatus_EVENT_NOT_FOUND {
-        return nil, fmt.Errorf("Event with id %d not found",entity.EventID)
-    }
-
-    if resp.Status == events.ReserveStatus_EVENT_FULL {
-        return nil, fmt.Errorf("Event with id %d is full", entity.EventID)
-    }
-
-    if resp.Status == events.ReserveStatus_INTERNAL_ERROR {
-        return nil, fmt.Errorf("Internal error")
+    if err != nil {
+        return nil, err
     }
 
-    updatedRegistration , err := s.GenericService.Create(claims, entity)
+    email, _ := claims["email"].(string)
+    var created *models.Registration
+    err = s.uow.Do(ctx, func(ctx context.Context) error {
+        var err error
+        if created, err = s.GenericService.WithContext(ctx).Create(claims, entity); err != nil {
+            return err
+        }
+        return s.publish(ctx, eventbus.TypeRegistrationCreated, created, username, email)
+    })
     if err != nil {
         return nil, err
     }
 
-    return updatedRegistration, nil
+    return created, nil
 }
 
-func (s *RegistrationService) Delete(claims jwt.MapClaims, id int) error {
+// Delete cancels the registration of the user for the event, it takes the id of the
+// event and not the one of the registration.
+func (s *RegistrationService) Delete(claims jwt.MapClaims, eventID int) error {
+    return s.DeleteContext(context.Background(), claims, eventID)
+}
 
+// DeleteContext cancels the registration of the user for the event with the given id,
+// its seat goes to the first registration on the waitlist or back to the event.
+// Calls to event-service and the database are made with ctx.
+func (s *RegistrationService) DeleteContext(ctx context.Context, claims jwt.MapClaims, eventID int) error {
     userIDFloat, ok := claims["userID"].(float64)
     if !ok {
         return fmt.Errorf("UserID is not a number")
@@ -93,7 +142,7 @@ func (s *RegistrationService) Delete(claims jwt.MapClaims, id int) error {
     }
  
 
-    existing, err := s.FindFirst(claims, "event_id = ? AND user_id = ?", id, userID)
+    existing, err := s.WithContext(ctx).FindOfUser(uint(eventID), userID)
     if err != nil || existing == nil {
         return fmt.Errorf("You're not registrated")
     }
@@ -102,24 +151,57 @@ func (s *RegistrationService) Delete(claims jwt.MapClaims, id int) error {
         return fmt.Errorf("It's not your registration!")
     }
 
-       
-    resp, err := s.eventClient.RemoveRegistration(context.Background(), uint32(id), username)
-    if err != nil {
-        return err
-    }
-    if resp.Status == events.ReserveStatus_EVENT_NOT_FOUND {
-        return fmt.Errorf("Event with id %d not found", id)
-    }
-
-    if resp.Status == events.ReserveStatus_INTERNAL_ERROR {
-        return fmt.Errorf("Internal error")
-    }
-
-    err = s.GenericService.Delete(claims, int(existing.ID))
-    if err != nil {
-        return err
-    }
+    email, _ := claims["email"].(string)
+    return s.uow.Do(ctx, func(ctx context.Context) error {
+        scoped := s.WithContext(ctx)
+        var promoted *models.Registration
+        var err error
+        if existing.Status == models.StatusWaitlisted {
+            // a waitlisted registration holds no seat to give back
+            err = scoped.GenericService.Delete(claims, int(existing.ID))
+        } else {
+            promoted, err = scoped.repo.CancelAndPromote(existing, func() error {
+                return s.freeSeat(ctx, eventID)
+            })
+        }
+        if err != nil {
+            return err
+        }
+
+        if err := s.publish(ctx, eventbus.TypeRegistrationCancelled, existing, username, email); err != nil {
+            return err
+        }
+        if promoted != nil {
+            // the username and email of the promoted user are only known to notification-service
+            return s.publish(ctx, eventbus.TypeRegistrationPromoted, promoted, "", "")
+        }
+        return nil
+    })
+}
 
-    return nil
+// freeSeat gives the seat of a cancelled registration back to the event
+func (s *RegistrationService) freeSeat(ctx context.Context, eventID int) error {
+    _, err := s.eventClient.RemoveRegistration(ctx, uint32(eventID))
+    switch eventsclient.Outcome(err) {
+    case events.ReserveStatus_EVENT_NOT_FOUND:
+        return fmt.Errorf("Event with id %d not found", eventID)
+    case events.ReserveStatus_NO_PARTICIPANTS:
+        // the counter is already at zero, the registration goes anyway
+        return nil
+    }
+    return err
 }
 
+// publish sends the registration change to the bus in the unit of work of the change,
+// failing to publish it rolls the change back.
+func (s *RegistrationService) publish(ctx context.Context, eventType string, registration *models.Registration, username, email string) error {
+    payload := eventbus.RegistrationPayload{
+        RegistrationID: registration.ID,
+        EventID:        registration.EventID,
+        UserID:         registration.UserID,
+        Username:       username,
+        Email:          email,
+        Status:         registration.Status,
+    }
+    return eventbus.Publish(ctx, s.bus, eventType, payload, eventbus.WithKey(strconv.FormatUint(uint64(registration.EventID), 10)))
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"registration-service/internal/models"
+	"registration-service/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventbus"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventsclient"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeEvents is an event-service answering every call with success
+type fakeEvents struct {
+	calls []string
+}
+
+func (f *fakeEvents) Invoke(_ context.Context, method string, _, _ any, _ ...grpc.CallOption) error {
+	f.calls = append(f.calls, method)
+	return nil
+}
+
+func (f *fakeEvents) NewStream(context.Context, *grpc.StreamDesc, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, fmt.Errorf("streams are not supported")
+}
+
+type testEnv struct {
+	svc    *RegistrationService
+	repo   *repository.RegistrationRepository
+	uow    *db.UnitOfWork
+	events *fakeEvents
+	bus    *eventbus.MemoryBus
+}
+
+func setupTestEnv(t *testing.T) *testEnv {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, conn.AutoMigrate(&models.Registration{}))
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	env := &testEnv{
+		repo:   repository.NewRegistrationRepository(conn),
+		uow:    db.NewUnitOfWork(conn),
+		events: &fakeEvents{},
+		bus:    eventbus.NewMemoryBus(16, eventbus.DefaultRetryPolicy),
+	}
+	env.svc = NewRegistrationService(env.repo, env.uow, eventsclient.NewFromConn(env.events, log), env.bus)
+	return env
+}
+
+func userClaims(userID uint, username string) jwt.MapClaims {
+	return jwt.MapClaims{"userID": float64(userID), "username": username, "email": username + "@example.com"}
+}
+
+func TestCreate_IgnoresStatusAndCheckInOfTheBody(t *testing.T) {
+	env := setupTestEnv(t)
+	checkedInAt := time.Now()
+
+	created, err := env.svc.CreateContext(context.Background(), userClaims(7, "ivan"), &models.Registration{
+		ID:          42,
+		EventID:     1,
+		UserID:      99,
+		Status:      models.StatusAttended,
+		CheckedInAt: &checkedInAt,
+		CheckedInBy: "ivan",
+	})
+	require.NoError(t, err)
+
+	stored, err := env.repo.GetByID(int(created.ID))
+	require.NoError(t, err)
+	assert.NotEqual(t, uint(42), stored.ID)
+	assert.Equal(t, uint(7), stored.UserID)
+	assert.Equal(t, models.StatusRegistered, stored.Status)
+	assert.Nil(t, stored.CheckedInAt)
+	assert.Empty(t, stored.CheckedInBy)
+}
+
+func TestDelete_PassesTheSeatToTheWaitlist(t *testing.T) {
+	env := setupTestEnv(t)
+	_, err := env.repo.Create(&models.Registration{EventID: 1, UserID: 7, Status: models.StatusRegistered})
+	require.NoError(t, err)
+	for _, userID := range []uint{8, 9} {
+		_, err := env.repo.Create(&models.Registration{EventID: 1, UserID: userID, Status: models.StatusWaitlisted})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, env.svc.DeleteContext(context.Background(), userClaims(7, "ivan"), 1))
+
+	first, err := env.svc.FindOfUser(1, 8)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusRegistered, first.Status, "the first on the waitlist takes the seat")
+	second, err := env.svc.FindOfUser(1, 9)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusWaitlisted, second.Status)
+	assert.Empty(t, env.events.calls, "the seat stays taken in event-service")
+
+	published := env.bus.Published()
+	require.Len(t, published, 2)
+	assert.Equal(t, eventbus.TypeRegistrationCancelled, published[0].Type)
+	assert.Equal(t, eventbus.TypeRegistrationPromoted, published[1].Type)
+
+	// the last one registered gives the seat back
+	require.NoError(t, env.svc.DeleteContext(context.Background(), userClaims(9, "anna"), 1))
+	require.NoError(t, env.svc.DeleteContext(context.Background(), userClaims(8, "petr"), 1))
+	assert.Equal(t, []string{"/events.EventService/RemoveRegistration"}, env.events.calls)
+}
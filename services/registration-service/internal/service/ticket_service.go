@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"registration-service/internal/models"
+	"registration-service/internal/repository"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/db"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventbus"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/eventsclient"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/protos/events"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+var (
+    ErrRegistrationNotFound = errors.New("registration not found")
+    ErrEventNotFound        = errors.New("event not found")
+    ErrEventEnded           = errors.New("event has already ended")
+    ErrInvalidTicket        = errors.New("invalid ticket")
+    ErrNotOrganizer         = errors.New("only the organizers of the event can do this")
+    ErrAlreadyCheckedIn     = errors.New("ticket is already checked in")
+    ErrWaitlisted           = errors.New("registration is on the waitlist")
+)
+
+// EventGetter is implemented by the event-service gRPC client.
+type EventGetter interface {
+    GetEvent(ctx context.Context, eventID uint32) (*events.GetEventResponse, error)
+}
+
+// TicketOptions configures tickets
+type TicketOptions struct {
+    // ValidAfterEnd is how long a ticket stays valid after the end of the event
+    ValidAfterEnd time.Duration
+}
+
+// Ticket is a signed ticket for a registration. The token is an ES256 JWT,
+// so it can be verified offline with the public key of the service.
+type Ticket struct {
+    Token          string    `json:"token"`
+    RegistrationID uint      `json:"registration_id"`
+    EventID        uint      `json:"event_id"`
+    EventName      string    `json:"event_name"`
+    Username       string    `json:"username"`
+    ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// CheckIn is the result of a successful check-in
+type CheckIn struct {
+    Registration *models.Registration `json:"registration"`
+    Username     string               `json:"username"`
+}
+
+// AttendanceStats describes the attendance of an event
+type AttendanceStats struct {
+    EventID        uint    `json:"event_id"`
+    Registered     int64   `json:"registered"`
+    Attended       int64   `json:"attended"`
+    NotAttended    int64   `json:"not_attended"`
+    AttendanceRate float64 `json:"attendance_rate"`
+}
+
+// TicketService issues signed tickets and checks attendees in.
+type TicketService struct {
+    registrations *repository.RegistrationRepository
+    events        EventGetter
+    signer        *auth.Signer
+    verifier      *auth.Verifier
+    uow           *db.UnitOfWork
+    bus           eventbus.Publisher
+    opts          TicketOptions
+}
+
+func NewTicketService(
+    registrations *repository.RegistrationRepository,
+    events EventGetter,
+    signer *auth.Signer,
+    uow *db.UnitOfWork,
+    bus eventbus.Publisher,
+    opts TicketOptions,
+) *TicketService {
+    return &TicketService{
+        registrations: registrations,
+        events:        events,
+        signer:        signer,
+        verifier:      signer.Verifier(),
+        uow:           uow,
+        bus:           bus,
+        opts:          opts,
+    }
+}
+
+// PublicKey returns the key that verifies tickets.
+func (s *TicketService) PublicKey() auth.JWK {
+    return s.verifier.JWK()
+}
+
+// Issue signs a ticket for the registration of the current user.
+// The ticket expires ValidAfterEnd after the end of the event.
+func (s *TicketService) Issue(ctx context.Context, claims jwt.MapClaims, registrationID uint) (*Ticket, error) {
+    userID, err := userIDFromClaims(claims)
+    if err != nil {
+        return nil, err
+    }
+    username, ok := claims["username"].(string)
+    if !ok {
+        return nil, fmt.Errorf("invalid token: username not found or not a string")
+    }
+
+    registration, err := s.registrations.GetByID(int(registrationID))
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, ErrRegistrationNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    // Someone else's registration looks the same as a missing one
+    if registration.UserID != userID {
+        return nil, ErrRegistrationNotFound
+    }
+    if registration.Status == models.StatusWaitlisted {
+        return nil, ErrWaitlisted
+    }
+
+    event, err := s.getEvent(ctx, registration.EventID)
+    if err != nil {
+        return nil, err
+    }
+    expiresAt := event.EndTime.AsTime().Add(s.opts.ValidAfterEnd)
+    if !expiresAt.After(time.Now()) {
+        return nil, ErrEventEnded
+    }
+
+    token, err := s.signer.Sign(jwt.MapClaims{
+        "aud":      auth.TicketAudience,
+        "sub":      strconv.FormatUint(uint64(registration.ID), 10),
+        "event_id": registration.EventID,
+        "user_id":  registration.UserID,
+        "username": username,
+        "iat":      time.Now().Unix(),
+        "exp":      expiresAt.Unix(),
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to sign ticket: %w", err)
+    }
+
+    return &Ticket{
+        Token:          token,
+        RegistrationID: registration.ID,
+        EventID:        registration.EventID,
+        EventName:      event.Name,
+        Username:       username,
+        ExpiresAt:      expiresAt,
+    }, nil
+}
+
+// CheckIn validates the ticket and marks its registration attended.
+// Only the organizers of the event can check attendees in, and every ticket is accepted once.
+func (s *TicketService) CheckIn(ctx context.Context, claims jwt.MapClaims, token string) (*CheckIn, error) {
+    ticket, err := s.parse(token)
+    if err != nil {
+        return nil, err
+    }
+    organizer, err := s.organizerOf(ctx, claims, ticket.EventID)
+    if err != nil {
+        return nil, err
+    }
+
+    registration, err := s.registrations.GetByID(int(ticket.RegistrationID))
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, fmt.Errorf("%w: registration was cancelled", ErrInvalidTicket)
+    }
+    if err != nil {
+        return nil, err
+    }
+    if registration.EventID != ticket.EventID || registration.UserID != ticket.UserID {
+        return nil, ErrInvalidTicket
+    }
+
+    err = s.uow.Do(ctx, func(ctx context.Context) error {
+        registrations := &repository.RegistrationRepository{GenericRepository: s.registrations.WithContext(ctx)}
+        updated, err := registrations.CheckIn(registration.ID, organizer, time.Now().UTC())
+        if err != nil {
+            return err
+        }
+        if !updated {
+            // Reload to tell a second scan apart from a registration in another status
+            registration, err = registrations.GetByID(int(registration.ID))
+            if err != nil {
+                return err
+            }
+            if registration.Status == models.StatusAttended && registration.CheckedInAt != nil {
+                return fmt.Errorf("%w at %s", ErrAlreadyCheckedIn, registration.CheckedInAt.Format(time.RFC3339))
+            }
+            return fmt.Errorf("%w: registration is %s", ErrInvalidTicket, registration.Status)
+        }
+
+        registration, err = registrations.GetByID(int(registration.ID))
+        if err != nil {
+            return err
+        }
+        return s.publish(ctx, registration, ticket.Username)
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return &CheckIn{
+        Registration: registration,
+        Username:     ticket.Username,
+    }, nil
+}
+
+// Stats returns the attendance of the event to its organizers.
+func (s *TicketService) Stats(ctx context.Context, claims jwt.MapClaims, eventID uint) (*AttendanceStats, error) {
+    if _, err := s.organizerOf(ctx, claims, eventID); err != nil {
+        return nil, err
+    }
+
+    counts, err := s.registrations.CountByStatus(eventID)
+    if err != nil {
+        return nil, err
+    }
+    stats := &AttendanceStats{
+        EventID:  eventID,
+        Attended: counts[models.StatusAttended],
+    }
+    stats.Registered = counts[models.StatusRegistered] + stats.Attended
+    stats.NotAttended = stats.Registered - stats.Attended
+    if stats.Registered > 0 {
+        stats.AttendanceRate = float64(stats.Attended) / float64(stats.Registered)
+    }
+    return stats, nil
+}
+
+type ticketClaims struct {
+    RegistrationID uint
+    EventID        uint
+    UserID         uint
+    Username       string
+}
+
+// parse verifies the signature, audience and expiry of the ticket.
+func (s *TicketService) parse(token string) (*ticketClaims, error) {
+    claims, err := s.verifier.VerifyAudience(token, auth.TicketAudience)
+    if err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidTicket, err)
+    }
+
+    subject, _ := claims.GetSubject()
+    registrationID, err := strconv.ParseUint(subject, 10, 64)
+    if err != nil {
+        return nil, fmt.Errorf("%w: bad subject", ErrInvalidTicket)
+    }
+    eventID, ok1 := claims["event_id"].(float64)
+    userID, ok2 := claims["user_id"].(float64)
+    username, ok3 := claims["username"].(string)
+    if !ok1 || !ok2 || !ok3 {
+        return nil, fmt.Errorf("%w: missing claims", ErrInvalidTicket)
+    }
+
+    return &ticketClaims{
+        RegistrationID: uint(registrationID),
+        EventID:        uint(eventID),
+        UserID:         uint(userID),
+        Username:       username,
+    }, nil
+}
+
+// organizerOf returns the username of the caller if they manage the event, following
+// the rule of event-service: the owners and organizers of the organization of the event,
+// its creator for an event without one, and users granted to update any event.
+func (s *TicketService) organizerOf(ctx context.Context, claims jwt.MapClaims, eventID uint) (string, error) {
+    username, ok := claims["username"].(string)
+    if !ok {
+        return "", fmt.Errorf("invalid token: username not found or not a string")
+    }
+    event, err := s.getEvent(ctx, eventID)
+    if err != nil {
+        return "", err
+    }
+    switch {
+    case auth.HasPermission(claims, auth.PermEventsUpdateAny):
+    case event.OrganizationId != 0:
+        if !auth.ManagesOrgEvents(claims, uint(event.OrganizationId)) {
+            return "", ErrNotOrganizer
+        }
+    case strings.TrimSpace(event.CreatedBy) != strings.TrimSpace(username):
+        return "", ErrNotOrganizer
+    }
+    return username, nil
+}
+
+func (s *TicketService) getEvent(ctx context.Context, eventID uint) (*events.Event, error) {
+    resp, err := s.events.GetEvent(ctx, uint32(eventID))
+    if eventsclient.Outcome(err) == events.ReserveStatus_EVENT_NOT_FOUND {
+        return nil, ErrEventNotFound
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to get event: %w", err)
+    }
+    return resp.Event, nil
+}
+
+// publish sends RegistrationAttended to the bus in the unit of work of the check-in.
+func (s *TicketService) publish(ctx context.Context, registration *models.Registration, username string) error {
+    payload := eventbus.RegistrationPayload{
+        RegistrationID: registration.ID,
+        EventID:        registration.EventID,
+        UserID:         registration.UserID,
+        Username:       username,
+        Status:         registration.Status,
+    }
+    return eventbus.Publish(ctx, s.bus, eventbus.TypeRegistrationAttended, payload, eventbus.WithKey(strconv.FormatUint(uint64(registration.EventID), 10)))
+}
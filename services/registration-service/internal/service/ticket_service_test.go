@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"registration-service/internal/models"
+	"testing"
+	"time"
+
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/auth"
+	"github.com/evgeniyfimushkin/event-planner/services/common/pkg/protos/events"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type fakeEventGetter map[uint32]*events.Event
+
+func (f fakeEventGetter) GetEvent(_ context.Context, eventID uint32) (*events.GetEventResponse, error) {
+	return &events.GetEventResponse{Status: events.ReserveStatus_SUCCESS, Event: f[eventID]}, nil
+}
+
+func newTestSigner(t *testing.T) *auth.Signer {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	signer, err := auth.NewSigner(base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})))
+	require.NoError(t, err)
+	return signer
+}
+
+// setupTicketTest registers ivan for an event of organization 3 created by olga
+func setupTicketTest(t *testing.T) (*TicketService, *models.Registration) {
+	env := setupTestEnv(t)
+	registration, err := env.repo.Create(&models.Registration{EventID: 1, UserID: 7, Status: models.StatusRegistered})
+	require.NoError(t, err)
+
+	getter := fakeEventGetter{1: {
+		Id:             1,
+		Name:           "Баскетбол",
+		CreatedBy:      "olga",
+		OrganizationId: 3,
+		StartTime:      timestamppb.New(time.Now().Add(time.Hour)),
+		EndTime:        timestamppb.New(time.Now().Add(2 * time.Hour)),
+	}}
+	svc := NewTicketService(env.repo, getter, newTestSigner(t), env.uow, env.bus, TicketOptions{ValidAfterEnd: time.Hour})
+	return svc, registration
+}
+
+func TestCheckIn_Organizers(t *testing.T) {
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		allowed bool
+	}{
+		{"organizer of the organization", jwt.MapClaims{"username": "petr", auth.OrgsClaim: map[string]string{"3": auth.OrgOrganizer}}, true},
+		{"owner of the organization", jwt.MapClaims{"username": "anna", auth.OrgsClaim: map[string]string{"3": auth.OrgOwner}}, true},
+		{"moderator", jwt.MapClaims{"username": "max", auth.PermissionsClaim: []string{auth.PermEventsUpdateAny}}, true},
+		{"viewer of the organization", jwt.MapClaims{"username": "oleg", auth.OrgsClaim: map[string]string{"3": auth.OrgViewer}}, false},
+		{"organizer of another organization", jwt.MapClaims{"username": "petr", auth.OrgsClaim: map[string]string{"4": auth.OrgOrganizer}}, false},
+		{"creator no longer managing the organization", jwt.MapClaims{"username": "olga"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, registration := setupTicketTest(t)
+			ticket, err := svc.Issue(context.Background(), userClaims(7, "ivan"), registration.ID)
+			require.NoError(t, err)
+
+			checkIn, err := svc.CheckIn(context.Background(), tt.claims, ticket.Token)
+			if !tt.allowed {
+				assert.ErrorIs(t, err, ErrNotOrganizer)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, models.StatusAttended, checkIn.Registration.Status)
+			assert.Equal(t, tt.claims["username"], checkIn.Registration.CheckedInBy)
+		})
+	}
+}
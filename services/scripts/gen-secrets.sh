@@ -0,0 +1,69 @@
+#!/bin/sh
+# Writes the secrets docker-compose mounts into the services to the given directory,
+# ./secrets by default. PRIVATE_KEY, PUBLIC_KEY, TICKET_PRIVATE_KEY and POSTGRES_PASSWORD
+# are written when set, otherwise the files already there are kept and the missing ones
+# are generated, the gRPC certificates in the grpc subdirectory included.
+set -eu
+
+dir="${1:-secrets}"
+mkdir -p "$dir"
+# compose bind mounts the files, postgres reads its password as a non-root user,
+# so the directory rather than the files keeps them private on the host
+chmod 700 "$dir"
+
+if [ -n "${PRIVATE_KEY:-}" ]; then
+    printf '%s' "$PRIVATE_KEY" > "$dir/private_key"
+    printf '%s' "${PUBLIC_KEY:?PUBLIC_KEY is required with PRIVATE_KEY}" > "$dir/public_key"
+elif [ ! -f "$dir/private_key" ]; then
+    # the services expect base64 encoded PEM of an ecdsa P-256 key
+    pem="$(openssl ecparam -name prime256v1 -genkey -noout)"
+    printf '%s\n' "$pem" | base64 | tr -d '\n' > "$dir/private_key"
+    printf '%s\n' "$pem" | openssl ec -pubout 2>/dev/null | base64 | tr -d '\n' > "$dir/public_key"
+fi
+
+# registration-service signs tickets with a key of its own, never the access token key
+if [ -n "${TICKET_PRIVATE_KEY:-}" ]; then
+    printf '%s' "$TICKET_PRIVATE_KEY" > "$dir/ticket_private_key"
+elif [ ! -f "$dir/ticket_private_key" ]; then
+    openssl ecparam -name prime256v1 -genkey -noout | base64 | tr -d '\n' > "$dir/ticket_private_key"
+fi
+
+if [ -n "${POSTGRES_PASSWORD:-}" ]; then
+    printf '%s' "$POSTGRES_PASSWORD" > "$dir/db_password"
+elif [ ! -f "$dir/db_password" ]; then
+    openssl rand -hex 16 | tr -d '\n' > "$dir/db_password"
+fi
+
+# services calling others over gRPC get service tokens from auth-service with these
+for service in registration chat; do
+    if [ ! -f "$dir/${service}_service_secret" ]; then
+        openssl rand -hex 32 | tr -d '\n' > "$dir/${service}_service_secret"
+    fi
+done
+printf 'registration-service:%s,chat-service:%s' \
+    "$(cat "$dir/registration_service_secret")" "$(cat "$dir/chat_service_secret")" > "$dir/service_clients"
+
+# the gRPC channels between services use mutual TLS: a CA and a certificate per service,
+# named after the service, used as both its server and its client certificate. The layout
+# is the one of `go run ./common/cmd/devca`, which can issue them as well
+grpc="$dir/grpc"
+mkdir -p "$grpc"
+if [ ! -f "$grpc/ca.crt" ]; then
+    openssl ecparam -name prime256v1 -genkey -noout -out "$grpc/ca.key"
+    openssl req -x509 -new -key "$grpc/ca.key" -subj "/CN=event-planner CA" -days 825 \
+        -addext "basicConstraints=critical,CA:TRUE" -addext "keyUsage=critical,keyCertSign,cRLSign" \
+        -out "$grpc/ca.crt"
+fi
+for service in event-service registration-service chat-service; do
+    if [ ! -f "$grpc/$service.crt" ]; then
+        openssl ecparam -name prime256v1 -genkey -noout -out "$grpc/$service.key"
+        printf 'subjectAltName=DNS:%s,DNS:localhost,IP:127.0.0.1\nkeyUsage=critical,digitalSignature\nextendedKeyUsage=serverAuth,clientAuth\n' \
+            "$service" > "$grpc/$service.ext"
+        openssl req -new -key "$grpc/$service.key" -subj "/CN=$service" |
+            openssl x509 -req -CA "$grpc/ca.crt" -CAkey "$grpc/ca.key" -set_serial "0x$(openssl rand -hex 16)" \
+                -days 825 -extfile "$grpc/$service.ext" -out "$grpc/$service.crt" 2>/dev/null
+        rm "$grpc/$service.ext"
+    fi
+done
+
+echo "secrets are in $dir"